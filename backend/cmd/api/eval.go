@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"triploom/backend/internal/ai"
+	"triploom/backend/internal/eval"
+	"triploom/backend/internal/prompts"
+	"triploom/backend/internal/providers/openai"
+)
+
+const evalUsage = "usage: api eval [-provider openai|offline] [-model m] [-prompt name@vN]... [-prompts-dir dir] [-judge-model m] [-guardrails policy] [-out path] suite.yaml..."
+
+// stringList collects a repeatable flag.
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+// runEval implements `api eval ...`: it runs YAML suites through the assistant, writes
+// <out>.json and <out>.md, and fails when any case fails.
+func runEval(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("eval", flag.ContinueOnError)
+	provider := fs.String("provider", "openai", "openai, or offline for the local fallback answers")
+	model := fs.String("model", getenvOr("OPENAI_MODEL_DEFAULT", "gpt-5-mini"), "model to answer with")
+	promptsDir := fs.String("prompts-dir", os.Getenv("PROMPTS_DIR"), "directory of prompt template overrides")
+	judgeModel := fs.String("judge-model", "", "model that grades answers against each case's rubric (requires OPENAI_API_KEY)")
+	guardrails := fs.String("guardrails", ai.GuardrailOff, "guardrail policy to answer with: off scores the model's raw answers; retry, rewrite or flag score what users see")
+	out := fs.String("out", "eval-report", "report path without extension")
+	var pins stringList
+	fs.Var(&pins, "prompt", "prompt version to run every case with, e.g. copilot@v2 (repeatable)")
+	if err := fs.Parse(args); err != nil {
+		return errors.New(evalUsage)
+	}
+	if fs.NArg() == 0 {
+		return errors.New(evalUsage)
+	}
+	switch *guardrails {
+	case ai.GuardrailRetry, ai.GuardrailRewrite, ai.GuardrailFlag, ai.GuardrailOff:
+	default:
+		return errors.New(evalUsage)
+	}
+
+	suites := make([]*eval.Suite, 0, fs.NArg())
+	for _, path := range fs.Args() {
+		suite, err := eval.LoadSuite(path)
+		if err != nil {
+			return err
+		}
+		suites = append(suites, suite)
+	}
+
+	defs := prompts.Bundled()
+	if *promptsDir != "" {
+		dirDefs, err := prompts.ParseFS(os.DirFS(*promptsDir))
+		if err != nil {
+			return err
+		}
+		defs = append(defs, dirDefs...)
+	}
+	for _, id := range pins {
+		var err error
+		if defs, err = prompts.Pin(defs, id); err != nil {
+			return err
+		}
+	}
+	registry, err := prompts.New(defs)
+	if err != nil {
+		return err
+	}
+
+	var client *openai.Client
+	if key := os.Getenv("OPENAI_API_KEY"); key != "" {
+		client = openai.NewClient(key)
+	}
+	runner := &eval.Runner{Model: *model, Prompts: registry, Guardrails: *guardrails}
+	switch *provider {
+	case "openai":
+		if client == nil {
+			return errors.New("-provider openai requires OPENAI_API_KEY")
+		}
+		runner.LLM = client
+	case "offline":
+		runner.LLM = eval.OfflineLLM{}
+	default:
+		return errors.New(evalUsage)
+	}
+	if *judgeModel != "" {
+		if client == nil {
+			return errors.New("-judge-model requires OPENAI_API_KEY")
+		}
+		runner.Judge = &eval.LLMJudge{LLM: client, Model: *judgeModel}
+	}
+
+	results, err := runner.Run(ctx, suites)
+	if err != nil {
+		return err
+	}
+	report := eval.NewReport(*provider, *model, *judgeModel, results)
+	if err := writeReport(*out+".json", report.WriteJSON); err != nil {
+		return err
+	}
+	if err := writeReport(*out+".md", report.WriteMarkdown); err != nil {
+		return err
+	}
+	log.Printf("eval: %d/%d cases passed, report written to %s.json and %s.md", report.Passed, report.Total, *out, *out)
+	if report.Failed > 0 {
+		return fmt.Errorf("%d of %d cases failed", report.Failed, report.Total)
+	}
+	return nil
+}
+
+func writeReport(path string, write func(io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func getenvOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
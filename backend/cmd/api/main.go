@@ -3,46 +3,170 @@ package main
 import (
 	"context"
 	"log"
+	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
 
 	"triploom/backend/internal/ai"
+	"triploom/backend/internal/cache"
+	"triploom/backend/internal/cassette"
 	"triploom/backend/internal/config"
+	"triploom/backend/internal/events"
+	"triploom/backend/internal/flightwatch"
 	"triploom/backend/internal/http"
+	"triploom/backend/internal/knowledge"
+	"triploom/backend/internal/live"
+	"triploom/backend/internal/migrate"
+	"triploom/backend/internal/prompts"
+	"triploom/backend/internal/providers/flightstatus"
 	"triploom/backend/internal/providers/nextbridge"
 	"triploom/backend/internal/providers/openai"
+	"triploom/backend/internal/providers/serpflights"
 	"triploom/backend/internal/store"
+	"triploom/backend/internal/tripevents"
+	"triploom/backend/internal/webhooks"
 )
 
 func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(ctx, os.Args[2:]); err != nil {
+			log.Fatalf("migrate: %v", err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		if err := runReplay(ctx, os.Args[2:]); err != nil {
+			log.Fatalf("replay: %v", err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "eval" {
+		if err := runEval(ctx, os.Args[2:]); err != nil {
+			log.Fatalf("eval: %v", err)
+		}
+		return
+	}
+
 	cfg, err := config.Load()
 	if err != nil {
 		log.Fatalf("load config: %v", err)
 	}
 
-	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
-	defer stop()
-
-	var repo *store.AIRepository
+	var repo store.AIStore
+	var watchRepo store.FlightWatchStore
+	var eventRepo store.TripEventStore
+	var webhookRepo store.WebhookStore
+	var proposalRepo store.ProposalStore
+	var preferenceRepo store.PreferenceStore
+	var db *pgxpool.Pool
 	if cfg.UseSupabase {
-		db, err := store.NewPostgres(ctx, cfg.SupabaseDBURL)
+		db, err = store.NewPostgres(ctx, cfg.SupabaseDBURL)
 		if err != nil {
 			log.Fatalf("connect db: %v", err)
 		}
 		defer db.Close()
+		ensureSchema(ctx, cfg, migrate.NewPostgres(db))
 		repo = store.NewAIRepository(db)
+		watchRepo = store.NewFlightWatchRepository(db)
+		eventRepo = store.NewTripEventRepository(db)
+		webhookRepo = store.NewWebhookRepository(db)
+		proposalRepo = store.NewProposalRepository(db)
+		preferenceRepo = store.NewPreferenceRepository(db)
 		log.Printf("running with Supabase/Postgres persistence enabled")
+	} else if cfg.SQLitePath != "" {
+		lite, err := store.OpenSQLite(ctx, cfg.SQLitePath)
+		if err != nil {
+			log.Fatalf("open sqlite: %v", err)
+		}
+		defer lite.Close()
+		ensureSchema(ctx, cfg, migrate.NewSQLite(lite))
+		repo = store.NewSQLiteAIRepository(lite)
+		watchRepo = store.NewSQLiteFlightWatchRepository(lite)
+		eventRepo = store.NewSQLiteTripEventRepository(lite)
+		webhookRepo = store.NewSQLiteWebhookRepository(lite)
+		proposalRepo = store.NewSQLiteProposalRepository(lite)
+		preferenceRepo = store.NewSQLitePreferenceRepository(lite)
+		if cfg.JWTAuth {
+			log.Printf("running with SQLite persistence at %s", cfg.SQLitePath)
+		} else {
+			log.Printf("running with SQLite persistence at %s: INSECURE_TEST_AUTH trusts X-User-Id, keep the API behind your own auth proxy", cfg.SQLitePath)
+		}
 	} else {
 		repo = store.NewInMemoryAIRepository()
+		watchRepo = store.NewInMemoryFlightWatchRepository()
+		memoryWebhooks := store.NewInMemoryWebhookRepository()
+		eventRepo = store.NewInMemoryTripEventRepository(memoryWebhooks)
+		webhookRepo = memoryWebhooks
+		proposalRepo = store.NewInMemoryProposalRepository()
+		preferenceRepo = store.NewInMemoryPreferenceRepository()
 		log.Printf("running in test mode: Supabase auth and persistence are disabled")
 	}
 
 	oa := openai.NewClient(cfg.OpenAIAPIKey)
 	next := nextbridge.NewClient(cfg.NextAPIBaseURL)
-	modelSelector := ai.NewModelSelector(cfg.OpenAIModelDefault)
-	aiService := ai.NewService(repo, oa, next, modelSelector)
+	modelSelector := ai.NewModelSelector(cfg.OpenAIModelDefault, cfg.OpenAIModels...)
+
+	tripEvents := tripevents.NewRecorder(eventRepo)
+	hooks := webhooks.NewService(repo, webhookRepo)
+	bus := newEventBus(ctx, cfg, db)
+	tripEvents.AddSink(tripevents.NewBusSink(bus))
+	hub := live.NewHub()
+	tripevents.Forward(bus, hub)
+	go webhooks.NewWorker(webhookRepo, nil).Run(ctx)
 
-	app, err := http.NewRouter(cfg, aiService, repo)
+	sanitize, err := ai.ParseSanitizePolicies(cfg.AISanitize)
+	if err != nil {
+		log.Fatalf("ai sanitize: %v", err)
+	}
+	opts := []ai.Option{ai.WithTripEvents(tripEvents), ai.WithPrompts(newPromptRegistry(ctx, cfg, repo)), ai.WithGuardrails(cfg.AIGuardrails), ai.WithSanitizePolicies(sanitize), ai.WithProposals(proposalRepo), ai.WithPreferences(preferenceRepo), ai.WithRetrievalBudget(cfg.AIRetrievalTokens)}
+	if cfg.CassetteDir != "" {
+		opts = append(opts, ai.WithCassettes(cassette.NewRecorder(cfg.CassetteDir)))
+		log.Printf("recording assistant requests to %s: cassettes contain prompts, trip context and answers verbatim", cfg.CassetteDir)
+	}
+	if c := newProviderCache(ctx, cfg, db); c != nil {
+		opts = append(opts, ai.WithCache(c))
+	}
+	if kb := newKnowledgeBase(ctx, cfg, oa); kb != nil {
+		opts = append(opts, ai.WithKnowledge(kb))
+	}
+	opts = append(opts, newFallbackChain(cfg, oa))
+	flightStatus, err := flightstatus.New(flightstatus.Config{
+		Provider:             cfg.FlightStatusProvider,
+		AeroAPIKey:           cfg.AeroAPIKey,
+		AeroAPIBaseURL:       cfg.AeroAPIBaseURL,
+		AviationstackKey:     cfg.FlightStatusAPIKey,
+		AviationstackBaseURL: cfg.FlightStatusBaseURL,
+	})
+	if err != nil {
+		log.Fatalf("flight status provider: %v", err)
+	}
+	if flightStatus != nil {
+		opts = append(opts, ai.WithFlightStatusProvider(flightStatus))
+		log.Printf("flight status provider: %s", flightStatus.Name())
+		go flightwatch.NewPoller(flightStatus, watchRepo, tripEvents).Run(ctx)
+	} else {
+		log.Printf("flight status provider: none configured, proxying through Next bridge; flight watches will not be polled")
+	}
+
+	aiService := ai.NewService(repo, oa, next, modelSelector, opts...)
+
+	var serp *serpflights.Client
+	if cfg.SerpAPIKey != "" {
+		serp = serpflights.NewClient(cfg.SerpAPIBaseURL, cfg.SerpAPIKey, nil)
+	} else {
+		log.Printf("SERPAPI_API_KEY not set: /v1/flights endpoints will return 503")
+	}
+
+	watches := flightwatch.NewService(repo, watchRepo, tripEvents)
+
+	app, err := http.NewRouter(cfg, aiService, repo, serp, watches, hooks, live.NewServer(hub, repo, tripEvents))
 	if err != nil {
 		log.Fatalf("create router: %v", err)
 	}
@@ -57,3 +181,141 @@ func main() {
 		log.Fatalf("listen: %v", err)
 	}
 }
+
+func newEventBus(ctx context.Context, cfg *config.Config, db *pgxpool.Pool) events.Bus {
+	if cfg.EventBus == "postgres" {
+		pg := events.NewPostgres(db, events.DefaultChannel)
+		go pg.Run(ctx)
+		log.Printf("event bus: postgres LISTEN/NOTIFY on %q", events.DefaultChannel)
+		return pg
+	}
+	log.Printf("event bus: in-process (single instance)")
+	return events.NewMemory()
+}
+
+// newPromptRegistry serves the bundled prompt templates, overridden by PROMPTS_DIR and then by
+// ai_prompt_templates rows. The overrides are reloaded every minute so that wording and A/B
+// weights change without a deploy; a reload that fails to parse keeps the previous set.
+func newPromptRegistry(ctx context.Context, cfg *config.Config, repo store.PromptStore) *prompts.Registry {
+	load := func() ([]prompts.Definition, error) {
+		defs := prompts.Bundled()
+		if cfg.PromptsDir != "" {
+			dirDefs, err := prompts.ParseFS(os.DirFS(cfg.PromptsDir))
+			if err != nil {
+				return nil, err
+			}
+			defs = append(defs, dirDefs...)
+		}
+		rows, err := repo.ListPromptTemplates(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			defs = append(defs, prompts.Definition{Name: row.Name, Version: row.Version, Body: row.Body, Weight: row.Weight})
+		}
+		return defs, nil
+	}
+	defs, err := load()
+	if err != nil {
+		log.Fatalf("prompts: %v", err)
+	}
+	reg, err := prompts.New(defs)
+	if err != nil {
+		log.Fatalf("prompts: %v", err)
+	}
+	for _, name := range []string{"copilot", "planner"} {
+		for _, def := range reg.Versions(name) {
+			log.Printf("prompt %s (weight %d)", def.ID(), def.Weight)
+		}
+	}
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				defs, err := load()
+				if err == nil {
+					err = reg.Replace(defs)
+				}
+				if err != nil {
+					log.Printf("prompts reload: %v", err)
+				}
+			}
+		}
+	}()
+	return reg
+}
+
+// newFallbackChain parses AI_FALLBACK_CHAIN against the configured providers.
+func newFallbackChain(cfg *config.Config, oa *openai.Client) ai.Option {
+	chain, err := ai.ParseFallbackChain(cfg.AIFallbackChain)
+	if err != nil {
+		log.Fatalf("ai fallback chain: %v", err)
+	}
+	providers := map[string]ai.LLM{ai.ProviderOpenAI: oa}
+	if cfg.AICompatibleBaseURL != "" {
+		providers["compatible"] = openai.NewCompatibleClient(cfg.AICompatibleBaseURL, cfg.AICompatibleAPIKey)
+	}
+	steps := make([]string, 0, len(chain))
+	for _, a := range chain {
+		if _, ok := providers[a.Provider]; !ok {
+			log.Fatalf("ai fallback chain: unknown provider %q (set AI_COMPATIBLE_BASE_URL for \"compatible\")", a.Provider)
+		}
+		steps = append(steps, a.String())
+	}
+	log.Printf("ai fallback chain: %s, then the local answer", strings.Join(steps, " -> "))
+	return ai.WithFallbackChain(chain, providers)
+}
+
+// newKnowledgeBase indexes the guides in KNOWLEDGE_DIR, or returns nil when it is not set.
+func newKnowledgeBase(ctx context.Context, cfg *config.Config, oa *openai.Client) *knowledge.Base {
+	if cfg.KnowledgeDir == "" {
+		return nil
+	}
+	var embedder knowledge.Embedder = knowledge.NewHashingEmbedder(0)
+	if cfg.KnowledgeEmbedder == "openai" {
+		embedder = knowledge.NewOpenAIEmbedder(oa, cfg.KnowledgeEmbeddingModel)
+	}
+	kb, err := knowledge.Load(ctx, cfg.KnowledgeDir, embedder)
+	if err != nil {
+		log.Fatalf("knowledge base: %v", err)
+	}
+	log.Printf("knowledge base: %d passages from %s, embedded with %s", kb.Len(), cfg.KnowledgeDir, embedder.Name())
+	return kb
+}
+
+func newProviderCache(ctx context.Context, cfg *config.Config, db *pgxpool.Pool) *cache.Cache {
+	ttls, err := cache.ParseTTLs(cfg.CacheTTLs)
+	if err != nil {
+		log.Fatalf("cache ttls: %v", err)
+	}
+	switch cfg.CacheBackend {
+	case "off":
+		log.Printf("provider cache disabled")
+		return nil
+	case "postgres":
+		pg := cache.NewPostgres(db)
+		go func() {
+			ticker := time.NewTicker(10 * time.Minute)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					if _, err := pg.DeleteExpired(ctx); err != nil {
+						log.Printf("provider cache cleanup: %v", err)
+					}
+				}
+			}
+		}()
+		log.Printf("provider cache: postgres")
+		return cache.New(pg, ttls)
+	default:
+		log.Printf("provider cache: in-process LRU (%d entries)", cfg.CacheMaxEntries)
+		return cache.New(cache.NewLRU(cfg.CacheMaxEntries), ttls)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"triploom/backend/internal/config"
+	"triploom/backend/internal/migrate"
+	"triploom/backend/internal/store"
+	"triploom/backend/migrations"
+)
+
+const migrateUsage = "usage: api migrate up | down [steps] | status | baseline <version>"
+
+// runMigrate implements `api migrate ...` against SUPABASE_DB_URL or DATABASE_URL=sqlite://.
+func runMigrate(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+	cfg, err := config.LoadDatabase()
+	if err != nil {
+		return err
+	}
+
+	var driver migrate.Driver
+	switch {
+	case cfg.SupabaseDBURL != "" && cfg.SQLitePath != "":
+		return errors.New("set either SUPABASE_DB_URL or DATABASE_URL, not both")
+	case cfg.SupabaseDBURL != "":
+		db, err := store.NewPostgres(ctx, cfg.SupabaseDBURL)
+		if err != nil {
+			return fmt.Errorf("connect db: %w", err)
+		}
+		defer db.Close()
+		driver = migrate.NewPostgres(db)
+	case cfg.SQLitePath != "":
+		db, err := store.OpenSQLite(ctx, cfg.SQLitePath)
+		if err != nil {
+			return fmt.Errorf("open sqlite: %w", err)
+		}
+		defer db.Close()
+		driver = migrate.NewSQLite(db)
+	default:
+		return errors.New("no database configured: set SUPABASE_DB_URL or DATABASE_URL=sqlite://...")
+	}
+	runner, err := newMigrationRunner(driver)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := runner.Up(ctx)
+		logMigrations("applied", applied)
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps <= 0 {
+				return errors.New(migrateUsage)
+			}
+		}
+		reverted, err := runner.Down(ctx, steps)
+		logMigrations("reverted", reverted)
+		return err
+	case "baseline":
+		if len(args) < 2 {
+			return errors.New(migrateUsage)
+		}
+		version, err := strconv.Atoi(args[1])
+		if err != nil {
+			return errors.New(migrateUsage)
+		}
+		marked, err := runner.Baseline(ctx, version)
+		logMigrations("marked as applied", marked)
+		return err
+	case "status":
+		statuses, err := runner.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, s := range statuses {
+			at := "pending"
+			if s.AppliedAt != nil {
+				at = s.AppliedAt.Local().Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%03d\t%s\t%s\n", s.Version, s.Name, at)
+		}
+		return w.Flush()
+	default:
+		return errors.New(migrateUsage)
+	}
+}
+
+// ensureSchema refuses to serve against a schema that does not match this binary, applying
+// pending migrations first when MIGRATE_ON_START is set.
+func ensureSchema(ctx context.Context, cfg *config.Config, driver migrate.Driver) {
+	runner, err := newMigrationRunner(driver)
+	if err != nil {
+		log.Fatalf("load migrations: %v", err)
+	}
+	if cfg.MigrateOnStart {
+		applied, err := runner.Up(ctx)
+		logMigrations("applied", applied)
+		if err != nil {
+			log.Fatalf("migrate: %v", err)
+		}
+	}
+	if err := runner.Check(ctx); err != nil {
+		if errors.Is(err, migrate.ErrPending) {
+			log.Fatalf("%v: run `api migrate up` (or `api migrate baseline <version>` for a schema applied by hand), or set MIGRATE_ON_START=true", err)
+		}
+		log.Fatalf("check schema: %v", err)
+	}
+}
+
+func newMigrationRunner(driver migrate.Driver) (*migrate.Runner, error) {
+	all, err := migrate.Load(migrations.FS)
+	if err != nil {
+		return nil, err
+	}
+	return migrate.NewRunner(driver, all), nil
+}
+
+func logMigrations(verb string, ms []migrate.Migration) {
+	for _, m := range ms {
+		log.Printf("migration %s %s", m.ID(), verb)
+	}
+}
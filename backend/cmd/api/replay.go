@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"triploom/backend/internal/ai"
+	"triploom/backend/internal/cassette"
+	"triploom/backend/internal/prompts"
+)
+
+const replayUsage = "usage: api replay [-prompts-dir dir] [-match-prompt] cassette.json..."
+
+// runReplay implements `api replay ...`: it feeds recorded requests back through the service
+// and reports cassettes whose answer or planner draft no longer matches the recording.
+func runReplay(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	promptsDir := fs.String("prompts-dir", os.Getenv("PROMPTS_DIR"), "directory of prompt template overrides")
+	matchPrompt := fs.Bool("match-prompt", false, "match model calls on the whole system prompt, not just its ContextJSON")
+	if err := fs.Parse(args); err != nil || fs.NArg() == 0 {
+		return errors.New(replayUsage)
+	}
+	defs := prompts.Bundled()
+	if *promptsDir != "" {
+		dirDefs, err := prompts.ParseFS(os.DirFS(*promptsDir))
+		if err != nil {
+			return err
+		}
+		defs = append(defs, dirDefs...)
+	}
+	registry, err := prompts.New(defs)
+	if err != nil {
+		return err
+	}
+
+	changed := 0
+	for _, path := range fs.Args() {
+		c, err := cassette.Load(path)
+		if err != nil {
+			return err
+		}
+		player := cassette.NewPlayer(c)
+		player.MatchSystemPrompt = *matchPrompt
+		resp, err := ai.Replay(ctx, player, ai.WithPrompts(registry))
+		var diffs []string
+		switch {
+		case err != nil && c.Error == "":
+			diffs = []string{err.Error()}
+		case err == nil:
+			diffs = c.Diff(resp, "answer", "plannerDraft")
+		}
+		if len(diffs) == 0 {
+			fmt.Printf("ok       %s\n", path)
+		} else {
+			changed++
+			fmt.Printf("changed  %s\n", path)
+			for _, d := range diffs {
+				fmt.Printf("         %s\n", d)
+			}
+		}
+		for _, in := range player.Unused() {
+			fmt.Printf("         unused %s\n", in)
+		}
+	}
+	if changed > 0 {
+		return fmt.Errorf("%d of %d cassettes changed", changed, fs.NArg())
+	}
+	return nil
+}
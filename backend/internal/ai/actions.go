@@ -0,0 +1,306 @@
+package ai
+
+import (
+	"encoding/json"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"triploom/backend/internal/store"
+)
+
+// Highlight kinds.
+const (
+	HighlightTip      = "tip"
+	HighlightRisk     = "risk"
+	HighlightDeadline = "deadline"
+	HighlightCost     = "cost"
+)
+
+// Suggested action kinds.
+const (
+	ActionAddItineraryItem  = "add_itinerary_item"
+	ActionMoveItineraryItem = "move_itinerary_item"
+	ActionSaveFlight        = "save_flight"
+	ActionAddExpense        = "add_expense"
+	ActionAskMissingField   = "ask_missing_field"
+)
+
+// Highlight is one point of an answer the UI can show on its own.
+type Highlight struct {
+	Kind string `json:"kind"`
+	Text string `json:"text"`
+}
+
+// SuggestedAction is a next step the UI can render as a button. Params depend on Kind:
+// add_itinerary_item has title and optionally date, dayIndex (1-based, from the trip start)
+// and timeBlock; move_itinerary_item has itemId, date, dayIndex and timeBlock; save_flight has
+// flightNumber and date; add_expense has title, amount, currency, category and date;
+// ask_missing_field has field.
+type SuggestedAction struct {
+	Kind   string         `json:"kind"`
+	Label  string         `json:"label"`
+	Params map[string]any `json:"params,omitempty"`
+	// ProposalID is set when the action was stored as a proposal awaiting approval.
+	ProposalID string `json:"proposalId,omitempty"`
+}
+
+const (
+	maxHighlights   = 4
+	maxActions      = 3
+	maxActionLabel  = 80
+	maxHighlightLen = 200
+)
+
+var (
+	highlightKinds = []string{HighlightTip, HighlightRisk, HighlightDeadline, HighlightCost}
+	actionKinds    = []string{ActionAddItineraryItem, ActionMoveItineraryItem, ActionSaveFlight, ActionAddExpense, ActionAskMissingField}
+	timeBlocks     = []string{"morning", "afternoon", "evening"}
+	// categories are the trip_expenses categories; itinerary items use them too.
+	categories = []string{"flights", "hotels", "transit", "food", "activities", "misc"}
+	// missingFields are what an ask_missing_field action may ask for.
+	missingFields = []string{"origin", "destination", "dates", "travelers", "budget", "flightNumber", "flightDate"}
+	flightNumber  = regexp.MustCompile(`^[A-Z0-9]{2}[A-Z]?\d{1,4}[A-Z]?$`)
+	currencyCode  = regexp.MustCompile(`^[A-Z]{3}$`)
+)
+
+// replySchema is the structured output copilot answers are requested in. Strict mode needs
+// every property listed as required, so optional action params are nullable.
+var replySchema = map[string]any{
+	"type":                 "object",
+	"additionalProperties": false,
+	"required":             []string{"answer", "highlights", "actions", "preferences"},
+	"properties": map[string]any{
+		"answer": map[string]any{"type": "string"},
+		"highlights": map[string]any{
+			"type": "array",
+			"items": map[string]any{
+				"type":                 "object",
+				"additionalProperties": false,
+				"required":             []string{"kind", "text"},
+				"properties": map[string]any{
+					"kind": map[string]any{"type": "string", "enum": highlightKinds},
+					"text": map[string]any{"type": "string"},
+				},
+			},
+		},
+		"actions": map[string]any{
+			"type": "array",
+			"items": map[string]any{
+				"type":                 "object",
+				"additionalProperties": false,
+				"required":             []string{"kind", "label", "params"},
+				"properties": map[string]any{
+					"kind":  map[string]any{"type": "string", "enum": actionKinds},
+					"label": map[string]any{"type": "string"},
+					"params": map[string]any{
+						"type":                 "object",
+						"additionalProperties": false,
+						"required":             []string{"title", "itemId", "date", "timeBlock", "flightNumber", "amount", "currency", "category", "field"},
+						"properties": map[string]any{
+							"title":        map[string]any{"type": []string{"string", "null"}},
+							"itemId":       map[string]any{"type": []string{"string", "null"}},
+							"date":         map[string]any{"type": []string{"string", "null"}, "description": "YYYY-MM-DD"},
+							"timeBlock":    map[string]any{"type": []string{"string", "null"}, "enum": []any{"morning", "afternoon", "evening", nil}},
+							"flightNumber": map[string]any{"type": []string{"string", "null"}},
+							"amount":       map[string]any{"type": []string{"number", "null"}},
+							"currency":     map[string]any{"type": []string{"string", "null"}, "description": "ISO 4217 code"},
+							"category":     map[string]any{"type": []string{"string", "null"}, "enum": append(toAny(categories), nil)},
+							"field":        map[string]any{"type": []string{"string", "null"}, "enum": append(toAny(missingFields), nil)},
+						},
+					},
+				},
+			},
+		},
+		"preferences": preferencesSchema,
+	},
+}
+
+func toAny(values []string) []any {
+	out := make([]any, 0, len(values)+1)
+	for _, v := range values {
+		out = append(out, v)
+	}
+	return out
+}
+
+// proposedAction is an action as the model sent it, before validation.
+type proposedAction struct {
+	Kind   string         `json:"kind"`
+	Label  string         `json:"label"`
+	Params map[string]any `json:"params"`
+}
+
+// structuredReply is a copilot answer in replySchema, or a planner answer in
+// plannerReplySchema.
+type structuredReply struct {
+	Answer      string               `json:"answer"`
+	Highlights  []Highlight          `json:"highlights"`
+	Actions     []proposedAction     `json:"actions"`
+	Preferences []proposedPreference `json:"preferences"`
+}
+
+// parseReply reads a structured answer. Plain-text answers, from models or fakes that ignore
+// the schema, are not structured.
+func parseReply(text string) (structuredReply, bool) {
+	var r structuredReply
+	trimmed := strings.TrimSpace(text)
+	if !strings.HasPrefix(trimmed, "{") || json.Unmarshal([]byte(trimmed), &r) != nil {
+		return structuredReply{}, false
+	}
+	r.Answer = strings.TrimSpace(r.Answer)
+	return r, r.Answer != ""
+}
+
+// validHighlights keeps well-formed highlights that pass the guardrail checks the answer
+// itself is held to.
+func validHighlights(in []Highlight, grounding string) []Highlight {
+	out := make([]Highlight, 0, len(in))
+	for _, h := range in {
+		h.Text = strings.TrimSpace(h.Text)
+		if !contains(highlightKinds, h.Kind) || h.Text == "" || utf8.RuneCountInString(h.Text) > maxHighlightLen {
+			continue
+		}
+		if len(CheckAnswer(h.Text, grounding)) > 0 {
+			continue
+		}
+		out = append(out, h)
+		if len(out) == maxHighlights {
+			break
+		}
+	}
+	return out
+}
+
+// validActions keeps the actions that make sense for trip, normalising their params and
+// dropping the ones the model left null. Itinerary items must fall within the trip's dates,
+// and flights within a day of them.
+func validActions(in []proposedAction, trip *store.Trip) []SuggestedAction {
+	out := make([]SuggestedAction, 0, len(in))
+	for _, a := range in {
+		label := strings.TrimSpace(a.Label)
+		if label == "" || utf8.RuneCountInString(label) > maxActionLabel {
+			continue
+		}
+		params, ok := actionParams(a, trip)
+		if !ok {
+			continue
+		}
+		out = append(out, SuggestedAction{Kind: a.Kind, Label: label, Params: params})
+		if len(out) == maxActions {
+			break
+		}
+	}
+	return out
+}
+
+func actionParams(a proposedAction, trip *store.Trip) (map[string]any, bool) {
+	switch a.Kind {
+	case ActionAddItineraryItem:
+		title := stringFromMap(a.Params, "title")
+		if title == "" || utf8.RuneCountInString(title) > maxActionLabel {
+			return nil, false
+		}
+		params := map[string]any{"title": title}
+		if block := stringFromMap(a.Params, "timeBlock"); contains(timeBlocks, block) {
+			params["timeBlock"] = block
+		}
+		if raw := stringFromMap(a.Params, "date"); raw != "" {
+			day, ok := tripDay(raw, trip, 0)
+			if !ok {
+				return nil, false
+			}
+			params["date"] = raw
+			if day > 0 {
+				params["dayIndex"] = day
+			}
+		}
+		if category := stringFromMap(a.Params, "category"); contains(categories, category) {
+			params["category"] = category
+		}
+		return params, true
+	case ActionMoveItineraryItem:
+		itemID := stringFromMap(a.Params, "itemId")
+		block := stringFromMap(a.Params, "timeBlock")
+		date := stringFromMap(a.Params, "date")
+		if itemID == "" || !contains(timeBlocks, block) {
+			return nil, false
+		}
+		day, ok := tripDay(date, trip, 0)
+		if !ok || day == 0 {
+			return nil, false
+		}
+		return map[string]any{"itemId": itemID, "date": date, "dayIndex": day, "timeBlock": block}, true
+	case ActionSaveFlight:
+		number := strings.ToUpper(strings.ReplaceAll(stringFromMap(a.Params, "flightNumber"), " ", ""))
+		date := stringFromMap(a.Params, "date")
+		if !flightNumber.MatchString(number) {
+			return nil, false
+		}
+		if _, ok := tripDay(date, trip, 1); !ok {
+			return nil, false
+		}
+		return map[string]any{"flightNumber": number, "date": date}, true
+	case ActionAddExpense:
+		title := stringFromMap(a.Params, "title")
+		amount, _ := a.Params["amount"].(float64)
+		currency := strings.ToUpper(stringFromMap(a.Params, "currency"))
+		date := stringFromMap(a.Params, "date")
+		if title == "" || utf8.RuneCountInString(title) > maxActionLabel || amount <= 0 || amount >= 1e10 || !currencyCode.MatchString(currency) {
+			return nil, false
+		}
+		// Expenses are often paid well before the trip, so only the date's format is checked.
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			return nil, false
+		}
+		category := stringFromMap(a.Params, "category")
+		if !contains(categories, category) {
+			category = "misc"
+		}
+		return map[string]any{"title": title, "amount": math.Round(amount*100) / 100, "currency": currency, "category": category, "date": date}, true
+	case ActionAskMissingField:
+		field := stringFromMap(a.Params, "field")
+		if !contains(missingFields, field) {
+			return nil, false
+		}
+		return map[string]any{"field": field}, true
+	}
+	return nil, false
+}
+
+// tripDay checks that date (YYYY-MM-DD) falls within the trip, widened by slack days on each
+// side, and returns its 1-based day number; 0 when the trip has no dates or the date is in the
+// slack.
+func tripDay(date string, trip *store.Trip, slack int) (int, bool) {
+	d, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return 0, false
+	}
+	if trip == nil || trip.StartDate.IsZero() || trip.EndDate.IsZero() {
+		return 0, true
+	}
+	start := truncateDay(trip.StartDate)
+	end := truncateDay(trip.EndDate)
+	if d.Before(start.AddDate(0, 0, -slack)) || d.After(end.AddDate(0, 0, slack)) {
+		return 0, false
+	}
+	if d.Before(start) || d.After(end) {
+		return 0, true
+	}
+	return int(d.Sub(start).Hours()/24) + 1, true
+}
+
+func truncateDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func contains(values []string, v string) bool {
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
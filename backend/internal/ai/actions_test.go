@@ -0,0 +1,93 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"triploom/backend/internal/store"
+)
+
+func TestValidActions(t *testing.T) {
+	trip := &store.Trip{StartDate: time.Date(2026, 11, 2, 0, 0, 0, 0, time.UTC), EndDate: time.Date(2026, 11, 6, 0, 0, 0, 0, time.UTC)}
+	item := func(params map[string]any) proposedAction {
+		return proposedAction{Kind: ActionAddItineraryItem, Label: "Add it", Params: params}
+	}
+	flight := func(number, date string) proposedAction {
+		return proposedAction{Kind: ActionSaveFlight, Label: "Save flight", Params: map[string]any{"flightNumber": number, "date": date}}
+	}
+	cases := []struct {
+		action proposedAction
+		params string
+	}{
+		{item(map[string]any{"title": "Fushimi Inari", "date": "2026-11-03", "timeBlock": "morning", "flightNumber": nil}), `{"date":"2026-11-03","dayIndex":2,"timeBlock":"morning","title":"Fushimi Inari"}`},
+		{item(map[string]any{"title": "Nishiki Market", "timeBlock": "brunch"}), `{"title":"Nishiki Market"}`},
+		{item(map[string]any{"title": "Nara day trip", "date": "2026-11-09"}), ""},
+		{item(map[string]any{"date": "2026-11-03"}), ""},
+		{flight("ac 856", "2026-11-01"), `{"date":"2026-11-01","flightNumber":"AC856"}`},
+		{flight("AC856", "2026-10-30"), ""},
+		{flight("Air Canada", "2026-11-02"), ""},
+		{proposedAction{Kind: ActionAskMissingField, Label: "Share your dates", Params: map[string]any{"field": "dates"}}, `{"field":"dates"}`},
+		{proposedAction{Kind: ActionAskMissingField, Label: "Share your shoe size", Params: map[string]any{"field": "shoeSize"}}, ""},
+		{proposedAction{Kind: "book_hotel", Label: "Book it", Params: map[string]any{}}, ""},
+		{proposedAction{Kind: ActionAskMissingField, Label: " ", Params: map[string]any{"field": "dates"}}, ""},
+	}
+	for _, c := range cases {
+		got := validActions([]proposedAction{c.action}, trip)
+		params := ""
+		if len(got) == 1 {
+			raw, _ := json.Marshal(got[0].Params)
+			params = string(raw)
+		}
+		if params != c.params {
+			t.Errorf("%+v: expected %s, got %s", c.action, c.params, params)
+		}
+	}
+
+	if got := validActions([]proposedAction{item(map[string]any{"title": "Anywhere", "date": "2030-01-01"})}, &store.Trip{}); len(got) != 1 || got[0].Params["dayIndex"] != nil {
+		t.Errorf("expected any date to be accepted for an undated trip, got %+v", got)
+	}
+}
+
+func TestChatStructuredReply(t *testing.T) {
+	ctx := context.Background()
+	structured, _ := json.Marshal(map[string]any{
+		"answer": "Start at Fushimi Inari before the crowds, then walk down to Tofuku-ji.",
+		"highlights": []any{
+			map[string]any{"kind": "tip", "text": "Arrive before the tour buses."},
+			map[string]any{"kind": "cost", "text": "Entry is $12."},
+			map[string]any{"kind": "gossip", "text": "Unlisted kind."},
+		},
+		"actions": []any{
+			map[string]any{"kind": ActionAddItineraryItem, "label": "Add Fushimi Inari", "params": map[string]any{"title": "Fushimi Inari", "date": nil, "timeBlock": "morning", "flightNumber": nil, "field": nil}},
+			map[string]any{"kind": ActionSaveFlight, "label": "Save flight", "params": map[string]any{"title": nil, "date": nil, "timeBlock": nil, "flightNumber": "JL1", "field": nil}},
+		},
+	})
+	llm := &fakeLLM{answer: string(structured), title: "Temples"}
+	svc, repo, trip := newTestService(t, llm)
+
+	resp, err := svc.Chat(ctx, "alice", chat(trip.ID, "", "plan our temple morning"))
+	if err != nil {
+		t.Fatalf("chat: %v", err)
+	}
+	if resp.Answer != "Start at Fushimi Inari before the crowds, then walk down to Tofuku-ji." {
+		t.Fatalf("expected the answer field, got %q", resp.Answer)
+	}
+	if len(resp.Highlights) != 1 || resp.Highlights[0].Text != "Arrive before the tour buses." {
+		t.Fatalf("expected only the valid, grounded highlight, got %+v", resp.Highlights)
+	}
+	if len(resp.SuggestedActions) != 1 || resp.SuggestedActions[0].Params["title"] != "Fushimi Inari" {
+		t.Fatalf("expected only the itinerary action, got %+v", resp.SuggestedActions)
+	}
+	msg, _ := repo.GetMessage(ctx, resp.MessageID)
+	if msg.Content != resp.Answer {
+		t.Fatalf("expected the answer text to be stored, got %q", msg.Content)
+	}
+
+	llm.answer = "Plain text answers still work."
+	resp, _ = svc.Chat(ctx, "alice", chat(trip.ID, resp.ConversationID, "and after lunch?"))
+	if resp.Answer != llm.answer || resp.Highlights == nil || len(resp.Highlights) != 0 || len(resp.SuggestedActions) != 0 {
+		t.Fatalf("expected a plain answer without highlights or actions, got %+v", resp)
+	}
+}
@@ -0,0 +1,213 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"triploom/backend/internal/cassette"
+	"triploom/backend/internal/providers/openai"
+)
+
+const (
+	// ProviderOpenAI is the provider name of the LLM passed to NewService.
+	ProviderOpenAI = "openai"
+	// ProviderLocal names the local fallback answer in an AnswerAttempt.
+	ProviderLocal = "local"
+	// DefaultAttemptTimeout bounds an attempt that sets no timeout of its own.
+	DefaultAttemptTimeout = 60 * time.Second
+)
+
+// Attempt is one step of the fallback chain.
+type Attempt struct {
+	Provider string
+	// Model is the model to ask; empty means the model picked for the request.
+	Model   string
+	Timeout time.Duration
+}
+
+func (a Attempt) String() string {
+	s := a.Provider
+	if a.Model != "" {
+		s += ":" + a.Model
+	}
+	return s + "@" + a.Timeout.String()
+}
+
+// AnswerAttempt reports which step of the fallback chain produced an answer.
+type AnswerAttempt struct {
+	// Index is the 1-based position of the attempt in the chain, or 0 for the local fallback
+	// answer.
+	Index    int    `json:"index"`
+	Provider string `json:"provider"`
+	Model    string `json:"model,omitempty"`
+	// Failures are the earlier attempts, in order.
+	Failures []AttemptFailure `json:"failures,omitempty"`
+
+	llm     LLM
+	timeout time.Duration
+}
+
+// AttemptFailure is an attempt that did not produce an answer. Reason is one of timeout,
+// rate_limited, unavailable, misconfigured, rejected or error.
+type AttemptFailure struct {
+	Provider  string `json:"provider"`
+	Model     string `json:"model"`
+	Reason    string `json:"reason"`
+	Retryable bool   `json:"retryable"`
+}
+
+// degraded reports whether the answer came from anywhere but the first attempt.
+func (a AnswerAttempt) degraded() bool {
+	return a.Index != 1
+}
+
+// reason explains a degraded answer, e.g. "fallback to openai:gpt-4.1-mini after rate_limited".
+func (a AnswerAttempt) reason() string {
+	if !a.degraded() {
+		return ""
+	}
+	reasons := make([]string, 0, len(a.Failures))
+	for _, f := range a.Failures {
+		reasons = append(reasons, f.Reason)
+	}
+	if a.Provider == ProviderLocal {
+		return "local fallback answer after " + strings.Join(reasons, ", ")
+	}
+	return fmt.Sprintf("fallback to %s:%s after %s", a.Provider, a.Model, strings.Join(reasons, ", "))
+}
+
+// modelName is the model to record against the answer.
+func (a AnswerAttempt) modelName() string {
+	if a.Provider == ProviderLocal {
+		return ProviderLocal
+	}
+	return a.Model
+}
+
+// WithFallbackChain answers each copilot and planner request with the first attempt of chain
+// that succeeds, falling back to the local answer when every attempt fails or one fails in a
+// way no other attempt would fix. providers maps the attempts' provider names to their
+// clients; ProviderOpenAI is the LLM given to NewService unless providers sets it. The
+// default chain is a single ProviderOpenAI attempt with the picked model.
+func WithFallbackChain(chain []Attempt, providers map[string]LLM) Option {
+	return func(s *Service) {
+		s.chain = chain
+		for name, llm := range providers {
+			s.providers[name] = llm
+		}
+	}
+}
+
+// ParseFallbackChain reads a comma-separated chain of provider[:model][@timeout] attempts,
+// e.g. "openai@20s,openai:gpt-4.1-mini@15s,compatible:llama-3.3-70b". Attempts without a
+// timeout get DefaultAttemptTimeout. An empty string is the default chain.
+func ParseFallbackChain(raw string) ([]Attempt, error) {
+	chain := make([]Attempt, 0)
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		a := Attempt{Timeout: DefaultAttemptTimeout}
+		if spec, timeout, ok := strings.Cut(part, "@"); ok {
+			d, err := time.ParseDuration(timeout)
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("fallback attempt %q: timeout must be a positive duration", part)
+			}
+			part, a.Timeout = spec, d
+		}
+		a.Provider, a.Model, _ = strings.Cut(part, ":")
+		a.Provider, a.Model = strings.TrimSpace(a.Provider), strings.TrimSpace(a.Model)
+		if a.Provider == "" || a.Provider == ProviderLocal {
+			return nil, fmt.Errorf("fallback attempt %q: missing provider", part)
+		}
+		chain = append(chain, a)
+	}
+	if len(chain) == 0 {
+		return []Attempt{{Provider: ProviderOpenAI, Timeout: DefaultAttemptTimeout}}, nil
+	}
+	return chain, nil
+}
+
+// callChain runs the fallback chain for one model call. It returns a nil result, with the
+// local attempt, when no attempt answered. The error is only set when ctx itself ended, or
+// when a replayed call has no recording.
+func (s *Service) callChain(ctx context.Context, model, systemPrompt string, messages []openai.Message) (*openai.ChatResult, AnswerAttempt, error) {
+	failures := make([]AttemptFailure, 0)
+	for i, a := range s.chain {
+		llm, ok := s.providers[a.Provider]
+		if !ok {
+			failures = append(failures, AttemptFailure{Provider: a.Provider, Model: a.Model, Reason: "misconfigured", Retryable: true})
+			continue
+		}
+		attemptModel := a.Model
+		if attemptModel == "" {
+			attemptModel = model
+		}
+		attemptCtx, cancel := context.WithTimeout(ctx, a.Timeout)
+		result, err := llm.ResponsesChat(attemptCtx, attemptModel, systemPrompt, messages)
+		cancel()
+		if err == nil {
+			return result, AnswerAttempt{Index: i + 1, Provider: a.Provider, Model: attemptModel, Failures: failures, llm: llm, timeout: a.Timeout}, nil
+		}
+		if ctx.Err() != nil {
+			return nil, AnswerAttempt{}, ctx.Err()
+		}
+		if errors.Is(err, cassette.ErrNoMatch) {
+			return nil, AnswerAttempt{}, err
+		}
+		reason, retryable := classifyError(err)
+		log.Printf("model attempt %d/%d %s:%s failed (%s): %v", i+1, len(s.chain), a.Provider, attemptModel, reason, err)
+		failures = append(failures, AttemptFailure{Provider: a.Provider, Model: attemptModel, Reason: reason, Retryable: retryable})
+		if !retryable {
+			break
+		}
+	}
+	return nil, AnswerAttempt{Provider: ProviderLocal, Failures: failures}, nil
+}
+
+// classifyError names why a model call failed and whether another attempt may succeed.
+// Requests the API refused as invalid would be refused by every attempt; rate limits,
+// timeouts, outages and a provider's own configuration problems are worth another attempt.
+func classifyError(err error) (string, bool) {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return "timeout", true
+	}
+	switch code := openai.StatusCode(err); {
+	case code == http.StatusTooManyRequests:
+		return "rate_limited", true
+	case code == http.StatusRequestTimeout:
+		return "timeout", true
+	case code >= 500:
+		return "unavailable", true
+	case code == http.StatusUnauthorized || code == http.StatusForbidden || code == http.StatusNotFound:
+		return "misconfigured", true
+	case code >= 400:
+		return "rejected", false
+	}
+	return "error", true
+}
+
+// addAttempt records in audit metadata how far down the fallback chain an answer came from.
+func addAttempt(meta map[string]any, attempt AnswerAttempt) {
+	if attempt.degraded() {
+		meta["attempt"] = attempt.Index
+		meta["attemptFailures"] = attempt.Failures
+	}
+}
+
+// degradedReason joins the reasons an answer is degraded.
+func degradedReason(attempt AnswerAttempt, guarded guardOutcome) string {
+	reasons := make([]string, 0, 2)
+	for _, r := range []string{attempt.reason(), guarded.reason()} {
+		if r != "" {
+			reasons = append(reasons, r)
+		}
+	}
+	return strings.Join(reasons, "; ")
+}
@@ -0,0 +1,163 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	sdk "github.com/openai/openai-go/v3"
+
+	"triploom/backend/internal/providers/openai"
+	"triploom/backend/internal/store"
+)
+
+// scriptedLLM fails the models in errs and answers the rest; models in slow block until the
+// call's context ends.
+type scriptedLLM struct {
+	mu     sync.Mutex
+	errs   map[string]error
+	slow   map[string]bool
+	answer string
+	models []string
+}
+
+func (f *scriptedLLM) ResponsesChat(ctx context.Context, model string, systemPrompt string, _ []openai.Message) (*openai.ChatResult, error) {
+	if systemPrompt != titleInstructions {
+		f.mu.Lock()
+		f.models = append(f.models, model)
+		f.mu.Unlock()
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if f.slow[model] {
+		<-ctx.Done()
+		return nil, fmt.Errorf("openai responses error: %w", ctx.Err())
+	}
+	if err := f.errs[model]; err != nil {
+		return nil, err
+	}
+	return &openai.ChatResult{Text: f.answer + " (" + model + ")"}, nil
+}
+
+func apiError(code int) error {
+	return fmt.Errorf("openai responses error: %w", &sdk.Error{
+		StatusCode: code,
+		Request:    httptest.NewRequest(http.MethodPost, "https://api.openai.com/v1/responses", nil),
+		Response:   &http.Response{StatusCode: code},
+	})
+}
+
+func TestFallbackChain(t *testing.T) {
+	ctx := context.Background()
+	primary := &scriptedLLM{answer: "Take the tram", errs: map[string]error{
+		"big":      apiError(http.StatusTooManyRequests),
+		"bad":      apiError(http.StatusBadRequest),
+		"outage":   apiError(http.StatusServiceUnavailable),
+		"unlisted": errors.New("connection reset"),
+	}, slow: map[string]bool{"slow": true}}
+	backup := &scriptedLLM{answer: "Walk"}
+
+	newService := func(chain string) (*Service, *store.Trip) {
+		t.Helper()
+		attempts, err := ParseFallbackChain(chain)
+		if err != nil {
+			t.Fatalf("parse %q: %v", chain, err)
+		}
+		repo := store.NewInMemoryAIRepository()
+		trip, _ := repo.CreateTrip(ctx, store.Trip{Destination: "Lisbon"}, "alice")
+		return NewService(repo, primary, nil, NewModelSelector("big"), WithFallbackChain(attempts, map[string]LLM{"backup": backup})), trip
+	}
+
+	svc, trip := newService("")
+	resp, err := svc.Chat(ctx, "alice", chat(trip.ID, "", "how do I get to Belém?"))
+	if err != nil {
+		t.Fatalf("chat: %v", err)
+	}
+	if !resp.Degraded || resp.Attempt.Index != 0 || resp.Attempt.Provider != ProviderLocal || resp.DegradedReason != "local fallback answer after rate_limited" {
+		t.Fatalf("expected the local answer after a rate limit, got %+v", resp)
+	}
+	if resp.ConversationTitle == "" {
+		t.Fatalf("expected a fallback title")
+	}
+
+	svc, trip = newService("openai:slow@20ms,openai:outage,backup:small@1s")
+	resp, err = svc.Chat(ctx, "alice", chat(trip.ID, "", "how do I get to Belém?"))
+	if err != nil {
+		t.Fatalf("chat: %v", err)
+	}
+	if resp.Answer != "Walk (small)" || !resp.Degraded || resp.Attempt.Index != 3 || resp.Attempt.Provider != "backup" || len(resp.Attempt.Failures) != 2 {
+		t.Fatalf("expected the third attempt to answer, got %+v", resp)
+	}
+	if resp.DegradedReason != "fallback to backup:small after timeout, unavailable" {
+		t.Fatalf("unexpected reason %q", resp.DegradedReason)
+	}
+	msgs, _, _ := svc.conversations.ListMessages(ctx, resp.ConversationID, store.PageRequest{Limit: 10})
+	if len(msgs) != 2 || msgs[0].Model != "small" {
+		t.Fatalf("expected the answering model to be stored, got %+v", msgs)
+	}
+
+	primary.models = nil
+	svc, trip = newService("openai:bad,backup")
+	resp, err = svc.Chat(ctx, "alice", chat(trip.ID, "", "how do I get to Belém?"))
+	if err != nil {
+		t.Fatalf("chat: %v", err)
+	}
+	if resp.Attempt.Provider != ProviderLocal || resp.Attempt.Failures[0].Reason != "rejected" || resp.Attempt.Failures[0].Retryable || len(backup.models) != 1 {
+		t.Fatalf("expected a rejected request to stop the chain, got %+v", resp.Attempt)
+	}
+
+	svc, trip = newService("openai:unlisted,openai@1s,backup")
+	planned, err := svc.PlannerChat(ctx, "alice", PlannerChatRequest{Messages: []ChatMessage{{Role: "user", Content: "plan a weekend in Porto"}}})
+	if err != nil {
+		t.Fatalf("planner: %v", err)
+	}
+	if planned.Answer != "Walk (big)" || planned.Attempt.Index != 3 || planned.DegradedReason != "fallback to backup:big after error, rate_limited" {
+		t.Fatalf("expected the third attempt with the picked model, got %+v", planned)
+	}
+
+	svc, trip = newService("openai:other")
+	resp, err = svc.Chat(ctx, "alice", chat(trip.ID, "", "how do I get to Belém?"))
+	if err != nil || resp.Degraded || resp.Attempt.Index != 1 || resp.DegradedReason != "" {
+		t.Fatalf("expected the first attempt to answer undegraded, got %+v (%v)", resp, err)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := svc.Chat(cancelled, "alice", chat(trip.ID, "", "how do I get to Belém?")); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected a cancelled request to fail, got %v", err)
+	}
+}
+
+func TestParseFallbackChain(t *testing.T) {
+	chain, err := ParseFallbackChain(" openai@20s, openai:gpt-4.1-mini ,compatible:llama-3.3-70b@5s")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	want := []Attempt{
+		{Provider: "openai", Timeout: 20 * time.Second},
+		{Provider: "openai", Model: "gpt-4.1-mini", Timeout: DefaultAttemptTimeout},
+		{Provider: "compatible", Model: "llama-3.3-70b", Timeout: 5 * time.Second},
+	}
+	if len(chain) != len(want) {
+		t.Fatalf("expected %v, got %v", want, chain)
+	}
+	for i := range want {
+		if chain[i] != want[i] {
+			t.Fatalf("attempt %d: expected %v, got %v", i, want[i], chain[i])
+		}
+	}
+	if chain, _ := ParseFallbackChain(""); len(chain) != 1 || chain[0].Provider != ProviderOpenAI {
+		t.Fatalf("expected the default chain, got %v", chain)
+	}
+	for _, bad := range []string{"openai@soon", "openai@-1s", ":gpt-4.1", "local"} {
+		if _, err := ParseFallbackChain(bad); err == nil {
+			t.Fatalf("expected %q to be rejected", bad)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package ai
+
+import (
+	"context"
+	"maps"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"triploom/backend/internal/store"
+	"triploom/backend/internal/tripevents"
+)
+
+// FeedbackReasons are the tags a thumbs up or down may carry.
+var FeedbackReasons = []string{"incorrect", "outdated", "incomplete", "unhelpful", "ignored_context", "too_long", "unsafe", "other"}
+
+const (
+	maxFeedbackComment = 2000
+	// regenerateHistory is how many earlier messages a regenerated answer is given.
+	regenerateHistory = 20
+	// defaultReportWindow applies when a feedback report does not say since when.
+	defaultReportWindow = 30 * 24 * time.Hour
+)
+
+type FeedbackRequest struct {
+	Rating  string   `json:"rating"`
+	Reasons []string `json:"reasons"`
+	Comment string   `json:"comment"`
+}
+
+// RegenerateRequest re-runs the turn that produced an answer. Model must be the default
+// model or one of the configured alternatives; empty keeps the usual selection.
+type RegenerateRequest struct {
+	Model string `json:"model"`
+}
+
+// SubmitFeedback rates an answer in one of the caller's conversations. Rating again replaces
+// the earlier rating.
+func (s *Service) SubmitFeedback(ctx context.Context, userID, messageID string, req FeedbackRequest) (*store.Feedback, error) {
+	if req.Rating != "up" && req.Rating != "down" {
+		return nil, ErrInvalidInput
+	}
+	reasons := make([]string, 0, len(req.Reasons))
+	seen := make(map[string]bool)
+	for _, reason := range req.Reasons {
+		if !isFeedbackReason(reason) {
+			return nil, ErrInvalidInput
+		}
+		if !seen[reason] {
+			seen[reason] = true
+			reasons = append(reasons, reason)
+		}
+	}
+	comment := strings.TrimSpace(req.Comment)
+	if utf8.RuneCountInString(comment) > maxFeedbackComment {
+		return nil, ErrInvalidInput
+	}
+
+	msg, _, err := s.ownedAnswer(ctx, userID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	return s.feedback.UpsertFeedback(ctx, store.Feedback{
+		MessageID: msg.ID,
+		UserID:    userID,
+		Rating:    req.Rating,
+		Reasons:   reasons,
+		Comment:   comment,
+	})
+}
+
+// FeedbackReport summarises the ratings given in the trip's conversations since since (the
+// last 30 days when zero), per model and page.
+func (s *Service) FeedbackReport(ctx context.Context, userID, tripID string, since time.Time) ([]store.FeedbackSummary, error) {
+	if tripID == "" {
+		return nil, ErrInvalidInput
+	}
+	ok, err := s.trips.IsTripMember(ctx, tripID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrUnauthorizedTrip
+	}
+	if since.IsZero() {
+		since = time.Now().Add(-defaultReportWindow)
+	}
+	return s.feedback.FeedbackReport(ctx, tripID, since)
+}
+
+// Regenerate answers the prompt behind messageID again and stores the result as a new version
+// linked to the original answer. The conversation history comes from the stored thread and
+// the trip context from the one stored with the original answer.
+func (s *Service) Regenerate(ctx context.Context, userID, messageID string, req RegenerateRequest) (*ChatResponse, error) {
+	if req.Model != "" && !s.modelSelector.Allowed(req.Model) {
+		return nil, ErrInvalidInput
+	}
+	target, conv, err := s.ownedAnswer(ctx, userID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	original := target
+	if target.RegeneratedFrom != "" {
+		if original, err = s.conversations.GetMessage(ctx, target.RegeneratedFrom); err != nil {
+			return nil, err
+		}
+	}
+
+	history, err := s.turnHistory(ctx, original)
+	if err != nil {
+		return nil, err
+	}
+	trip, err := s.trips.GetTripByID(ctx, conv.TripID)
+	if err != nil {
+		return nil, err
+	}
+	pageKey := original.PageKey
+	source := Source{Name: "trip_db_context", Status: "ok", FetchedAt: original.CreatedAt.UTC().Format(time.RFC3339)}
+	contextPayload, err := s.conversations.MessageContext(ctx, original.ID)
+	if err == store.ErrNotFound {
+		// Answers stored without their context get the trip's current one, built for the
+		// caller as for a new turn.
+		var untrusted map[string]any
+		contextPayload, untrusted, _, _ = s.tripContext(ctx, userID, trip, conv.ID, pageKey, history[len(history)-1].Content)
+		if len(untrusted) > 0 {
+			contextPayload["untrusted"] = s.sanitize.For(pageKey).Sanitize(untrusted, nil)
+		}
+		source.FetchedAt = time.Now().UTC().Format(time.RFC3339)
+	} else if err != nil {
+		return nil, err
+	}
+	answerContext := maps.Clone(contextPayload)
+	s.addPreferences(ctx, userID, pageKey, contextPayload)
+
+	model := req.Model
+	if model == "" {
+		model = s.modelSelector.Select(history[len(history)-1].Content, history)
+	}
+	systemPrompt, promptVersion, err := s.systemPrompt(copilotPrompt, userID, pageKey, contextPayload, false)
+	if err != nil {
+		return nil, err
+	}
+	grounding := groundingText(contextPayload, history)
+	result, guarded, err := s.complete(ctx, model, systemPrompt, history, grounding, buildLocalFallbackAnswer(pageKey, history, false), copilotReply)
+	if err != nil {
+		return nil, err
+	}
+	answeredBy := result.Attempt.modelName()
+	degraded := result.Attempt.degraded() || guarded.degraded()
+	answer, err := s.conversations.InsertMessage(ctx, store.Message{
+		ConversationID:  conv.ID,
+		Role:            "assistant",
+		Content:         result.Text,
+		Model:           answeredBy,
+		TokenUsageJSON:  result.TokenUsage,
+		PageKey:         pageKey,
+		PromptVersion:   promptVersion,
+		RegeneratedFrom: original.ID,
+		ContextJSON:     answerContext,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	auditMeta := map[string]any{"pageKey": pageKey, "model": answeredBy, "promptVersion": promptVersion, "regeneratedFrom": original.ID}
+	addAttempt(auditMeta, result.Attempt)
+	_ = s.audit.InsertAuditLog(ctx, userID, conv.TripID, "ai_regenerate", auditMeta)
+	s.logViolations(ctx, userID, conv.TripID, pageKey, answeredBy, promptVersion, guarded)
+	if s.events != nil {
+		_, _ = s.events.Record(ctx, conv.TripID, tripevents.TypeAIChatCompleted, chatCompleted(conv.ID, pageKey, degraded))
+	}
+	resp := chatResponse(conv, answer, []Source{source}, degraded)
+	resp.DegradedReason = degradedReason(result.Attempt, guarded)
+	resp.Attempt = result.Attempt
+	resp.Highlights = validHighlights(result.Highlights, grounding)
+	resp.SuggestedActions, resp.Proposals = s.propose(ctx, userID, conv, answer.ID, trip, validActions(result.Actions, trip))
+	resp.PreferenceUpdates = s.suggestPreferences(ctx, userID, conv.ID, answer.ID, result.Preferences)
+	return resp, nil
+}
+
+// turnHistory returns the stored messages leading up to answer, oldest first, ending with the
+// prompt it answered. Other regenerated versions are left out.
+func (s *Service) turnHistory(ctx context.Context, answer *store.Message) ([]ChatMessage, error) {
+	earlier, _, err := s.conversations.ListMessages(ctx, answer.ConversationID, store.PageRequest{Cursor: store.MessageCursor(*answer), Limit: regenerateHistory})
+	if err != nil {
+		return nil, err
+	}
+	history := make([]ChatMessage, 0, len(earlier))
+	for i := len(earlier) - 1; i >= 0; i-- {
+		if m := earlier[i]; m.RegeneratedFrom == "" {
+			history = append(history, ChatMessage{Role: m.Role, Content: m.Content})
+		}
+	}
+	if len(history) == 0 || history[len(history)-1].Role != "user" {
+		return nil, ErrInvalidInput
+	}
+	return history, nil
+}
+
+// ownedAnswer returns an assistant message from one of the caller's conversations, hiding
+// everything else behind ErrUnauthorizedTrip like ownedConversation.
+func (s *Service) ownedAnswer(ctx context.Context, userID, messageID string) (*store.Message, *store.Conversation, error) {
+	msg, err := s.conversations.GetMessage(ctx, messageID)
+	if err == store.ErrNotFound {
+		return nil, nil, ErrUnauthorizedTrip
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	conv, err := s.ownedConversation(ctx, userID, msg.ConversationID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if msg.Role != "assistant" {
+		return nil, nil, ErrInvalidInput
+	}
+	return msg, conv, nil
+}
+
+func isFeedbackReason(reason string) bool {
+	for _, known := range FeedbackReasons {
+		if known == reason {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,270 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"triploom/backend/internal/cassette"
+	"triploom/backend/internal/providers/openai"
+)
+
+// Guardrail policies: what to do with an answer that breaks the prompt's rules.
+const (
+	// GuardrailRetry asks the model once more with the violations spelled out, and rewrites
+	// the second answer if it still breaks the rules.
+	GuardrailRetry = "retry"
+	// GuardrailRewrite drops the offending sentences.
+	GuardrailRewrite = "rewrite"
+	// GuardrailFlag serves the answer unchanged, marked degraded.
+	GuardrailFlag = "flag"
+	GuardrailOff  = "off"
+)
+
+// Guardrail rules.
+const (
+	RuleBookingClaim     = "booking_claim"
+	RuleConfirmationCode = "confirmation_code"
+	RuleUnsupportedPrice = "unsupported_price"
+	RuleUnsupportedTime  = "unsupported_time"
+)
+
+// Violation is one place an answer breaks a guardrail rule.
+type Violation struct {
+	Rule string `json:"rule"`
+	Text string `json:"text"`
+}
+
+var (
+	// bookingClaim matches first-person statements that something was booked or changed, and
+	// statements that a booking now exists.
+	bookingClaim = regexp.MustCompile(`(?i)\b(?:i|we)(?:'ve| have| had)?\s+(?:just\s+|now\s+|already\s+|successfully\s+)?(?:booked|reserved|purchased|paid for|confirmed|cancell?ed|changed|modified|rebooked|upgraded)\b|\b(?:booking|reservation) (?:is|has been|was) (?:confirmed|complete|made|changed|updated)\b`)
+	// confirmationCode captures the code after phrases like "confirmation number is".
+	confirmationCode = regexp.MustCompile(`(?i:confirmation|booking|reservation|ticket)\s+(?i:code|number|reference|ref|no\.?)\s*(?i:is\s*)?:?\s*#?([A-Z0-9]{3,12})\b|(?i:PNR|record locator)\s*(?i:is\s*)?:?\s*([A-Z0-9]{6})\b`)
+	// priceMention matches amounts with a currency symbol or code before or after them.
+	priceMention = regexp.MustCompile(`(?:[$€£¥]|\b(?:USD|EUR|GBP|JPY|CAD|AUD|CHF)\s?)(\d[\d,]*(?:\.\d+)?)|\b(\d[\d,]*(?:\.\d+)?)\s?(?:USD|EUR|GBP|JPY|CAD|AUD|CHF|dollars|euros|pounds|yen)\b`)
+	// clockTime matches 14:05, 2:05 pm and 9am.
+	clockTime = regexp.MustCompile(`(?i)\b([01]?\d|2[0-3]):([0-5]\d)(?:\s*([ap])\.?m\b\.?)?|\b(1[0-2]|0?[1-9])\s*([ap])\.?m\b\.?`)
+	// contextTime is clockTime without the leading word boundary, so that it also finds the
+	// time in ISO timestamps such as 2026-11-02T08:05:00Z.
+	contextTime = regexp.MustCompile(`(?i)([01]?\d|2[0-3]):([0-5]\d)(?:\s*([ap])\.?m\b\.?)?|\b(1[0-2]|0?[1-9])\s*([ap])\.?m\b\.?`)
+	number      = regexp.MustCompile(`\d[\d,]*(?:\.\d+)?`)
+)
+
+// CheckAnswer returns the guardrail violations in answer. Prices and times count as grounded
+// when grounding (the prompt's ContextJSON plus what the user wrote) mentions them; with an
+// empty grounding only booking claims and confirmation codes are checked.
+func CheckAnswer(answer, grounding string) []Violation {
+	violations := make([]Violation, 0)
+	for _, m := range bookingClaim.FindAllString(answer, -1) {
+		violations = append(violations, Violation{Rule: RuleBookingClaim, Text: m})
+	}
+	upperGrounding := strings.ToUpper(grounding)
+	for _, m := range confirmationCode.FindAllStringSubmatch(answer, -1) {
+		code := m[1] + m[2]
+		if !strings.Contains(upperGrounding, strings.ToUpper(code)) {
+			violations = append(violations, Violation{Rule: RuleConfirmationCode, Text: m[0]})
+		}
+	}
+	if grounding == "" {
+		return violations
+	}
+
+	amounts := make(map[float64]bool)
+	for _, n := range number.FindAllString(grounding, -1) {
+		if v, ok := parseAmount(n); ok {
+			amounts[v] = true
+		}
+	}
+	for _, m := range priceMention.FindAllStringSubmatch(answer, -1) {
+		if v, ok := parseAmount(m[1] + m[2]); ok && !amounts[v] {
+			violations = append(violations, Violation{Rule: RuleUnsupportedPrice, Text: strings.TrimSpace(m[0])})
+		}
+	}
+
+	times := make(map[string]bool)
+	for _, m := range contextTime.FindAllStringSubmatch(grounding, -1) {
+		times[clock(m)] = true
+	}
+	for _, m := range clockTime.FindAllStringSubmatch(answer, -1) {
+		if !times[clock(m)] {
+			violations = append(violations, Violation{Rule: RuleUnsupportedTime, Text: strings.TrimSpace(m[0])})
+		}
+	}
+	return violations
+}
+
+func parseAmount(s string) (float64, bool) {
+	v, err := strconv.ParseFloat(strings.ReplaceAll(s, ",", ""), 64)
+	return v, err == nil
+}
+
+// clock normalises a clockTime match to 24-hour HH:MM.
+func clock(m []string) string {
+	hour, minute, half := m[1], m[2], m[3]
+	if hour == "" {
+		hour, minute, half = m[4], "00", m[5]
+	}
+	h, _ := strconv.Atoi(hour)
+	switch strings.ToLower(half) {
+	case "a":
+		if h == 12 {
+			h = 0
+		}
+	case "p":
+		if h < 12 {
+			h += 12
+		}
+	}
+	return fmt.Sprintf("%02d:%s", h, minute)
+}
+
+// groundingText is what prices and times in an answer may come from: the prompt's context and
+// the user's own messages.
+func groundingText(contextPayload map[string]any, messages []ChatMessage) string {
+	ctxBytes, _ := json.Marshal(contextPayload)
+	return string(ctxBytes) + "\n" + collectUserMessages(messages)
+}
+
+// guardOutcome says what the guardrails found in a model answer and what was done about it.
+type guardOutcome struct {
+	Violations []Violation
+	// Action is empty, or "retried", "rewritten" or "flagged". Rewritten and flagged answers
+	// are served as degraded.
+	Action string
+}
+
+func (g guardOutcome) degraded() bool {
+	return g.Action == "rewritten" || g.Action == "flagged"
+}
+
+// reason is the degradedReason sent to clients.
+func (g guardOutcome) reason() string {
+	if !g.degraded() {
+		return ""
+	}
+	rules := make([]string, 0, len(g.Violations))
+	seen := make(map[string]bool)
+	for _, v := range g.Violations {
+		if !seen[v.Rule] {
+			seen[v.Rule] = true
+			rules = append(rules, v.Rule)
+		}
+	}
+	return "guardrail " + g.Action + ": " + strings.Join(rules, ", ")
+}
+
+// guard applies the service's guardrail policy to a model answer, retrying with the attempt
+// that gave it. A failed retry is handled as with GuardrailRewrite.
+func (s *Service) guard(ctx context.Context, systemPrompt string, mapped []openai.Message, grounding, fallback string, result *reply) (*reply, guardOutcome, error) {
+	violations := CheckAnswer(result.Text, grounding)
+	if s.guardrails == GuardrailOff || len(violations) == 0 {
+		return result, guardOutcome{}, nil
+	}
+	outcome := guardOutcome{Violations: violations}
+	switch s.guardrails {
+	case GuardrailFlag:
+		outcome.Action = "flagged"
+		return result, outcome, nil
+	case GuardrailRetry:
+		retryCtx, cancel := context.WithTimeout(ctx, result.Attempt.timeout)
+		retried, err := result.Attempt.llm.ResponsesChat(retryCtx, result.Attempt.Model, systemPrompt+correction(violations), mapped)
+		cancel()
+		if err != nil {
+			// The first answer is still usable once rewritten.
+			if ctx.Err() != nil || errors.Is(err, cassette.ErrNoMatch) {
+				return nil, outcome, err
+			}
+			log.Printf("guardrail: retry on %s:%s failed: %v", result.Attempt.Provider, result.Attempt.Model, err)
+		} else if retry := newReply(retried); strings.TrimSpace(retry.Text) != "" {
+			retry.Attempt = result.Attempt
+			result = retry
+			remaining := CheckAnswer(result.Text, grounding)
+			if len(remaining) == 0 {
+				outcome.Action = "retried"
+				return result, outcome, nil
+			}
+			outcome.Violations = remaining
+		}
+	}
+	outcome.Action = "rewritten"
+	rewritten := *result
+	rewritten.Text = dropSentences(result.Text, outcome.Violations)
+	if strings.TrimSpace(rewritten.Text) == "" {
+		rewritten.Text = fallback
+	}
+	return &rewritten, outcome, nil
+}
+
+// correction is appended to the system prompt when an answer is retried.
+func correction(violations []Violation) string {
+	var b strings.Builder
+	b.WriteString("\n\nYour previous answer to this conversation broke these rules:\n")
+	for _, v := range violations {
+		fmt.Fprintf(&b, "- %s: %q\n", v.Rule, v.Text)
+	}
+	b.WriteString("Answer again without claiming any booking or change was made, without confirmation codes, and without prices or times that are not in ContextJSON or the user's messages.")
+	return b.String()
+}
+
+// dropSentences removes every sentence that contains a violation, keeping line breaks.
+func dropSentences(text string, violations []Violation) string {
+	lines := strings.Split(text, "\n")
+	kept := make([]string, 0, len(lines))
+	for _, line := range lines {
+		sentences := splitSentences(line)
+		out := make([]string, 0, len(sentences))
+		for _, sentence := range sentences {
+			bad := false
+			for _, v := range violations {
+				if strings.Contains(sentence, v.Text) {
+					bad = true
+					break
+				}
+			}
+			if !bad {
+				out = append(out, sentence)
+			}
+		}
+		if len(out) > 0 || strings.TrimSpace(line) == "" {
+			kept = append(kept, strings.Join(out, " "))
+		}
+	}
+	return strings.TrimSpace(strings.Join(kept, "\n"))
+}
+
+// splitSentences splits after ., ! or ? followed by a space.
+func splitSentences(line string) []string {
+	out := make([]string, 0)
+	start := 0
+	for i := 0; i < len(line)-1; i++ {
+		if (line[i] == '.' || line[i] == '!' || line[i] == '?') && line[i+1] == ' ' {
+			out = append(out, strings.TrimSpace(line[start:i+1]))
+			start = i + 2
+		}
+	}
+	if rest := strings.TrimSpace(line[start:]); rest != "" {
+		out = append(out, rest)
+	}
+	return out
+}
+
+// logViolations records guardrail findings for review, in the audit log and the server log.
+func (s *Service) logViolations(ctx context.Context, userID, tripID, pageKey, model, promptVersion string, outcome guardOutcome) {
+	if len(outcome.Violations) == 0 {
+		return
+	}
+	log.Printf("guardrail: %s answer on %q by %s (%s): %d violation(s), %s", model, pageKey, userID, promptVersion, len(outcome.Violations), outcome.Action)
+	_ = s.audit.InsertAuditLog(ctx, userID, tripID, "ai_guardrail", map[string]any{
+		"pageKey":       pageKey,
+		"model":         model,
+		"promptVersion": promptVersion,
+		"action":        outcome.Action,
+		"violations":    outcome.Violations,
+	})
+}
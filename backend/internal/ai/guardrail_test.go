@@ -0,0 +1,131 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"triploom/backend/internal/providers/openai"
+	"triploom/backend/internal/store"
+)
+
+func TestCheckAnswer(t *testing.T) {
+	grounding := `{"flightStatus":{"departure":{"scheduled":"2026-11-02T08:05:00Z"}},"fare":"129.50"}` + "\nIs 9pm too late? Budget 1,200 EUR."
+	cases := []struct {
+		answer string
+		rules  []string
+	}{
+		{"Departure is scheduled for 08:05 and the fare is €129.50.", nil},
+		{"Dinner at 9 pm fits within your 1200 EUR budget.", nil},
+		{"I've booked the 08:05 flight for you.", []string{RuleBookingClaim}},
+		{"Your reservation has been confirmed.", []string{RuleBookingClaim}},
+		{"Your confirmation code is QX7P2L.", []string{RuleConfirmationCode}},
+		{"Tickets cost $45 and the doors open at 7:30pm.", []string{RuleUnsupportedPrice, RuleUnsupportedTime}},
+		{"The train leaves at 10am and costs 35 euros.", []string{RuleUnsupportedPrice, RuleUnsupportedTime}},
+		{"Once you have booked, share the confirmation with the group.", nil},
+	}
+	for _, c := range cases {
+		got := make([]string, 0)
+		for _, v := range CheckAnswer(c.answer, grounding) {
+			got = append(got, v.Rule)
+		}
+		if strings.Join(got, ",") != strings.Join(c.rules, ",") {
+			t.Errorf("%q: expected %v, got %v", c.answer, c.rules, got)
+		}
+	}
+	if v := CheckAnswer("Doors open at 7:30pm.", ""); len(v) != 0 {
+		t.Errorf("expected times to go unchecked without grounding, got %v", v)
+	}
+}
+
+// errHang makes seqLLM wait for the call's context to end.
+var errHang = errors.New("hang")
+
+// seqLLM answers chat prompts with answers in turn, repeating the last one, and records the
+// system prompts it was sent. A call with an entry in errs fails with it instead.
+type seqLLM struct {
+	mu      sync.Mutex
+	answers []string
+	errs    []error
+	prompts []string
+}
+
+func (s *seqLLM) ResponsesChat(ctx context.Context, _ string, systemPrompt string, _ []openai.Message) (*openai.ChatResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if systemPrompt == titleInstructions {
+		return &openai.ChatResult{Text: "Title"}, nil
+	}
+	s.prompts = append(s.prompts, systemPrompt)
+	if n := len(s.prompts); n <= len(s.errs) && s.errs[n-1] != nil {
+		if s.errs[n-1] == errHang {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return nil, s.errs[n-1]
+	}
+	answer := s.answers[min(len(s.prompts), len(s.answers))-1]
+	return &openai.ChatResult{Text: answer}, nil
+}
+
+func TestGuardrailPolicies(t *testing.T) {
+	ctx := context.Background()
+	claim := "Kinkaku-ji opens early. I've booked your tickets for 10am."
+	cases := []struct {
+		policy, answer, reason string
+		answers                []string
+		calls                  int
+	}{
+		{GuardrailRetry, "Kinkaku-ji opens early; buy tickets at the gate.", "", []string{claim, "Kinkaku-ji opens early; buy tickets at the gate."}, 2},
+		{GuardrailRetry, "Kinkaku-ji opens early.", "guardrail rewritten: booking_claim, unsupported_time", []string{claim}, 2},
+		{GuardrailRewrite, "Kinkaku-ji opens early.", "guardrail rewritten: booking_claim, unsupported_time", []string{claim}, 1},
+		{GuardrailFlag, claim, "guardrail flagged: booking_claim, unsupported_time", []string{claim}, 1},
+		{GuardrailOff, claim, "", []string{claim}, 1},
+		{GuardrailRewrite, "Share a bit more detail and I’ll give a concrete next-step recommendation.", "guardrail rewritten: booking_claim", []string{"I've booked it."}, 1},
+	}
+	for _, c := range cases {
+		repo := store.NewInMemoryAIRepository()
+		trip, _ := repo.CreateTrip(ctx, store.Trip{Destination: "Kyoto"}, "alice")
+		llm := &seqLLM{answers: c.answers}
+		svc := NewService(repo, llm, nil, NewModelSelector("test-model"), WithGuardrails(c.policy))
+
+		resp, err := svc.Chat(ctx, "alice", chat(trip.ID, "", "plan our temple morning"))
+		if err != nil {
+			t.Fatalf("%s: chat: %v", c.policy, err)
+		}
+		if resp.Answer != c.answer || resp.DegradedReason != c.reason || resp.Degraded != (c.reason != "") || len(llm.prompts) != c.calls {
+			t.Errorf("%s: unexpected response %+v after %d calls", c.policy, resp, len(llm.prompts))
+		}
+		if c.calls == 2 && !strings.Contains(llm.prompts[1], `- booking_claim: "I've booked"`) {
+			t.Errorf("%s: expected the retry to name the violations, got %q", c.policy, llm.prompts[1])
+		}
+		msg, _ := repo.GetMessage(ctx, resp.MessageID)
+		if msg.Content != c.answer {
+			t.Errorf("%s: expected the served answer to be stored, got %q", c.policy, msg.Content)
+		}
+	}
+}
+
+func TestGuardrailRetryFailureRewritesFirstAnswer(t *testing.T) {
+	ctx := context.Background()
+	claim := "Kinkaku-ji opens early. I've booked your tickets for 10am."
+	for _, retryErr := range []error{apiError(http.StatusTooManyRequests), errHang} {
+		repo := store.NewInMemoryAIRepository()
+		trip, _ := repo.CreateTrip(ctx, store.Trip{Destination: "Kyoto"}, "alice")
+		llm := &seqLLM{answers: []string{claim}, errs: []error{nil, retryErr}}
+		chain := []Attempt{{Provider: ProviderOpenAI, Timeout: 50 * time.Millisecond}}
+		svc := NewService(repo, llm, nil, NewModelSelector("test-model"), WithGuardrails(GuardrailRetry), WithFallbackChain(chain, nil))
+
+		resp, err := svc.Chat(ctx, "alice", chat(trip.ID, "", "plan our temple morning"))
+		if err != nil {
+			t.Fatalf("%v: expected the first answer to be served, got %v", retryErr, err)
+		}
+		if resp.Answer != "Kinkaku-ji opens early." || resp.DegradedReason != "guardrail rewritten: booking_claim, unsupported_time" || len(llm.prompts) != 2 {
+			t.Fatalf("%v: unexpected response %+v after %d calls", retryErr, resp, len(llm.prompts))
+		}
+	}
+}
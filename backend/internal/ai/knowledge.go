@@ -0,0 +1,55 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"triploom/backend/internal/knowledge"
+)
+
+// maxKnowledgePassages bounds the guide passages added to a prompt.
+const maxKnowledgePassages = 3
+
+// WithKnowledge adds the curated guide passages most relevant to each copilot and planner
+// question to its prompt, cited in the response's sources.
+func WithKnowledge(kb *knowledge.Base) Option {
+	return func(s *Service) {
+		s.knowledge = kb
+	}
+}
+
+// knowledgeRef is a guide passage as the prompt sees it; Ref is what the answer cites.
+type knowledgeRef struct {
+	Ref     string `json:"ref"`
+	Title   string `json:"title"`
+	Section string `json:"section,omitempty"`
+	Text    string `json:"text"`
+}
+
+// knowledgeContext searches the knowledge base for question among the guides covering
+// destination. A failed search only leaves the passages out.
+func (s *Service) knowledgeContext(ctx context.Context, question, destination string) ([]knowledgeRef, []Source) {
+	if s.knowledge == nil {
+		return nil, nil
+	}
+	passages, err := s.knowledge.Search(ctx, question, destination, maxKnowledgePassages)
+	if err != nil {
+		log.Printf("knowledge: search for %q: %v", destination, err)
+		return nil, nil
+	}
+	fetchedAt := time.Now().UTC().Format(time.RFC3339)
+	refs := make([]knowledgeRef, 0, len(passages))
+	sources := make([]Source, 0, len(passages))
+	for i, p := range passages {
+		ref := fmt.Sprintf("K%d", i+1)
+		refs = append(refs, knowledgeRef{Ref: ref, Title: p.Title, Section: p.Section, Text: p.Text})
+		detail := p.Title
+		if p.Section != "" {
+			detail += ": " + p.Section
+		}
+		sources = append(sources, Source{Name: "knowledge_base", Status: "ok", FetchedAt: fetchedAt, Detail: detail + " (" + p.Path + ")", Ref: ref})
+	}
+	return refs, sources
+}
@@ -0,0 +1,67 @@
+package ai
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"triploom/backend/internal/knowledge"
+	"triploom/backend/internal/store"
+)
+
+func TestKnowledgePassagesAreCited(t *testing.T) {
+	ctx := context.Background()
+	kb, err := knowledge.LoadFS(ctx, fstest.MapFS{
+		"japan.md":    {Data: []byte("# Japan\n\n## Tipping\n\nTipping is not customary in Japanese restaurants and can cause confusion.\n\n## Visas\n\nMany passports get 90 days visa-free on arrival.\n")},
+		"portugal.md": {Data: []byte("# Portugal\n\n## Tipping\n\nIn Portuguese restaurants rounding up or leaving five to ten percent is customary.\n")},
+	}, knowledge.NewHashingEmbedder(0))
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	repo := store.NewInMemoryAIRepository()
+	trip, _ := repo.CreateTrip(ctx, store.Trip{Destination: "Japan"}, "alice")
+	llm := &fakeLLM{answer: "Tipping is not customary in Japan [K1].", title: "Tipping"}
+	svc := NewService(repo, llm, nil, NewModelSelector("test-model"), WithKnowledge(kb))
+
+	resp, err := svc.Chat(ctx, "alice", chat(trip.ID, "", "is tipping customary in restaurants?"))
+	if err != nil {
+		t.Fatalf("chat: %v", err)
+	}
+	cited := knowledgeSourceDetails(resp.Sources)
+	if len(cited) != 1 || cited[0] != "K1=Japan: Tipping (japan.md)" {
+		t.Fatalf("expected only the Japan tipping passage, got %v", cited)
+	}
+	prompt := llm.calls[len(llm.calls)-2]
+	if !strings.Contains(prompt, `"knowledge":[{"ref":"K1"`) || strings.Contains(prompt, "Portuguese") {
+		t.Fatalf("expected the passage in the prompt, got %s", prompt)
+	}
+
+	planned, err := svc.PlannerChat(ctx, "alice", PlannerChatRequest{
+		Messages:       []ChatMessage{{Role: "user", Content: "is tipping customary in restaurants?"}},
+		PlannerContext: map[string]any{"destination": "Portugal"},
+	})
+	if err != nil {
+		t.Fatalf("planner: %v", err)
+	}
+	if cited := knowledgeSourceDetails(planned.Sources); len(cited) != 1 || cited[0] != "K1=Portugal: Tipping (portugal.md)" {
+		t.Fatalf("expected the Portugal tipping passage for the planner, got %v", cited)
+	}
+
+	without := NewService(repo, llm, nil, NewModelSelector("test-model"))
+	resp, _ = without.Chat(ctx, "alice", chat(trip.ID, "", "is tipping customary in restaurants?"))
+	if cited := knowledgeSourceDetails(resp.Sources); len(cited) != 0 {
+		t.Fatalf("expected no passages without a knowledge base, got %v", cited)
+	}
+}
+
+func knowledgeSourceDetails(sources []Source) []string {
+	out := make([]string, 0)
+	for _, src := range sources {
+		if src.Name == "knowledge_base" {
+			out = append(out, src.Ref+"="+src.Detail)
+		}
+	}
+	return out
+}
@@ -5,12 +5,27 @@ package ai
 // returns something other than DefaultModel when appropriate. For now it's a single-model placeholder.
 type ModelSelector struct {
 	DefaultModel string
+	// Alternatives are the other models a client may ask for when regenerating an answer.
+	Alternatives []string
 }
 
-func NewModelSelector(defaultModel string) *ModelSelector {
-	return &ModelSelector{DefaultModel: defaultModel}
+func NewModelSelector(defaultModel string, alternatives ...string) *ModelSelector {
+	return &ModelSelector{DefaultModel: defaultModel, Alternatives: alternatives}
 }
 
 func (m *ModelSelector) Select(_ string, _ []ChatMessage) string {
 	return m.DefaultModel
 }
+
+// Allowed reports whether clients may request model by name.
+func (m *ModelSelector) Allowed(model string) bool {
+	if model == m.DefaultModel {
+		return true
+	}
+	for _, alt := range m.Alternatives {
+		if alt == model {
+			return true
+		}
+	}
+	return false
+}
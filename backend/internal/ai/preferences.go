@@ -0,0 +1,239 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"triploom/backend/internal/store"
+)
+
+const (
+	// maxPreferenceUpdates bounds the preference updates suggested per answer.
+	maxPreferenceUpdates = 2
+	maxPreferenceValue   = 120
+	maxListedUpdates     = 20
+)
+
+// preferenceKeys are the travel preferences kept per user.
+var preferenceKeys = []string{"seat", "cabin", "budget", "accommodation", "neighbourhood", "pace", "diet", "transport", "accessibility", "interests"}
+
+// preferencesSchema is the part of the copilot and planner reply schemas in which the model
+// suggests preference updates.
+var preferencesSchema = map[string]any{
+	"type": "array",
+	"items": map[string]any{
+		"type":                 "object",
+		"additionalProperties": false,
+		"required":             []string{"preference", "value"},
+		"properties": map[string]any{
+			"preference": map[string]any{"type": "string", "enum": preferenceKeys},
+			"value":      map[string]any{"type": "string", "description": "empty to forget the preference"},
+		},
+	},
+}
+
+// plannerReplySchema is the structured output planner answers are requested in.
+var plannerReplySchema = map[string]any{
+	"type":                 "object",
+	"additionalProperties": false,
+	"required":             []string{"answer", "preferences"},
+	"properties": map[string]any{
+		"answer":      map[string]any{"type": "string"},
+		"preferences": preferencesSchema,
+	},
+}
+
+// proposedPreference is a preference update as the model sent it, before validation.
+type proposedPreference struct {
+	Preference string `json:"preference"`
+	Value      string `json:"value"`
+}
+
+// PreferencesResponse is a user's saved preferences and the updates awaiting their approval.
+type PreferencesResponse struct {
+	Preferences    map[string]string        `json:"preferences"`
+	UpdatedAt      *time.Time               `json:"updatedAt,omitempty"`
+	PendingUpdates []store.PreferenceUpdate `json:"pendingUpdates"`
+}
+
+type UpdatePreferencesRequest struct {
+	Preferences map[string]string `json:"preferences"`
+}
+
+// WithPreferences keeps each user's travel preferences in repo, adds them to copilot and
+// planner prompts, and stores the updates the assistant suggests for the user to approve.
+func WithPreferences(repo store.PreferenceStore) Option {
+	return func(s *Service) {
+		s.preferences = repo
+	}
+}
+
+// addPreferences puts the user's saved preferences, cleaned like page context, under
+// "preferences" in the prompt context. A failed lookup only leaves them out.
+func (s *Service) addPreferences(ctx context.Context, userID, pageKey string, contextPayload map[string]any) {
+	if s.preferences == nil {
+		return
+	}
+	prefs, err := s.preferences.GetPreferences(ctx, userID)
+	if err != nil {
+		log.Printf("preferences: get for %s: %v", userID, err)
+		return
+	}
+	if len(prefs.Preferences) > 0 {
+		contextPayload["preferences"] = s.sanitize.For(pageKey).Sanitize(prefs.Preferences, nil)
+	}
+}
+
+// suggestPreferences stores the valid preference updates in proposed as pending. Updates that
+// would change nothing, or that are already pending, are dropped.
+func (s *Service) suggestPreferences(ctx context.Context, userID, conversationID, messageID string, proposed []proposedPreference) []store.PreferenceUpdate {
+	out := make([]store.PreferenceUpdate, 0)
+	if s.preferences == nil || len(proposed) == 0 {
+		return out
+	}
+	current, err := s.preferences.GetPreferences(ctx, userID)
+	if err != nil {
+		log.Printf("preferences: get for %s: %v", userID, err)
+		return out
+	}
+	pending, err := s.preferences.ListPreferenceUpdates(ctx, userID, store.PreferenceUpdatePending, maxListedUpdates)
+	if err != nil {
+		log.Printf("preferences: list updates for %s: %v", userID, err)
+		return out
+	}
+	for _, p := range proposed {
+		key := p.Preference
+		value := strings.TrimSpace(p.Value)
+		if !contains(preferenceKeys, key) || utf8.RuneCountInString(value) > maxPreferenceValue || strings.EqualFold(current.Preferences[key], value) {
+			continue
+		}
+		if pendingUpdate(pending, key, value) {
+			continue
+		}
+		u, err := s.preferences.CreatePreferenceUpdate(ctx, store.PreferenceUpdate{
+			UserID:         userID,
+			ConversationID: conversationID,
+			MessageID:      messageID,
+			Preference:     key,
+			Value:          value,
+			Previous:       current.Preferences[key],
+		})
+		if err != nil {
+			log.Printf("preferences: create update for %s: %v", userID, err)
+			continue
+		}
+		pending = append(pending, *u)
+		out = append(out, *u)
+		if len(out) == maxPreferenceUpdates {
+			break
+		}
+	}
+	return out
+}
+
+func pendingUpdate(pending []store.PreferenceUpdate, key, value string) bool {
+	for _, u := range pending {
+		if u.Preference == key && strings.EqualFold(u.Value, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetPreferences returns the user's saved preferences and the updates awaiting approval.
+func (s *Service) GetPreferences(ctx context.Context, userID string) (*PreferencesResponse, error) {
+	if s.preferences == nil || userID == "" {
+		return nil, ErrInvalidInput
+	}
+	return s.preferencesResponse(ctx, userID, nil)
+}
+
+// UpdatePreferences replaces the user's preferences. Keys must be known preferences; empty
+// values are dropped.
+func (s *Service) UpdatePreferences(ctx context.Context, userID string, req UpdatePreferencesRequest) (*PreferencesResponse, error) {
+	if s.preferences == nil || userID == "" {
+		return nil, ErrInvalidInput
+	}
+	prefs := make(map[string]string, len(req.Preferences))
+	for key, value := range req.Preferences {
+		value = strings.TrimSpace(value)
+		if !contains(preferenceKeys, key) || utf8.RuneCountInString(value) > maxPreferenceValue {
+			return nil, ErrInvalidInput
+		}
+		if value != "" {
+			prefs[key] = value
+		}
+	}
+	saved, err := s.preferences.SavePreferences(ctx, userID, prefs)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(prefs))
+	for key := range prefs {
+		keys = append(keys, key)
+	}
+	_ = s.audit.InsertAuditLog(ctx, userID, "", "preferences_updated", map[string]any{"preferences": keys})
+	return s.preferencesResponse(ctx, userID, saved)
+}
+
+// ApprovePreferenceUpdate writes a pending update to the user's preferences.
+func (s *Service) ApprovePreferenceUpdate(ctx context.Context, userID, updateID string) (*PreferencesResponse, error) {
+	u, err := s.ownedPreferenceUpdate(ctx, userID, updateID)
+	if err != nil {
+		return nil, err
+	}
+	_, saved, err := s.preferences.ApprovePreferenceUpdate(ctx, u.ID)
+	if err != nil {
+		return nil, err
+	}
+	_ = s.audit.InsertAuditLog(ctx, userID, "", "ai_preference_approved", map[string]any{"updateId": u.ID, "preference": u.Preference})
+	return s.preferencesResponse(ctx, userID, saved)
+}
+
+// RejectPreferenceUpdate discards a pending update.
+func (s *Service) RejectPreferenceUpdate(ctx context.Context, userID, updateID string) (*PreferencesResponse, error) {
+	u, err := s.ownedPreferenceUpdate(ctx, userID, updateID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.preferences.RejectPreferenceUpdate(ctx, u.ID); err != nil {
+		return nil, err
+	}
+	_ = s.audit.InsertAuditLog(ctx, userID, "", "ai_preference_rejected", map[string]any{"updateId": u.ID, "preference": u.Preference})
+	return s.preferencesResponse(ctx, userID, nil)
+}
+
+// ownedPreferenceUpdate loads one of the user's updates. Other users' updates look the same
+// as unknown ones.
+func (s *Service) ownedPreferenceUpdate(ctx context.Context, userID, updateID string) (*store.PreferenceUpdate, error) {
+	if s.preferences == nil || updateID == "" {
+		return nil, ErrInvalidInput
+	}
+	u, err := s.preferences.GetPreferenceUpdate(ctx, updateID)
+	if errors.Is(err, store.ErrNotFound) || (err == nil && u.UserID != userID) {
+		return nil, ErrUnauthorizedTrip
+	}
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// preferencesResponse lists the pending updates alongside saved, which is loaded when nil.
+func (s *Service) preferencesResponse(ctx context.Context, userID string, saved *store.UserPreferences) (*PreferencesResponse, error) {
+	if saved == nil {
+		var err error
+		if saved, err = s.preferences.GetPreferences(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
+	pending, err := s.preferences.ListPreferenceUpdates(ctx, userID, store.PreferenceUpdatePending, maxListedUpdates)
+	if err != nil {
+		return nil, err
+	}
+	return &PreferencesResponse{Preferences: saved.Preferences, UpdatedAt: saved.UpdatedAt, PendingUpdates: pending}, nil
+}
@@ -0,0 +1,85 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"triploom/backend/internal/store"
+)
+
+func TestPreferenceUpdatesAreApprovedAndPrompted(t *testing.T) {
+	ctx := context.Background()
+	repo := store.NewInMemoryAIRepository()
+	trip, _ := repo.CreateTrip(ctx, store.Trip{Destination: "Lisbon"}, "alice")
+	prefs := store.NewInMemoryPreferenceRepository()
+	if _, err := prefs.SavePreferences(ctx, "alice", map[string]string{"seat": "aisle"}); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+
+	structured, _ := json.Marshal(map[string]any{
+		"answer":     "Alfama and Baixa are both walkable; Baixa suits a mid-range budget.",
+		"highlights": []any{},
+		"actions":    []any{},
+		"preferences": []any{
+			map[string]any{"preference": "neighbourhood", "value": "walkable"},
+			map[string]any{"preference": "budget", "value": " mid-range "},
+			map[string]any{"preference": "seat", "value": "Aisle"},
+			map[string]any{"preference": "shoe size", "value": "42"},
+		},
+	})
+	llm := &fakeLLM{answer: string(structured), title: "Lisbon"}
+	svc := NewService(repo, llm, nil, NewModelSelector("test-model"), WithPreferences(prefs))
+
+	resp, err := svc.Chat(ctx, "alice", chat(trip.ID, "", "we always want walkable areas and a mid-range budget"))
+	if err != nil {
+		t.Fatalf("chat: %v", err)
+	}
+	if len(resp.PreferenceUpdates) != 2 || resp.PreferenceUpdates[0].Preference != "neighbourhood" || resp.PreferenceUpdates[1].Value != "mid-range" || resp.PreferenceUpdates[0].MessageID != resp.MessageID {
+		t.Fatalf("expected the new preferences to be suggested, got %+v", resp.PreferenceUpdates)
+	}
+	prompt := llm.calls[len(llm.calls)-2]
+	if !strings.Contains(prompt, `"preferences":{"seat":"aisle"}`) {
+		t.Fatalf("expected the saved preferences in the prompt, got %s", prompt)
+	}
+	if got, _ := svc.GetPreferences(ctx, "alice"); len(got.Preferences) != 1 || len(got.PendingUpdates) != 2 {
+		t.Fatalf("expected nothing saved before approval, got %+v", got)
+	}
+	again, _ := svc.Chat(ctx, "alice", chat(trip.ID, resp.ConversationID, "and near a metro"))
+	if len(again.PreferenceUpdates) != 0 {
+		t.Fatalf("expected pending updates not to be suggested twice, got %+v", again.PreferenceUpdates)
+	}
+
+	neighbourhood, budget := resp.PreferenceUpdates[0], resp.PreferenceUpdates[1]
+	if _, err := svc.ApprovePreferenceUpdate(ctx, "bob", neighbourhood.ID); !errors.Is(err, ErrUnauthorizedTrip) {
+		t.Fatalf("expected another user's approval to be refused, got %v", err)
+	}
+	approved, err := svc.ApprovePreferenceUpdate(ctx, "alice", neighbourhood.ID)
+	if err != nil || approved.Preferences["neighbourhood"] != "walkable" || len(approved.PendingUpdates) != 1 {
+		t.Fatalf("unexpected approval %+v (%v)", approved, err)
+	}
+	rejected, err := svc.RejectPreferenceUpdate(ctx, "alice", budget.ID)
+	if err != nil || rejected.Preferences["budget"] != "" || len(rejected.PendingUpdates) != 0 {
+		t.Fatalf("unexpected rejection %+v (%v)", rejected, err)
+	}
+	if _, err := svc.RejectPreferenceUpdate(ctx, "alice", budget.ID); !errors.Is(err, store.ErrPreferenceUpdateDecided) {
+		t.Fatalf("expected a decided update to conflict, got %v", err)
+	}
+
+	if _, err := svc.PlannerChat(ctx, "alice", PlannerChatRequest{Messages: []ChatMessage{{Role: "user", Content: "plan a weekend in Porto"}}}); err != nil {
+		t.Fatalf("planner: %v", err)
+	}
+	if prompt := llm.calls[len(llm.calls)-1]; !strings.Contains(prompt, `"neighbourhood":"walkable"`) {
+		t.Fatalf("expected the approved preference in the planner prompt, got %s", prompt)
+	}
+
+	if _, err := svc.UpdatePreferences(ctx, "alice", UpdatePreferencesRequest{Preferences: map[string]string{"shoe size": "42"}}); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("expected unknown preferences to be rejected, got %v", err)
+	}
+	edited, err := svc.UpdatePreferences(ctx, "alice", UpdatePreferencesRequest{Preferences: map[string]string{"seat": "window", "diet": " "}})
+	if err != nil || len(edited.Preferences) != 1 || edited.Preferences["seat"] != "window" || edited.UpdatedAt == nil {
+		t.Fatalf("unexpected edit %+v (%v)", edited, err)
+	}
+}
@@ -0,0 +1,253 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"triploom/backend/internal/store"
+	"triploom/backend/internal/tripevents"
+)
+
+const maxListedProposals = 50
+
+// WithProposals stores the trip changes the copilot suggests in repo as pending proposals,
+// which members approve or reject. Without it change actions are only suggestions.
+func WithProposals(repo store.ProposalStore) Option {
+	return func(s *Service) {
+		s.proposals = repo
+	}
+}
+
+// proposalEvents are the trip events an approved proposal records.
+var proposalEvents = map[string]string{
+	store.ProposalAddItineraryItem:  tripevents.TypeItineraryUpdated,
+	store.ProposalMoveItineraryItem: tripevents.TypeItineraryUpdated,
+	store.ProposalSaveFlight:        tripevents.TypeTripFlightsUpdated,
+	store.ProposalAddExpense:        tripevents.TypeExpenseAdded,
+}
+
+// itineraryContext lists the trip's itinerary items for the prompt, so that the model can
+// refer to them by ID when proposing a move.
+func (s *Service) itineraryContext(ctx context.Context, tripID string) []store.ItineraryItem {
+	if s.proposals == nil {
+		return nil
+	}
+	items, err := s.proposals.ListItineraryItems(ctx, tripID)
+	if err != nil {
+		log.Printf("proposals: list itinerary items for %s: %v", tripID, err)
+		return nil
+	}
+	return items
+}
+
+// propose stores the change actions among actions as pending proposals, each with a preview
+// of what it would write. Moves of items the trip does not have are dropped, and actions not
+// complete enough to apply stay plain suggestions. It returns the actions, with ProposalID set
+// on the proposed ones, and the proposals.
+func (s *Service) propose(ctx context.Context, userID string, conv *store.Conversation, messageID string, trip *store.Trip, actions []SuggestedAction) ([]SuggestedAction, []store.Proposal) {
+	if s.proposals == nil {
+		return actions, nil
+	}
+	var items []store.ItineraryItem
+	out := make([]SuggestedAction, 0, len(actions))
+	proposals := make([]store.Proposal, 0)
+	for _, a := range actions {
+		if a.Kind == ActionMoveItineraryItem && items == nil {
+			items = s.itineraryContext(ctx, trip.ID)
+		}
+		p, ok := proposalFor(a, trip, items)
+		if !ok {
+			if a.Kind != ActionMoveItineraryItem {
+				out = append(out, a)
+			}
+			continue
+		}
+		p.TripID, p.ConversationID, p.MessageID, p.UserID, p.Label = trip.ID, conv.ID, messageID, userID, a.Label
+		stored, err := s.proposals.CreateProposal(ctx, p)
+		if err != nil {
+			log.Printf("proposals: create %s for %s: %v", p.Kind, trip.ID, err)
+			out = append(out, a)
+			continue
+		}
+		a.ProposalID = stored.ID
+		out = append(out, a)
+		proposals = append(proposals, *stored)
+	}
+	return out, proposals
+}
+
+// proposalFor turns a validated action into the change it would make and its preview.
+func proposalFor(a SuggestedAction, trip *store.Trip, items []store.ItineraryItem) (store.Proposal, bool) {
+	day, _ := a.Params["dayIndex"].(int)
+	block, _ := a.Params["timeBlock"].(string)
+	date, _ := a.Params["date"].(string)
+	title, _ := a.Params["title"].(string)
+	switch a.Kind {
+	case ActionAddItineraryItem:
+		if day == 0 || block == "" {
+			return store.Proposal{}, false
+		}
+		category, _ := a.Params["category"].(string)
+		if category == "" {
+			category = "activities"
+		}
+		change := store.ProposalChange{DayIndex: day, TimeBlock: block, Title: title, Category: category}
+		return store.Proposal{Kind: store.ProposalAddItineraryItem, Change: change, Preview: store.ProposalPreview{
+			Table: "trip_itinerary_items",
+			After: map[string]any{"dayIndex": day, "date": date, "timeBlock": block, "title": title, "category": category},
+		}}, true
+	case ActionMoveItineraryItem:
+		itemID, _ := a.Params["itemId"].(string)
+		for _, it := range items {
+			if it.ID != itemID {
+				continue
+			}
+			if it.DayIndex == day && it.TimeBlock == block {
+				return store.Proposal{}, false
+			}
+			change := store.ProposalChange{ItemID: it.ID, FromDayIndex: it.DayIndex, FromTimeBlock: it.TimeBlock, DayIndex: day, TimeBlock: block}
+			return store.Proposal{Kind: store.ProposalMoveItineraryItem, Change: change, Preview: store.ProposalPreview{
+				Table:  "trip_itinerary_items",
+				Before: map[string]any{"id": it.ID, "title": it.Title, "dayIndex": it.DayIndex, "timeBlock": it.TimeBlock},
+				After:  map[string]any{"id": it.ID, "title": it.Title, "dayIndex": day, "date": date, "timeBlock": block},
+			}}, true
+		}
+		return store.Proposal{}, false
+	case ActionSaveFlight:
+		number, _ := a.Params["flightNumber"].(string)
+		source := flightSource(date, trip)
+		change := store.ProposalChange{FlightNumber: number, Date: date, Source: source}
+		return store.Proposal{Kind: store.ProposalSaveFlight, Change: change, Preview: store.ProposalPreview{
+			Table: "trip_flights",
+			After: map[string]any{"flightNumber": number, "flightDate": date, "source": source},
+		}}, true
+	case ActionAddExpense:
+		amount, _ := a.Params["amount"].(float64)
+		currency, _ := a.Params["currency"].(string)
+		category, _ := a.Params["category"].(string)
+		change := store.ProposalChange{Title: title, Amount: amount, Currency: currency, Category: category, Date: date}
+		return store.Proposal{Kind: store.ProposalAddExpense, Change: change, Preview: store.ProposalPreview{
+			Table: "trip_expenses",
+			After: map[string]any{"title": title, "amount": amount, "currency": currency, "category": category, "date": date},
+		}}, true
+	}
+	return store.Proposal{}, false
+}
+
+// flightSource picks the trip_flights source for a flight on date: outbound on or before the
+// first day, inbound on or after the last, one_way in between or for undated trips.
+func flightSource(date string, trip *store.Trip) string {
+	if trip == nil || trip.StartDate.IsZero() || trip.EndDate.IsZero() {
+		return "one_way"
+	}
+	switch {
+	case date <= trip.StartDate.Format("2006-01-02"):
+		return "outbound"
+	case date >= trip.EndDate.Format("2006-01-02"):
+		return "inbound"
+	}
+	return "one_way"
+}
+
+// ListProposals returns a trip's proposals, newest first, optionally only those with status.
+func (s *Service) ListProposals(ctx context.Context, userID, tripID, status string) ([]store.Proposal, error) {
+	if s.proposals == nil || tripID == "" {
+		return nil, ErrInvalidInput
+	}
+	if status != "" && status != store.ProposalPending && status != store.ProposalApproved && status != store.ProposalRejected {
+		return nil, ErrInvalidInput
+	}
+	ok, err := s.trips.IsTripMember(ctx, tripID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrUnauthorizedTrip
+	}
+	return s.proposals.ListProposals(ctx, tripID, status, maxListedProposals)
+}
+
+// ApproveProposal applies a pending proposal to the trip. Only owners and editors may approve;
+// the change is written in the same transaction that marks the proposal approved.
+func (s *Service) ApproveProposal(ctx context.Context, userID, proposalID string) (*store.Proposal, error) {
+	p, err := s.decidableProposal(ctx, userID, proposalID)
+	if err != nil {
+		return nil, err
+	}
+	role, err := s.trips.TripRole(ctx, p.TripID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if role != "owner" && role != "editor" {
+		_ = s.audit.InsertAuditLog(ctx, userID, p.TripID, "ai_proposal_denied", map[string]any{"proposalId": p.ID, "kind": p.Kind, "role": role})
+		return nil, ErrUnauthorizedTrip
+	}
+
+	approved, err := s.proposals.ApproveProposal(ctx, proposalID, userID)
+	if err != nil {
+		if errors.Is(err, store.ErrProposalStale) {
+			_ = s.audit.InsertAuditLog(ctx, userID, p.TripID, "ai_proposal_failed", map[string]any{"proposalId": p.ID, "kind": p.Kind, "error": err.Error()})
+		}
+		return nil, err
+	}
+	_ = s.audit.InsertAuditLog(ctx, userID, p.TripID, "ai_proposal_approved", map[string]any{"proposalId": p.ID, "kind": p.Kind, "resultId": approved.ResultID, "proposedBy": p.UserID})
+	if s.events != nil {
+		_, _ = s.events.Record(ctx, p.TripID, proposalEvents[p.Kind], map[string]any{
+			"proposalId": p.ID,
+			"kind":       p.Kind,
+			"resultId":   approved.ResultID,
+			"userId":     userID,
+			"change":     approved.Preview.After,
+		})
+	}
+	return approved, nil
+}
+
+// RejectProposal discards a pending proposal. Owners, editors and the user the proposal was
+// made to may reject.
+func (s *Service) RejectProposal(ctx context.Context, userID, proposalID string) (*store.Proposal, error) {
+	p, err := s.decidableProposal(ctx, userID, proposalID)
+	if err != nil {
+		return nil, err
+	}
+	if p.UserID != userID {
+		role, err := s.trips.TripRole(ctx, p.TripID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if role != "owner" && role != "editor" {
+			_ = s.audit.InsertAuditLog(ctx, userID, p.TripID, "ai_proposal_denied", map[string]any{"proposalId": p.ID, "kind": p.Kind, "role": role})
+			return nil, ErrUnauthorizedTrip
+		}
+	}
+	rejected, err := s.proposals.RejectProposal(ctx, proposalID, userID)
+	if err != nil {
+		return nil, err
+	}
+	_ = s.audit.InsertAuditLog(ctx, userID, p.TripID, "ai_proposal_rejected", map[string]any{"proposalId": p.ID, "kind": p.Kind, "proposedBy": p.UserID})
+	return rejected, nil
+}
+
+// decidableProposal loads a proposal for a member of its trip. Unknown proposals look the same
+// as other trips' proposals.
+func (s *Service) decidableProposal(ctx context.Context, userID, proposalID string) (*store.Proposal, error) {
+	if s.proposals == nil || proposalID == "" {
+		return nil, ErrInvalidInput
+	}
+	p, err := s.proposals.GetProposal(ctx, proposalID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrUnauthorizedTrip
+	}
+	if err != nil {
+		return nil, err
+	}
+	ok, err := s.trips.IsTripMember(ctx, p.TripID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrUnauthorizedTrip
+	}
+	return p, nil
+}
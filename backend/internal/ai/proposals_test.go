@@ -0,0 +1,108 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"triploom/backend/internal/store"
+	"triploom/backend/internal/tripevents"
+)
+
+func TestChatProposalsApproveAndReject(t *testing.T) {
+	ctx := context.Background()
+	repo := store.NewInMemoryAIRepository()
+	trip, _ := repo.CreateTrip(ctx, store.Trip{Destination: "Kyoto", StartDate: time.Date(2026, 11, 2, 0, 0, 0, 0, time.UTC), EndDate: time.Date(2026, 11, 6, 0, 0, 0, 0, time.UTC)}, "alice")
+	_ = repo.AddTripMember(ctx, trip.ID, "bob", "viewer")
+	_ = repo.AddTripMember(ctx, trip.ID, "erin", "editor")
+	proposals := store.NewInMemoryProposalRepository()
+	item, _ := proposals.AddItineraryItem(ctx, store.ItineraryItem{TripID: trip.ID, DayIndex: 1, TimeBlock: "evening", Category: "food", Title: "Nishiki Market"})
+	events := tripevents.NewRecorder(store.NewInMemoryTripEventRepository(nil))
+
+	params := func(extra map[string]any) map[string]any {
+		p := map[string]any{"title": nil, "itemId": nil, "date": nil, "timeBlock": nil, "flightNumber": nil, "amount": nil, "currency": nil, "category": nil, "field": nil}
+		for k, v := range extra {
+			p[k] = v
+		}
+		return p
+	}
+	structured, _ := json.Marshal(map[string]any{
+		"answer":     "Nishiki is quieter on a weekday morning. I can move it for your approval.",
+		"highlights": []any{},
+		"actions": []any{
+			map[string]any{"kind": ActionMoveItineraryItem, "label": "Move Nishiki Market to day 3", "params": params(map[string]any{"itemId": item.ID, "date": "2026-11-04", "timeBlock": "morning"})},
+			map[string]any{"kind": ActionMoveItineraryItem, "label": "Move a ghost", "params": params(map[string]any{"itemId": "missing", "date": "2026-11-04", "timeBlock": "morning"})},
+			map[string]any{"kind": ActionAddExpense, "label": "Log the tea ceremony", "params": params(map[string]any{"title": "Tea ceremony", "amount": 45.0, "currency": "usd", "category": "activities", "date": "2026-11-03"})},
+		},
+	})
+	llm := &fakeLLM{answer: string(structured), title: "Markets"}
+	svc := NewService(repo, llm, nil, NewModelSelector("test-model"), WithProposals(proposals), WithTripEvents(events))
+
+	resp, err := svc.Chat(ctx, "alice", chat(trip.ID, "", "when is Nishiki quietest?"))
+	if err != nil {
+		t.Fatalf("chat: %v", err)
+	}
+	if len(resp.SuggestedActions) != 2 || len(resp.Proposals) != 2 || resp.SuggestedActions[0].ProposalID != resp.Proposals[0].ID {
+		t.Fatalf("expected the move and the expense to be proposed, got %+v / %+v", resp.SuggestedActions, resp.Proposals)
+	}
+	move, expense := resp.Proposals[0], resp.Proposals[1]
+	if move.Status != store.ProposalPending || move.MessageID != resp.MessageID || move.Preview.Before["dayIndex"] != 1 || move.Preview.After["dayIndex"] != 3 {
+		t.Fatalf("unexpected move proposal %+v", move)
+	}
+	if expense.Change.Currency != "USD" || expense.Change.Amount != 45 {
+		t.Fatalf("unexpected expense proposal %+v", expense)
+	}
+	if items, _ := proposals.ListItineraryItems(ctx, trip.ID); items[0].DayIndex != 1 {
+		t.Fatalf("expected nothing to change before approval, got %+v", items[0])
+	}
+
+	if _, err := svc.ApproveProposal(ctx, "bob", move.ID); !errors.Is(err, ErrUnauthorizedTrip) {
+		t.Fatalf("expected a viewer not to approve, got %v", err)
+	}
+	if _, err := svc.ApproveProposal(ctx, "mallory", move.ID); !errors.Is(err, ErrUnauthorizedTrip) {
+		t.Fatalf("expected a non-member not to approve, got %v", err)
+	}
+	approved, err := svc.ApproveProposal(ctx, "alice", move.ID)
+	if err != nil || approved.Status != store.ProposalApproved || approved.ResultID != item.ID {
+		t.Fatalf("unexpected approval %+v (%v)", approved, err)
+	}
+	if items, _ := proposals.ListItineraryItems(ctx, trip.ID); items[0].DayIndex != 3 || items[0].TimeBlock != "morning" {
+		t.Fatalf("expected the item to be moved, got %+v", items[0])
+	}
+	if _, err := svc.ApproveProposal(ctx, "alice", move.ID); !errors.Is(err, store.ErrProposalDecided) {
+		t.Fatalf("expected a decided proposal to stay decided, got %v", err)
+	}
+
+	if _, err := svc.RejectProposal(ctx, "bob", expense.ID); !errors.Is(err, ErrUnauthorizedTrip) {
+		t.Fatalf("expected a viewer not to reject another user's proposal, got %v", err)
+	}
+	if _, err := svc.RejectProposal(ctx, "erin", expense.ID); err != nil {
+		t.Fatalf("reject: %v", err)
+	}
+	if expenses, _ := proposals.ListExpenses(ctx, trip.ID); len(expenses) != 0 {
+		t.Fatalf("expected a rejected expense not to be added, got %+v", expenses)
+	}
+	if pending, _ := svc.ListProposals(ctx, "bob", trip.ID, store.ProposalPending); len(pending) != 0 {
+		t.Fatalf("expected no pending proposals, got %+v", pending)
+	}
+
+	logs, _ := repo.ListAuditLogs(ctx, trip.ID, 10)
+	actions := map[string]int{}
+	for _, l := range logs {
+		actions[l.Action]++
+	}
+	if actions["ai_proposal_approved"] != 1 || actions["ai_proposal_rejected"] != 1 || actions["ai_proposal_denied"] != 2 {
+		t.Fatalf("expected every decision to be audited, got %v", actions)
+	}
+	evs, _ := events.List(ctx, trip.ID, 10)
+	if len(evs) == 0 || evs[0].Type != tripevents.TypeItineraryUpdated || evs[0].PayloadJSON["proposalId"] != move.ID {
+		t.Fatalf("expected an itinerary_updated event for the approval, got %+v", evs)
+	}
+	for _, ev := range evs {
+		if ev.Type == tripevents.TypeAIChatCompleted && (len(ev.PayloadJSON) != 3 || ev.PayloadJSON["conversationId"] != resp.ConversationID || ev.PayloadJSON["answer"] != nil) {
+			t.Fatalf("expected ai_chat_completed to carry only the thread, page and degraded flag, got %v", ev.PayloadJSON)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"triploom/backend/internal/cassette"
+	"triploom/backend/internal/store"
+)
+
+// Replay runs the player's recorded request again through a fresh in-memory service whose
+// model, bridge and flight status calls are answered by player. It returns a *ChatResponse or a
+// *PlannerChatResponse. A call the cassette has no recording of fails with
+// cassette.ErrNoMatch, except for conversation titles, which fall back as they do live.
+//
+// opts are applied last; pass WithPrompts to replay against the template versions that were
+// served when the cassette was recorded.
+func Replay(ctx context.Context, player *cassette.Player, opts ...Option) (any, error) {
+	c := player.Cassette()
+	repo := store.NewInMemoryAIRepository()
+	base := make([]Option, 0, len(opts)+1)
+	if player.HasFlightStatus() {
+		base = append(base, WithFlightStatusProvider(player))
+	}
+	svc := NewService(repo, player, player, NewModelSelector(c.Model()), append(base, opts...)...)
+
+	switch c.Kind {
+	case cassette.KindChat:
+		var req ChatRequest
+		if err := json.Unmarshal(c.Request, &req); err != nil {
+			return nil, fmt.Errorf("decode chat request: %w", err)
+		}
+		if c.Trip != nil {
+			if _, err := repo.CreateTrip(ctx, *c.Trip, c.UserID); err != nil {
+				return nil, err
+			}
+		}
+		// The recorded thread does not exist in the fresh store.
+		req.ConversationID = ""
+		resp, err := svc.Chat(ctx, c.UserID, req)
+		return resp, err
+	case cassette.KindPlanner:
+		var req PlannerChatRequest
+		if err := json.Unmarshal(c.Request, &req); err != nil {
+			return nil, fmt.Errorf("decode planner request: %w", err)
+		}
+		resp, err := svc.PlannerChat(ctx, c.UserID, req)
+		return resp, err
+	default:
+		return nil, fmt.Errorf("unknown cassette kind %q", c.Kind)
+	}
+}
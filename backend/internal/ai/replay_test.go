@@ -0,0 +1,96 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"triploom/backend/internal/cassette"
+	"triploom/backend/internal/prompts"
+	"triploom/backend/internal/store"
+)
+
+// fakeBridge answers Next bridge calls by path.
+type fakeBridge map[string]map[string]any
+
+func (b fakeBridge) PostJSON(_ context.Context, path string, _ any) (map[string]any, error) {
+	return b[path], nil
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	repo := store.NewInMemoryAIRepository()
+	trip, _ := repo.CreateTrip(ctx, store.Trip{Destination: "Prague", Timezone: "Europe/Prague"}, "alice")
+	bridge := fakeBridge{"/api/transit/suggest": {"options": []any{map[string]any{"mode": "bus", "departs": "08:00", "minutes": 270.0}}}}
+	llm := &fakeLLM{answer: "The 08:00 bus takes 4.5 hours.", title: "Berlin to Prague"}
+	svc := NewService(repo, llm, bridge, NewModelSelector("test-model"), WithCassettes(cassette.NewRecorder(dir)))
+
+	req := ChatRequest{TripID: trip.ID, PageKey: "transit", Refresh: true, Messages: []ChatMessage{{Role: "user", Content: "from Berlin to Prague"}}}
+	live, err := svc.Chat(ctx, "alice", req)
+	if err != nil {
+		t.Fatalf("chat: %v", err)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*-chat-*.json"))
+	if len(files) != 1 {
+		t.Fatalf("expected one cassette, got %v", files)
+	}
+	c, err := cassette.Load(files[0])
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(c.Interactions) != 3 || c.Trip == nil || c.Trip.ID == trip.ID || !strings.Contains(string(c.Request), c.Trip.ID) {
+		t.Fatalf("expected the bridge, answer and title calls and the trip under a placeholder ID, got %+v", c)
+	}
+
+	player := cassette.NewPlayer(c)
+	resp, err := Replay(ctx, player)
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	replayed := resp.(*ChatResponse)
+	if replayed.Answer != live.Answer || replayed.ConversationTitle != "Berlin to Prague" || len(player.Unused()) != 0 {
+		t.Fatalf("expected the recorded answer, got %+v (unused %v)", replayed, player.Unused())
+	}
+	if diffs := c.Diff(replayed, "answer"); len(diffs) != 0 {
+		t.Fatalf("unexpected differences %v", diffs)
+	}
+
+	defs, _ := prompts.Pin(append(prompts.Bundled(), prompts.Definition{Name: "copilot", Version: "v99", Body: "Reworded for {{.PageKey}}"}), "copilot@v99")
+	reworded, _ := prompts.New(defs)
+	if _, err := Replay(ctx, cassette.NewPlayer(c), WithPrompts(reworded)); err != nil {
+		t.Fatalf("expected a reworded prompt with the same context to replay, got %v", err)
+	}
+	strict := cassette.NewPlayer(c)
+	strict.MatchSystemPrompt = true
+	if _, err := Replay(ctx, strict, WithPrompts(reworded)); !errors.Is(err, cassette.ErrNoMatch) {
+		t.Fatalf("expected a changed prompt not to match the recording, got %v", err)
+	}
+}
+
+// TestCassettes replays the regression cassettes in testdata/cassettes. Record new ones with
+// CASSETTE_DIR and copy the file here. Model calls match on their ContextJSON, so rewording a
+// template keeps them valid; a change to the context itself means re-recording them.
+func TestCassettes(t *testing.T) {
+	files, _ := filepath.Glob(filepath.Join("testdata", "cassettes", "*.json"))
+	if len(files) == 0 {
+		t.Fatalf("no cassettes found")
+	}
+	for _, path := range files {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			c, err := cassette.Load(path)
+			if err != nil {
+				t.Fatalf("load: %v", err)
+			}
+			resp, err := Replay(context.Background(), cassette.NewPlayer(c))
+			if err != nil {
+				t.Fatalf("replay: %v", err)
+			}
+			for _, d := range c.Diff(resp, "answer", "highlights", "suggestedActions", "plannerDraft") {
+				t.Error(d)
+			}
+		})
+	}
+}
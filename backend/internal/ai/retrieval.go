@@ -0,0 +1,190 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"triploom/backend/internal/retrieval"
+	"triploom/backend/internal/store"
+)
+
+// DefaultRetrievalTokens is the prompt budget for retrieved trip data.
+const DefaultRetrievalTokens = 1200
+
+const (
+	// maxRecalledMessages bounds the earlier messages considered for retrieval.
+	maxRecalledMessages = 8
+	maxRecalledRunes    = 600
+	// maxRecallTerms bounds the message searches run per question.
+	maxRecallTerms = 4
+)
+
+// retrievalSources names the Source each document kind is cited as.
+var retrievalSources = map[string]string{
+	"flight":       "trip_flights",
+	"itinerary":    "trip_itinerary",
+	"expense":      "trip_expenses",
+	"conversation": "conversation_history",
+}
+
+// WithRetrievalBudget caps the trip data retrieved into each copilot prompt at tokens
+// (estimated); 0 turns retrieval off. The default is DefaultRetrievalTokens.
+func WithRetrievalBudget(tokens int) Option {
+	return func(s *Service) {
+		s.retrievalTokens = tokens
+	}
+}
+
+// retrieved is a retrieval result as the prompt sees it; Ref is what the answer cites.
+type retrieved struct {
+	Ref   string `json:"ref"`
+	Kind  string `json:"kind"`
+	Title string `json:"title"`
+	Text  string `json:"text"`
+}
+
+// retrieve ranks the trip's saved flights, itinerary items, expenses and the user's earlier
+// messages about it against question, and keeps the best within the token budget. Messages
+// of conversationID are left out; they are already in the prompt.
+func (s *Service) retrieve(ctx context.Context, userID string, trip *store.Trip, conversationID, question string) ([]retrieved, []Source) {
+	if s.retrievalTokens <= 0 {
+		return nil, nil
+	}
+	docs := s.tripDocuments(ctx, trip)
+	docs = append(docs, s.conversationDocuments(ctx, userID, trip.ID, conversationID, question)...)
+	if len(docs) == 0 {
+		return nil, nil
+	}
+
+	fetchedAt := time.Now().UTC().Format(time.RFC3339)
+	kept := retrieval.Budget(retrieval.Rank(question, docs), s.retrievalTokens)
+	items := make([]retrieved, 0, len(kept))
+	sources := make([]Source, 0, len(kept))
+	for i, r := range kept {
+		ref := fmt.Sprintf("R%d", i+1)
+		items = append(items, retrieved{Ref: ref, Kind: r.Kind, Title: r.Title, Text: r.Text})
+		sources = append(sources, Source{Name: retrievalSources[r.Kind], Status: "ok", FetchedAt: fetchedAt, Detail: r.Title, Ref: ref})
+	}
+	return items, sources
+}
+
+// tripDocuments turns the trip's persisted plans into retrieval documents.
+func (s *Service) tripDocuments(ctx context.Context, trip *store.Trip) []retrieval.Document {
+	if s.proposals == nil {
+		return nil
+	}
+	docs := make([]retrieval.Document, 0)
+	flights, err := s.proposals.ListSavedFlights(ctx, trip.ID)
+	if err != nil {
+		log.Printf("retrieval: list flights for %s: %v", trip.ID, err)
+	}
+	for _, f := range flights {
+		name := strings.TrimSpace(f.FlightNumber + " " + f.Airline)
+		if name == "" {
+			name = f.Route
+		}
+		docs = append(docs, retrieval.Document{
+			ID:    "flight:" + f.ID,
+			Kind:  "flight",
+			Title: strings.TrimSpace(strings.ReplaceAll(f.Source, "_", "-") + " flight " + name),
+			Text:  joinFields(f.Route, f.FlightDate, prefixed("departs ", f.Departure), prefixed("arrives ", f.Arrival), f.Cost),
+		})
+	}
+	items, err := s.proposals.ListItineraryItems(ctx, trip.ID)
+	if err != nil {
+		log.Printf("retrieval: list itinerary for %s: %v", trip.ID, err)
+	}
+	for _, it := range items {
+		day := fmt.Sprintf("day %d", it.DayIndex)
+		if !trip.StartDate.IsZero() {
+			day += " (" + trip.StartDate.AddDate(0, 0, it.DayIndex-1).Format("2006-01-02") + ")"
+		}
+		docs = append(docs, retrieval.Document{
+			ID:    "itinerary:" + it.ID,
+			Kind:  "itinerary",
+			Title: it.Title,
+			Text:  joinFields(day, it.TimeBlock, it.Category, it.Status, it.LocationLabel, it.Notes),
+		})
+	}
+	expenses, err := s.proposals.ListExpenses(ctx, trip.ID)
+	if err != nil {
+		log.Printf("retrieval: list expenses for %s: %v", trip.ID, err)
+	}
+	for _, e := range expenses {
+		docs = append(docs, retrieval.Document{
+			ID:    "expense:" + e.ID,
+			Kind:  "expense",
+			Title: e.Title,
+			Text:  joinFields(e.Category, fmt.Sprintf("%.2f %s", e.Amount, e.Currency), e.Date, prefixed("paid by ", e.PayerName), e.Notes),
+		})
+	}
+	return docs
+}
+
+// conversationDocuments recalls the user's earlier messages about the trip that share words
+// with question. Message search wants every word to match, so each term is searched alone.
+func (s *Service) conversationDocuments(ctx context.Context, userID, tripID, conversationID, question string) []retrieval.Document {
+	seen := make(map[string]bool)
+	hits := make([]store.MessageHit, 0)
+	for _, term := range retrieval.Terms(question) {
+		if seen[term] || len(seen) == maxRecallTerms {
+			continue
+		}
+		seen[term] = true
+		found, err := s.conversations.SearchMessages(ctx, userID, tripID, term, maxRecalledMessages)
+		if err != nil {
+			log.Printf("retrieval: search messages for %s: %v", tripID, err)
+			return nil
+		}
+		hits = append(hits, found...)
+	}
+
+	recalled := make(map[string]bool)
+	docs := make([]retrieval.Document, 0)
+	for _, hit := range hits {
+		if hit.ConversationID == conversationID || recalled[hit.MessageID] || len(recalled) == maxRecalledMessages {
+			continue
+		}
+		recalled[hit.MessageID] = true
+		msg, err := s.conversations.GetMessage(ctx, hit.MessageID)
+		if err != nil {
+			continue
+		}
+		text := msg.Content
+		if utf8.RuneCountInString(text) > maxRecalledRunes {
+			text = string([]rune(text)[:maxRecalledRunes]) + "…"
+		}
+		title := hit.ConversationTitle
+		if title == "" {
+			title = "Earlier conversation"
+		}
+		docs = append(docs, retrieval.Document{
+			ID:    "message:" + msg.ID,
+			Kind:  "conversation",
+			Title: title,
+			Text:  fmt.Sprintf("%s on %s: %s", msg.Role, msg.CreatedAt.UTC().Format("2006-01-02"), text),
+		})
+	}
+	return docs
+}
+
+func joinFields(fields ...string) string {
+	out := make([]string, 0, len(fields))
+	for _, f := range fields {
+		if f = strings.TrimSpace(f); f != "" {
+			out = append(out, f)
+		}
+	}
+	return strings.Join(out, ", ")
+}
+
+func prefixed(prefix, value string) string {
+	if strings.TrimSpace(value) == "" {
+		return ""
+	}
+	return prefix + value
+}
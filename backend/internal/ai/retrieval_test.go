@@ -0,0 +1,119 @@
+package ai
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"triploom/backend/internal/store"
+)
+
+func TestChatRetrievesTripData(t *testing.T) {
+	ctx := context.Background()
+	repo := store.NewInMemoryAIRepository()
+	trip, _ := repo.CreateTrip(ctx, store.Trip{Destination: "Kyoto", StartDate: time.Date(2026, 11, 2, 0, 0, 0, 0, time.UTC), EndDate: time.Date(2026, 11, 6, 0, 0, 0, 0, time.UTC)}, "alice")
+	data := store.NewInMemoryProposalRepository()
+	_, _ = data.AddItineraryItem(ctx, store.ItineraryItem{TripID: trip.ID, DayIndex: 2, TimeBlock: "morning", Category: "activities", Title: "Fushimi Inari"})
+	for _, change := range []store.ProposalChange{
+		{FlightNumber: "JL1", Date: "2026-11-01", Source: "outbound"},
+		{Title: "Ryokan deposit", Category: "hotels", Amount: 300, Currency: "USD", Date: "2026-10-01"},
+	} {
+		kind := store.ProposalSaveFlight
+		if change.Amount > 0 {
+			kind = store.ProposalAddExpense
+		}
+		p, _ := data.CreateProposal(ctx, store.Proposal{TripID: trip.ID, UserID: "alice", Kind: kind, Change: change})
+		if _, err := data.ApproveProposal(ctx, p.ID, "alice"); err != nil {
+			t.Fatalf("seed %s: %v", kind, err)
+		}
+	}
+	llm := &fakeLLM{answer: "Your ryokan deposit was 300 USD [R1].", title: "Costs"}
+	svc := NewService(repo, llm, nil, NewModelSelector("test-model"), WithProposals(data))
+
+	earlier, _ := svc.Chat(ctx, "alice", chat(trip.ID, "", "we want a quiet ryokan near Gion"))
+	resp, err := svc.Chat(ctx, "alice", ChatRequest{TripID: trip.ID, PageKey: "finance", Messages: []ChatMessage{{Role: "user", Content: "how much was the ryokan deposit?"}}, ConversationID: mustCreateConversation(t, svc, trip.ID)})
+	if err != nil {
+		t.Fatalf("chat: %v", err)
+	}
+	refs := make([]string, 0)
+	for _, src := range resp.Sources {
+		if src.Ref != "" {
+			refs = append(refs, src.Ref+"="+src.Name+":"+src.Detail)
+		}
+	}
+	if len(refs) < 3 || refs[0] != "R1=trip_expenses:Ryokan deposit" {
+		t.Fatalf("expected the expense to be retrieved first, got %v", refs)
+	}
+	joined := strings.Join(refs, " ")
+	if !strings.Contains(joined, "conversation_history:") || !strings.Contains(joined, "trip_flights:outbound flight JL1") || !strings.Contains(joined, "trip_itinerary:Fushimi Inari") {
+		t.Fatalf("expected the whole trip and the earlier conversation within the budget, got %v", refs)
+	}
+	prompt := llm.calls[len(llm.calls)-2]
+	if !strings.Contains(prompt, `"ref":"R1"`) || !strings.Contains(prompt, "300.00 USD") {
+		t.Fatalf("expected the retrieved items in the prompt, got %s", prompt)
+	}
+	if resp.ConversationID == earlier.ConversationID {
+		t.Fatalf("expected a separate conversation")
+	}
+
+	tight := NewService(repo, llm, nil, NewModelSelector("test-model"), WithProposals(data), WithRetrievalBudget(20))
+	resp, _ = tight.Chat(ctx, "alice", ChatRequest{TripID: trip.ID, PageKey: "finance", Messages: []ChatMessage{{Role: "user", Content: "how much was the ryokan deposit?"}}})
+	if n := countRefs(resp.Sources); n == 0 || n >= len(refs) || resp.Sources[1].Detail != "Ryokan deposit" {
+		t.Fatalf("expected the budget to keep the best match and drop others, got %+v", resp.Sources)
+	}
+	off := NewService(repo, llm, nil, NewModelSelector("test-model"), WithProposals(data), WithRetrievalBudget(0))
+	resp, _ = off.Chat(ctx, "alice", ChatRequest{TripID: trip.ID, PageKey: "finance", Messages: []ChatMessage{{Role: "user", Content: "how much was the ryokan deposit?"}}})
+	if n := countRefs(resp.Sources); n != 0 {
+		t.Fatalf("expected retrieval to be off, got %d sources", n)
+	}
+}
+
+func TestRetrievedThreadsStayPrivate(t *testing.T) {
+	ctx := context.Background()
+	llm := &fakeLLM{answer: "Try the night ferry.", title: "Ferries"}
+	svc, repo, trip := newTestService(t, llm)
+	_ = repo.AddTripMember(ctx, trip.ID, "bob", "editor")
+
+	bobs, _ := svc.Chat(ctx, "bob", chat(trip.ID, "", "which ferries run at night?"))
+	_, _ = svc.Chat(ctx, "alice", chat(trip.ID, "", "surprise for bob: okapi ferries to the island"))
+	if _, err := svc.Chat(ctx, "alice", chat(trip.ID, mustCreateConversation(t, svc, trip.ID), "which okapi ferries run at night?")); err != nil {
+		t.Fatalf("chat: %v", err)
+	}
+	if prompt := llm.calls[len(llm.calls)-2]; !strings.Contains(prompt, "surprise for bob") {
+		t.Fatalf("expected alice's own thread in her prompt, got %s", prompt)
+	}
+	snap, _ := repo.LatestContextSnapshot(ctx, trip.ID, "itinerary")
+	if untrusted, _ := snap.ContextJSON["untrusted"].(map[string]any); untrusted["retrieved"] != nil {
+		t.Fatalf("expected retrieved items to stay out of the trip snapshot, got %v", snap.ContextJSON)
+	}
+
+	calls := len(llm.calls)
+	if _, err := svc.Regenerate(ctx, "bob", bobs.MessageID, RegenerateRequest{}); err != nil {
+		t.Fatalf("regenerate: %v", err)
+	}
+	for _, prompt := range llm.calls[calls:] {
+		if strings.Contains(prompt, "okapi") {
+			t.Fatalf("expected alice's thread to stay out of bob's prompt, got %s", prompt)
+		}
+	}
+}
+
+func mustCreateConversation(t *testing.T, svc *Service, tripID string) string {
+	t.Helper()
+	conv, err := svc.CreateConversation(context.Background(), "alice", CreateConversationRequest{TripID: tripID})
+	if err != nil {
+		t.Fatalf("create conversation: %v", err)
+	}
+	return conv.ID
+}
+
+func countRefs(sources []Source) int {
+	n := 0
+	for _, src := range sources {
+		if src.Ref != "" {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,256 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// How instruction-like text in untrusted data is handled.
+const (
+	// InstructionsStrip replaces it with [filtered].
+	InstructionsStrip = "strip"
+	// InstructionsEscape keeps it, quoted and marked as untrusted.
+	InstructionsEscape = "escape"
+	InstructionsKeep   = "keep"
+)
+
+// Kinds of personal data redacted from untrusted data.
+const (
+	PIIEmail    = "email"
+	PIIPhone    = "phone"
+	PIIPassport = "passport"
+)
+
+// SanitizePolicy limits what page fields and tool responses may put into a prompt.
+type SanitizePolicy struct {
+	// MaxFieldRunes truncates each string value.
+	MaxFieldRunes int
+	// MaxItems truncates each list.
+	MaxItems int
+	// MaxTotalBytes bounds the JSON of one untrusted value; a larger value is left out.
+	MaxTotalBytes int
+	Instructions  string
+	Redact        []string
+}
+
+// DefaultSanitizePolicy applies to every pageKey without its own policy.
+var DefaultSanitizePolicy = SanitizePolicy{
+	MaxFieldRunes: 2000,
+	MaxItems:      50,
+	MaxTotalBytes: 16000,
+	Instructions:  InstructionsStrip,
+	Redact:        []string{PIIEmail, PIIPhone, PIIPassport},
+}
+
+// SanitizePolicies maps pageKeys to policies. The "*" entry, when present, replaces
+// DefaultSanitizePolicy.
+type SanitizePolicies map[string]SanitizePolicy
+
+// For returns the policy for pageKey.
+func (p SanitizePolicies) For(pageKey string) SanitizePolicy {
+	if policy, ok := p[pageKey]; ok {
+		return policy
+	}
+	if policy, ok := p["*"]; ok {
+		return policy
+	}
+	return DefaultSanitizePolicy
+}
+
+// ParseSanitizePolicies reads entries of the form page:setting=value, separated by commas,
+// e.g. "*:maxField=1000,finance:redact=email+phone,docs:instructions=escape". Settings are
+// maxField, maxItems, maxTotal, instructions (strip, escape or keep) and redact (kinds joined
+// with +, or none). A page starts from the "*" policy, which starts from
+// DefaultSanitizePolicy.
+func ParseSanitizePolicies(raw string) (SanitizePolicies, error) {
+	type entry struct{ page, key, value string }
+	entries := make([]entry, 0)
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		page, setting, ok := strings.Cut(part, ":")
+		key, value, ok2 := strings.Cut(setting, "=")
+		if !ok || !ok2 || strings.TrimSpace(page) == "" {
+			return nil, fmt.Errorf("invalid sanitize setting %q: want page:setting=value", part)
+		}
+		entries = append(entries, entry{strings.TrimSpace(page), strings.TrimSpace(key), strings.TrimSpace(value)})
+	}
+
+	out := SanitizePolicies{"*": DefaultSanitizePolicy}
+	// Defaults first, so that page entries inherit them whatever the order.
+	for _, pass := range []bool{true, false} {
+		for _, e := range entries {
+			if (e.page == "*") != pass {
+				continue
+			}
+			policy, ok := out[e.page]
+			if !ok {
+				policy = out["*"]
+			}
+			if err := policy.set(e.key, e.value); err != nil {
+				return nil, fmt.Errorf("invalid sanitize setting %s:%s=%s: %w", e.page, e.key, e.value, err)
+			}
+			out[e.page] = policy
+		}
+	}
+	return out, nil
+}
+
+func (p *SanitizePolicy) set(key, value string) error {
+	switch key {
+	case "maxField", "maxItems", "maxTotal":
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			return fmt.Errorf("want a positive integer")
+		}
+		switch key {
+		case "maxField":
+			p.MaxFieldRunes = n
+		case "maxItems":
+			p.MaxItems = n
+		default:
+			p.MaxTotalBytes = n
+		}
+	case "instructions":
+		if value != InstructionsStrip && value != InstructionsEscape && value != InstructionsKeep {
+			return fmt.Errorf("want strip, escape or keep")
+		}
+		p.Instructions = value
+	case "redact":
+		kinds := make([]string, 0)
+		if value != "none" {
+			for _, kind := range strings.Split(value, "+") {
+				if kind != PIIEmail && kind != PIIPhone && kind != PIIPassport {
+					return fmt.Errorf("unknown kind %q", kind)
+				}
+				kinds = append(kinds, kind)
+			}
+		}
+		p.Redact = kinds
+	default:
+		return fmt.Errorf("unknown setting")
+	}
+	return nil
+}
+
+var (
+	instructionLike = regexp.MustCompile(`(?i)\b(?:ignore|disregard|forget|override)\b[^.\n]{0,40}?\b(?:previous|prior|above|earlier|all|any|system|your)\b[^.\n]{0,20}?\b(?:instructions?|rules|prompts?|messages|guidelines)\b` +
+		`|\byou are now\b|\bnew (?:instructions|rules|system prompt)\b` +
+		`|\b(?:reveal|print|show|repeat)\b[^.\n]{0,30}\b(?:system prompt|instructions)\b` +
+		`|(?m:^\s*(?:system|assistant|developer)\s*:)|<\|[^|>]{1,40}\|>|\[/?(?:INST|SYS)\]`)
+
+	piiPatterns = []struct {
+		kind string
+		re   *regexp.Regexp
+	}{
+		{PIIEmail, regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)},
+		{PIIPhone, regexp.MustCompile(`\+\d{1,3}[\s.-]?\(?\d{1,4}\)?(?:[\s.-]?\d{2,4}){2,4}\b|\(?\b\d{3}\)?[\s.-]\d{3}[\s.-]\d{4}\b`)},
+		{PIIPassport, regexp.MustCompile(`(?i:passport)(?:\s*(?i:no\.?|number|#))?\s*:?\s*[A-Z0-9]{6,9}\b|\b[A-Z]{1,2}\d{6,8}\b`)},
+	}
+)
+
+// SanitizeReport counts what sanitising changed, for the audit log.
+type SanitizeReport struct {
+	Redacted     map[string]int `json:"redacted,omitempty"`
+	Instructions int            `json:"instructions,omitempty"`
+	Truncated    int            `json:"truncated,omitempty"`
+	Omitted      int            `json:"omitted,omitempty"`
+}
+
+func (r *SanitizeReport) empty() bool {
+	return len(r.Redacted) == 0 && r.Instructions == 0 && r.Truncated == 0 && r.Omitted == 0
+}
+
+// Sanitize returns a copy of v, as generic JSON values, that is safe to put in a prompt under
+// the policy: personal data redacted, instruction-like text handled, and sizes capped. What
+// changed is counted in report, which may be nil.
+func (p SanitizePolicy) Sanitize(v any, report *SanitizeReport) any {
+	if report == nil {
+		report = &SanitizeReport{}
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		report.Omitted++
+		return map[string]any{"omitted": "value could not be encoded"}
+	}
+	var generic any
+	_ = json.Unmarshal(raw, &generic)
+	clean := p.walk(generic, report)
+	if out, _ := json.Marshal(clean); p.MaxTotalBytes > 0 && len(out) > p.MaxTotalBytes {
+		report.Omitted++
+		return map[string]any{"omitted": fmt.Sprintf("%d bytes exceeds the %d byte limit", len(out), p.MaxTotalBytes)}
+	}
+	return clean
+}
+
+func (p SanitizePolicy) walk(v any, report *SanitizeReport) any {
+	switch v := v.(type) {
+	case string:
+		return p.text(v, report)
+	case []any:
+		items := v
+		if p.MaxItems > 0 && len(items) > p.MaxItems {
+			report.Truncated++
+			items = items[:p.MaxItems]
+		}
+		out := make([]any, 0, len(items)+1)
+		for _, item := range items {
+			out = append(out, p.walk(item, report))
+		}
+		if len(items) < len(v) {
+			out = append(out, fmt.Sprintf("[%d more items omitted]", len(v)-len(items)))
+		}
+		return out
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, item := range v {
+			out[p.text(k, report)] = p.walk(item, report)
+		}
+		return out
+	}
+	return v
+}
+
+func (p SanitizePolicy) text(s string, report *SanitizeReport) string {
+	for _, pii := range piiPatterns {
+		if !p.redacts(pii.kind) {
+			continue
+		}
+		s = pii.re.ReplaceAllStringFunc(s, func(string) string {
+			if report.Redacted == nil {
+				report.Redacted = make(map[string]int)
+			}
+			report.Redacted[pii.kind]++
+			return "[redacted " + pii.kind + "]"
+		})
+	}
+	if p.Instructions != InstructionsKeep {
+		s = instructionLike.ReplaceAllStringFunc(s, func(m string) string {
+			report.Instructions++
+			if p.Instructions == InstructionsEscape {
+				return fmt.Sprintf("[untrusted text: %q]", m)
+			}
+			return "[filtered]"
+		})
+	}
+	if p.MaxFieldRunes > 0 && utf8.RuneCountInString(s) > p.MaxFieldRunes {
+		report.Truncated++
+		s = string([]rune(s)[:p.MaxFieldRunes]) + "…[truncated]"
+	}
+	return s
+}
+
+func (p SanitizePolicy) redacts(kind string) bool {
+	for _, k := range p.Redact {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
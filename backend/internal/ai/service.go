@@ -4,14 +4,21 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log"
+	"maps"
 	"regexp"
 	"strconv"
 	"strings"
 	"time"
 
-	"triploom/backend/internal/providers/nextbridge"
+	"triploom/backend/internal/cache"
+	"triploom/backend/internal/cassette"
+	"triploom/backend/internal/knowledge"
+	"triploom/backend/internal/prompts"
+	"triploom/backend/internal/providers/flightstatus"
 	"triploom/backend/internal/providers/openai"
 	"triploom/backend/internal/store"
+	"triploom/backend/internal/tripevents"
 )
 
 var (
@@ -25,11 +32,14 @@ type ChatMessage struct {
 }
 
 type ChatRequest struct {
-	TripID      string         `json:"tripId"`
-	PageKey     string         `json:"pageKey"`
-	PageContext map[string]any `json:"pageContext"`
-	Messages    []ChatMessage  `json:"messages"`
-	Refresh     bool           `json:"refresh"`
+	TripID string `json:"tripId"`
+	// ConversationID continues a specific thread; when empty the most recent active thread
+	// is continued, or a new one started.
+	ConversationID string         `json:"conversationId"`
+	PageKey        string         `json:"pageKey"`
+	PageContext    map[string]any `json:"pageContext"`
+	Messages       []ChatMessage  `json:"messages"`
+	Refresh        bool           `json:"refresh"`
 }
 
 type PlannerChatRequest struct {
@@ -43,15 +53,31 @@ type Source struct {
 	Status    string `json:"status"`
 	FetchedAt string `json:"fetchedAt"`
 	Detail    string `json:"detail,omitempty"`
+	// Ref is how the answer cites a retrieved item, e.g. R1 for [R1].
+	Ref string `json:"ref,omitempty"`
 }
 
 type ChatResponse struct {
-	ConversationID   string   `json:"conversationId"`
-	Answer           string   `json:"answer"`
-	Highlights       []string `json:"highlights"`
-	SuggestedActions []string `json:"suggestedActions"`
-	Sources          []Source `json:"sources"`
-	Degraded         bool     `json:"degraded"`
+	ConversationID    string `json:"conversationId"`
+	ConversationTitle string `json:"conversationTitle"`
+	// MessageID identifies the stored answer, for feedback and regeneration.
+	MessageID        string            `json:"messageId"`
+	RegeneratedFrom  string            `json:"regeneratedFrom,omitempty"`
+	Answer           string            `json:"answer"`
+	Highlights       []Highlight       `json:"highlights"`
+	SuggestedActions []SuggestedAction `json:"suggestedActions"`
+	Sources          []Source          `json:"sources"`
+	Degraded         bool              `json:"degraded"`
+	// DegradedReason explains a degraded answer: a fallback answered, or the guardrails
+	// rewrote or flagged it.
+	DegradedReason string `json:"degradedReason,omitempty"`
+	// Attempt is the step of the fallback chain that answered.
+	Attempt AnswerAttempt `json:"attempt"`
+	// Proposals are the suggested trip changes stored for approval.
+	Proposals []store.Proposal `json:"proposals,omitempty"`
+	// PreferenceUpdates are the suggested changes to the user's saved preferences, stored for
+	// the user to approve.
+	PreferenceUpdates []store.PreferenceUpdate `json:"preferenceUpdates,omitempty"`
 }
 
 type PlannerDraftItem struct {
@@ -75,10 +101,15 @@ type PlannerDraft struct {
 }
 
 type PlannerChatResponse struct {
-	Answer       string        `json:"answer"`
-	Sources      []Source      `json:"sources"`
-	Degraded     bool          `json:"degraded"`
-	PlannerDraft *PlannerDraft `json:"plannerDraft,omitempty"`
+	Answer   string   `json:"answer"`
+	Sources  []Source `json:"sources"`
+	Degraded bool     `json:"degraded"`
+	// DegradedReason explains a degraded answer: a fallback answered, or the guardrails
+	// rewrote or flagged it.
+	DegradedReason    string                   `json:"degradedReason,omitempty"`
+	Attempt           AnswerAttempt            `json:"attempt"`
+	PlannerDraft      *PlannerDraft            `json:"plannerDraft,omitempty"`
+	PreferenceUpdates []store.PreferenceUpdate `json:"preferenceUpdates,omitempty"`
 }
 
 type RefreshContextRequest struct {
@@ -91,23 +122,181 @@ type RefreshContextResponse struct {
 	PageKey   string `json:"pageKey"`
 }
 
+type CreateConversationRequest struct {
+	TripID string `json:"tripId"`
+	Title  string `json:"title"`
+}
+
+// UpdateConversationRequest changes only the fields that are set.
+type UpdateConversationRequest struct {
+	Title    *string `json:"title"`
+	Archived *bool   `json:"archived"`
+}
+
+// LLM is the model call the service depends on; *openai.Client implements it.
+type LLM interface {
+	ResponsesChat(ctx context.Context, model string, systemPrompt string, messages []openai.Message) (*openai.ChatResult, error)
+}
+
+// Bridge is the Next app's API the service reads live data through; *nextbridge.Client
+// implements it.
+type Bridge interface {
+	PostJSON(ctx context.Context, path string, body any) (map[string]any, error)
+}
+
 type Service struct {
-	repo          *store.AIRepository
-	openaiClient  *openai.Client
-	nextClient    *nextbridge.Client
+	trips         store.TripStore
+	conversations store.ConversationStore
+	snapshots     store.SnapshotStore
+	audit         store.AuditStore
+	feedback      store.FeedbackStore
+	nextClient    Bridge
 	modelSelector *ModelSelector
+	flightStatus  flightstatus.Provider
+	cache         *cache.Cache
+	events        *tripevents.Recorder
+	prompts       *prompts.Registry
+	cassettes     *cassette.Recorder
+	guardrails    string
+	sanitize      SanitizePolicies
+	proposals     store.ProposalStore
+	// retrievalTokens is the prompt budget for retrieved trip data; 0 turns retrieval off.
+	retrievalTokens int
+	knowledge       *knowledge.Base
+	preferences     store.PreferenceStore
+	// chain is tried in order for every answer, with its providers looked up in providers.
+	chain     []Attempt
+	providers map[string]LLM
+}
+
+// Option configures optional Service dependencies.
+type Option func(*Service)
+
+// WithFlightStatusProvider makes live flight status come from p instead of the Next bridge.
+func WithFlightStatusProvider(p flightstatus.Provider) Option {
+	return func(s *Service) {
+		s.flightStatus = p
+	}
+}
+
+// WithCache puts c in front of flight status and transit provider calls.
+func WithCache(c *cache.Cache) Option {
+	return func(s *Service) {
+		s.cache = c
+	}
+}
+
+// WithTripEvents records an ai_chat_completed trip event after every successful trip chat.
+func WithTripEvents(r *tripevents.Recorder) Option {
+	return func(s *Service) {
+		s.events = r
+	}
+}
+
+// WithPrompts serves system prompts from r instead of the bundled templates.
+func WithPrompts(r *prompts.Registry) Option {
+	return func(s *Service) {
+		s.prompts = r
+	}
+}
+
+// WithGuardrails sets what happens to answers that break the prompt's rules: GuardrailRetry
+// (the default), GuardrailRewrite, GuardrailFlag or GuardrailOff.
+func WithGuardrails(policy string) Option {
+	return func(s *Service) {
+		s.guardrails = policy
+	}
 }
 
-func NewService(repo *store.AIRepository, openaiClient *openai.Client, nextClient *nextbridge.Client, modelSelector *ModelSelector) *Service {
-	return &Service{repo: repo, openaiClient: openaiClient, nextClient: nextClient, modelSelector: modelSelector}
+// WithCassettes records every chat and planner request, with its model, bridge and flight
+// status traffic, to r.
+func WithCassettes(r *cassette.Recorder) Option {
+	return func(s *Service) {
+		s.cassettes = r
+	}
+}
+
+// WithSanitizePolicies sets how page context, planner context and tool responses are cleaned
+// before they reach a prompt. Without it every page uses DefaultSanitizePolicy.
+func WithSanitizePolicies(p SanitizePolicies) Option {
+	return func(s *Service) {
+		s.sanitize = p
+	}
+}
+
+func NewService(repo store.AIStore, openaiClient LLM, nextClient Bridge, modelSelector *ModelSelector, opts ...Option) *Service {
+	s := &Service{
+		trips:         repo,
+		conversations: repo,
+		snapshots:     repo,
+		audit:         repo,
+		feedback:      repo,
+		nextClient:    nextClient,
+		modelSelector: modelSelector,
+
+		retrievalTokens: DefaultRetrievalTokens,
+		providers:       map[string]LLM{},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.prompts == nil {
+		s.prompts = prompts.Default()
+	}
+	if s.guardrails == "" {
+		s.guardrails = GuardrailRetry
+	}
+	if _, ok := s.providers[ProviderOpenAI]; !ok {
+		s.providers[ProviderOpenAI] = openaiClient
+	}
+	if len(s.chain) == 0 {
+		s.chain = []Attempt{{Provider: ProviderOpenAI}}
+	}
+	s.chain = append([]Attempt(nil), s.chain...)
+	for i := range s.chain {
+		if s.chain[i].Timeout <= 0 {
+			s.chain[i].Timeout = DefaultAttemptTimeout
+		}
+	}
+	if s.cassettes != nil {
+		for name, llm := range s.providers {
+			s.providers[name] = cassette.WrapLLM(llm)
+		}
+	}
+	return s
+}
+
+// startTape begins recording a request when cassettes are on.
+func (s *Service) startTape(ctx context.Context, kind, userID string, req any) (context.Context, *cassette.Tape) {
+	if s.cassettes == nil {
+		return ctx, nil
+	}
+	return cassette.Start(ctx, kind, userID, req)
+}
+
+// saveTape writes a finished recording. A failed write only loses the cassette.
+func (s *Service) saveTape(tape *cassette.Tape, resp any, err error) {
+	if tape == nil {
+		return
+	}
+	if _, saveErr := s.cassettes.Save(tape.Finish(resp, err)); saveErr != nil {
+		log.Printf("cassette: %v", saveErr)
+	}
 }
 
 func (s *Service) Chat(ctx context.Context, userID string, req ChatRequest) (*ChatResponse, error) {
+	ctx, tape := s.startTape(ctx, cassette.KindChat, userID, req)
+	resp, err := s.chat(ctx, userID, req)
+	s.saveTape(tape, resp, err)
+	return resp, err
+}
+
+func (s *Service) chat(ctx context.Context, userID string, req ChatRequest) (*ChatResponse, error) {
 	if req.TripID == "" || req.PageKey == "" || len(req.Messages) == 0 {
 		return nil, ErrInvalidInput
 	}
 
-	ok, err := s.repo.IsTripMember(ctx, req.TripID, userID)
+	ok, err := s.trips.IsTripMember(ctx, req.TripID, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -115,28 +304,22 @@ func (s *Service) Chat(ctx context.Context, userID string, req ChatRequest) (*Ch
 		return nil, ErrUnauthorizedTrip
 	}
 
-	trip, err := s.repo.GetTripByID(ctx, req.TripID)
+	trip, err := s.trips.GetTripByID(ctx, req.TripID)
 	if err != nil {
 		return nil, err
 	}
+	cassette.FromContext(ctx).SetTrip(trip)
 
-	conversationID, err := s.repo.UpsertConversation(ctx, req.TripID, userID, fmt.Sprintf("%s assistant", strings.Title(req.PageKey)))
+	conv, err := s.chatConversation(ctx, userID, req)
 	if err != nil {
 		return nil, err
 	}
+	conversationID := conv.ID
 
-	contextPayload := map[string]any{
-		"trip": map[string]any{
-			"id":          trip.ID,
-			"destination": trip.Destination,
-			"startDate":   trip.StartDate.Format("2006-01-02"),
-			"endDate":     trip.EndDate.Format("2006-01-02"),
-			"timezone":    trip.Timezone,
-		},
-		"pageKey": req.PageKey,
-	}
+	userPrompt := req.Messages[len(req.Messages)-1].Content
+	contextPayload, untrusted, retrievedCount, contextSources := s.tripContext(ctx, userID, trip, conversationID, req.PageKey, userPrompt)
 	if len(req.PageContext) > 0 {
-		contextPayload["pageContext"] = req.PageContext
+		untrusted["pageContext"] = req.PageContext
 	}
 
 	sources := make([]Source, 0)
@@ -145,63 +328,202 @@ func (s *Service) Chat(ctx context.Context, userID string, req ChatRequest) (*Ch
 		toolSources, toolDegraded, toolContext := s.fetchRealtimeContext(ctx, req.PageKey, req.Messages)
 		degraded = toolDegraded
 		for k, v := range toolContext {
-			contextPayload[k] = v
+			untrusted[k] = v
 		}
 		sources = append(sources, toolSources...)
 	}
+	var sanitized SanitizeReport
+	if len(untrusted) > 0 {
+		contextPayload["untrusted"] = s.sanitize.For(req.PageKey).Sanitize(untrusted, &sanitized)
+	}
 
 	if len(sources) == 0 {
 		sources = append(sources, Source{Name: "trip_db_context", Status: "ok", FetchedAt: time.Now().UTC().Format(time.RFC3339)})
 	}
 
-	_ = s.repo.InsertContextSnapshot(ctx, req.TripID, req.PageKey, contextPayload)
+	_ = s.snapshots.InsertContextSnapshot(ctx, req.TripID, req.PageKey, tripSnapshot(contextPayload))
+	// Preferences belong to the user, not the trip, so they stay out of the trip's snapshot,
+	// and out of the answer's stored context so a regeneration uses the current ones.
+	answerContext := maps.Clone(contextPayload)
+	s.addPreferences(ctx, userID, req.PageKey, contextPayload)
 
-	userPrompt := req.Messages[len(req.Messages)-1].Content
 	model := s.modelSelector.Select(userPrompt, req.Messages)
-	systemPrompt := BuildSystemPrompt(req.PageKey, contextPayload, degraded)
+	systemPrompt, promptVersion, err := s.systemPrompt(copilotPrompt, userID, req.PageKey, contextPayload, degraded)
+	if err != nil {
+		return nil, err
+	}
 
-	mapped := make([]openai.Message, 0, len(req.Messages))
-	for _, m := range req.Messages {
+	fallback := buildLocalFallbackAnswer(req.PageKey, req.Messages, degraded)
+	grounding := groundingText(contextPayload, req.Messages)
+	result, guarded, err := s.complete(cassette.WithPromptContext(ctx, contextPayload), model, systemPrompt, req.Messages, grounding, fallback, copilotReply)
+	if err != nil {
+		return nil, err
+	}
+	degraded = degraded || result.Attempt.degraded() || guarded.degraded()
+	answeredBy := result.Attempt.modelName()
+
+	if _, err := s.conversations.InsertMessage(ctx, store.Message{ConversationID: conversationID, Role: "user", Content: userPrompt, PageKey: req.PageKey}); err != nil {
+		return nil, err
+	}
+	answer, err := s.conversations.InsertMessage(ctx, store.Message{
+		ConversationID: conversationID,
+		Role:           "assistant",
+		Content:        result.Text,
+		Model:          answeredBy,
+		TokenUsageJSON: result.TokenUsage,
+		PageKey:        req.PageKey,
+		PromptVersion:  promptVersion,
+		ContextJSON:    answerContext,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if conv.Title == "" {
+		conv.Title = s.generateTitle(ctx, result.Attempt, userPrompt, result.Text)
+		_ = s.conversations.RenameConversation(ctx, conversationID, conv.Title)
+	}
+	for _, src := range sources {
+		_ = s.snapshots.InsertToolSnapshot(ctx, conversationID, req.PageKey, src.Name, src.Status, map[string]any{"detail": src.Detail, "fetchedAt": src.FetchedAt})
+	}
+	auditMeta := map[string]any{"pageKey": req.PageKey, "model": answeredBy, "promptVersion": promptVersion, "degraded": degraded}
+	addAttempt(auditMeta, result.Attempt)
+	if retrievedCount > 0 {
+		auditMeta["retrieved"] = retrievedCount
+	}
+	if !sanitized.empty() {
+		auditMeta["sanitized"] = sanitized
+	}
+	_ = s.audit.InsertAuditLog(ctx, userID, req.TripID, "ai_chat", auditMeta)
+	s.logViolations(ctx, userID, req.TripID, req.PageKey, answeredBy, promptVersion, guarded)
+	if s.events != nil {
+		_, _ = s.events.Record(ctx, req.TripID, tripevents.TypeAIChatCompleted, chatCompleted(conversationID, req.PageKey, degraded))
+	}
+
+	resp := chatResponse(conv, answer, append(sources, contextSources...), degraded)
+	resp.DegradedReason = degradedReason(result.Attempt, guarded)
+	resp.Attempt = result.Attempt
+	resp.Highlights = validHighlights(result.Highlights, grounding)
+	resp.SuggestedActions, resp.Proposals = s.propose(ctx, userID, conv, answer.ID, trip, validActions(result.Actions, trip))
+	resp.PreferenceUpdates = s.suggestPreferences(ctx, userID, conv.ID, answer.ID, result.Preferences)
+	return resp, nil
+}
+
+// reply is a model answer after fallbacks and guardrails.
+type reply struct {
+	Text       string
+	TokenUsage map[string]any
+	// Attempt is the step of the fallback chain that answered.
+	Attempt AnswerAttempt
+	// Highlights, Actions and Preferences come from a structured answer, unvalidated.
+	Highlights  []Highlight
+	Actions     []proposedAction
+	Preferences []proposedPreference
+}
+
+func newReply(result *openai.ChatResult) *reply {
+	r := &reply{Text: result.Text, TokenUsage: result.TokenUsage}
+	if structured, ok := parseReply(result.Text); ok {
+		r.Text, r.Highlights, r.Actions, r.Preferences = structured.Answer, structured.Highlights, structured.Actions, structured.Preferences
+	}
+	return r
+}
+
+// replyFormat names the structured output a model call asks for.
+type replyFormat struct {
+	name   string
+	schema map[string]any
+}
+
+var (
+	// copilotReply answers come with highlights, actions and preference updates.
+	copilotReply = replyFormat{name: "copilot_reply", schema: replySchema}
+	// plannerReply answers come with preference updates.
+	plannerReply = replyFormat{name: "planner_reply", schema: plannerReplySchema}
+)
+
+// complete runs the fallback chain for one answer, substituting fallback when no attempt
+// answers or the model returns nothing, and applying the guardrail policy to what it does
+// return. The model is asked for the answer in format.
+func (s *Service) complete(ctx context.Context, model, systemPrompt string, messages []ChatMessage, grounding, fallback string, format replyFormat) (*reply, guardOutcome, error) {
+	mapped := make([]openai.Message, 0, len(messages))
+	for _, m := range messages {
 		if m.Role != "assistant" {
 			m.Role = "user"
 		}
 		mapped = append(mapped, openai.Message{Role: m.Role, Content: m.Content})
 	}
+	ctx = openai.WithJSONSchema(ctx, format.name, format.schema)
 
-	result, err := s.openaiClient.ResponsesChat(ctx, model, systemPrompt, mapped)
+	result, attempt, err := s.callChain(ctx, model, systemPrompt, mapped)
 	if err != nil {
-		return nil, err
+		return nil, guardOutcome{}, err
 	}
-	if strings.TrimSpace(result.Text) == "" || result.Text == "I could not generate a response." {
-		result.Text = buildLocalFallbackAnswer(req.PageKey, req.Messages, degraded)
+	if result == nil {
+		return &reply{Text: fallback, Attempt: attempt}, guardOutcome{}, nil
 	}
+	r := newReply(result)
+	r.Attempt = attempt
+	if strings.TrimSpace(r.Text) == "" || r.Text == "I could not generate a response." {
+		r.Text = fallback
+		return r, guardOutcome{}, nil
+	}
+	return s.guard(ctx, systemPrompt, mapped, grounding, fallback, r)
+}
 
-	if err := s.repo.InsertMessage(ctx, conversationID, "user", userPrompt, "", nil); err != nil {
-		return nil, err
+// chatCompleted is the ai_chat_completed payload. Trip events reach every member and every
+// webhook, so it names the thread but carries nothing of the private conversation.
+func chatCompleted(conversationID, pageKey string, degraded bool) map[string]any {
+	return map[string]any{"conversationId": conversationID, "pageKey": pageKey, "degraded": degraded}
+}
+
+func chatResponse(conv *store.Conversation, answer *store.Message, sources []Source, degraded bool) *ChatResponse {
+	return &ChatResponse{
+		ConversationID:    conv.ID,
+		ConversationTitle: conv.Title,
+		MessageID:         answer.ID,
+		RegeneratedFrom:   answer.RegeneratedFrom,
+		Answer:            answer.Content,
+		Highlights:        []Highlight{},
+		SuggestedActions:  []SuggestedAction{},
+		Sources:           sources,
+		Degraded:          degraded,
+	}
+}
+
+// chatConversation resolves the thread a chat message belongs to. Writing to an archived
+// thread brings it back into the default list.
+func (s *Service) chatConversation(ctx context.Context, userID string, req ChatRequest) (*store.Conversation, error) {
+	if req.ConversationID == "" {
+		id, err := s.conversations.UpsertConversation(ctx, req.TripID, userID, "")
+		if err != nil {
+			return nil, err
+		}
+		return s.conversations.GetConversation(ctx, id)
 	}
-	if err := s.repo.InsertMessage(ctx, conversationID, "assistant", result.Text, model, result.TokenUsage); err != nil {
+	conv, err := s.ownedConversation(ctx, userID, req.ConversationID)
+	if err != nil {
 		return nil, err
 	}
-	for _, src := range sources {
-		_ = s.repo.InsertToolSnapshot(ctx, conversationID, req.PageKey, src.Name, src.Status, map[string]any{"detail": src.Detail, "fetchedAt": src.FetchedAt})
+	if conv.TripID != req.TripID {
+		return nil, ErrUnauthorizedTrip
 	}
-	_ = s.repo.InsertAuditLog(ctx, userID, req.TripID, "ai_chat", map[string]any{"pageKey": req.PageKey, "model": model, "degraded": degraded})
-
-	resp := &ChatResponse{
-		ConversationID: conversationID,
-		Answer:         result.Text,
-		Highlights: []string{
-			"Read-only guidance generated from current trip context",
-			fmt.Sprintf("Page-aware reasoning for %s", req.PageKey),
-		},
-		SuggestedActions: suggestActionsForPage(req.PageKey),
-		Sources:          sources,
-		Degraded:         degraded,
+	if conv.ArchivedAt != nil {
+		if err := s.conversations.SetConversationArchived(ctx, conv.ID, false); err != nil {
+			return nil, err
+		}
+		conv.ArchivedAt = nil
 	}
-	return resp, nil
+	return conv, nil
 }
 
 func (s *Service) PlannerChat(ctx context.Context, userID string, req PlannerChatRequest) (*PlannerChatResponse, error) {
+	ctx, tape := s.startTape(ctx, cassette.KindPlanner, userID, req)
+	resp, err := s.plannerChat(ctx, userID, req)
+	s.saveTape(tape, resp, err)
+	return resp, err
+}
+
+func (s *Service) plannerChat(ctx context.Context, userID string, req PlannerChatRequest) (*PlannerChatResponse, error) {
 	if len(req.Messages) == 0 {
 		return nil, ErrInvalidInput
 	}
@@ -211,8 +533,9 @@ func (s *Service) PlannerChat(ctx context.Context, userID string, req PlannerCha
 		"userID":  userID,
 	}
 	if len(req.PlannerContext) > 0 {
-		contextPayload["plannerContext"] = req.PlannerContext
+		contextPayload["untrusted"] = s.sanitize.For("agent").Sanitize(map[string]any{"plannerContext": req.PlannerContext}, nil)
 	}
+	s.addPreferences(ctx, userID, "agent", contextPayload)
 
 	sources := []Source{
 		{Name: "planner_context", Status: "ok", FetchedAt: time.Now().UTC().Format(time.RFC3339)},
@@ -220,35 +543,40 @@ func (s *Service) PlannerChat(ctx context.Context, userID string, req PlannerCha
 	degraded := false
 
 	userPrompt := req.Messages[len(req.Messages)-1].Content
+	destination := inferDestination(req.PlannerContext, collectUserMessages(req.Messages))
+	passages, knowledgeSources := s.knowledgeContext(ctx, userPrompt, destination)
+	if len(passages) > 0 {
+		contextPayload["knowledge"] = passages
+		sources = append(sources, knowledgeSources...)
+	}
 	model := s.modelSelector.Select(userPrompt, req.Messages)
-	systemPrompt := BuildPlannerSystemPrompt(contextPayload, degraded)
-
-	mapped := make([]openai.Message, 0, len(req.Messages))
-	for _, m := range req.Messages {
-		if m.Role != "assistant" {
-			m.Role = "user"
-		}
-		mapped = append(mapped, openai.Message{Role: m.Role, Content: m.Content})
+	systemPrompt, promptVersion, err := s.systemPrompt(plannerPrompt, userID, "", contextPayload, degraded)
+	if err != nil {
+		return nil, err
 	}
 
-	result, err := s.openaiClient.ResponsesChat(ctx, model, systemPrompt, mapped)
+	result, guarded, err := s.complete(cassette.WithPromptContext(ctx, contextPayload), model, systemPrompt, req.Messages, groundingText(contextPayload, req.Messages), plannerFallbackAnswer, plannerReply)
 	if err != nil {
 		return nil, err
 	}
-	if strings.TrimSpace(result.Text) == "" || result.Text == "I could not generate a response." {
-		result.Text = "I can help build this trip plan. Share destination, dates (or month), traveler count, and top experiences, then I’ll draft a practical plan you can apply."
-	}
+	degraded = degraded || result.Attempt.degraded() || guarded.degraded()
+	s.logViolations(ctx, userID, "", "agent", result.Attempt.modelName(), promptVersion, guarded)
 
 	draft := buildPlannerDraft(req.PlannerContext, req.Messages, result.Text)
 	resp := &PlannerChatResponse{
-		Answer:       result.Text,
-		Sources:      sources,
-		Degraded:     degraded,
-		PlannerDraft: draft,
+		Answer:         result.Text,
+		Sources:        sources,
+		Degraded:       degraded,
+		DegradedReason: degradedReason(result.Attempt, guarded),
+		Attempt:        result.Attempt,
+		PlannerDraft:   draft,
 	}
+	resp.PreferenceUpdates = s.suggestPreferences(ctx, userID, "", "", result.Preferences)
 	return resp, nil
 }
 
+const plannerFallbackAnswer = "I can help build this trip plan. Share destination, dates (or month), traveler count, and top experiences, then I’ll draft a practical plan you can apply."
+
 func buildLocalFallbackAnswer(pageKey string, messages []ChatMessage, degraded bool) string {
 	last := ""
 	if len(messages) > 0 {
@@ -278,33 +606,180 @@ func buildLocalFallbackAnswer(pageKey string, messages []ChatMessage, degraded b
 	return "Share a bit more detail and I’ll give a concrete next-step recommendation."
 }
 
-func (s *Service) ListConversations(ctx context.Context, userID, tripID string) ([]store.Conversation, error) {
-	ok, err := s.repo.IsTripMember(ctx, tripID, userID)
+// ListConversations returns one page of the user's threads on the trip, newest first, and the
+// cursor of the next page.
+func (s *Service) ListConversations(ctx context.Context, userID, tripID string, includeArchived bool, page store.PageRequest) ([]store.Conversation, string, error) {
+	ok, err := s.trips.IsTripMember(ctx, tripID, userID)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	if !ok {
-		return nil, ErrUnauthorizedTrip
+		return nil, "", ErrUnauthorizedTrip
+	}
+	convs, next, err := s.conversations.ListConversations(ctx, tripID, userID, includeArchived, page)
+	if err == store.ErrInvalidCursor {
+		return nil, "", ErrInvalidInput
 	}
-	return s.repo.ListConversations(ctx, tripID, userID)
+	return convs, next, err
 }
 
-func (s *Service) ListMessages(ctx context.Context, userID, conversationID string, limit int) ([]store.Message, error) {
-	ok, err := s.repo.ConversationBelongsToUser(ctx, conversationID, userID)
+// CreateConversation starts an empty thread. Without a title, one is generated from the
+// first exchange.
+func (s *Service) CreateConversation(ctx context.Context, userID string, req CreateConversationRequest) (*store.Conversation, error) {
+	if req.TripID == "" {
+		return nil, ErrInvalidInput
+	}
+	ok, err := s.trips.IsTripMember(ctx, req.TripID, userID)
 	if err != nil {
 		return nil, err
 	}
 	if !ok {
 		return nil, ErrUnauthorizedTrip
 	}
-	return s.repo.ListMessages(ctx, conversationID, limit)
+	return s.conversations.CreateConversation(ctx, req.TripID, userID, cleanTitle(req.Title))
+}
+
+func (s *Service) UpdateConversation(ctx context.Context, userID, conversationID string, req UpdateConversationRequest) (*store.Conversation, error) {
+	if req.Title == nil && req.Archived == nil {
+		return nil, ErrInvalidInput
+	}
+	if _, err := s.ownedConversation(ctx, userID, conversationID); err != nil {
+		return nil, err
+	}
+	if req.Title != nil {
+		title := cleanTitle(*req.Title)
+		if title == "" {
+			return nil, ErrInvalidInput
+		}
+		if err := s.conversations.RenameConversation(ctx, conversationID, title); err != nil {
+			return nil, err
+		}
+	}
+	if req.Archived != nil {
+		if err := s.conversations.SetConversationArchived(ctx, conversationID, *req.Archived); err != nil {
+			return nil, err
+		}
+	}
+	return s.conversations.GetConversation(ctx, conversationID)
+}
+
+func (s *Service) DeleteConversation(ctx context.Context, userID, conversationID string) error {
+	if _, err := s.ownedConversation(ctx, userID, conversationID); err != nil {
+		return err
+	}
+	return s.conversations.DeleteConversation(ctx, conversationID)
+}
+
+// ownedConversation hides other users' conversations behind the same error as missing ones.
+func (s *Service) ownedConversation(ctx context.Context, userID, conversationID string) (*store.Conversation, error) {
+	conv, err := s.conversations.GetConversation(ctx, conversationID)
+	if err == store.ErrNotFound || (err == nil && conv.UserID != userID) {
+		return nil, ErrUnauthorizedTrip
+	}
+	return conv, err
+}
+
+// ListMessages pages backwards through a thread, newest message first.
+func (s *Service) ListMessages(ctx context.Context, userID, conversationID string, page store.PageRequest) ([]store.Message, string, error) {
+	ok, err := s.conversations.ConversationBelongsToUser(ctx, conversationID, userID)
+	if err != nil {
+		return nil, "", err
+	}
+	if !ok {
+		return nil, "", ErrUnauthorizedTrip
+	}
+	msgs, next, err := s.conversations.ListMessages(ctx, conversationID, page)
+	if err == store.ErrInvalidCursor {
+		return nil, "", ErrInvalidInput
+	}
+	return msgs, next, err
+}
+
+// maxSearchQuery bounds the query text; longer input is rejected rather than truncated.
+const maxSearchQuery = 200
+
+// Search finds the caller's own messages matching q, optionally within one trip.
+func (s *Service) Search(ctx context.Context, userID, tripID, q string, limit int) ([]store.MessageHit, error) {
+	q = strings.TrimSpace(q)
+	if len(store.SearchTerms(q)) == 0 || len(q) > maxSearchQuery {
+		return nil, ErrInvalidInput
+	}
+	if tripID != "" {
+		ok, err := s.trips.IsTripMember(ctx, tripID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, ErrUnauthorizedTrip
+		}
+	}
+	return s.conversations.SearchMessages(ctx, userID, tripID, q, limit)
+}
+
+// tripContext starts the context of a copilot turn about question on trip: the trip and the
+// guide passages matching the question. The itinerary and the items retrieved for userID come
+// back in untrusted, for the caller to add its own data to and sanitize into the context,
+// with how many items were retrieved and the sources of both.
+func (s *Service) tripContext(ctx context.Context, userID string, trip *store.Trip, conversationID, pageKey, question string) (contextPayload, untrusted map[string]any, retrieved int, sources []Source) {
+	contextPayload = map[string]any{
+		"trip": map[string]any{
+			"id":          trip.ID,
+			"destination": trip.Destination,
+			"startDate":   trip.StartDate.Format("2006-01-02"),
+			"endDate":     trip.EndDate.Format("2006-01-02"),
+			"timezone":    trip.Timezone,
+		},
+		"pageKey": pageKey,
+	}
+	// Page fields and tool responses are written by users and third parties, so they go
+	// under "untrusted", cleaned, where the prompt treats them as data only.
+	untrusted = map[string]any{}
+	if items := s.itineraryContext(ctx, trip.ID); len(items) > 0 {
+		untrusted["itinerary"] = items
+	}
+	retrievedItems, retrievedSources := s.retrieve(ctx, userID, trip, conversationID, question)
+	if len(retrievedItems) > 0 {
+		untrusted["retrieved"] = retrievedItems
+	}
+	// Guide passages are curated, so they sit outside "untrusted".
+	passages, knowledgeSources := s.knowledgeContext(ctx, question, trip.Destination)
+	if len(passages) > 0 {
+		contextPayload["knowledge"] = passages
+	}
+	return contextPayload, untrusted, len(retrievedItems), append(retrievedSources, knowledgeSources...)
+}
+
+// tripSnapshot copies contextPayload for the trip's context snapshot, which every member's
+// requests can read. Retrieved items may quote the caller's own conversations, so they are
+// left out.
+func tripSnapshot(contextPayload map[string]any) map[string]any {
+	snapshot := make(map[string]any, len(contextPayload))
+	for k, v := range contextPayload {
+		snapshot[k] = v
+	}
+	untrusted, ok := contextPayload["untrusted"].(map[string]any)
+	if !ok || untrusted["retrieved"] == nil {
+		return snapshot
+	}
+	shared := make(map[string]any, len(untrusted))
+	for k, v := range untrusted {
+		if k != "retrieved" {
+			shared[k] = v
+		}
+	}
+	if len(shared) > 0 {
+		snapshot["untrusted"] = shared
+	} else {
+		delete(snapshot, "untrusted")
+	}
+	return snapshot
 }
 
 func (s *Service) RefreshContext(ctx context.Context, userID string, req RefreshContextRequest) (*RefreshContextResponse, error) {
 	if req.TripID == "" || req.PageKey == "" {
 		return nil, ErrInvalidInput
 	}
-	ok, err := s.repo.IsTripMember(ctx, req.TripID, userID)
+	ok, err := s.trips.IsTripMember(ctx, req.TripID, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -312,7 +787,7 @@ func (s *Service) RefreshContext(ctx context.Context, userID string, req Refresh
 		return nil, ErrUnauthorizedTrip
 	}
 	context := map[string]any{"pageKey": req.PageKey, "refreshedBy": userID}
-	if err := s.repo.InsertContextSnapshot(ctx, req.TripID, req.PageKey, context); err != nil {
+	if err := s.snapshots.InsertContextSnapshot(ctx, req.TripID, req.PageKey, context); err != nil {
 		return nil, err
 	}
 	now := time.Now().UTC().Format(time.RFC3339)
@@ -333,13 +808,36 @@ func (s *Service) fetchRealtimeContext(ctx context.Context, pageKey string, mess
 			sources = append(sources, Source{Name: "next_flight_status", Status: "skipped_missing_inputs", FetchedAt: now, Detail: "Include flight number and YYYY-MM-DD for live status."})
 			break
 		}
-		resp, err := s.nextClient.PostJSON(ctx, "/api/flights/status", map[string]any{"flight_number": flight, "departure_date": date})
+		if s.flightStatus != nil {
+			key := map[string]string{"provider": s.flightStatus.Name(), "flight": flight, "date": date}
+			status, cached, err := cache.Fetch(ctx, s.cache, cache.ToolFlightStatus, key, func(ctx context.Context) (*flightstatus.FlightStatus, error) {
+				return s.flightStatus.Lookup(ctx, flight, date)
+			})
+			cassette.FromContext(ctx).FlightStatus(s.flightStatus.Name(), flight, date, status, err)
+			switch {
+			case errors.Is(err, flightstatus.ErrNotFound):
+				sources = append(sources, Source{Name: "flight_status", Status: "not_found", FetchedAt: now, Detail: "No flight found for that number and date."})
+			case err != nil:
+				degraded = true
+				sources = append(sources, Source{Name: "flight_status", Status: "error", FetchedAt: now, Detail: err.Error()})
+			default:
+				data["flightStatus"] = status
+				sources = append(sources, Source{Name: "flight_status", Status: sourceStatus(cached), FetchedAt: now, Detail: status.Provider})
+			}
+			break
+		}
+		key := map[string]string{"provider": "next", "flight": flight, "date": date}
+		bridgeBody := map[string]any{"flight_number": flight, "departure_date": date}
+		resp, cached, err := cache.Fetch(ctx, s.cache, cache.ToolFlightStatus, key, func(ctx context.Context) (map[string]any, error) {
+			return s.nextClient.PostJSON(ctx, "/api/flights/status", bridgeBody)
+		})
+		cassette.FromContext(ctx).Bridge("/api/flights/status", bridgeBody, resp, err)
 		if err != nil {
 			degraded = true
 			sources = append(sources, Source{Name: "next_flight_status", Status: "error", FetchedAt: now, Detail: err.Error()})
 		} else {
 			data["flightStatus"] = resp
-			sources = append(sources, Source{Name: "next_flight_status", Status: "ok", FetchedAt: now})
+			sources = append(sources, Source{Name: "next_flight_status", Status: sourceStatus(cached), FetchedAt: now})
 		}
 	case "transit":
 		origin, destination := extractTransitInputs(last)
@@ -347,13 +845,17 @@ func (s *Service) fetchRealtimeContext(ctx context.Context, pageKey string, mess
 			sources = append(sources, Source{Name: "next_transit_suggest", Status: "skipped_missing_inputs", FetchedAt: now, Detail: "Use phrasing: from <origin> to <destination>."})
 			break
 		}
-		resp, err := s.nextClient.PostJSON(ctx, "/api/transit/suggest", map[string]any{"origin": origin, "destination": destination})
+		body := map[string]any{"origin": origin, "destination": destination}
+		resp, cached, err := cache.Fetch(ctx, s.cache, cache.ToolTransitSuggest, body, func(ctx context.Context) (map[string]any, error) {
+			return s.nextClient.PostJSON(ctx, "/api/transit/suggest", body)
+		})
+		cassette.FromContext(ctx).Bridge("/api/transit/suggest", body, resp, err)
 		if err != nil {
 			degraded = true
 			sources = append(sources, Source{Name: "next_transit_suggest", Status: "error", FetchedAt: now, Detail: err.Error()})
 		} else {
 			data["transitOptions"] = resp
-			sources = append(sources, Source{Name: "next_transit_suggest", Status: "ok", FetchedAt: now})
+			sources = append(sources, Source{Name: "next_transit_suggest", Status: sourceStatus(cached), FetchedAt: now})
 		}
 	case "finance":
 		sources = append(sources, Source{Name: "finance_guardrail", Status: "ok", FetchedAt: now, Detail: "Computed from DB trip totals in this phase."})
@@ -368,6 +870,14 @@ func (s *Service) fetchRealtimeContext(ctx context.Context, pageKey string, mess
 	return sources, degraded, data
 }
 
+// sourceStatus reports a provider result served from the response cache as "cache_hit".
+func sourceStatus(cached cache.Status) string {
+	if cached == cache.StatusHit || cached == cache.StatusShared {
+		return "cache_hit"
+	}
+	return "ok"
+}
+
 func extractFlightInputs(input string) (string, string) {
 	flightRe := regexp.MustCompile(`(?i)\b([A-Z0-9]{2,3}\s?\d{1,4}[A-Z]?)\b`)
 	dateRe := regexp.MustCompile(`\b(\d{4}-\d{2}-\d{2})\b`)
@@ -391,19 +901,6 @@ func extractTransitInputs(input string) (string, string) {
 	return strings.TrimSpace(m[1]), strings.TrimSpace(m[2])
 }
 
-func suggestActionsForPage(pageKey string) []string {
-	switch pageKey {
-	case "flights":
-		return []string{"Share exact flight number and date for live status", "Compare price-time tradeoff before selecting"}
-	case "transit":
-		return []string{"Provide 'from X to Y' for route options", "Save top route to itinerary notes"}
-	case "finance":
-		return []string{"Review highest-spend category", "Set per-day budget target"}
-	default:
-		return []string{"Ask for next best step", "Request a prioritized checklist"}
-	}
-}
-
 func buildPlannerDraft(plannerContext map[string]any, messages []ChatMessage, answer string) *PlannerDraft {
 	combined := strings.TrimSpace(strings.Join([]string{stringFromMap(plannerContext, "mustDoExperiences"), stringFromMap(plannerContext, "concerns"), answer, collectUserMessages(messages)}, "\n"))
 	draft := &PlannerDraft{}
@@ -0,0 +1,325 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"triploom/backend/internal/prompts"
+	"triploom/backend/internal/providers/openai"
+	"triploom/backend/internal/store"
+)
+
+// fakeLLM answers chat prompts with answer and title prompts with title.
+type fakeLLM struct {
+	mu       sync.Mutex
+	answer   string
+	title    string
+	titleErr error
+	calls    []string
+	// lastChat is the conversation sent with the latest chat prompt.
+	lastChat []openai.Message
+}
+
+func (f *fakeLLM) ResponsesChat(_ context.Context, _ string, systemPrompt string, messages []openai.Message) (*openai.ChatResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, systemPrompt)
+	if systemPrompt == titleInstructions {
+		if f.titleErr != nil {
+			return nil, f.titleErr
+		}
+		return &openai.ChatResult{Text: f.title}, nil
+	}
+	f.lastChat = messages
+	return &openai.ChatResult{Text: f.answer}, nil
+}
+
+func (f *fakeLLM) titleCalls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := 0
+	for _, c := range f.calls {
+		if c == titleInstructions {
+			n++
+		}
+	}
+	return n
+}
+
+func newTestService(t *testing.T, llm LLM) (*Service, *store.MemoryAIRepository, store.Trip) {
+	t.Helper()
+	repo := store.NewInMemoryAIRepository()
+	trip, err := repo.CreateTrip(context.Background(), store.Trip{Destination: "Kyoto"}, "alice")
+	if err != nil {
+		t.Fatalf("create trip: %v", err)
+	}
+	return NewService(repo, llm, nil, NewModelSelector("test-model")), repo, *trip
+}
+
+func chat(tripID, conversationID, text string) ChatRequest {
+	return ChatRequest{TripID: tripID, ConversationID: conversationID, PageKey: "itinerary", Messages: []ChatMessage{{Role: "user", Content: text}}}
+}
+
+func TestChatThreadsAndTitles(t *testing.T) {
+	ctx := context.Background()
+	llm := &fakeLLM{answer: "Go to Arashiyama early.", title: "\"Arashiyama morning plan.\""}
+	svc, _, trip := newTestService(t, llm)
+
+	first, err := svc.Chat(ctx, "alice", chat(trip.ID, "", "when should we visit the bamboo grove?"))
+	if err != nil {
+		t.Fatalf("chat: %v", err)
+	}
+	if first.ConversationTitle != "Arashiyama morning plan" {
+		t.Fatalf("expected a cleaned generated title, got %q", first.ConversationTitle)
+	}
+	again, _ := svc.Chat(ctx, "alice", chat(trip.ID, "", "and the temple?"))
+	if again.ConversationID != first.ConversationID || llm.titleCalls() != 1 {
+		t.Fatalf("expected the latest thread to be continued without retitling, got %+v after %d title calls", again, llm.titleCalls())
+	}
+
+	fresh, err := svc.CreateConversation(ctx, "alice", CreateConversationRequest{TripID: trip.ID})
+	if err != nil {
+		t.Fatalf("create conversation: %v", err)
+	}
+	llm.titleErr = errors.New("boom")
+	resp, err := svc.Chat(ctx, "alice", chat(trip.ID, fresh.ID, "cheap ramen near kyoto station please, thanks"))
+	if err != nil {
+		t.Fatalf("chat in new thread: %v", err)
+	}
+	if resp.ConversationID != fresh.ID || resp.ConversationTitle != "Cheap ramen near kyoto station please" {
+		t.Fatalf("expected the fallback title on the new thread, got %+v", resp)
+	}
+
+	convs, _, _ := svc.ListConversations(ctx, "alice", trip.ID, false, store.PageRequest{Limit: 50})
+	if len(convs) != 2 {
+		t.Fatalf("expected two threads, got %+v", convs)
+	}
+}
+
+func TestChatRejectsForeignConversations(t *testing.T) {
+	ctx := context.Background()
+	svc, repo, trip := newTestService(t, &fakeLLM{answer: "ok", title: "t"})
+	other, _ := repo.CreateTrip(ctx, store.Trip{Destination: "Osaka"}, "alice")
+	_ = repo.AddTripMember(ctx, trip.ID, "bob", "viewer")
+
+	bobs, _ := svc.CreateConversation(ctx, "bob", CreateConversationRequest{TripID: trip.ID})
+	if _, err := svc.Chat(ctx, "alice", chat(trip.ID, bobs.ID, "hi")); err != ErrUnauthorizedTrip {
+		t.Fatalf("expected another user's thread to be refused, got %v", err)
+	}
+	alices, _ := svc.CreateConversation(ctx, "alice", CreateConversationRequest{TripID: other.ID})
+	if _, err := svc.Chat(ctx, "alice", chat(trip.ID, alices.ID, "hi")); err != ErrUnauthorizedTrip {
+		t.Fatalf("expected a thread from another trip to be refused, got %v", err)
+	}
+	if _, err := svc.CreateConversation(ctx, "mallory", CreateConversationRequest{TripID: trip.ID}); err != ErrUnauthorizedTrip {
+		t.Fatalf("expected non-members to be refused, got %v", err)
+	}
+}
+
+func TestUpdateAndDeleteConversation(t *testing.T) {
+	ctx := context.Background()
+	svc, _, trip := newTestService(t, &fakeLLM{answer: "ok", title: "t"})
+	conv, _ := svc.CreateConversation(ctx, "alice", CreateConversationRequest{TripID: trip.ID, Title: "Food"})
+
+	title, archived := "  Food & drink  ", true
+	got, err := svc.UpdateConversation(ctx, "alice", conv.ID, UpdateConversationRequest{Title: &title, Archived: &archived})
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if got.Title != "Food & drink" || got.ArchivedAt == nil {
+		t.Fatalf("unexpected conversation %+v", got)
+	}
+	if convs, _, _ := svc.ListConversations(ctx, "alice", trip.ID, false, store.PageRequest{Limit: 50}); len(convs) != 0 {
+		t.Fatalf("expected archived threads to be hidden, got %+v", convs)
+	}
+	if _, err := svc.Chat(ctx, "alice", chat(trip.ID, conv.ID, "hi")); err != nil {
+		t.Fatalf("chat: %v", err)
+	}
+	if convs, _, _ := svc.ListConversations(ctx, "alice", trip.ID, false, store.PageRequest{Limit: 50}); len(convs) != 1 || convs[0].Title != "Food & drink" {
+		t.Fatalf("expected chatting to restore the thread with its title, got %+v", convs)
+	}
+
+	blank := "   "
+	if _, err := svc.UpdateConversation(ctx, "alice", conv.ID, UpdateConversationRequest{Title: &blank}); err != ErrInvalidInput {
+		t.Fatalf("expected blank titles to be rejected, got %v", err)
+	}
+	if err := svc.DeleteConversation(ctx, "bob", conv.ID); err != ErrUnauthorizedTrip {
+		t.Fatalf("expected only the owner to delete, got %v", err)
+	}
+	if err := svc.DeleteConversation(ctx, "alice", conv.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, _, err := svc.ListMessages(ctx, "alice", conv.ID, store.PageRequest{Limit: 10}); err != ErrUnauthorizedTrip {
+		t.Fatalf("expected the deleted thread to be gone, got %v", err)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	ctx := context.Background()
+	svc, _, trip := newTestService(t, &fakeLLM{answer: "Take the Hozugawa river boat.", title: "t"})
+	if _, err := svc.Chat(ctx, "alice", chat(trip.ID, "", "Any river trips near Arashiyama?")); err != nil {
+		t.Fatalf("chat: %v", err)
+	}
+
+	hits, err := svc.Search(ctx, "alice", trip.ID, "river", 10)
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if len(hits) != 2 || !strings.Contains(hits[0].Snippet, "<mark>river</mark>") {
+		t.Fatalf("expected both messages with highlights, got %+v", hits)
+	}
+	if hits, _ := svc.Search(ctx, "bob", "", "river", 10); len(hits) != 0 {
+		t.Fatalf("expected bob to see none of alice's messages, got %+v", hits)
+	}
+	if _, err := svc.Search(ctx, "bob", trip.ID, "river", 10); err != ErrUnauthorizedTrip {
+		t.Fatalf("expected a trip filter to require membership, got %v", err)
+	}
+	for _, q := range []string{"", " ?! ", strings.Repeat("a", maxSearchQuery+1)} {
+		if _, err := svc.Search(ctx, "alice", "", q, 10); err != ErrInvalidInput {
+			t.Fatalf("expected %q to be rejected, got %v", q, err)
+		}
+	}
+}
+
+func TestFeedback(t *testing.T) {
+	ctx := context.Background()
+	svc, _, trip := newTestService(t, &fakeLLM{answer: "Visit Fushimi Inari at dawn.", title: "t"})
+	resp, err := svc.Chat(ctx, "alice", chat(trip.ID, "", "When should I see Fushimi Inari?"))
+	if err != nil || resp.MessageID == "" {
+		t.Fatalf("chat: %+v %v", resp, err)
+	}
+
+	fb, err := svc.SubmitFeedback(ctx, "alice", resp.MessageID, FeedbackRequest{Rating: "down", Reasons: []string{"outdated", "outdated"}, Comment: " crowded "})
+	if err != nil || len(fb.Reasons) != 1 || fb.Comment != "crowded" {
+		t.Fatalf("unexpected feedback %+v %v", fb, err)
+	}
+	for _, req := range []FeedbackRequest{{Rating: "meh"}, {Rating: "up", Reasons: []string{"boring"}}, {Rating: "up", Comment: strings.Repeat("x", maxFeedbackComment+1)}} {
+		if _, err := svc.SubmitFeedback(ctx, "alice", resp.MessageID, req); err != ErrInvalidInput {
+			t.Fatalf("expected %+v to be rejected, got %v", req, err)
+		}
+	}
+	if _, err := svc.SubmitFeedback(ctx, "bob", resp.MessageID, FeedbackRequest{Rating: "up"}); err != ErrUnauthorizedTrip {
+		t.Fatalf("expected bob to be refused, got %v", err)
+	}
+
+	report, err := svc.FeedbackReport(ctx, "alice", trip.ID, time.Time{})
+	if err != nil || len(report) != 1 || report[0].Model != "test-model" || report[0].PageKey != "itinerary" || report[0].Down != 1 {
+		t.Fatalf("unexpected report %+v %v", report, err)
+	}
+}
+
+func TestRegenerate(t *testing.T) {
+	ctx := context.Background()
+	llm := &fakeLLM{answer: "Take the JR Nara line.", title: "t"}
+	svc, _, trip := newTestService(t, llm)
+	svc.modelSelector.Alternatives = []string{"big-model"}
+	first, _ := svc.Chat(ctx, "alice", chat(trip.ID, "", "How do I get to Nara?"))
+	second, _ := svc.Chat(ctx, "alice", chat(trip.ID, first.ConversationID, "And back?"))
+
+	llm.answer = "The Kintetsu line is faster."
+	again, err := svc.Regenerate(ctx, "alice", first.MessageID, RegenerateRequest{Model: "big-model"})
+	if err != nil {
+		t.Fatalf("regenerate: %v", err)
+	}
+	if again.RegeneratedFrom != first.MessageID || again.Answer != "The Kintetsu line is faster." || again.ConversationID != first.ConversationID {
+		t.Fatalf("unexpected regenerated answer %+v", again)
+	}
+	if len(llm.lastChat) != 1 || llm.lastChat[0].Content != "How do I get to Nara?" {
+		t.Fatalf("expected the turn to be replayed from its own prompt, got %+v", llm.lastChat)
+	}
+	// Regenerating a regeneration links back to the original, not the copy.
+	third, _ := svc.Regenerate(ctx, "alice", again.MessageID, RegenerateRequest{})
+	if third.RegeneratedFrom != first.MessageID {
+		t.Fatalf("expected versions to share the original, got %+v", third)
+	}
+	msg, _ := svc.conversations.GetMessage(ctx, again.MessageID)
+	if msg.Model != "big-model" || msg.PageKey != "itinerary" {
+		t.Fatalf("unexpected stored version %+v", msg)
+	}
+
+	if _, err := svc.Regenerate(ctx, "alice", second.MessageID, RegenerateRequest{Model: "unlisted"}); err != ErrInvalidInput {
+		t.Fatalf("expected unknown models to be rejected, got %v", err)
+	}
+	if _, err := svc.Regenerate(ctx, "bob", second.MessageID, RegenerateRequest{}); err != ErrUnauthorizedTrip {
+		t.Fatalf("expected bob to be refused, got %v", err)
+	}
+	msgs, _, _ := svc.ListMessages(ctx, "alice", first.ConversationID, store.PageRequest{Limit: 10})
+	if len(msgs) != 6 {
+		t.Fatalf("expected both versions to be kept, got %d messages", len(msgs))
+	}
+}
+
+func TestRegenerateUsesTheAnswersContext(t *testing.T) {
+	ctx := context.Background()
+	llm := &fakeLLM{answer: "Take the JR Nara line.", title: "t"}
+	svc, repo, trip := newTestService(t, llm)
+	_ = repo.AddTripMember(ctx, trip.ID, "bob", "editor")
+	first, _ := svc.Chat(ctx, "alice", chat(trip.ID, "", "How do I get to Nara?"))
+	if _, err := svc.RefreshContext(ctx, "bob", RefreshContextRequest{TripID: trip.ID, PageKey: "itinerary"}); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+
+	again, err := svc.Regenerate(ctx, "alice", first.MessageID, RegenerateRequest{})
+	if err != nil {
+		t.Fatalf("regenerate: %v", err)
+	}
+	if prompt := llm.calls[len(llm.calls)-1]; !strings.Contains(prompt, `"destination":"Kyoto"`) || strings.Contains(prompt, "refreshedBy") {
+		t.Fatalf("expected the original answer's context, got %s", prompt)
+	}
+	if stored, err := repo.MessageContext(ctx, again.MessageID); err != nil || stored["pageKey"] != "itinerary" {
+		t.Fatalf("expected the regenerated answer to keep its context, got %v %v", stored, err)
+	}
+
+	// Answers stored without a context are regenerated from the trip as it is now.
+	_, _ = repo.InsertMessage(ctx, store.Message{ConversationID: first.ConversationID, Role: "user", Content: "Any day trips?"})
+	bare, _ := repo.InsertMessage(ctx, store.Message{ConversationID: first.ConversationID, Role: "assistant", Content: "Nara or Uji.", PageKey: "itinerary"})
+	if _, err := svc.Regenerate(ctx, "alice", bare.ID, RegenerateRequest{}); err != nil {
+		t.Fatalf("regenerate: %v", err)
+	}
+	if prompt := llm.calls[len(llm.calls)-1]; !strings.Contains(prompt, `"destination":"Kyoto"`) {
+		t.Fatalf("expected a fresh trip context, got %s", prompt)
+	}
+}
+
+func TestPromptVariants(t *testing.T) {
+	ctx := context.Background()
+	llm := &fakeLLM{answer: "ok", title: "t"}
+	svc, repo, trip := newTestService(t, llm)
+	reg, err := prompts.New(append(prompts.Bundled(), prompts.Definition{Name: "copilot", Version: "v2", Body: "Variant B for {{.PageKey}}", Weight: 100}))
+	if err != nil {
+		t.Fatalf("registry: %v", err)
+	}
+	WithPrompts(reg)(svc)
+
+	resp, err := svc.Chat(ctx, "alice", chat(trip.ID, "", "hi"))
+	if err != nil {
+		t.Fatalf("chat: %v", err)
+	}
+	if llm.calls[0] != "Variant B for itinerary" {
+		t.Fatalf("expected the weighted variant to be used, got %q", llm.calls[0])
+	}
+	msg, _ := repo.GetMessage(ctx, resp.MessageID)
+	if msg.PromptVersion != "copilot@v2" {
+		t.Fatalf("expected the prompt version to be recorded, got %+v", msg)
+	}
+	_, _ = svc.SubmitFeedback(ctx, "alice", resp.MessageID, FeedbackRequest{Rating: "up"})
+	if report, _ := svc.FeedbackReport(ctx, "alice", trip.ID, time.Time{}); len(report) != 1 || report[0].PromptVersion != "copilot@v2" {
+		t.Fatalf("expected feedback per prompt version, got %+v", report)
+	}
+}
+
+func TestCleanTitle(t *testing.T) {
+	cases := map[string]string{
+		"Title: Kyoto food crawl.\nMore text": "Kyoto food crawl",
+		"  “Rail pass maths”  ":               "Rail pass maths",
+		strings.Repeat("word ", 20):           "word word word word word word word word word word word word",
+	}
+	for in, want := range cases {
+		if got := cleanTitle(in); got != want {
+			t.Fatalf("cleanTitle(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
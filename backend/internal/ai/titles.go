@@ -0,0 +1,68 @@
+package ai
+
+import (
+	"context"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"triploom/backend/internal/providers/openai"
+)
+
+const (
+	titleInstructions = "Summarise this travel-planning exchange as a conversation title of at most six words. Reply with the title only: no quotes, no trailing punctuation."
+	maxTitleRunes     = 60
+	titleTimeout      = 10 * time.Second
+)
+
+// generateTitle names a new conversation after its first exchange, falling back to the
+// opening words of the question when the model call fails. The title is asked of the attempt
+// that answered; a local fallback answer gets the fallback title.
+func (s *Service) generateTitle(ctx context.Context, attempt AnswerAttempt, question, answer string) string {
+	if attempt.llm == nil {
+		return fallbackTitle(question)
+	}
+	ctx, cancel := context.WithTimeout(ctx, titleTimeout)
+	defer cancel()
+	result, err := attempt.llm.ResponsesChat(ctx, attempt.Model, titleInstructions, []openai.Message{
+		{Role: "user", Content: question},
+		{Role: "assistant", Content: answer},
+	})
+	if err == nil && result.Text != "I could not generate a response." {
+		if title := cleanTitle(result.Text); title != "" {
+			return title
+		}
+	}
+	return fallbackTitle(question)
+}
+
+// cleanTitle keeps the first line, strips wrapping quotes and trailing punctuation, and cuts
+// long titles at a word boundary.
+func cleanTitle(raw string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(raw), "\n")
+	line = strings.TrimPrefix(strings.TrimSpace(line), "Title:")
+	title := strings.Join(strings.Fields(line), " ")
+	title = strings.Trim(title, "\"'`“”‘’*#")
+	title = strings.TrimRight(title, ".!?:;, ")
+	if runes := []rune(title); len(runes) > maxTitleRunes {
+		title = string(runes[:maxTitleRunes])
+		if cut := strings.LastIndex(title, " "); cut > 0 {
+			title = title[:cut]
+		}
+	}
+	return strings.TrimSpace(title)
+}
+
+func fallbackTitle(question string) string {
+	words := strings.Fields(question)
+	if len(words) > 6 {
+		words = words[:6]
+	}
+	title := cleanTitle(strings.Join(words, " "))
+	if title == "" {
+		return "New conversation"
+	}
+	first, size := utf8.DecodeRuneInString(title)
+	return string(unicode.ToUpper(first)) + title[size:]
+}
@@ -0,0 +1,176 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// Tool names used as cache namespaces and TTL keys.
+const (
+	ToolFlightStatus   = "flight_status"
+	ToolTransitSuggest = "transit_suggest"
+)
+
+// DefaultTTLs is how long each tool's responses stay fresh unless overridden by CACHE_TTLS.
+var DefaultTTLs = map[string]time.Duration{
+	ToolFlightStatus:   2 * time.Minute,
+	ToolTransitSuggest: 24 * time.Hour,
+}
+
+type Status string
+
+const (
+	StatusMiss   Status = "miss"
+	StatusHit    Status = "hit"
+	StatusShared Status = "shared"
+)
+
+// Store is a cache backend holding opaque JSON values with an expiry.
+type Store interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+// Cache sits in front of provider calls: it serves fresh values from the store and collapses
+// concurrent identical misses into a single upstream call.
+type Cache struct {
+	store Store
+	ttls  map[string]time.Duration
+	group singleflight.Group
+}
+
+func New(store Store, ttls map[string]time.Duration) *Cache {
+	merged := make(map[string]time.Duration, len(DefaultTTLs)+len(ttls))
+	for k, v := range DefaultTTLs {
+		merged[k] = v
+	}
+	for k, v := range ttls {
+		merged[k] = v
+	}
+	return &Cache{store: store, ttls: merged}
+}
+
+// TTL returns the freshness window for tool; zero means the tool is not cached.
+func (c *Cache) TTL(tool string) time.Duration {
+	return c.ttls[tool]
+}
+
+type result struct {
+	raw []byte
+}
+
+// Fetch returns the cached value for (tool, request), calling load on a miss. Errors are never
+// cached. A nil Cache, or a tool without a TTL, always calls load.
+func Fetch[T any](ctx context.Context, c *Cache, tool string, request any, load func(context.Context) (T, error)) (T, Status, error) {
+	if c == nil || c.TTL(tool) <= 0 {
+		v, err := load(ctx)
+		return v, StatusMiss, err
+	}
+
+	var zero T
+	key, err := Key(tool, request)
+	if err != nil {
+		return zero, StatusMiss, err
+	}
+	if raw, ok, err := c.store.Get(ctx, key); err == nil && ok {
+		var v T
+		if err := json.Unmarshal(raw, &v); err == nil {
+			return v, StatusHit, nil
+		}
+	}
+
+	// The shared call must not die with whichever caller happened to start it.
+	loadCtx := context.WithoutCancel(ctx)
+	out, err, shared := c.group.Do(key, func() (any, error) {
+		v, err := load(loadCtx)
+		if err != nil {
+			return nil, err
+		}
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		_ = c.store.Set(loadCtx, key, raw, c.TTL(tool))
+		return result{raw: raw}, nil
+	})
+	if err != nil {
+		return zero, StatusMiss, err
+	}
+
+	var v T
+	if err := json.Unmarshal(out.(result).raw, &v); err != nil {
+		return zero, StatusMiss, err
+	}
+	if shared {
+		return v, StatusShared, nil
+	}
+	return v, StatusMiss, nil
+}
+
+// Key builds a stable cache key from tool and a normalised copy of request: strings are
+// trimmed, lower-cased and whitespace-collapsed, and map keys are sorted by encoding/json.
+func Key(tool string, request any) (string, error) {
+	raw, err := json.Marshal(request)
+	if err != nil {
+		return "", fmt.Errorf("cache key: %w", err)
+	}
+	var generic any
+	if err := json.Unmarshal(raw, &generic); err != nil {
+		return "", fmt.Errorf("cache key: %w", err)
+	}
+	normalized, err := json.Marshal(normalize(generic))
+	if err != nil {
+		return "", fmt.Errorf("cache key: %w", err)
+	}
+	sum := sha256.Sum256(normalized)
+	return tool + ":" + hex.EncodeToString(sum[:]), nil
+}
+
+func normalize(v any) any {
+	switch t := v.(type) {
+	case string:
+		return strings.ToLower(strings.Join(strings.Fields(t), " "))
+	case map[string]any:
+		out := make(map[string]any, len(t))
+		for k, val := range t {
+			out[k] = normalize(val)
+		}
+		return out
+	case []any:
+		out := make([]any, len(t))
+		for i, val := range t {
+			out[i] = normalize(val)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// ParseTTLs parses "tool=duration" pairs separated by commas, e.g. "flight_status=2m,transit_suggest=24h".
+func ParseTTLs(raw string) (map[string]time.Duration, error) {
+	out := map[string]time.Duration{}
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		tool, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid cache ttl %q: want tool=duration", part)
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid cache ttl %q: %w", part, err)
+		}
+		out[strings.TrimSpace(tool)] = d
+	}
+	return out, nil
+}
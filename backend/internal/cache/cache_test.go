@@ -0,0 +1,142 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFetchCachesPerNormalisedRequest(t *testing.T) {
+	c := New(NewLRU(10), nil)
+	calls := 0
+	load := func(context.Context) (map[string]any, error) {
+		calls++
+		return map[string]any{"status": "on time"}, nil
+	}
+
+	_, status, err := Fetch(context.Background(), c, ToolFlightStatus, map[string]string{"flight": "AC856", "date": "2026-11-02"}, load)
+	if err != nil || status != StatusMiss {
+		t.Fatalf("expected miss, got %s %v", status, err)
+	}
+	got, status, err := Fetch(context.Background(), c, ToolFlightStatus, map[string]string{"date": "2026-11-02", "flight": " ac856 "}, load)
+	if err != nil || status != StatusHit {
+		t.Fatalf("expected hit for normalised request, got %s %v", status, err)
+	}
+	if got["status"] != "on time" || calls != 1 {
+		t.Fatalf("unexpected value %v after %d calls", got, calls)
+	}
+
+	if _, status, _ := Fetch(context.Background(), c, ToolTransitSuggest, map[string]string{"flight": "AC856", "date": "2026-11-02"}, load); status != StatusMiss {
+		t.Fatalf("expected tools to have separate namespaces, got %s", status)
+	}
+}
+
+func TestFetchDoesNotCacheErrors(t *testing.T) {
+	c := New(NewLRU(10), nil)
+	boom := errors.New("upstream down")
+	calls := 0
+	load := func(context.Context) (string, error) {
+		calls++
+		if calls == 1 {
+			return "", boom
+		}
+		return "ok", nil
+	}
+	if _, _, err := Fetch(context.Background(), c, ToolFlightStatus, "x", load); !errors.Is(err, boom) {
+		t.Fatalf("expected upstream error, got %v", err)
+	}
+	if v, status, err := Fetch(context.Background(), c, ToolFlightStatus, "x", load); err != nil || v != "ok" || status != StatusMiss {
+		t.Fatalf("expected retry after error, got %q %s %v", v, status, err)
+	}
+}
+
+func TestFetchCollapsesConcurrentMisses(t *testing.T) {
+	c := New(NewLRU(10), nil)
+	var calls atomic.Int32
+	release := make(chan struct{})
+	load := func(context.Context) (int, error) {
+		calls.Add(1)
+		<-release
+		return 42, nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([]int, 8)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			v, _, err := Fetch(context.Background(), c, ToolFlightStatus, "same", load)
+			if err != nil {
+				t.Errorf("fetch: %v", err)
+			}
+			results[i] = v
+		}(i)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("expected a single upstream call, got %d", n)
+	}
+	for _, v := range results {
+		if v != 42 {
+			t.Fatalf("expected every caller to get the shared value, got %v", results)
+		}
+	}
+}
+
+func TestFetchWithoutCacheOrTTL(t *testing.T) {
+	calls := 0
+	load := func(context.Context) (int, error) { calls++; return calls, nil }
+	Fetch(context.Background(), nil, ToolFlightStatus, "x", load)
+	c := New(NewLRU(10), map[string]time.Duration{ToolFlightStatus: 0})
+	Fetch(context.Background(), c, ToolFlightStatus, "x", load)
+	Fetch(context.Background(), c, ToolFlightStatus, "x", load)
+	if calls != 3 {
+		t.Fatalf("expected every call to reach the loader, got %d", calls)
+	}
+}
+
+func TestLRUEvictsAndExpires(t *testing.T) {
+	ctx := context.Background()
+	l := NewLRU(2)
+	now := time.Date(2026, 11, 2, 12, 0, 0, 0, time.UTC)
+	l.now = func() time.Time { return now }
+
+	_ = l.Set(ctx, "a", []byte("1"), time.Minute)
+	_ = l.Set(ctx, "b", []byte("2"), time.Minute)
+	_, _, _ = l.Get(ctx, "a")
+	_ = l.Set(ctx, "c", []byte("3"), time.Minute)
+	if _, ok, _ := l.Get(ctx, "b"); ok {
+		t.Fatalf("expected least recently used entry to be evicted")
+	}
+	if _, ok, _ := l.Get(ctx, "a"); !ok {
+		t.Fatalf("expected recently used entry to survive")
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, ok, _ := l.Get(ctx, "c"); ok {
+		t.Fatalf("expected expired entry to miss")
+	}
+	if l.Len() != 1 {
+		t.Fatalf("expected expired entry to be dropped, len=%d", l.Len())
+	}
+}
+
+func TestParseTTLs(t *testing.T) {
+	got, err := ParseTTLs("flight_status=30s, transit_suggest=6h")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if got[ToolFlightStatus] != 30*time.Second || got[ToolTransitSuggest] != 6*time.Hour {
+		t.Fatalf("unexpected ttls: %v", got)
+	}
+	if _, err := ParseTTLs("flight_status"); err == nil {
+		t.Fatalf("expected error for missing duration")
+	}
+}
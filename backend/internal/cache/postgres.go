@@ -0,0 +1,50 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Postgres is a Store backed by the provider_cache table, so cached responses are shared
+// across API instances and survive restarts.
+type Postgres struct {
+	db *pgxpool.Pool
+}
+
+func NewPostgres(db *pgxpool.Pool) *Postgres {
+	return &Postgres{db: db}
+}
+
+func (p *Postgres) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	const q = `SELECT value_json FROM provider_cache WHERE cache_key = $1 AND expires_at > NOW()`
+	var raw []byte
+	if err := p.db.QueryRow(ctx, q, key).Scan(&raw); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	return raw, true, nil
+}
+
+func (p *Postgres) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	const q = `
+		INSERT INTO provider_cache (cache_key, value_json, expires_at, created_at)
+		VALUES ($1, $2::jsonb, NOW() + $3 * INTERVAL '1 millisecond', NOW())
+		ON CONFLICT (cache_key) DO UPDATE SET value_json = EXCLUDED.value_json, expires_at = EXCLUDED.expires_at, created_at = NOW()`
+	_, err := p.db.Exec(ctx, q, key, string(value), ttl.Milliseconds())
+	return err
+}
+
+// DeleteExpired removes stale rows; expired rows are already ignored by Get.
+func (p *Postgres) DeleteExpired(ctx context.Context) (int64, error) {
+	tag, err := p.db.Exec(ctx, `DELETE FROM provider_cache WHERE expires_at <= NOW()`)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
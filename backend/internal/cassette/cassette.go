@@ -0,0 +1,292 @@
+// Package cassette records the model, Next bridge and flight status traffic behind one
+// assistant request into a JSON file, and plays it back, so that a production answer can be
+// reproduced and kept as a regression test.
+package cassette
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"triploom/backend/internal/providers/flightstatus"
+	"triploom/backend/internal/providers/openai"
+	"triploom/backend/internal/store"
+)
+
+// Request kinds.
+const (
+	KindChat    = "chat"
+	KindPlanner = "planner"
+)
+
+// Interaction kinds.
+const (
+	InteractionLLM          = "llm"
+	InteractionBridge       = "bridge"
+	InteractionFlightStatus = "flight_status"
+)
+
+// Cassette is one recorded request.
+type Cassette struct {
+	Kind       string    `json:"kind"`
+	RecordedAt time.Time `json:"recordedAt"`
+	UserID     string    `json:"userId"`
+	// Trip is the trip a chat request was about, as read from the store.
+	Trip         *store.Trip     `json:"trip,omitempty"`
+	Request      json.RawMessage `json:"request"`
+	Response     json.RawMessage `json:"response,omitempty"`
+	Error        string          `json:"error,omitempty"`
+	Interactions []Interaction   `json:"interactions"`
+}
+
+// Interaction is one outbound call. Which fields are set depends on Kind.
+type Interaction struct {
+	Kind string `json:"kind"`
+
+	Model        string `json:"model,omitempty"`
+	SystemPrompt string `json:"systemPrompt,omitempty"`
+	// ContextJSON is the context rendered into SystemPrompt, when the call was made under
+	// WithPromptContext. Replays match on it rather than on the whole prompt.
+	ContextJSON string         `json:"contextJson,omitempty"`
+	Messages    []Message      `json:"messages,omitempty"`
+	Text        string         `json:"text,omitempty"`
+	TokenUsage  map[string]any `json:"tokenUsage,omitempty"`
+
+	Path    string          `json:"path,omitempty"`
+	Body    json.RawMessage `json:"body,omitempty"`
+	Payload map[string]any  `json:"payload,omitempty"`
+
+	Provider string                     `json:"provider,omitempty"`
+	Flight   string                     `json:"flight,omitempty"`
+	Date     string                     `json:"date,omitempty"`
+	Status   *flightstatus.FlightStatus `json:"status,omitempty"`
+
+	Error string `json:"error,omitempty"`
+}
+
+func (in Interaction) String() string {
+	switch in.Kind {
+	case InteractionLLM:
+		return "llm call to " + in.Model
+	case InteractionBridge:
+		return "bridge call to " + in.Path
+	case InteractionFlightStatus:
+		return "flight status lookup of " + in.Flight + " on " + in.Date
+	}
+	return in.Kind
+}
+
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// Model is the model of the first recorded model call.
+func (c *Cassette) Model() string {
+	for _, in := range c.Interactions {
+		if in.Kind == InteractionLLM {
+			return in.Model
+		}
+	}
+	return ""
+}
+
+// Load reads a cassette file.
+func Load(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Cassette
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if c.Kind != KindChat && c.Kind != KindPlanner {
+		return nil, fmt.Errorf("%s: unknown cassette kind %q", path, c.Kind)
+	}
+	return &c, nil
+}
+
+// Tape collects a cassette while its request runs. A nil *Tape records nothing, so call
+// sites do not need to check whether recording is on.
+type Tape struct {
+	mu sync.Mutex
+	c  Cassette
+}
+
+type tapeKey struct{}
+
+// Start begins a cassette for request and returns a context that carries it.
+func Start(ctx context.Context, kind, userID string, request any) (context.Context, *Tape) {
+	raw, _ := json.Marshal(request)
+	t := &Tape{c: Cassette{Kind: kind, RecordedAt: time.Now().UTC(), UserID: userID, Request: raw, Interactions: make([]Interaction, 0)}}
+	return context.WithValue(ctx, tapeKey{}, t), t
+}
+
+type promptContextKey struct{}
+
+// WithPromptContext notes the ContextJSON rendered into the system prompt of the model calls
+// made under ctx, so that recordings keep it and replays can match on it.
+func WithPromptContext(ctx context.Context, payload any) context.Context {
+	raw, _ := json.Marshal(payload)
+	return context.WithValue(ctx, promptContextKey{}, string(raw))
+}
+
+func promptContext(ctx context.Context) string {
+	s, _ := ctx.Value(promptContextKey{}).(string)
+	return s
+}
+
+// FromContext returns the tape being recorded for ctx, or nil.
+func FromContext(ctx context.Context) *Tape {
+	t, _ := ctx.Value(tapeKey{}).(*Tape)
+	return t
+}
+
+func (t *Tape) add(in Interaction) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	t.c.Interactions = append(t.c.Interactions, in)
+	t.mu.Unlock()
+}
+
+// SetTrip records the trip a chat request read.
+func (t *Tape) SetTrip(trip *store.Trip) {
+	if t == nil || trip == nil {
+		return
+	}
+	t.mu.Lock()
+	copied := *trip
+	t.c.Trip = &copied
+	t.mu.Unlock()
+}
+
+// Bridge records a Next bridge call as the service saw it, including answers served from
+// the provider cache.
+func (t *Tape) Bridge(path string, body any, payload map[string]any, err error) {
+	if t == nil {
+		return
+	}
+	raw, _ := json.Marshal(body)
+	t.add(Interaction{Kind: InteractionBridge, Path: path, Body: raw, Payload: payload, Error: errorText(err)})
+}
+
+// FlightStatus records a flight status lookup as the service saw it.
+func (t *Tape) FlightStatus(provider, flight, date string, status *flightstatus.FlightStatus, err error) {
+	t.add(Interaction{Kind: InteractionFlightStatus, Provider: provider, Flight: flight, Date: date, Status: status, Error: errorText(err)})
+}
+
+// Finish closes the tape with the request's outcome.
+func (t *Tape) Finish(response any, err error) *Cassette {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err != nil {
+		t.c.Error = err.Error()
+	} else {
+		t.c.Response, _ = json.Marshal(response)
+	}
+	c := t.c
+	c.Interactions = append([]Interaction(nil), t.c.Interactions...)
+	return &c
+}
+
+func errorText(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// LLM is the model call a recording wraps; it matches ai.LLM.
+type LLM interface {
+	ResponsesChat(ctx context.Context, model string, systemPrompt string, messages []openai.Message) (*openai.ChatResult, error)
+}
+
+type recordingLLM struct {
+	next LLM
+}
+
+// WrapLLM records every call made through next under a context that carries a tape.
+func WrapLLM(next LLM) LLM {
+	return &recordingLLM{next: next}
+}
+
+func (r *recordingLLM) ResponsesChat(ctx context.Context, model string, systemPrompt string, messages []openai.Message) (*openai.ChatResult, error) {
+	result, err := r.next.ResponsesChat(ctx, model, systemPrompt, messages)
+	if t := FromContext(ctx); t != nil {
+		in := Interaction{Kind: InteractionLLM, Model: model, SystemPrompt: systemPrompt, ContextJSON: promptContext(ctx), Messages: toMessages(messages), Error: errorText(err)}
+		if result != nil {
+			in.Text, in.TokenUsage = result.Text, result.TokenUsage
+		}
+		t.add(in)
+	}
+	return result, err
+}
+
+func toMessages(messages []openai.Message) []Message {
+	out := make([]Message, 0, len(messages))
+	for _, m := range messages {
+		out = append(out, Message{Role: m.Role, Content: m.Content})
+	}
+	return out
+}
+
+// Recorder writes finished cassettes to a directory.
+type Recorder struct {
+	dir string
+}
+
+func NewRecorder(dir string) *Recorder {
+	return &Recorder{dir: dir}
+}
+
+// Save writes c, normalized, as <dir>/<time>-<kind>-<random>.json and returns the path.
+func (r *Recorder) Save(c *Cassette) (string, error) {
+	c = c.Normalized()
+	if err := os.MkdirAll(r.dir, 0o700); err != nil {
+		return "", err
+	}
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+	path := filepath.Join(r.dir, fmt.Sprintf("%s-%s-%s.json", c.RecordedAt.Format("20060102T150405Z"), c.Kind, hex.EncodeToString(suffix)))
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return path, os.WriteFile(path, append(data, '\n'), 0o600)
+}
+
+// Diff compares the named top-level fields of the recorded response with got's JSON, after
+// normalizing both, and describes each field that changed. A cassette recorded from a failed request has nothing
+// to compare.
+func (c *Cassette) Diff(got any, fields ...string) []string {
+	var want, have map[string]json.RawMessage
+	_ = json.Unmarshal(newNormalizer().raw(c.Response), &want)
+	raw, _ := json.Marshal(got)
+	_ = json.Unmarshal(newNormalizer().raw(raw), &have)
+	diffs := make([]string, 0)
+	if want == nil {
+		return diffs
+	}
+	for _, field := range fields {
+		if !sameJSON(want[field], have[field]) {
+			diffs = append(diffs, fmt.Sprintf("%s: recorded %s, got %s", field, orNull(want[field]), orNull(have[field])))
+		}
+	}
+	return diffs
+}
+
+func orNull(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return "null"
+	}
+	return string(raw)
+}
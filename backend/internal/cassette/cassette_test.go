@@ -0,0 +1,124 @@
+package cassette
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"triploom/backend/internal/providers/flightstatus"
+	"triploom/backend/internal/providers/openai"
+)
+
+type echoLLM struct{}
+
+func (echoLLM) ResponsesChat(_ context.Context, model string, _ string, messages []openai.Message) (*openai.ChatResult, error) {
+	return &openai.ChatResult{Text: model + ": " + messages[len(messages)-1].Content, TokenUsage: map[string]any{"total": 3.0}}, nil
+}
+
+func TestRecordSaveAndPlay(t *testing.T) {
+	llm := WrapLLM(echoLLM{})
+	if _, err := llm.ResponsesChat(context.Background(), "m", "sys", []openai.Message{{Role: "user", Content: "untaped"}}); err != nil {
+		t.Fatalf("chat without a tape: %v", err)
+	}
+
+	ctx, tape := Start(context.Background(), KindPlanner, "alice", map[string]any{"messages": []string{"hi"}})
+	result, _ := llm.ResponsesChat(ctx, "m", "sys", []openai.Message{{Role: "user", Content: "hi"}})
+	FromContext(ctx).Bridge("/api/transit/suggest", map[string]any{"origin": "Berlin", "destination": "Prague"}, map[string]any{"options": []any{"bus"}}, nil)
+	FromContext(ctx).FlightStatus("aeroapi", "AC856", "2026-11-02", nil, flightstatus.ErrNotFound)
+	var nilTape *Tape
+	nilTape.Bridge("/ignored", nil, nil, nil)
+
+	dir := t.TempDir()
+	path, err := NewRecorder(dir).Save(tape.Finish(map[string]any{"answer": result.Text}, nil))
+	if err != nil || !strings.HasPrefix(path, dir) || !strings.Contains(path, "-planner-") {
+		t.Fatalf("save: %s %v", path, err)
+	}
+	c, err := Load(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if c.UserID != "alice" || len(c.Interactions) != 3 || c.Model() != "m" {
+		t.Fatalf("unexpected cassette %+v", c)
+	}
+
+	p := NewPlayer(c)
+	if !p.HasFlightStatus() || p.Name() != "aeroapi" {
+		t.Fatalf("expected the recorded flight status provider, got %q", p.Name())
+	}
+	if _, err := p.ResponsesChat(context.Background(), "m", "sys v2", []openai.Message{{Role: "user", Content: "hi"}}); !errors.Is(err, ErrNoMatch) || !strings.Contains(err.Error(), `at byte 3: got "sys v2", recorded "sys"`) {
+		t.Fatalf("expected a prompt mismatch, got %v", err)
+	}
+	got, err := p.ResponsesChat(context.Background(), "m", "sys", []openai.Message{{Role: "user", Content: "hi"}})
+	if err != nil || got.Text != "m: hi" {
+		t.Fatalf("replay chat: %+v %v", got, err)
+	}
+	if _, err := p.ResponsesChat(context.Background(), "m", "sys", []openai.Message{{Role: "user", Content: "hi"}}); !errors.Is(err, ErrNoMatch) {
+		t.Fatalf("expected each recording to answer once, got %v", err)
+	}
+	payload, err := p.PostJSON(context.Background(), "/api/transit/suggest", map[string]any{"destination": "Prague", "origin": "Berlin"})
+	if err != nil || payload["options"] == nil {
+		t.Fatalf("replay bridge: %v %v", payload, err)
+	}
+	if _, err := p.Lookup(context.Background(), "AC856", "2026-11-02"); !errors.Is(err, flightstatus.ErrNotFound) {
+		t.Fatalf("expected the recorded not-found answer, got %v", err)
+	}
+	if unused := p.Unused(); len(unused) != 0 {
+		t.Fatalf("expected every call to be used, got %v", unused)
+	}
+
+	if diffs := c.Diff(map[string]any{"answer": "m: hi"}, "answer", "plannerDraft"); len(diffs) != 0 {
+		t.Fatalf("expected no differences, got %v", diffs)
+	}
+	if diffs := c.Diff(map[string]any{"answer": "other"}, "answer"); len(diffs) != 1 || diffs[0] != `answer: recorded "m: hi", got "other"` {
+		t.Fatalf("unexpected diff %v", diffs)
+	}
+}
+
+func TestSaveNormalizesAndMatchesOnContext(t *testing.T) {
+	const tripID = "6f1c2a9e-1111-4c3d-9e2f-0123456789ab"
+	tripContext := map[string]any{"trip": map[string]any{"id": tripID, "createdAt": "2026-10-18T09:00:00Z", "departingAt": "2026-11-02T08:00:00Z"}}
+	llm := WrapLLM(echoLLM{})
+	ctx, tape := Start(context.Background(), KindChat, "alice", map[string]any{"tripId": tripID})
+	ctx = WithPromptContext(ctx, tripContext)
+	if _, err := llm.ResponsesChat(ctx, "m", "Context for "+tripID, []openai.Message{{Role: "user", Content: "hi"}}); err != nil {
+		t.Fatalf("chat: %v", err)
+	}
+	path, err := NewRecorder(t.TempDir()).Save(tape.Finish(map[string]any{"conversationId": "0b7e4f6a-2222-4d1e-8f3a-0123456789ab", "fetchedAt": "2026-10-18T09:00:01Z"}, nil))
+	if err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	c, _ := Load(path)
+	in := c.Interactions[0]
+	const placeholder = "00000000-0000-4000-8000-000000000001"
+	if !strings.Contains(string(c.Request), placeholder) || in.SystemPrompt != "Context for "+placeholder {
+		t.Fatalf("expected one placeholder for the trip ID, got %s and %q", c.Request, in.SystemPrompt)
+	}
+	if !strings.Contains(in.ContextJSON, `"createdAt":"`+fixedTime+`"`) || !strings.Contains(in.ContextJSON, `"departingAt":"2026-11-02T08:00:00Z"`) {
+		t.Fatalf("expected only volatile timestamps to be fixed, got %s", in.ContextJSON)
+	}
+	if strings.Contains(string(c.Response), "2026-10-18") {
+		t.Fatalf("expected the response timestamps to be fixed, got %s", c.Response)
+	}
+
+	// A later run has fresh IDs and timestamps and a reworded prompt.
+	const freshID = "a3d5c7e9-3333-4b2a-9c8d-0123456789ab"
+	fresh := map[string]any{"trip": map[string]any{"id": freshID, "createdAt": "2026-10-19T10:30:00Z", "departingAt": "2026-11-02T08:00:00Z"}}
+	replayCtx := WithPromptContext(context.Background(), fresh)
+	strict := NewPlayer(c)
+	strict.MatchSystemPrompt = true
+	if _, err := strict.ResponsesChat(replayCtx, "m", "Reworded for "+freshID, []openai.Message{{Role: "user", Content: "hi"}}); !errors.Is(err, ErrNoMatch) {
+		t.Fatalf("expected the whole prompt match to fail, got %v", err)
+	}
+	p := NewPlayer(c)
+	if _, err := p.ResponsesChat(replayCtx, "m", "Reworded for "+freshID, []openai.Message{{Role: "user", Content: "hi"}}); err != nil {
+		t.Fatalf("expected the ContextJSON to match, got %v", err)
+	}
+	fresh["trip"].(map[string]any)["departingAt"] = "2026-11-03T08:00:00Z"
+	if _, err := NewPlayer(c).ResponsesChat(WithPromptContext(context.Background(), fresh), "m", "Context for "+freshID, []openai.Message{{Role: "user", Content: "hi"}}); !errors.Is(err, ErrNoMatch) || !strings.Contains(err.Error(), "ContextJSON differs") {
+		t.Fatalf("expected a changed trip to miss, got %v", err)
+	}
+	if diffs := c.Diff(map[string]any{"conversationId": freshID, "fetchedAt": "2026-10-19T10:30:02Z"}, "conversationId", "fetchedAt"); len(diffs) != 0 {
+		t.Fatalf("expected fresh IDs and timestamps not to differ, got %v", diffs)
+	}
+}
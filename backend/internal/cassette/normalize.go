@@ -0,0 +1,106 @@
+package cassette
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// fixedTime replaces the volatile timestamps of a recording.
+const fixedTime = "2000-01-01T00:00:00Z"
+
+var (
+	uuidPattern = regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`)
+	// volatileTime matches the JSON fields that hold when something was stored or fetched,
+	// as opposed to trip data such as departingAt.
+	volatileTime = regexp.MustCompile(`("(?:createdAt|updatedAt|fetchedAt|generatedAt|decidedAt|lastPolledAt|nextPollAt|expiresAt)":\s*)"[^"]*"`)
+)
+
+// normalizer rewrites the parts of a recording that change on every run: UUIDs become
+// placeholder UUIDs numbered by first appearance, so one ID keeps one placeholder throughout,
+// and volatile timestamps become fixedTime.
+type normalizer struct {
+	ids map[string]string
+}
+
+func newNormalizer() *normalizer {
+	return &normalizer{ids: make(map[string]string)}
+}
+
+func (n *normalizer) text(s string) string {
+	s = volatileTime.ReplaceAllString(s, `${1}"`+fixedTime+`"`)
+	return uuidPattern.ReplaceAllStringFunc(s, func(id string) string {
+		id = strings.ToLower(id)
+		placeholder, ok := n.ids[id]
+		if !ok {
+			placeholder = fmt.Sprintf("00000000-0000-4000-8000-%012d", len(n.ids)+1)
+			n.ids[id] = placeholder
+		}
+		return placeholder
+	})
+}
+
+func (n *normalizer) raw(raw json.RawMessage) json.RawMessage {
+	if len(raw) == 0 {
+		return raw
+	}
+	return json.RawMessage(n.text(string(raw)))
+}
+
+// value normalizes v in place through its JSON encoding.
+func (n *normalizer) value(v any) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	_ = json.Unmarshal(n.raw(raw), v)
+}
+
+func (n *normalizer) messages(messages []Message) []Message {
+	out := make([]Message, len(messages))
+	for i, m := range messages {
+		out[i] = Message{Role: m.Role, Content: n.text(m.Content)}
+	}
+	return out
+}
+
+// Normalized returns a copy of c with the IDs and timestamps that differ between runs
+// replaced, so that recordings of the same request compare equal and diff cleanly. One
+// mapping covers the whole cassette: the trip ID in the request, the trip and ContextJSON
+// stays the same placeholder.
+func (c *Cassette) Normalized() *Cassette {
+	n := newNormalizer()
+	out := *c
+	if c.Trip != nil {
+		trip := *c.Trip
+		n.value(&trip)
+		out.Trip = &trip
+	}
+	out.UserID = n.text(c.UserID)
+	out.Request = n.raw(c.Request)
+	out.Interactions = make([]Interaction, len(c.Interactions))
+	for i, in := range c.Interactions {
+		in.SystemPrompt = n.text(in.SystemPrompt)
+		in.ContextJSON = n.text(in.ContextJSON)
+		in.Messages = n.messages(in.Messages)
+		in.Text = n.text(in.Text)
+		in.Body = n.raw(in.Body)
+		if in.Payload != nil {
+			payload := make(map[string]any, len(in.Payload))
+			for k, v := range in.Payload {
+				payload[k] = v
+			}
+			n.value(&payload)
+			in.Payload = payload
+		}
+		if in.Status != nil {
+			status := *in.Status
+			n.value(&status)
+			in.Status = &status
+		}
+		out.Interactions[i] = in
+	}
+	out.Response = n.raw(c.Response)
+	return &out
+}
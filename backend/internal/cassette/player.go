@@ -0,0 +1,187 @@
+package cassette
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"triploom/backend/internal/providers/flightstatus"
+	"triploom/backend/internal/providers/openai"
+)
+
+// ErrNoMatch is returned when a replayed request makes a call the cassette has no recording
+// of, usually because the prompt or tool inputs are now assembled differently.
+var ErrNoMatch = errors.New("no recorded call matches")
+
+// Player answers model, bridge and flight status calls from a cassette. Each recorded call
+// answers at most once, in recording order among equal calls. Calls are compared after
+// normalization, so fresh IDs and timestamps still match.
+type Player struct {
+	// MatchSystemPrompt makes model calls match on the whole system prompt. By default they
+	// match on the recorded ContextJSON, so that rewording a template keeps cassettes
+	// replayable; cassettes recorded without one always match on the whole prompt.
+	MatchSystemPrompt bool
+
+	mu   sync.Mutex
+	c    *Cassette
+	used []bool
+}
+
+func NewPlayer(c *Cassette) *Player {
+	return &Player{c: c, used: make([]bool, len(c.Interactions))}
+}
+
+// Cassette is the recording the player answers from.
+func (p *Player) Cassette() *Cassette {
+	return p.c
+}
+
+// next claims the first unused interaction of kind that match accepts. When none does, it
+// describes how the nearest candidate differs.
+func (p *Player) next(kind string, match func(Interaction) string) (Interaction, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	diff := "the cassette has no unused " + kind + " call"
+	for i, in := range p.c.Interactions {
+		if p.used[i] || in.Kind != kind {
+			continue
+		}
+		d := match(in)
+		if d == "" {
+			p.used[i] = true
+			return in, nil
+		}
+		diff = d
+	}
+	return Interaction{}, fmt.Errorf("%w: %s", ErrNoMatch, diff)
+}
+
+func (p *Player) ResponsesChat(ctx context.Context, model string, systemPrompt string, messages []openai.Message) (*openai.ChatResult, error) {
+	got := newNormalizer().messages(toMessages(messages))
+	prompt := newNormalizer().text(systemPrompt)
+	contextJSON := newNormalizer().text(promptContext(ctx))
+	in, err := p.next(InteractionLLM, func(in Interaction) string {
+		recordedPrompt := newNormalizer().text(in.SystemPrompt)
+		switch {
+		case in.Model != model:
+			return fmt.Sprintf("model %q, recorded %q", model, in.Model)
+		case (p.MatchSystemPrompt || in.ContextJSON == "") && prompt != recordedPrompt:
+			return "system prompt differs from the recording " + firstDifference(prompt, recordedPrompt)
+		case in.ContextJSON != "" && contextJSON != newNormalizer().text(in.ContextJSON):
+			return "ContextJSON differs from the recording " + firstDifference(contextJSON, newNormalizer().text(in.ContextJSON))
+		case !sameJSON(got, newNormalizer().messages(in.Messages)):
+			return "messages differ from the recording"
+		}
+		return ""
+	})
+	if err != nil {
+		return nil, err
+	}
+	if in.Error != "" {
+		return nil, errors.New(in.Error)
+	}
+	return &openai.ChatResult{Text: in.Text, TokenUsage: in.TokenUsage}, nil
+}
+
+// PostJSON answers a Next bridge call.
+func (p *Player) PostJSON(_ context.Context, path string, body any) (map[string]any, error) {
+	in, err := p.next(InteractionBridge, func(in Interaction) string {
+		if in.Path != path {
+			return fmt.Sprintf("bridge path %s, recorded %s", path, in.Path)
+		}
+		var recorded any
+		_ = json.Unmarshal(newNormalizer().raw(in.Body), &recorded)
+		live, _ := json.Marshal(body)
+		if !sameJSON(newNormalizer().raw(live), recorded) {
+			return fmt.Sprintf("bridge body for %s differs from the recording", path)
+		}
+		return ""
+	})
+	if err != nil {
+		return nil, err
+	}
+	if in.Error != "" {
+		return nil, errors.New(in.Error)
+	}
+	return in.Payload, nil
+}
+
+// HasFlightStatus reports whether the recording used a native flight status provider.
+func (p *Player) HasFlightStatus() bool {
+	for _, in := range p.c.Interactions {
+		if in.Kind == InteractionFlightStatus {
+			return true
+		}
+	}
+	return false
+}
+
+// Name is the recorded flight status provider's name.
+func (p *Player) Name() string {
+	for _, in := range p.c.Interactions {
+		if in.Kind == InteractionFlightStatus {
+			return in.Provider
+		}
+	}
+	return "cassette"
+}
+
+// Lookup answers a flight status lookup. A recorded not-found answer is returned as
+// flightstatus.ErrNotFound.
+func (p *Player) Lookup(_ context.Context, flightNumber, departureDate string) (*flightstatus.FlightStatus, error) {
+	in, err := p.next(InteractionFlightStatus, func(in Interaction) string {
+		if in.Flight != flightNumber || in.Date != departureDate {
+			return fmt.Sprintf("flight %s on %s, recorded %s on %s", flightNumber, departureDate, in.Flight, in.Date)
+		}
+		return ""
+	})
+	if err != nil {
+		return nil, err
+	}
+	switch in.Error {
+	case "":
+		return in.Status, nil
+	case flightstatus.ErrNotFound.Error():
+		return nil, flightstatus.ErrNotFound
+	default:
+		return nil, errors.New(in.Error)
+	}
+}
+
+// Unused lists the recorded calls the replay did not make.
+func (p *Player) Unused() []Interaction {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	out := make([]Interaction, 0)
+	for i, in := range p.c.Interactions {
+		if !p.used[i] {
+			out = append(out, in)
+		}
+	}
+	return out
+}
+
+func sameJSON(a, b any) bool {
+	aj, errA := json.Marshal(a)
+	bj, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(aj) == string(bj)
+}
+
+// firstDifference quotes both strings around the first byte where they differ.
+func firstDifference(got, want string) string {
+	i := 0
+	for i < len(got) && i < len(want) && got[i] == want[i] {
+		i++
+	}
+	start := max(0, i-30)
+	return fmt.Sprintf("at byte %d: got %q, recorded %q", i, excerpt(got, start, i+30), excerpt(want, start, i+30))
+}
+
+func excerpt(s string, from, to int) string {
+	if from > len(s) {
+		return ""
+	}
+	return s[from:min(to, len(s))]
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 )
 
@@ -16,6 +17,61 @@ type Config struct {
 	NextAPIBaseURL     string
 	AllowedOrigins     string
 	UseSupabase        bool
+
+	// OpenAIModels lists further models clients may pick when regenerating an answer.
+	OpenAIModels []string
+	// PromptsDir holds <name>.v<N>.tmpl files that override the bundled prompt templates.
+	PromptsDir string
+	// CassetteDir, when set, receives a recording of every copilot and planner request.
+	CassetteDir string
+	// AIGuardrails is what happens to answers that break the prompt's rules: retry, rewrite,
+	// flag or off.
+	AIGuardrails string
+	// AISanitize tunes how untrusted page and tool data is cleaned per pageKey; see
+	// ai.ParseSanitizePolicies.
+	AISanitize string
+	// AIRetrievalTokens is the prompt budget for trip data retrieved into copilot prompts; 0
+	// turns retrieval off.
+	AIRetrievalTokens int
+	// KnowledgeDir holds the markdown destination guides added to copilot and planner prompts;
+	// empty turns the knowledge base off.
+	KnowledgeDir string
+	// KnowledgeEmbedder indexes the guides: hashing (offline) or openai.
+	KnowledgeEmbedder string
+	// KnowledgeEmbeddingModel is the OpenAI embedding model used with KnowledgeEmbedder=openai.
+	KnowledgeEmbeddingModel string
+	// AIFallbackChain is the ordered provider[:model][@timeout] attempts made for every
+	// answer before the local fallback; see ai.ParseFallbackChain.
+	AIFallbackChain string
+	// AICompatibleBaseURL, when set, adds the "compatible" provider: an OpenAI-compatible
+	// Responses API at that URL, called with AICompatibleAPIKey.
+	AICompatibleBaseURL string
+	AICompatibleAPIKey  string
+
+	FlightStatusProvider string
+	AeroAPIKey           string
+	AeroAPIBaseURL       string
+	FlightStatusAPIKey   string
+	FlightStatusBaseURL  string
+
+	SerpAPIKey     string
+	SerpAPIBaseURL string
+
+	CacheBackend    string
+	CacheMaxEntries int
+	CacheTTLs       string
+
+	EventBus string
+
+	DatabaseURL    string
+	SQLitePath     string
+	MigrateOnStart bool
+
+	// JWTAuth authenticates requests with Supabase JWTs: always with Supabase persistence, and
+	// with SQLite when SUPABASE_JWKS_URL is set. Otherwise X-User-Id is trusted.
+	JWTAuth bool
+	// InsecureTestAuth allows SQLite persistence without JWTAuth, trusting X-User-Id.
+	InsecureTestAuth bool
 }
 
 func Load() (*Config, error) {
@@ -23,13 +79,65 @@ func Load() (*Config, error) {
 		Port:               getOrDefault("PORT", "8080"),
 		OpenAIAPIKey:       os.Getenv("OPENAI_API_KEY"),
 		OpenAIModelDefault: getOrDefault("OPENAI_MODEL_DEFAULT", "gpt-5-mini"),
+		OpenAIModels:       splitList(os.Getenv("OPENAI_MODELS")),
+		PromptsDir:         os.Getenv("PROMPTS_DIR"),
+		CassetteDir:        os.Getenv("CASSETTE_DIR"),
+		AIGuardrails:       strings.ToLower(getOrDefault("AI_GUARDRAILS", "retry")),
+		AISanitize:         os.Getenv("AI_SANITIZE"),
 		SupabaseURL:        os.Getenv("SUPABASE_URL"),
 		SupabaseJWKSURL:    os.Getenv("SUPABASE_JWKS_URL"),
 		SupabaseDBURL:      os.Getenv("SUPABASE_DB_URL"),
 		NextAPIBaseURL:     getOrDefault("NEXT_API_BASE_URL", "http://localhost:3000"),
 		AllowedOrigins:     getOrDefault("ALLOWED_ORIGINS", "http://localhost:3000"),
+
+		KnowledgeDir:            os.Getenv("KNOWLEDGE_DIR"),
+		KnowledgeEmbedder:       strings.ToLower(getOrDefault("KNOWLEDGE_EMBEDDER", "hashing")),
+		KnowledgeEmbeddingModel: getOrDefault("KNOWLEDGE_EMBEDDING_MODEL", "text-embedding-3-small"),
+
+		AIFallbackChain:     os.Getenv("AI_FALLBACK_CHAIN"),
+		AICompatibleBaseURL: os.Getenv("AI_COMPATIBLE_BASE_URL"),
+		AICompatibleAPIKey:  os.Getenv("AI_COMPATIBLE_API_KEY"),
+
+		FlightStatusProvider: os.Getenv("FLIGHT_STATUS_PROVIDER"),
+		AeroAPIKey:           firstEnv("AERO_API_KEY", "AEROAPI_KEY"),
+		AeroAPIBaseURL:       os.Getenv("AEROAPI_BASE_URL"),
+		FlightStatusAPIKey:   firstEnv("FLIGHT_STATUS_API_KEY", "AVIATIONSTACK_API_KEY", "AVIATIONSTACK_ACCESS_KEY"),
+		FlightStatusBaseURL:  os.Getenv("FLIGHT_STATUS_BASE_URL"),
+
+		SerpAPIKey:     firstEnv("SERPAPI_API_KEY", "SERP_API_KEY"),
+		SerpAPIBaseURL: os.Getenv("SERPAPI_BASE_URL"),
+
+		CacheBackend: strings.ToLower(getOrDefault("CACHE_BACKEND", "memory")),
+		CacheTTLs:    os.Getenv("CACHE_TTLS"),
+
+		EventBus: strings.ToLower(getOrDefault("EVENT_BUS", "memory")),
+	}
+	if err := cfg.loadDatabase(); err != nil {
+		return nil, err
+	}
+	maxEntries, err := strconv.Atoi(getOrDefault("CACHE_MAX_ENTRIES", "1000"))
+	if err != nil {
+		return nil, fmt.Errorf("CACHE_MAX_ENTRIES must be an integer: %w", err)
+	}
+	cfg.CacheMaxEntries = maxEntries
+	retrievalTokens, err := strconv.Atoi(getOrDefault("AI_RETRIEVAL_TOKENS", "1200"))
+	if err != nil || retrievalTokens < 0 {
+		return nil, fmt.Errorf("AI_RETRIEVAL_TOKENS must be a non-negative integer")
 	}
+	cfg.AIRetrievalTokens = retrievalTokens
 	cfg.UseSupabase = strings.TrimSpace(cfg.SupabaseDBURL) != "" && strings.TrimSpace(cfg.SupabaseJWKSURL) != ""
+	if cfg.UseSupabase && cfg.SQLitePath != "" {
+		return nil, fmt.Errorf("DATABASE_URL=sqlite:// cannot be combined with SUPABASE_DB_URL")
+	}
+	insecure, err := strconv.ParseBool(getOrDefault("INSECURE_TEST_AUTH", "false"))
+	if err != nil {
+		return nil, fmt.Errorf("INSECURE_TEST_AUTH must be true or false: %w", err)
+	}
+	cfg.InsecureTestAuth = insecure
+	cfg.JWTAuth = cfg.UseSupabase || (cfg.SQLitePath != "" && strings.TrimSpace(cfg.SupabaseJWKSURL) != "")
+	if cfg.SQLitePath != "" && !cfg.JWTAuth && !cfg.InsecureTestAuth {
+		return nil, fmt.Errorf("DATABASE_URL=sqlite:// requires SUPABASE_JWKS_URL for auth, or INSECURE_TEST_AUTH=true to trust X-User-Id")
+	}
 
 	if cfg.OpenAIAPIKey == "" {
 		return nil, fmt.Errorf("OPENAI_API_KEY is required")
@@ -37,12 +145,100 @@ func Load() (*Config, error) {
 	if cfg.UseSupabase && cfg.SupabaseURL == "" {
 		return nil, fmt.Errorf("SUPABASE_URL is required when SUPABASE_DB_URL and SUPABASE_JWKS_URL are set")
 	}
+	switch cfg.CacheBackend {
+	case "memory", "off":
+	case "postgres":
+		if !cfg.UseSupabase {
+			return nil, fmt.Errorf("CACHE_BACKEND=postgres requires SUPABASE_DB_URL and SUPABASE_JWKS_URL")
+		}
+	default:
+		return nil, fmt.Errorf("CACHE_BACKEND must be memory, postgres or off")
+	}
+	switch cfg.AIGuardrails {
+	case "retry", "rewrite", "flag", "off":
+	default:
+		return nil, fmt.Errorf("AI_GUARDRAILS must be retry, rewrite, flag or off")
+	}
+	switch cfg.KnowledgeEmbedder {
+	case "hashing", "openai":
+	default:
+		return nil, fmt.Errorf("KNOWLEDGE_EMBEDDER must be hashing or openai")
+	}
+	switch cfg.EventBus {
+	case "memory":
+	case "postgres":
+		if !cfg.UseSupabase {
+			return nil, fmt.Errorf("EVENT_BUS=postgres requires SUPABASE_DB_URL and SUPABASE_JWKS_URL")
+		}
+	default:
+		return nil, fmt.Errorf("EVENT_BUS must be memory or postgres")
+	}
+	return cfg, nil
+}
+
+// LoadDatabase reads only the database settings, for commands such as `migrate` that do not
+// serve the API and so do not need provider keys.
+func LoadDatabase() (*Config, error) {
+	cfg := &Config{SupabaseDBURL: os.Getenv("SUPABASE_DB_URL")}
+	if err := cfg.loadDatabase(); err != nil {
+		return nil, err
+	}
 	return cfg, nil
 }
 
+func (cfg *Config) loadDatabase() error {
+	cfg.DatabaseURL = strings.TrimSpace(os.Getenv("DATABASE_URL"))
+	if cfg.DatabaseURL != "" {
+		path, err := sqlitePath(cfg.DatabaseURL)
+		if err != nil {
+			return err
+		}
+		cfg.SQLitePath = path
+	}
+	migrateOnStart, err := strconv.ParseBool(getOrDefault("MIGRATE_ON_START", "false"))
+	if err != nil {
+		return fmt.Errorf("MIGRATE_ON_START must be true or false: %w", err)
+	}
+	cfg.MigrateOnStart = migrateOnStart
+	return nil
+}
+
+// sqlitePath accepts sqlite:///abs/path.db and sqlite://relative/path.db. Postgres is still
+// configured through SUPABASE_DB_URL.
+func sqlitePath(databaseURL string) (string, error) {
+	path, ok := strings.CutPrefix(databaseURL, "sqlite://")
+	if !ok {
+		return "", fmt.Errorf("DATABASE_URL must start with sqlite:// (use SUPABASE_DB_URL for Postgres)")
+	}
+	if path == "" || path == "/" {
+		return "", fmt.Errorf("DATABASE_URL is missing the SQLite file path")
+	}
+	return path, nil
+}
+
 func getOrDefault(key, fallback string) string {
 	if v := os.Getenv(key); v != "" {
 		return v
 	}
 	return fallback
 }
+
+// splitList parses a comma-separated setting, dropping blanks.
+func splitList(v string) []string {
+	out := make([]string, 0)
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+func firstEnv(keys ...string) string {
+	for _, key := range keys {
+		if v := os.Getenv(key); v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,211 @@
+package eval
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"triploom/backend/internal/ai"
+	"triploom/backend/internal/prompts"
+	"triploom/backend/internal/store"
+)
+
+// evalUser owns every case's trip. Prompt variants are assigned per user, so all cases of a
+// run see the same variant.
+const evalUser = "eval"
+
+// Check statuses.
+const (
+	StatusPass = "pass"
+	StatusFail = "fail"
+	StatusSkip = "skip"
+)
+
+// Runner runs cases through an ai.Service backed by a fresh in-memory store per case.
+type Runner struct {
+	LLM     ai.LLM
+	Model   string
+	Prompts *prompts.Registry
+	// Judge grades answers against a case's rubric; nil skips those checks.
+	Judge Judge
+	// Guardrails is the service's guardrail policy. Empty scores the model's raw answers
+	// (ai.GuardrailOff).
+	Guardrails string
+}
+
+// Check is the outcome of one expectation.
+type Check struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Result is the outcome of one case.
+type Result struct {
+	Suite         string           `json:"suite"`
+	Case          string           `json:"case"`
+	Kind          string           `json:"kind"`
+	PageKey       string           `json:"pageKey,omitempty"`
+	PromptVersion string           `json:"promptVersion"`
+	Answer        string           `json:"answer"`
+	Draft         *ai.PlannerDraft `json:"draft,omitempty"`
+	Checks        []Check          `json:"checks"`
+	Passed        bool             `json:"passed"`
+	Error         string           `json:"error,omitempty"`
+}
+
+// Run runs every case of suites in order. Only context cancellation stops a run; a failing
+// case is recorded in its Result.
+func (r *Runner) Run(ctx context.Context, suites []*Suite) ([]Result, error) {
+	results := make([]Result, 0)
+	for _, suite := range suites {
+		for _, c := range suite.Cases {
+			if err := ctx.Err(); err != nil {
+				return results, err
+			}
+			results = append(results, r.runCase(ctx, suite.Name, c))
+		}
+	}
+	return results, nil
+}
+
+func (r *Runner) runCase(ctx context.Context, suiteName string, c Case) Result {
+	res := Result{Suite: suiteName, Case: c.ID, Kind: c.Kind, PageKey: c.PageKey, Checks: make([]Check, 0)}
+	if t, err := r.Prompts.Pick(c.Kind, evalUser); err == nil {
+		res.PromptVersion = t.ID()
+	}
+	repo := store.NewInMemoryAIRepository()
+	guardrails := r.Guardrails
+	if guardrails == "" {
+		guardrails = ai.GuardrailOff
+	}
+	svc := ai.NewService(repo, r.LLM, nil, ai.NewModelSelector(r.Model), ai.WithPrompts(r.Prompts), ai.WithGuardrails(guardrails))
+
+	var err error
+	switch c.Kind {
+	case KindPlanner:
+		var resp *ai.PlannerChatResponse
+		resp, err = svc.PlannerChat(ctx, evalUser, ai.PlannerChatRequest{Messages: c.Messages, PlannerContext: c.PlannerContext})
+		if err == nil {
+			res.Answer, res.Draft = resp.Answer, resp.PlannerDraft
+		}
+	default:
+		var trip *store.Trip
+		trip, err = repo.CreateTrip(ctx, c.trip(), evalUser)
+		if err != nil {
+			break
+		}
+		var resp *ai.ChatResponse
+		resp, err = svc.Chat(ctx, evalUser, ai.ChatRequest{TripID: trip.ID, PageKey: c.PageKey, PageContext: c.PageContext, Messages: c.Messages})
+		if err == nil {
+			res.Answer = resp.Answer
+		}
+	}
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+
+	res.Checks = append(res.Checks, ruleChecks(c.Expect, res.Answer, res.Draft)...)
+	if c.Expect.Judge != "" {
+		res.Checks = append(res.Checks, r.judge(ctx, c, res.Answer))
+	}
+	res.Passed = true
+	for _, check := range res.Checks {
+		if check.Status == StatusFail {
+			res.Passed = false
+		}
+	}
+	return res
+}
+
+func (c Case) trip() store.Trip {
+	start, _ := parseDate(c.Trip.StartDate)
+	end, _ := parseDate(c.Trip.EndDate)
+	return store.Trip{Destination: c.Trip.Destination, StartDate: start, EndDate: end, Timezone: c.Trip.Timezone}
+}
+
+func (r *Runner) judge(ctx context.Context, c Case, answer string) Check {
+	if r.Judge == nil {
+		return Check{Name: "judge", Status: StatusSkip, Detail: "no judge model configured"}
+	}
+	pass, reason, err := r.Judge.Grade(ctx, c, answer)
+	switch {
+	case err != nil:
+		return Check{Name: "judge", Status: StatusFail, Detail: "judge error: " + err.Error()}
+	case pass:
+		return Check{Name: "judge", Status: StatusPass, Detail: reason}
+	default:
+		return Check{Name: "judge", Status: StatusFail, Detail: reason}
+	}
+}
+
+func ruleChecks(exp Expect, answer string, draft *ai.PlannerDraft) []Check {
+	checks := make([]Check, 0)
+	lower := strings.ToLower(answer)
+	for _, term := range exp.MustMention {
+		check := Check{Name: "mentions " + term, Status: StatusPass}
+		if !strings.Contains(lower, strings.ToLower(term)) {
+			check.Status = StatusFail
+		}
+		checks = append(checks, check)
+	}
+	for _, term := range exp.MustNotMention {
+		check := Check{Name: "does not mention " + term, Status: StatusPass}
+		if strings.Contains(lower, strings.ToLower(term)) {
+			check.Status = StatusFail
+		}
+		checks = append(checks, check)
+	}
+	if exp.MustNotClaimBooking {
+		check := Check{Name: "no booking claim", Status: StatusPass}
+		if violations := ai.CheckAnswer(answer, ""); len(violations) > 0 {
+			check.Status, check.Detail = StatusFail, fmt.Sprintf("claims %q", violations[0].Text)
+		}
+		checks = append(checks, check)
+	}
+	if exp.MaxWords > 0 {
+		check := Check{Name: fmt.Sprintf("at most %d words", exp.MaxWords), Status: StatusPass}
+		if n := len(strings.Fields(answer)); n > exp.MaxWords {
+			check.Status, check.Detail = StatusFail, fmt.Sprintf("%d words", n)
+		}
+		checks = append(checks, check)
+	}
+	if len(exp.Draft) > 0 {
+		checks = append(checks, draftChecks(exp.Draft, draft)...)
+	}
+	return checks
+}
+
+// draftChecks compares fields through their JSON encoding, so that YAML integers match JSON
+// numbers and lists compare element by element in order.
+func draftChecks(want map[string]any, draft *ai.PlannerDraft) []Check {
+	got := make(map[string]any)
+	if draft != nil {
+		raw, _ := json.Marshal(draft)
+		_ = json.Unmarshal(raw, &got)
+	}
+	fields := make([]string, 0, len(want))
+	for field := range want {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	checks := make([]Check, 0, len(fields))
+	for _, field := range fields {
+		wantJSON, err := json.Marshal(want[field])
+		if err != nil {
+			checks = append(checks, Check{Name: "draft." + field, Status: StatusFail, Detail: err.Error()})
+			continue
+		}
+		gotJSON, _ := json.Marshal(got[field])
+		check := Check{Name: "draft." + field, Status: StatusPass}
+		if string(wantJSON) != string(gotJSON) {
+			check.Status, check.Detail = StatusFail, fmt.Sprintf("want %s, got %s", wantJSON, gotJSON)
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
@@ -0,0 +1,153 @@
+package eval
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"triploom/backend/internal/prompts"
+	"triploom/backend/internal/providers/openai"
+)
+
+const testSuite = `
+name: unit
+cases:
+  - id: kyoto-temples
+    pageKey: itinerary
+    trip: {destination: Kyoto, startDate: 2026-04-01, endDate: 2026-04-05}
+    messages:
+      - {role: user, content: "which temples first?"}
+    expect:
+      mustMention: [Kinkaku-ji]
+      mustNotMention: [Tokyo]
+      mustNotClaimBooking: true
+      maxWords: 20
+      judge: Names a temple.
+  - id: lisbon-plan
+    kind: planner
+    plannerContext: {destination: Portugal, travelers: 2}
+    messages:
+      - {role: user, content: "Lisbon from 2026-09-10 to 2026-09-12"}
+    expect:
+      draft:
+        destination: Portugal
+        travelers: 2
+        startDate: 2026-09-10
+`
+
+// scriptedLLM answers with answer, whatever the prompt.
+type scriptedLLM struct{ answer string }
+
+func (s scriptedLLM) ResponsesChat(context.Context, string, string, []openai.Message) (*openai.ChatResult, error) {
+	return &openai.ChatResult{Text: s.answer}, nil
+}
+
+type fixedJudge struct{ pass bool }
+
+func (j fixedJudge) Grade(context.Context, Case, string) (bool, string, error) {
+	return j.pass, "fixed", nil
+}
+
+func TestParseSuite(t *testing.T) {
+	suite, err := ParseSuite([]byte(testSuite))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if suite.Cases[0].Kind != KindCopilot || suite.Cases[1].Expect.Draft["startDate"] != "2026-09-10" {
+		t.Fatalf("expected defaults and plain dates, got %+v", suite.Cases)
+	}
+
+	for name, doc := range map[string]string{
+		"unknown field":     "cases:\n  - {id: a, pageKey: x, messages: [{role: user, content: hi}], expect: {mustMentoin: [x]}}",
+		"missing page key":  "cases:\n  - {id: a, messages: [{role: user, content: hi}]}",
+		"assistant last":    "cases:\n  - {id: a, pageKey: x, messages: [{role: assistant, content: hi}]}",
+		"copilot draft":     "cases:\n  - {id: a, pageKey: x, messages: [{role: user, content: hi}], expect: {draft: {travelers: 1}}}",
+		"duplicate id":      "cases:\n  - {id: a, pageKey: x, messages: [{role: user, content: hi}]}\n  - {id: a, pageKey: x, messages: [{role: user, content: hi}]}",
+		"bad trip date":     "cases:\n  - {id: a, pageKey: x, trip: {startDate: April}, messages: [{role: user, content: hi}]}",
+		"no cases":          "name: empty",
+		"unknown case kind": "cases:\n  - {id: a, kind: flights, messages: [{role: user, content: hi}]}",
+	} {
+		if _, err := ParseSuite([]byte(doc)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestRuleChecks(t *testing.T) {
+	claims := []string{
+		"Great news, I've booked the 9am train for you.",
+		"We have reserved a table at Kikunoi.",
+		"Your reservation has been confirmed.",
+		"Your confirmation number is X12.",
+	}
+	for _, answer := range claims {
+		if checks := ruleChecks(Expect{MustNotClaimBooking: true}, answer, nil); checks[0].Status != StatusFail {
+			t.Errorf("expected %q to be flagged as a booking claim", answer)
+		}
+	}
+	for _, answer := range []string{
+		"I can't book for you, but Kikunoi takes reservations online.",
+		"Once you have booked, add the confirmation to the trip.",
+	} {
+		if checks := ruleChecks(Expect{MustNotClaimBooking: true}, answer, nil); checks[0].Status != StatusPass {
+			t.Errorf("expected %q not to be flagged: %+v", answer, checks[0])
+		}
+	}
+
+	checks := ruleChecks(Expect{MustMention: []string{"kyoto"}, MaxWords: 2}, "Visit Kyoto early please", nil)
+	if checks[0].Status != StatusPass || checks[1].Status != StatusFail || checks[1].Detail != "4 words" {
+		t.Fatalf("unexpected checks %+v", checks)
+	}
+}
+
+func TestRunAndReport(t *testing.T) {
+	ctx := context.Background()
+	suite, err := ParseSuite([]byte(testSuite))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	suite.Name = "unit"
+	runner := &Runner{LLM: scriptedLLM{answer: "Start at Kinkaku-ji, then Ryoan-ji."}, Model: "test-model", Prompts: prompts.Default(), Judge: fixedJudge{pass: true}}
+
+	results, err := runner.Run(ctx, []*Suite{suite})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if len(results) != 2 || !results[0].Passed || results[0].PromptVersion != "copilot@v7" {
+		t.Fatalf("expected the copilot case to pass, got %+v", results[0])
+	}
+	planner := results[1]
+	if !planner.Passed || planner.Draft == nil || planner.Draft.StartDate != "2026-09-10" {
+		t.Fatalf("expected the planner draft to match, got %+v", planner)
+	}
+
+	runner.LLM = scriptedLLM{answer: "I've booked Tokyo Tower for you."}
+	runner.Judge = nil
+	results, _ = runner.Run(ctx, []*Suite{suite})
+	failed := results[0]
+	if failed.Passed {
+		t.Fatalf("expected the copilot case to fail, got %+v", failed)
+	}
+	if last := failed.Checks[len(failed.Checks)-1]; last.Name != "judge" || last.Status != StatusSkip {
+		t.Fatalf("expected the judge to be skipped without a judge, got %+v", last)
+	}
+
+	report := NewReport("test", "test-model", "", results)
+	if report.Passed != 1 || report.Failed != 1 || strings.Join(report.Prompts, ",") != "copilot@v7,planner@v4" {
+		t.Fatalf("unexpected totals %+v", report)
+	}
+	var md, again bytes.Buffer
+	if err := report.WriteMarkdown(&md); err != nil {
+		t.Fatalf("markdown: %v", err)
+	}
+	for _, want := range []string{"| unit | kyoto-temples | copilot | copilot@v7 | FAIL | 1/4 |", "- mentions Kinkaku-ji", `- no booking claim: claims "I've booked"`} {
+		if !strings.Contains(md.String(), want) {
+			t.Fatalf("expected %q in the report:\n%s", want, md.String())
+		}
+	}
+	_ = NewReport("test", "test-model", "", results).WriteMarkdown(&again)
+	if md.String() != again.String() {
+		t.Fatalf("expected reports of the same results to be identical")
+	}
+}
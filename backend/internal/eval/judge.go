@@ -0,0 +1,58 @@
+package eval
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"triploom/backend/internal/ai"
+	"triploom/backend/internal/providers/openai"
+)
+
+// Judge grades an answer against the case's rubric.
+type Judge interface {
+	Grade(ctx context.Context, c Case, answer string) (pass bool, reason string, err error)
+}
+
+const judgeInstructions = `You grade answers from a read-only travel assistant.
+You are given a conversation, the assistant's answer and a rubric.
+Reply with PASS or FAIL on the first line, then one sentence explaining why.
+Judge only against the rubric; do not reward length.`
+
+// LLMJudge asks a model to grade answers.
+type LLMJudge struct {
+	LLM   ai.LLM
+	Model string
+}
+
+func (j *LLMJudge) Grade(ctx context.Context, c Case, answer string) (bool, string, error) {
+	var b strings.Builder
+	b.WriteString("Conversation:\n")
+	for _, m := range c.Messages {
+		fmt.Fprintf(&b, "%s: %s\n", m.Role, m.Content)
+	}
+	fmt.Fprintf(&b, "\nAnswer:\n%s\n\nRubric:\n%s\n", answer, c.Expect.Judge)
+
+	result, err := j.LLM.ResponsesChat(ctx, j.Model, judgeInstructions, []openai.Message{{Role: "user", Content: b.String()}})
+	if err != nil {
+		return false, "", err
+	}
+	first, reason, _ := strings.Cut(strings.TrimSpace(result.Text), "\n")
+	reason = strings.TrimSpace(reason)
+	switch strings.ToUpper(strings.Trim(strings.TrimSpace(first), "*.:")) {
+	case "PASS":
+		return true, reason, nil
+	case "FAIL":
+		return false, reason, nil
+	default:
+		return false, "", fmt.Errorf("unexpected verdict %q", first)
+	}
+}
+
+// OfflineLLM answers every prompt with nothing, so the service falls back to its local
+// answers. It exercises the rule checks and planner drafts without a provider key.
+type OfflineLLM struct{}
+
+func (OfflineLLM) ResponsesChat(context.Context, string, string, []openai.Message) (*openai.ChatResult, error) {
+	return &openai.ChatResult{}, nil
+}
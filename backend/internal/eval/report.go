@@ -0,0 +1,117 @@
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Report is a run's results. It carries no timestamps or token counts, so that reports of two
+// runs diff only where the answers or verdicts changed.
+type Report struct {
+	Provider string   `json:"provider"`
+	Model    string   `json:"model"`
+	Judge    string   `json:"judge,omitempty"`
+	Prompts  []string `json:"prompts"`
+	Total    int      `json:"total"`
+	Passed   int      `json:"passed"`
+	Failed   int      `json:"failed"`
+	Results  []Result `json:"results"`
+}
+
+// NewReport totals results and lists the prompt versions they used.
+func NewReport(provider, model, judge string, results []Result) *Report {
+	rep := &Report{Provider: provider, Model: model, Judge: judge, Prompts: make([]string, 0), Total: len(results), Results: results}
+	seen := make(map[string]bool)
+	for _, res := range results {
+		if res.PromptVersion != "" && !seen[res.PromptVersion] {
+			seen[res.PromptVersion] = true
+			rep.Prompts = append(rep.Prompts, res.PromptVersion)
+		}
+		if res.Passed {
+			rep.Passed++
+		} else {
+			rep.Failed++
+		}
+	}
+	sort.Strings(rep.Prompts)
+	return rep
+}
+
+func (rep *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(rep)
+}
+
+// WriteMarkdown writes a summary table followed by the answers and failed checks of every
+// failing case.
+func (rep *Report) WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Eval report\n\n")
+	fmt.Fprintf(&b, "- Provider: %s\n- Model: %s\n", rep.Provider, rep.Model)
+	if rep.Judge != "" {
+		fmt.Fprintf(&b, "- Judge: %s\n", rep.Judge)
+	}
+	fmt.Fprintf(&b, "- Prompts: %s\n", strings.Join(rep.Prompts, ", "))
+	fmt.Fprintf(&b, "- Passed: %d/%d\n\n", rep.Passed, rep.Total)
+
+	b.WriteString("| Suite | Case | Kind | Prompt | Result | Checks |\n")
+	b.WriteString("| --- | --- | --- | --- | --- | --- |\n")
+	for _, res := range rep.Results {
+		passed, run := 0, 0
+		for _, check := range res.Checks {
+			if check.Status != StatusSkip {
+				run++
+			}
+			if check.Status == StatusPass {
+				passed++
+			}
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %d/%d |\n", cell(res.Suite), cell(res.Case), res.Kind, res.PromptVersion, verdict(res), passed, run)
+	}
+
+	if rep.Failed > 0 {
+		b.WriteString("\n## Failures\n")
+		for _, res := range rep.Results {
+			if res.Passed {
+				continue
+			}
+			fmt.Fprintf(&b, "\n### %s / %s\n\n", res.Suite, res.Case)
+			if res.Error != "" {
+				fmt.Fprintf(&b, "Error: %s\n", res.Error)
+				continue
+			}
+			for _, check := range res.Checks {
+				if check.Status != StatusFail {
+					continue
+				}
+				fmt.Fprintf(&b, "- %s", check.Name)
+				if check.Detail != "" {
+					fmt.Fprintf(&b, ": %s", check.Detail)
+				}
+				b.WriteString("\n")
+			}
+			fmt.Fprintf(&b, "\n```text\n%s\n```\n", strings.TrimSpace(res.Answer))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func verdict(res Result) string {
+	switch {
+	case res.Error != "":
+		return "error"
+	case res.Passed:
+		return "pass"
+	default:
+		return "FAIL"
+	}
+}
+
+func cell(v string) string {
+	return strings.ReplaceAll(v, "|", `\|`)
+}
@@ -0,0 +1,169 @@
+// Package eval runs suites of recorded conversations through the assistant and scores the
+// answers, so that prompt and model changes can be compared before they ship.
+package eval
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"triploom/backend/internal/ai"
+)
+
+// Case kinds; each runs through the matching ai.Service method and prompt of the same name.
+const (
+	KindCopilot = "copilot"
+	KindPlanner = "planner"
+)
+
+// Suite is one YAML file of cases.
+type Suite struct {
+	Name  string `yaml:"name"`
+	Cases []Case `yaml:"cases"`
+}
+
+// Case is one conversation and what its answer must satisfy.
+type Case struct {
+	ID string `yaml:"id"`
+	// Kind is copilot (the default) or planner.
+	Kind    string `yaml:"kind"`
+	PageKey string `yaml:"pageKey"`
+	// Trip is the trip a copilot case chats about.
+	Trip           TripContext      `yaml:"trip"`
+	PageContext    map[string]any   `yaml:"pageContext"`
+	PlannerContext map[string]any   `yaml:"plannerContext"`
+	Messages       []ai.ChatMessage `yaml:"messages"`
+	Expect         Expect           `yaml:"expect"`
+}
+
+type TripContext struct {
+	Destination string `yaml:"destination"`
+	StartDate   string `yaml:"startDate"`
+	EndDate     string `yaml:"endDate"`
+	Timezone    string `yaml:"timezone"`
+}
+
+// Expect lists the checks for a case. Unset fields are not checked.
+type Expect struct {
+	// MustMention and MustNotMention are matched case-insensitively against the answer.
+	MustMention    []string `yaml:"mustMention"`
+	MustNotMention []string `yaml:"mustNotMention"`
+	// MustNotClaimBooking fails answers that say a booking or reservation was made; the
+	// assistant is read-only.
+	MustNotClaimBooking bool `yaml:"mustNotClaimBooking"`
+	MaxWords            int  `yaml:"maxWords"`
+	// Draft maps planner draft fields (as in the API's plannerDraft JSON) to their expected
+	// values.
+	Draft map[string]any `yaml:"draft"`
+	// Judge is a rubric for the LLM judge, skipped when no judge is configured.
+	Judge string `yaml:"judge"`
+}
+
+// LoadSuite reads and validates a suite file. A suite without a name is named after the file.
+func LoadSuite(path string) (*Suite, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	suite, err := ParseSuite(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if suite.Name == "" {
+		suite.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	return suite, nil
+}
+
+// ParseSuite decodes a suite, rejecting unknown fields so that a misspelt expectation does not
+// silently pass.
+func ParseSuite(data []byte) (*Suite, error) {
+	var suite Suite
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&suite); err != nil {
+		return nil, fmt.Errorf("parse suite: %w", err)
+	}
+	seen := make(map[string]bool)
+	for i := range suite.Cases {
+		c := &suite.Cases[i]
+		if c.Kind == "" {
+			c.Kind = KindCopilot
+		}
+		c.PageContext, _ = yamlDates(c.PageContext).(map[string]any)
+		c.PlannerContext, _ = yamlDates(c.PlannerContext).(map[string]any)
+		c.Expect.Draft, _ = yamlDates(c.Expect.Draft).(map[string]any)
+		if err := c.validate(); err != nil {
+			return nil, fmt.Errorf("case %d (%s): %w", i+1, c.ID, err)
+		}
+		if seen[c.ID] {
+			return nil, fmt.Errorf("case %s is defined twice", c.ID)
+		}
+		seen[c.ID] = true
+	}
+	if len(suite.Cases) == 0 {
+		return nil, fmt.Errorf("suite has no cases")
+	}
+	return &suite, nil
+}
+
+func (c *Case) validate() error {
+	if c.ID == "" {
+		return fmt.Errorf("id is required")
+	}
+	switch c.Kind {
+	case KindCopilot:
+		if c.PageKey == "" {
+			return fmt.Errorf("pageKey is required for copilot cases")
+		}
+		if len(c.Expect.Draft) > 0 {
+			return fmt.Errorf("draft expectations only apply to planner cases")
+		}
+	case KindPlanner:
+	default:
+		return fmt.Errorf("kind must be copilot or planner")
+	}
+	if len(c.Messages) == 0 || c.Messages[len(c.Messages)-1].Role != "user" {
+		return fmt.Errorf("messages must end with a user message")
+	}
+	for _, d := range []string{c.Trip.StartDate, c.Trip.EndDate} {
+		if _, err := parseDate(d); err != nil {
+			return fmt.Errorf("trip dates must be YYYY-MM-DD: %w", err)
+		}
+	}
+	return nil
+}
+
+func parseDate(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse("2006-01-02", v)
+}
+
+// yamlDates turns the timestamps that YAML decodes unquoted dates into back into YYYY-MM-DD,
+// the form the service and planner drafts use.
+func yamlDates(v any) any {
+	switch v := v.(type) {
+	case time.Time:
+		return v.Format("2006-01-02")
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = yamlDates(item)
+		}
+		return out
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, item := range v {
+			out[k] = yamlDates(item)
+		}
+		return out
+	}
+	return v
+}
@@ -0,0 +1,64 @@
+package events
+
+import (
+	"context"
+	"sync"
+)
+
+// Handler receives a published payload. Handlers run on the publishing (or, for Postgres, the
+// listening) goroutine, so they must not block.
+type Handler func(topic string, payload []byte)
+
+// Bus is a topic-based publish/subscribe channel between the goroutines of one process or,
+// with Postgres, between every API replica. Payloads are JSON documents.
+type Bus interface {
+	Publish(ctx context.Context, topic string, payload []byte) error
+	Subscribe(topic string, h Handler) (unsubscribe func())
+}
+
+// Memory is an in-process Bus: Publish calls every subscriber of the topic before returning.
+type Memory struct {
+	mu   sync.RWMutex
+	next int
+	subs map[string]map[int]Handler
+}
+
+func NewMemory() *Memory {
+	return &Memory{subs: make(map[string]map[int]Handler)}
+}
+
+func (m *Memory) Publish(_ context.Context, topic string, payload []byte) error {
+	m.mu.RLock()
+	handlers := make([]Handler, 0, len(m.subs[topic]))
+	for _, h := range m.subs[topic] {
+		handlers = append(handlers, h)
+	}
+	m.mu.RUnlock()
+	for _, h := range handlers {
+		h(topic, payload)
+	}
+	return nil
+}
+
+func (m *Memory) Subscribe(topic string, h Handler) func() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.subs[topic] == nil {
+		m.subs[topic] = make(map[int]Handler)
+	}
+	id := m.next
+	m.next++
+	m.subs[topic][id] = h
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			delete(m.subs[topic], id)
+			if len(m.subs[topic]) == 0 {
+				delete(m.subs, topic)
+			}
+		})
+	}
+}
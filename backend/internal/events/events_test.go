@@ -0,0 +1,30 @@
+package events
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMemoryDeliversToTopicSubscribers(t *testing.T) {
+	bus := NewMemory()
+	ctx := context.Background()
+
+	var a, b []string
+	stopA := bus.Subscribe("trip_events", func(_ string, p []byte) { a = append(a, string(p)) })
+	bus.Subscribe("trip_events", func(_ string, p []byte) { b = append(b, string(p)) })
+	bus.Subscribe("other", func(string, []byte) { t.Fatalf("unexpected delivery on other topic") })
+
+	if err := bus.Publish(ctx, "trip_events", []byte(`{"n":1}`)); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	stopA()
+	stopA()
+	_ = bus.Publish(ctx, "trip_events", []byte(`{"n":2}`))
+
+	if len(a) != 1 || a[0] != `{"n":1}` {
+		t.Fatalf("expected first subscriber to stop after unsubscribe, got %v", a)
+	}
+	if len(b) != 2 {
+		t.Fatalf("expected second subscriber to see both messages, got %v", b)
+	}
+}
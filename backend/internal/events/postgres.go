@@ -0,0 +1,147 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DefaultChannel is the Postgres NOTIFY channel every replica listens on.
+const DefaultChannel = "triploom_events"
+
+// maxInlineBytes keeps NOTIFY payloads under Postgres' 8000 byte limit. Larger messages are
+// parked in event_bus_messages and only their id is sent.
+const maxInlineBytes = 7000
+
+// retention is how long parked messages are kept for slow or reconnecting listeners.
+const retention = time.Hour
+
+// notification is the NOTIFY payload: either the message itself or a reference to it.
+type notification struct {
+	Topic   string          `json:"topic"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+	Ref     string          `json:"ref,omitempty"`
+}
+
+// Postgres is a Bus over LISTEN/NOTIFY on the shared pool. Every replica, including the
+// publisher, receives each message once through its listener; Run must be running for
+// subscribers to see anything. Messages sent while a listener is reconnecting are lost.
+type Postgres struct {
+	db      *pgxpool.Pool
+	channel string
+	local   *Memory
+}
+
+func NewPostgres(db *pgxpool.Pool, channel string) *Postgres {
+	if channel == "" {
+		channel = DefaultChannel
+	}
+	return &Postgres{db: db, channel: channel, local: NewMemory()}
+}
+
+func (p *Postgres) Publish(ctx context.Context, topic string, payload []byte) error {
+	if !json.Valid(payload) {
+		return errors.New("event payload must be JSON")
+	}
+	msg, err := json.Marshal(notification{Topic: topic, Payload: payload})
+	if err != nil {
+		return err
+	}
+	if len(msg) > maxInlineBytes {
+		ref := uuid.NewString()
+		const q = `INSERT INTO event_bus_messages (id, topic, payload_json, created_at) VALUES ($1, $2, $3::jsonb, NOW())`
+		if _, err := p.db.Exec(ctx, q, ref, topic, string(payload)); err != nil {
+			return err
+		}
+		msg, _ = json.Marshal(notification{Topic: topic, Ref: ref})
+	}
+	_, err = p.db.Exec(ctx, `SELECT pg_notify($1, $2)`, p.channel, string(msg))
+	return err
+}
+
+func (p *Postgres) Subscribe(topic string, h Handler) func() {
+	return p.local.Subscribe(topic, h)
+}
+
+// Run listens until ctx is cancelled, reconnecting with backoff when the connection drops,
+// and prunes parked messages past their retention.
+func (p *Postgres) Run(ctx context.Context) {
+	go p.prune(ctx)
+	backoff := time.Second
+	for {
+		err := p.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("event bus: %v (reconnecting in %s)", err, backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		if backoff < 30*time.Second {
+			backoff *= 2
+		}
+	}
+}
+
+func (p *Postgres) listen(ctx context.Context) error {
+	conn, err := p.db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// The connection holds LISTEN state, so it is taken out of the pool rather than returned.
+	pgConn := conn.Hijack()
+	defer pgConn.Close(context.Background())
+
+	if _, err := pgConn.Exec(ctx, "LISTEN "+pgx.Identifier{p.channel}.Sanitize()); err != nil {
+		return err
+	}
+	for {
+		n, err := pgConn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		if err := p.dispatch(ctx, n.Payload); err != nil {
+			log.Printf("event bus: %v", err)
+		}
+	}
+}
+
+func (p *Postgres) dispatch(ctx context.Context, raw string) error {
+	var n notification
+	if err := json.Unmarshal([]byte(raw), &n); err != nil {
+		return fmt.Errorf("decode notification: %w", err)
+	}
+	payload := []byte(n.Payload)
+	if n.Ref != "" {
+		const q = `SELECT payload_json FROM event_bus_messages WHERE id = $1`
+		if err := p.db.QueryRow(ctx, q, n.Ref).Scan(&payload); err != nil {
+			return fmt.Errorf("load message %s: %w", n.Ref, err)
+		}
+	}
+	return p.local.Publish(ctx, n.Topic, payload)
+}
+
+func (p *Postgres) prune(ctx context.Context) {
+	ticker := time.NewTicker(10 * time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			const q = `DELETE FROM event_bus_messages WHERE created_at < NOW() - $1 * INTERVAL '1 second'`
+			if _, err := p.db.Exec(ctx, q, int(retention.Seconds())); err != nil {
+				log.Printf("event bus prune: %v", err)
+			}
+		}
+	}
+}
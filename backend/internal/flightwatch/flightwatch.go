@@ -0,0 +1,138 @@
+package flightwatch
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"triploom/backend/internal/providers/flightstatus"
+	"triploom/backend/internal/store"
+	"triploom/backend/internal/tripevents"
+)
+
+var (
+	ErrUnauthorizedTrip = errors.New("unauthorized trip access")
+	ErrInvalidInput     = errors.New("invalid input")
+)
+
+// EventFlightStatusChanged is the trip event type written when a watched flight changes.
+const EventFlightStatusChanged = tripevents.TypeFlightStatusChanged
+
+var (
+	flightNumberRe = regexp.MustCompile(`^[A-Z0-9]{2,3}\d{1,4}[A-Z]?$`)
+	dateRe         = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+)
+
+// TripMembership reports whether a user may act on a trip.
+type TripMembership interface {
+	IsTripMember(ctx context.Context, tripID, userID string) (bool, error)
+}
+
+type CreateWatchRequest struct {
+	FlightNumber  string `json:"flightNumber"`
+	DepartureDate string `json:"departureDate"`
+}
+
+type Service struct {
+	members TripMembership
+	watches store.FlightWatchStore
+	events  *tripevents.Recorder
+}
+
+func NewService(members TripMembership, watches store.FlightWatchStore, events *tripevents.Recorder) *Service {
+	return &Service{members: members, watches: watches, events: events}
+}
+
+func (s *Service) CreateWatch(ctx context.Context, userID, tripID string, req CreateWatchRequest) (*store.FlightWatch, error) {
+	flight := flightstatus.NormalizeFlightNumber(req.FlightNumber)
+	date := strings.TrimSpace(req.DepartureDate)
+	if tripID == "" || !flightNumberRe.MatchString(flight) || !dateRe.MatchString(date) {
+		return nil, ErrInvalidInput
+	}
+	if err := s.authorize(ctx, tripID, userID); err != nil {
+		return nil, err
+	}
+	return s.watches.CreateFlightWatch(ctx, tripID, userID, flight, date)
+}
+
+func (s *Service) ListWatches(ctx context.Context, userID, tripID string) ([]store.FlightWatch, error) {
+	if err := s.authorize(ctx, tripID, userID); err != nil {
+		return nil, err
+	}
+	return s.watches.ListFlightWatches(ctx, tripID)
+}
+
+func (s *Service) ListEvents(ctx context.Context, userID, tripID string, limit int) ([]store.TripEvent, error) {
+	if err := s.authorize(ctx, tripID, userID); err != nil {
+		return nil, err
+	}
+	return s.events.List(ctx, tripID, limit)
+}
+
+func (s *Service) authorize(ctx context.Context, tripID, userID string) error {
+	ok, err := s.members.IsTripMember(ctx, tripID, userID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrUnauthorizedTrip
+	}
+	return nil
+}
+
+// Change is one difference between two polls of the same flight.
+type Change struct {
+	Kind string `json:"kind"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+const (
+	ChangeDelay     = "delay"
+	ChangeGate      = "gate"
+	ChangeCancelled = "cancelled"
+)
+
+// minDelayShift keeps small estimate jitter from producing a delay event on every poll.
+const minDelayShift = 5
+
+// DetectChanges compares two statuses of the same flight. A nil prev (first poll) only
+// reports conditions already worth telling the traveller: a cancellation or a delay.
+func DetectChanges(prev, next *flightstatus.FlightStatus) []Change {
+	changes := make([]Change, 0)
+	if next == nil {
+		return changes
+	}
+	firstPoll := prev == nil
+	if firstPoll {
+		prev = &flightstatus.FlightStatus{}
+	}
+
+	if next.Cancelled && !prev.Cancelled {
+		changes = append(changes, Change{Kind: ChangeCancelled, From: orUnknown(prev.Status), To: flightstatus.StatusCancelled})
+	}
+	if shift := next.DelayMinutes - prev.DelayMinutes; shift >= minDelayShift || (shift <= -minDelayShift && prev.DelayMinutes > 0) {
+		changes = append(changes, Change{Kind: ChangeDelay, From: minutes(prev.DelayMinutes), To: minutes(next.DelayMinutes)})
+	}
+	if !firstPoll && gateChanged(prev.Departure.Gate, next.Departure.Gate) {
+		changes = append(changes, Change{Kind: ChangeGate, From: prev.Departure.Gate, To: next.Departure.Gate})
+	}
+	return changes
+}
+
+func gateChanged(prev, next string) bool {
+	return strings.TrimSpace(next) != "" && !strings.EqualFold(strings.TrimSpace(prev), strings.TrimSpace(next))
+}
+
+func minutes(n int) string {
+	return strconv.Itoa(n) + "m"
+}
+
+func orUnknown(status string) string {
+	if status == "" {
+		return flightstatus.StatusUnknown
+	}
+	return status
+}
@@ -0,0 +1,127 @@
+package flightwatch
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"triploom/backend/internal/providers/flightstatus"
+	"triploom/backend/internal/providers/flightstatus/flightstatustest"
+	"triploom/backend/internal/store"
+	"triploom/backend/internal/tripevents"
+)
+
+func TestDetectChanges(t *testing.T) {
+	base := flightstatus.FlightStatus{Status: flightstatus.StatusScheduled, Departure: flightstatus.Endpoint{Gate: "D41"}}
+	delayed := base
+	delayed.DelayMinutes = 40
+	jitter := base
+	jitter.DelayMinutes = 3
+	regated := base
+	regated.Departure.Gate = "D45"
+	cancelled := base
+	cancelled.Cancelled = true
+	cancelled.Status = flightstatus.StatusCancelled
+
+	cases := []struct {
+		name string
+		prev *flightstatus.FlightStatus
+		next flightstatus.FlightStatus
+		want []string
+	}{
+		{"first poll on time", nil, base, nil},
+		{"first poll already delayed", nil, delayed, []string{ChangeDelay}},
+		{"no change", &base, base, nil},
+		{"estimate jitter", &base, jitter, nil},
+		{"delay", &base, delayed, []string{ChangeDelay}},
+		{"delay recovered", &delayed, base, []string{ChangeDelay}},
+		{"gate", &base, regated, []string{ChangeGate}},
+		{"cancelled", &base, cancelled, []string{ChangeCancelled}},
+		{"still cancelled", &cancelled, cancelled, nil},
+	}
+	for _, tc := range cases {
+		next := tc.next
+		got := DetectChanges(tc.prev, &next)
+		if len(got) != len(tc.want) {
+			t.Fatalf("%s: expected %v, got %+v", tc.name, tc.want, got)
+		}
+		for i, c := range got {
+			if c.Kind != tc.want[i] {
+				t.Fatalf("%s: expected %v, got %+v", tc.name, tc.want, got)
+			}
+		}
+	}
+}
+
+func TestNextPollIntervalTightensNearDeparture(t *testing.T) {
+	now := time.Date(2026, 11, 1, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *flightstatus.FlightStatus {
+		dep := now.Add(d)
+		return &flightstatus.FlightStatus{Status: flightstatus.StatusScheduled, Departure: flightstatus.Endpoint{Scheduled: &dep}}
+	}
+
+	prev := 365 * 24 * time.Hour
+	for _, until := range []time.Duration{96 * time.Hour, 30 * time.Hour, 8 * time.Hour, 3 * time.Hour, time.Hour} {
+		interval, active := NextPollInterval(at(until), now)
+		if !active || interval >= prev {
+			t.Fatalf("expected tighter active interval %s before departure, got %s active=%v", until, interval, active)
+		}
+		prev = interval
+	}
+
+	landed := at(-5 * time.Hour)
+	landed.Status = flightstatus.StatusLanded
+	if _, active := NextPollInterval(landed, now); active {
+		t.Fatalf("expected landed flights to stop polling")
+	}
+}
+
+type allowAll struct{}
+
+func (allowAll) IsTripMember(context.Context, string, string) (bool, error) { return true, nil }
+
+func TestPollerRecordsChangeEvents(t *testing.T) {
+	ctx := context.Background()
+	provider := flightstatustest.NewProvider()
+	watches := store.NewInMemoryFlightWatchRepository()
+	events := tripevents.NewRecorder(store.NewInMemoryTripEventRepository(nil))
+	svc := NewService(allowAll{}, watches, events)
+
+	if _, err := svc.CreateWatch(ctx, "user-1", "trip-1", CreateWatchRequest{FlightNumber: "ac 856", DepartureDate: "2026-11-02"}); err != nil {
+		t.Fatalf("create watch: %v", err)
+	}
+	if _, err := svc.CreateWatch(ctx, "user-1", "trip-1", CreateWatchRequest{FlightNumber: "tomorrow", DepartureDate: "2026-11-02"}); err != ErrInvalidInput {
+		t.Fatalf("expected invalid input, got %v", err)
+	}
+
+	now := time.Date(2026, 11, 1, 12, 0, 0, 0, time.UTC)
+	dep := time.Date(2026, 11, 2, 0, 30, 0, 0, time.UTC)
+	p := NewPoller(provider, watches, events)
+	p.now = func() time.Time { return now }
+
+	provider.Set("AC856", "2026-11-02", flightstatus.FlightStatus{FlightNumber: "AC856", Status: flightstatus.StatusScheduled, Departure: flightstatus.Endpoint{Gate: "D41", Scheduled: &dep}})
+	if n, err := p.PollOnce(ctx); err != nil || n != 1 {
+		t.Fatalf("first poll: n=%d err=%v", n, err)
+	}
+	if n, _ := p.PollOnce(ctx); n != 0 {
+		t.Fatalf("expected watch to be rescheduled, polled %d", n)
+	}
+
+	now = now.Add(time.Hour)
+	provider.Set("AC856", "2026-11-02", flightstatus.FlightStatus{FlightNumber: "AC856", Status: flightstatus.StatusScheduled, DelayMinutes: 45, Departure: flightstatus.Endpoint{Gate: "D45", Scheduled: &dep}})
+	if _, err := p.PollOnce(ctx); err != nil {
+		t.Fatalf("second poll: %v", err)
+	}
+
+	got, err := svc.ListEvents(ctx, "user-1", "trip-1", 10)
+	if err != nil {
+		t.Fatalf("list events: %v", err)
+	}
+	if len(got) != 1 || got[0].Type != EventFlightStatusChanged {
+		t.Fatalf("expected one change event, got %+v", got)
+	}
+	changes, _ := got[0].PayloadJSON["changes"].([]any)
+	if len(changes) != 2 {
+		t.Fatalf("expected delay and gate changes, got %v", got[0].PayloadJSON["changes"])
+	}
+}
@@ -0,0 +1,175 @@
+package flightwatch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"triploom/backend/internal/providers/flightstatus"
+	"triploom/backend/internal/store"
+	"triploom/backend/internal/tripevents"
+)
+
+// Poller periodically refreshes due flight watches and records a trip event whenever a
+// watched flight is delayed, changes gate or is cancelled.
+type Poller struct {
+	provider flightstatus.Provider
+	watches  store.FlightWatchStore
+	events   *tripevents.Recorder
+	tick     time.Duration
+	batch    int
+	now      func() time.Time
+}
+
+func NewPoller(provider flightstatus.Provider, watches store.FlightWatchStore, events *tripevents.Recorder) *Poller {
+	return &Poller{provider: provider, watches: watches, events: events, tick: 30 * time.Second, batch: 50, now: time.Now}
+}
+
+// Run polls until ctx is cancelled.
+func (p *Poller) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.tick)
+	defer ticker.Stop()
+	for {
+		if _, err := p.PollOnce(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("flight watch poll: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PollOnce refreshes every watch that is due and returns how many were polled.
+func (p *Poller) PollOnce(ctx context.Context) (int, error) {
+	now := p.now().UTC()
+	due, err := p.watches.ListDueFlightWatches(ctx, now, p.batch)
+	if err != nil {
+		return 0, err
+	}
+	for _, w := range due {
+		if err := p.poll(ctx, w, now); err != nil {
+			log.Printf("flight watch %s (%s %s): %v", w.ID, w.FlightNumber, w.DepartureDate, err)
+		}
+	}
+	return len(due), nil
+}
+
+func (p *Poller) poll(ctx context.Context, w store.FlightWatch, now time.Time) error {
+	next, err := p.provider.Lookup(ctx, w.FlightNumber, w.DepartureDate)
+	if err != nil {
+		interval, active := retryInterval(w.DepartureDate, now, errors.Is(err, flightstatus.ErrNotFound))
+		if recErr := p.watches.RecordFlightWatchPoll(ctx, w.ID, nil, now.Add(interval), active); recErr != nil {
+			return recErr
+		}
+		if errors.Is(err, flightstatus.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	prev := statusFromJSON(w.LastStatusJSON)
+	if changes := DetectChanges(prev, next); len(changes) > 0 {
+		payload := map[string]any{
+			"watchId":       w.ID,
+			"flightNumber":  w.FlightNumber,
+			"departureDate": w.DepartureDate,
+			"changes":       changes,
+			"status":        next,
+		}
+		if _, err := p.events.Record(ctx, w.TripID, EventFlightStatusChanged, toJSONMap(payload)); err != nil {
+			return err
+		}
+	}
+
+	interval, active := NextPollInterval(next, now)
+	return p.watches.RecordFlightWatchPoll(ctx, w.ID, toJSONMap(next), now.Add(interval), active)
+}
+
+// NextPollInterval polls more often as departure approaches and stops once the flight has
+// landed, been cancelled or diverted, or is well past its arrival time.
+func NextPollInterval(status *flightstatus.FlightStatus, now time.Time) (time.Duration, bool) {
+	switch status.Status {
+	case flightstatus.StatusLanded, flightstatus.StatusCancelled, flightstatus.StatusDiverted:
+		return 0, false
+	}
+	if status.Cancelled {
+		return 0, false
+	}
+
+	departure := bestTime(status.Departure)
+	arrival := bestTime(status.Arrival)
+	if departure == nil {
+		return time.Hour, true
+	}
+	if arrival != nil && now.After(arrival.Add(2*time.Hour)) {
+		return 0, false
+	}
+
+	until := departure.Sub(now)
+	switch {
+	case until > 72*time.Hour:
+		return 12 * time.Hour, true
+	case until > 24*time.Hour:
+		return 3 * time.Hour, true
+	case until > 6*time.Hour:
+		return 30 * time.Minute, true
+	case until > 2*time.Hour:
+		return 10 * time.Minute, true
+	case until > -30*time.Minute:
+		return 5 * time.Minute, true
+	default:
+		return 15 * time.Minute, true
+	}
+}
+
+// retryInterval schedules the next attempt after a failed lookup from the departure date alone.
+func retryInterval(departureDate string, now time.Time, notFound bool) (time.Duration, bool) {
+	day, err := time.Parse("2006-01-02", departureDate)
+	if err != nil {
+		return 0, false
+	}
+	if now.After(day.Add(48 * time.Hour)) {
+		return 0, false
+	}
+	if notFound && day.Sub(now) > 72*time.Hour {
+		return 12 * time.Hour, true
+	}
+	return 15 * time.Minute, true
+}
+
+func bestTime(e flightstatus.Endpoint) *time.Time {
+	switch {
+	case e.Actual != nil:
+		return e.Actual
+	case e.Estimated != nil:
+		return e.Estimated
+	default:
+		return e.Scheduled
+	}
+}
+
+func statusFromJSON(m map[string]any) *flightstatus.FlightStatus {
+	if len(m) == 0 {
+		return nil
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return nil
+	}
+	var status flightstatus.FlightStatus
+	if err := json.Unmarshal(b, &status); err != nil {
+		return nil
+	}
+	return &status
+}
+
+func toJSONMap(v any) map[string]any {
+	b, _ := json.Marshal(v)
+	out := map[string]any{}
+	_ = json.Unmarshal(b, &out)
+	return out
+}
@@ -1,11 +1,14 @@
 package handlers
 
 import (
+	"errors"
 	"strconv"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 
 	"triploom/backend/internal/ai"
+	"triploom/backend/internal/store"
 )
 
 type AIHandler struct {
@@ -25,14 +28,7 @@ func (h *AIHandler) Chat(c *fiber.Ctx) error {
 
 	resp, err := h.service.Chat(c.UserContext(), userID, req)
 	if err != nil {
-		status := fiber.StatusInternalServerError
-		if err == ai.ErrUnauthorizedTrip {
-			status = fiber.StatusForbidden
-		}
-		if err == ai.ErrInvalidInput {
-			status = fiber.StatusBadRequest
-		}
-		return c.Status(status).JSON(fiber.Map{"ok": false, "error": err.Error()})
+		return aiError(c, err)
 	}
 	return c.JSON(fiber.Map{"ok": true, "data": resp})
 }
@@ -46,11 +42,7 @@ func (h *AIHandler) PlannerChat(c *fiber.Ctx) error {
 
 	resp, err := h.service.PlannerChat(c.UserContext(), userID, req)
 	if err != nil {
-		status := fiber.StatusInternalServerError
-		if err == ai.ErrInvalidInput {
-			status = fiber.StatusBadRequest
-		}
-		return c.Status(status).JSON(fiber.Map{"ok": false, "error": err.Error()})
+		return aiError(c, err)
 	}
 	return c.JSON(fiber.Map{"ok": true, "data": resp})
 }
@@ -58,31 +50,109 @@ func (h *AIHandler) PlannerChat(c *fiber.Ctx) error {
 func (h *AIHandler) ListConversations(c *fiber.Ctx) error {
 	userID, _ := c.Locals("userID").(string)
 	tripID := c.Params("tripId")
-	resp, err := h.service.ListConversations(c.UserContext(), userID, tripID)
+	resp, next, err := h.service.ListConversations(c.UserContext(), userID, tripID, c.QueryBool("archived"), pageRequest(c))
 	if err != nil {
-		status := fiber.StatusInternalServerError
-		if err == ai.ErrUnauthorizedTrip {
-			status = fiber.StatusForbidden
-		}
-		return c.Status(status).JSON(fiber.Map{"ok": false, "error": err.Error()})
+		return aiError(c, err)
+	}
+	return c.JSON(fiber.Map{"ok": true, "data": resp, "nextCursor": cursorOrNil(next)})
+}
+
+func (h *AIHandler) CreateConversation(c *fiber.Ctx) error {
+	userID, _ := c.Locals("userID").(string)
+	var req ai.CreateConversationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"ok": false, "error": "invalid request body"})
+	}
+	resp, err := h.service.CreateConversation(c.UserContext(), userID, req)
+	if err != nil {
+		return aiError(c, err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"ok": true, "data": resp})
+}
+
+func (h *AIHandler) UpdateConversation(c *fiber.Ctx) error {
+	userID, _ := c.Locals("userID").(string)
+	var req ai.UpdateConversationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"ok": false, "error": "invalid request body"})
+	}
+	resp, err := h.service.UpdateConversation(c.UserContext(), userID, c.Params("conversationId"), req)
+	if err != nil {
+		return aiError(c, err)
 	}
 	return c.JSON(fiber.Map{"ok": true, "data": resp})
 }
 
+func (h *AIHandler) DeleteConversation(c *fiber.Ctx) error {
+	userID, _ := c.Locals("userID").(string)
+	if err := h.service.DeleteConversation(c.UserContext(), userID, c.Params("conversationId")); err != nil {
+		return aiError(c, err)
+	}
+	return c.JSON(fiber.Map{"ok": true})
+}
+
 func (h *AIHandler) ListMessages(c *fiber.Ctx) error {
 	userID, _ := c.Locals("userID").(string)
 	conversationID := c.Params("conversationId")
-	limit, _ := strconv.Atoi(c.Query("limit", "50"))
-	if limit <= 0 || limit > 200 {
-		limit = 50
+	resp, next, err := h.service.ListMessages(c.UserContext(), userID, conversationID, pageRequest(c))
+	if err != nil {
+		return aiError(c, err)
+	}
+	return c.JSON(fiber.Map{"ok": true, "data": resp, "nextCursor": cursorOrNil(next)})
+}
+
+func (h *AIHandler) SubmitFeedback(c *fiber.Ctx) error {
+	userID, _ := c.Locals("userID").(string)
+	var req ai.FeedbackRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"ok": false, "error": "invalid request body"})
+	}
+	resp, err := h.service.SubmitFeedback(c.UserContext(), userID, c.Params("messageId"), req)
+	if err != nil {
+		return aiError(c, err)
+	}
+	return c.JSON(fiber.Map{"ok": true, "data": resp})
+}
+
+func (h *AIHandler) Regenerate(c *fiber.Ctx) error {
+	userID, _ := c.Locals("userID").(string)
+	var req ai.RegenerateRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"ok": false, "error": "invalid request body"})
+		}
 	}
-	resp, err := h.service.ListMessages(c.UserContext(), userID, conversationID, limit)
+	resp, err := h.service.Regenerate(c.UserContext(), userID, c.Params("messageId"), req)
 	if err != nil {
-		status := fiber.StatusInternalServerError
-		if err == ai.ErrUnauthorizedTrip {
-			status = fiber.StatusForbidden
+		return aiError(c, err)
+	}
+	return c.JSON(fiber.Map{"ok": true, "data": resp})
+}
+
+// FeedbackReport takes ?tripId= and an optional ?since= (RFC 3339 or YYYY-MM-DD).
+func (h *AIHandler) FeedbackReport(c *fiber.Ctx) error {
+	userID, _ := c.Locals("userID").(string)
+	var since time.Time
+	if raw := c.Query("since"); raw != "" {
+		var err error
+		if since, err = time.Parse(time.RFC3339, raw); err != nil {
+			if since, err = time.Parse("2006-01-02", raw); err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"ok": false, "error": "since must be RFC 3339 or YYYY-MM-DD"})
+			}
 		}
-		return c.Status(status).JSON(fiber.Map{"ok": false, "error": err.Error()})
+	}
+	resp, err := h.service.FeedbackReport(c.UserContext(), userID, c.Query("tripId"), since)
+	if err != nil {
+		return aiError(c, err)
+	}
+	return c.JSON(fiber.Map{"ok": true, "data": resp})
+}
+
+func (h *AIHandler) Search(c *fiber.Ctx) error {
+	userID, _ := c.Locals("userID").(string)
+	resp, err := h.service.Search(c.UserContext(), userID, c.Query("tripId"), c.Query("q"), queryLimit(c, 20, 50))
+	if err != nil {
+		return aiError(c, err)
 	}
 	return c.JSON(fiber.Map{"ok": true, "data": resp})
 }
@@ -95,14 +165,112 @@ func (h *AIHandler) RefreshContext(c *fiber.Ctx) error {
 	}
 	resp, err := h.service.RefreshContext(c.UserContext(), userID, req)
 	if err != nil {
-		status := fiber.StatusInternalServerError
-		if err == ai.ErrUnauthorizedTrip {
-			status = fiber.StatusForbidden
-		}
-		if err == ai.ErrInvalidInput {
-			status = fiber.StatusBadRequest
-		}
-		return c.Status(status).JSON(fiber.Map{"ok": false, "error": err.Error()})
+		return aiError(c, err)
+	}
+	return c.JSON(fiber.Map{"ok": true, "data": resp})
+}
+
+// ListProposals takes ?tripId= and an optional ?status= (pending, approved or rejected).
+func (h *AIHandler) ListProposals(c *fiber.Ctx) error {
+	userID, _ := c.Locals("userID").(string)
+	resp, err := h.service.ListProposals(c.UserContext(), userID, c.Query("tripId"), c.Query("status"))
+	if err != nil {
+		return aiError(c, err)
+	}
+	return c.JSON(fiber.Map{"ok": true, "data": resp})
+}
+
+func (h *AIHandler) ApproveProposal(c *fiber.Ctx) error {
+	userID, _ := c.Locals("userID").(string)
+	resp, err := h.service.ApproveProposal(c.UserContext(), userID, c.Params("proposalId"))
+	if err != nil {
+		return aiError(c, err)
+	}
+	return c.JSON(fiber.Map{"ok": true, "data": resp})
+}
+
+func (h *AIHandler) RejectProposal(c *fiber.Ctx) error {
+	userID, _ := c.Locals("userID").(string)
+	resp, err := h.service.RejectProposal(c.UserContext(), userID, c.Params("proposalId"))
+	if err != nil {
+		return aiError(c, err)
+	}
+	return c.JSON(fiber.Map{"ok": true, "data": resp})
+}
+
+func (h *AIHandler) GetPreferences(c *fiber.Ctx) error {
+	userID, _ := c.Locals("userID").(string)
+	resp, err := h.service.GetPreferences(c.UserContext(), userID)
+	if err != nil {
+		return aiError(c, err)
+	}
+	return c.JSON(fiber.Map{"ok": true, "data": resp})
+}
+
+func (h *AIHandler) UpdatePreferences(c *fiber.Ctx) error {
+	userID, _ := c.Locals("userID").(string)
+	var req ai.UpdatePreferencesRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"ok": false, "error": "invalid request body"})
+	}
+	resp, err := h.service.UpdatePreferences(c.UserContext(), userID, req)
+	if err != nil {
+		return aiError(c, err)
+	}
+	return c.JSON(fiber.Map{"ok": true, "data": resp})
+}
+
+func (h *AIHandler) ApprovePreferenceUpdate(c *fiber.Ctx) error {
+	userID, _ := c.Locals("userID").(string)
+	resp, err := h.service.ApprovePreferenceUpdate(c.UserContext(), userID, c.Params("updateId"))
+	if err != nil {
+		return aiError(c, err)
+	}
+	return c.JSON(fiber.Map{"ok": true, "data": resp})
+}
+
+func (h *AIHandler) RejectPreferenceUpdate(c *fiber.Ctx) error {
+	userID, _ := c.Locals("userID").(string)
+	resp, err := h.service.RejectPreferenceUpdate(c.UserContext(), userID, c.Params("updateId"))
+	if err != nil {
+		return aiError(c, err)
 	}
 	return c.JSON(fiber.Map{"ok": true, "data": resp})
 }
+
+// pageRequest reads ?cursor=&limit=. limit defaults to 50 and is capped at 200.
+func pageRequest(c *fiber.Ctx) store.PageRequest {
+	return store.PageRequest{Cursor: c.Query("cursor"), Limit: queryLimit(c, 50, 200)}
+}
+
+// queryLimit reads ?limit=, using def when it is missing or not a positive integer and max
+// when it is larger.
+func queryLimit(c *fiber.Ctx, def, max int) int {
+	limit, err := strconv.Atoi(c.Query("limit"))
+	if err != nil || limit <= 0 {
+		return def
+	}
+	return min(limit, max)
+}
+
+// cursorOrNil renders the last page's cursor as null.
+func cursorOrNil(next string) any {
+	if next == "" {
+		return nil
+	}
+	return next
+}
+
+func aiError(c *fiber.Ctx, err error) error {
+	status := fiber.StatusInternalServerError
+	if err == ai.ErrUnauthorizedTrip {
+		status = fiber.StatusForbidden
+	}
+	if err == ai.ErrInvalidInput {
+		status = fiber.StatusBadRequest
+	}
+	if errors.Is(err, store.ErrProposalDecided) || errors.Is(err, store.ErrProposalStale) || errors.Is(err, store.ErrPreferenceUpdateDecided) {
+		status = fiber.StatusConflict
+	}
+	return c.Status(status).JSON(fiber.Map{"ok": false, "error": err.Error()})
+}
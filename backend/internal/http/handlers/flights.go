@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"triploom/backend/internal/providers/serpflights"
+)
+
+type FlightsHandler struct {
+	serp *serpflights.Client
+}
+
+func NewFlightsHandler(serp *serpflights.Client) *FlightsHandler {
+	return &FlightsHandler{serp: serp}
+}
+
+func (h *FlightsHandler) Search(c *fiber.Ctx) error {
+	if h.serp == nil {
+		return serpUnavailable(c)
+	}
+	var req serpflights.SearchRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"ok": false, "error": "invalid request body"})
+	}
+	offers, err := h.serp.Search(c.UserContext(), req)
+	if err != nil {
+		return serpError(c, err)
+	}
+	return c.JSON(fiber.Map{"ok": true, "data": fiber.Map{"offers": offers}})
+}
+
+func (h *FlightsHandler) ReturnFlights(c *fiber.Ctx) error {
+	if h.serp == nil {
+		return serpUnavailable(c)
+	}
+	var req serpflights.ReturnFlightsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"ok": false, "error": "invalid request body"})
+	}
+	offers, err := h.serp.ReturnFlights(c.UserContext(), req)
+	if err != nil {
+		return serpError(c, err)
+	}
+	return c.JSON(fiber.Map{"ok": true, "data": fiber.Map{"offers": offers}})
+}
+
+func (h *FlightsHandler) BookingOptions(c *fiber.Ctx) error {
+	if h.serp == nil {
+		return serpUnavailable(c)
+	}
+	var req serpflights.BookingOptionsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"ok": false, "error": "invalid request body"})
+	}
+	resp, err := h.serp.BookingOptions(c.UserContext(), req)
+	if err != nil {
+		return serpError(c, err)
+	}
+	return c.JSON(fiber.Map{"ok": true, "data": resp})
+}
+
+func serpUnavailable(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"ok": false, "error": "SerpAPI flight search unavailable. Set SERPAPI_API_KEY in env."})
+}
+
+func serpError(c *fiber.Ctx, err error) error {
+	status := fiber.StatusBadGateway
+	if errors.Is(err, serpflights.ErrInvalidInput) {
+		status = fiber.StatusBadRequest
+	}
+	if errors.Is(err, serpflights.ErrNoBooking) {
+		status = fiber.StatusNotFound
+	}
+	return c.Status(status).JSON(fiber.Map{"ok": false, "error": err.Error()})
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+
+	"triploom/backend/internal/live"
+)
+
+type LiveHandler struct {
+	live *live.Server
+}
+
+func NewLiveHandler(server *live.Server) *LiveHandler {
+	return &LiveHandler{live: server}
+}
+
+// Upgrade rejects non-WebSocket requests and non-members before the handshake.
+func (h *LiveHandler) Upgrade(c *fiber.Ctx) error {
+	if !websocket.IsWebSocketUpgrade(c) {
+		return c.Status(fiber.StatusUpgradeRequired).JSON(fiber.Map{"ok": false, "error": "websocket upgrade required"})
+	}
+	userID, _ := c.Locals("userID").(string)
+	if err := h.live.Authorize(c.UserContext(), c.Params("tripId"), userID); err != nil {
+		status := fiber.StatusInternalServerError
+		if err == live.ErrUnauthorizedTrip {
+			status = fiber.StatusForbidden
+		}
+		return c.Status(status).JSON(fiber.Map{"ok": false, "error": err.Error()})
+	}
+	return c.Next()
+}
+
+func (h *LiveHandler) Serve(conn *websocket.Conn) {
+	userID, _ := conn.Locals("userID").(string)
+	conn.SetReadLimit(live.MaxFrameBytes)
+	h.live.Serve(context.Background(), conn, conn.Params("tripId"), userID)
+}
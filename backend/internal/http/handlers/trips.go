@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"triploom/backend/internal/flightwatch"
+	"triploom/backend/internal/trips"
+)
+
+type TripHandler struct {
+	watches *flightwatch.Service
+	trips   *trips.Service
+}
+
+func NewTripHandler(watches *flightwatch.Service, tripService *trips.Service) *TripHandler {
+	return &TripHandler{watches: watches, trips: tripService}
+}
+
+func (h *TripHandler) CreateTrip(c *fiber.Ctx) error {
+	userID, _ := c.Locals("userID").(string)
+	var req trips.CreateTripRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"ok": false, "error": "invalid request body"})
+	}
+	resp, err := h.trips.CreateTrip(c.UserContext(), userID, req)
+	if err != nil {
+		return tripError(c, err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"ok": true, "data": resp})
+}
+
+func (h *TripHandler) AddMember(c *fiber.Ctx) error {
+	userID, _ := c.Locals("userID").(string)
+	var req trips.AddMemberRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"ok": false, "error": "invalid request body"})
+	}
+	if err := h.trips.AddMember(c.UserContext(), userID, c.Params("tripId"), req); err != nil {
+		return tripError(c, err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"ok": true})
+}
+
+func (h *TripHandler) CreateFlightWatch(c *fiber.Ctx) error {
+	userID, _ := c.Locals("userID").(string)
+	var req flightwatch.CreateWatchRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"ok": false, "error": "invalid request body"})
+	}
+	resp, err := h.watches.CreateWatch(c.UserContext(), userID, c.Params("tripId"), req)
+	if err != nil {
+		return tripError(c, err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"ok": true, "data": resp})
+}
+
+func (h *TripHandler) ListFlightWatches(c *fiber.Ctx) error {
+	userID, _ := c.Locals("userID").(string)
+	resp, err := h.watches.ListWatches(c.UserContext(), userID, c.Params("tripId"))
+	if err != nil {
+		return tripError(c, err)
+	}
+	return c.JSON(fiber.Map{"ok": true, "data": resp})
+}
+
+func (h *TripHandler) ListEvents(c *fiber.Ctx) error {
+	userID, _ := c.Locals("userID").(string)
+	resp, err := h.watches.ListEvents(c.UserContext(), userID, c.Params("tripId"), queryLimit(c, 50, 200))
+	if err != nil {
+		return tripError(c, err)
+	}
+	return c.JSON(fiber.Map{"ok": true, "data": resp})
+}
+
+func tripError(c *fiber.Ctx, err error) error {
+	status := fiber.StatusInternalServerError
+	if err == flightwatch.ErrUnauthorizedTrip || err == trips.ErrUnauthorizedTrip {
+		status = fiber.StatusForbidden
+	}
+	if err == flightwatch.ErrInvalidInput || err == trips.ErrInvalidInput {
+		status = fiber.StatusBadRequest
+	}
+	return c.Status(status).JSON(fiber.Map{"ok": false, "error": err.Error()})
+}
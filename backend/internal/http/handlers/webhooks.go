@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"triploom/backend/internal/webhooks"
+)
+
+type WebhookHandler struct {
+	webhooks *webhooks.Service
+}
+
+func NewWebhookHandler(svc *webhooks.Service) *WebhookHandler {
+	return &WebhookHandler{webhooks: svc}
+}
+
+func (h *WebhookHandler) Create(c *fiber.Ctx) error {
+	userID, _ := c.Locals("userID").(string)
+	var req webhooks.CreateSubscriptionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"ok": false, "error": "invalid request body"})
+	}
+	resp, err := h.webhooks.CreateSubscription(c.UserContext(), userID, c.Params("tripId"), req)
+	if err != nil {
+		return webhookError(c, err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"ok": true, "data": resp})
+}
+
+func (h *WebhookHandler) List(c *fiber.Ctx) error {
+	userID, _ := c.Locals("userID").(string)
+	resp, err := h.webhooks.ListSubscriptions(c.UserContext(), userID, c.Params("tripId"))
+	if err != nil {
+		return webhookError(c, err)
+	}
+	return c.JSON(fiber.Map{"ok": true, "data": resp})
+}
+
+func (h *WebhookHandler) Delete(c *fiber.Ctx) error {
+	userID, _ := c.Locals("userID").(string)
+	if err := h.webhooks.DeleteSubscription(c.UserContext(), userID, c.Params("tripId"), c.Params("webhookId")); err != nil {
+		return webhookError(c, err)
+	}
+	return c.JSON(fiber.Map{"ok": true})
+}
+
+func (h *WebhookHandler) ListDeliveries(c *fiber.Ctx) error {
+	userID, _ := c.Locals("userID").(string)
+	resp, err := h.webhooks.ListDeliveries(c.UserContext(), userID, c.Params("tripId"), c.Params("webhookId"), queryLimit(c, 50, 200))
+	if err != nil {
+		return webhookError(c, err)
+	}
+	return c.JSON(fiber.Map{"ok": true, "data": resp})
+}
+
+func (h *WebhookHandler) Redeliver(c *fiber.Ctx) error {
+	userID, _ := c.Locals("userID").(string)
+	resp, err := h.webhooks.Redeliver(c.UserContext(), userID, c.Params("tripId"), c.Params("webhookId"), c.Params("deliveryId"))
+	if err != nil {
+		return webhookError(c, err)
+	}
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{"ok": true, "data": resp})
+}
+
+func webhookError(c *fiber.Ctx, err error) error {
+	status := fiber.StatusInternalServerError
+	switch err {
+	case webhooks.ErrUnauthorizedTrip:
+		status = fiber.StatusForbidden
+	case webhooks.ErrInvalidInput:
+		status = fiber.StatusBadRequest
+	case webhooks.ErrNotFound:
+		status = fiber.StatusNotFound
+	}
+	return c.Status(status).JSON(fiber.Map{"ok": false, "error": err.Error()})
+}
@@ -22,7 +22,23 @@ func (m *AuthMiddleware) RequireAuth(c *fiber.Ctx) error {
 	if !strings.HasPrefix(strings.ToLower(auth), "bearer ") {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"ok": false, "error": "missing bearer token"})
 	}
-	tokenString := strings.TrimSpace(auth[7:])
+	return m.authenticate(c, strings.TrimSpace(auth[7:]))
+}
+
+// RequireSocketAuth is RequireAuth for WebSocket upgrades. Browsers cannot set headers on a
+// WebSocket handshake, so the same JWT may also arrive as the access_token query parameter.
+func (m *AuthMiddleware) RequireSocketAuth(c *fiber.Ctx) error {
+	if auth := c.Get("Authorization"); strings.HasPrefix(strings.ToLower(auth), "bearer ") {
+		return m.authenticate(c, strings.TrimSpace(auth[7:]))
+	}
+	token := strings.TrimSpace(c.Query("access_token"))
+	if token == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"ok": false, "error": "missing bearer token"})
+	}
+	return m.authenticate(c, token)
+}
+
+func (m *AuthMiddleware) authenticate(c *fiber.Ctx, tokenString string) error {
 	token, err := jwt.Parse(tokenString, m.jwks.Keyfunc)
 	if err != nil || !token.Valid {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"ok": false, "error": "invalid token"})
@@ -54,3 +70,13 @@ func RequireTestUser(c *fiber.Ctx) error {
 	c.Locals("userID", userID)
 	return c.Next()
 }
+
+// RequireTestSocketUser is RequireTestUser for WebSocket upgrades, also accepting ?userId=.
+func RequireTestSocketUser(c *fiber.Ctx) error {
+	if strings.TrimSpace(c.Get("X-User-Id")) == "" {
+		if userID := strings.TrimSpace(c.Query("userId")); userID != "" {
+			c.Request().Header.Set("X-User-Id", userID)
+		}
+	}
+	return RequireTestUser(c)
+}
@@ -6,17 +6,23 @@ import (
 	"strings"
 
 	"github.com/MicahParks/keyfunc/v3"
+	"github.com/gofiber/contrib/websocket"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 
 	"triploom/backend/internal/ai"
 	"triploom/backend/internal/config"
+	"triploom/backend/internal/flightwatch"
 	"triploom/backend/internal/http/handlers"
 	"triploom/backend/internal/http/middleware"
+	"triploom/backend/internal/live"
+	"triploom/backend/internal/providers/serpflights"
 	"triploom/backend/internal/store"
+	"triploom/backend/internal/trips"
+	"triploom/backend/internal/webhooks"
 )
 
-func NewRouter(cfg *config.Config, aiService *ai.Service, repo *store.AIRepository) (*fiber.App, error) {
+func NewRouter(cfg *config.Config, aiService *ai.Service, repo store.AIStore, serp *serpflights.Client, watches *flightwatch.Service, hooks *webhooks.Service, liveServer *live.Server) (*fiber.App, error) {
 	app := fiber.New()
 
 	app.Use(cors.New(cors.Config{
@@ -25,29 +31,70 @@ func NewRouter(cfg *config.Config, aiService *ai.Service, repo *store.AIReposito
 	}))
 
 	h := handlers.NewAIHandler(aiService)
+	flights := handlers.NewFlightsHandler(serp)
+	trips := handlers.NewTripHandler(watches, trips.NewService(repo))
+	tripWebhooks := handlers.NewWebhookHandler(hooks)
+	liveTrips := handlers.NewLiveHandler(liveServer)
 	var api fiber.Router
-	if cfg.UseSupabase {
+	var socketAuth fiber.Handler
+	if cfg.JWTAuth {
 		jwks, err := keyfunc.NewDefaultCtx(context.Background(), []string{cfg.SupabaseJWKSURL})
 		if err != nil {
 			return nil, err
 		}
 		authMiddleware := middleware.NewAuthMiddleware(jwks, cfg.SupabaseURL)
 		api = app.Group("/v1", authMiddleware.RequireAuth)
+		socketAuth = authMiddleware.RequireSocketAuth
 	} else {
 		log.Printf("router auth mode: test user passthrough enabled (set X-User-Id header to override default user)")
 		api = app.Group("/v1", middleware.RequireTestUser)
+		socketAuth = middleware.RequireTestSocketUser
 	}
 
+	// Registered on app rather than api: the socket handshake authenticates with RequireSocketAuth.
+	app.Get("/v1/trips/:tripId/live", socketAuth, liveTrips.Upgrade, websocket.New(liveTrips.Serve))
+
 	api.Post("/ai/chat", h.Chat)
 	api.Post("/ai/planner/chat", h.PlannerChat)
+	api.Post("/ai/conversations", h.CreateConversation)
 	api.Get("/ai/conversations/:tripId", h.ListConversations)
+	api.Patch("/ai/conversations/:conversationId", h.UpdateConversation)
+	api.Delete("/ai/conversations/:conversationId", h.DeleteConversation)
 	api.Get("/ai/conversations/:conversationId/messages", h.ListMessages)
+	api.Post("/ai/messages/:messageId/feedback", h.SubmitFeedback)
+	api.Post("/ai/messages/:messageId/regenerate", h.Regenerate)
+	api.Get("/ai/feedback/report", h.FeedbackReport)
+	api.Get("/ai/search", h.Search)
 	api.Post("/ai/context/refresh", h.RefreshContext)
+	api.Get("/proposals", h.ListProposals)
+	api.Post("/proposals/:proposalId/approve", h.ApproveProposal)
+	api.Post("/proposals/:proposalId/reject", h.RejectProposal)
+	api.Get("/me/preferences", h.GetPreferences)
+	api.Put("/me/preferences", h.UpdatePreferences)
+	api.Post("/me/preferences/updates/:updateId/approve", h.ApprovePreferenceUpdate)
+	api.Post("/me/preferences/updates/:updateId/reject", h.RejectPreferenceUpdate)
+
+	api.Post("/flights/search", flights.Search)
+	api.Post("/flights/return-flights", flights.ReturnFlights)
+	api.Post("/flights/booking-options", flights.BookingOptions)
+
+	// With Supabase the Next app creates trips and manages members under its own rules.
+	if cfg.SQLitePath != "" {
+		api.Post("/trips", trips.CreateTrip)
+		api.Post("/trips/:tripId/members", trips.AddMember)
+	}
+	api.Post("/trips/:tripId/flight-watches", trips.CreateFlightWatch)
+	api.Get("/trips/:tripId/flight-watches", trips.ListFlightWatches)
+	api.Get("/trips/:tripId/events", trips.ListEvents)
+
+	api.Post("/trips/:tripId/webhooks", tripWebhooks.Create)
+	api.Get("/trips/:tripId/webhooks", tripWebhooks.List)
+	api.Delete("/trips/:tripId/webhooks/:webhookId", tripWebhooks.Delete)
+	api.Get("/trips/:tripId/webhooks/:webhookId/deliveries", tripWebhooks.ListDeliveries)
+	api.Post("/trips/:tripId/webhooks/:webhookId/deliveries/:deliveryId/redeliver", tripWebhooks.Redeliver)
 
 	app.Get("/healthz", func(c *fiber.Ctx) error {
 		return c.JSON(fiber.Map{"ok": true, "origins": strings.Split(cfg.AllowedOrigins, ",")})
 	})
-
-	_ = repo // kept for parity with constructor dependencies
 	return app, nil
 }
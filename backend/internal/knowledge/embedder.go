@@ -0,0 +1,120 @@
+package knowledge
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+
+	"triploom/backend/internal/retrieval"
+)
+
+// Embedder turns texts into vectors whose cosine similarity reflects their relatedness.
+type Embedder interface {
+	// Name identifies the embedder and its settings, e.g. "hashing-512".
+	Name() string
+	// Embed returns one vector per text, in the order given.
+	Embed(ctx context.Context, texts []string) ([][]float64, error)
+}
+
+// DefaultHashingDims is the vector size of NewHashingEmbedder(0).
+const DefaultHashingDims = 512
+
+// HashingEmbedder embeds text by hashing its words and word pairs into a fixed number of
+// signed buckets. It needs no model or network and always returns the same vector for the same
+// text, which suits offline use and tests; it matches shared vocabulary, not meaning.
+type HashingEmbedder struct {
+	dims int
+}
+
+// NewHashingEmbedder returns an embedder with dims buckets, or DefaultHashingDims when dims is
+// not positive.
+func NewHashingEmbedder(dims int) *HashingEmbedder {
+	if dims <= 0 {
+		dims = DefaultHashingDims
+	}
+	return &HashingEmbedder{dims: dims}
+}
+
+func (h *HashingEmbedder) Name() string {
+	return fmt.Sprintf("hashing-%d", h.dims)
+}
+
+func (h *HashingEmbedder) Embed(_ context.Context, texts []string) ([][]float64, error) {
+	out := make([][]float64, len(texts))
+	for i, text := range texts {
+		vec := make([]float64, h.dims)
+		terms := retrieval.Terms(text)
+		for j, term := range terms {
+			h.add(vec, term, 1)
+			if j > 0 {
+				h.add(vec, terms[j-1]+" "+term, 0.5)
+			}
+		}
+		out[i] = normalize(vec)
+	}
+	return out, nil
+}
+
+func (h *HashingEmbedder) add(vec []float64, feature string, weight float64) {
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(feature))
+	sum := hash.Sum64()
+	if sum>>63 == 1 {
+		weight = -weight
+	}
+	vec[sum%uint64(h.dims)] += weight
+}
+
+// openAIEmbeddingBatch bounds the texts sent per embeddings request.
+const openAIEmbeddingBatch = 64
+
+// OpenAIClient is the embeddings call OpenAIEmbedder needs; *openai.Client implements it.
+type OpenAIClient interface {
+	Embed(ctx context.Context, model string, texts []string) ([][]float64, error)
+}
+
+// OpenAIEmbedder embeds with an OpenAI embedding model.
+type OpenAIEmbedder struct {
+	client OpenAIClient
+	model  string
+}
+
+func NewOpenAIEmbedder(client OpenAIClient, model string) *OpenAIEmbedder {
+	return &OpenAIEmbedder{client: client, model: model}
+}
+
+func (o *OpenAIEmbedder) Name() string {
+	return "openai-" + o.model
+}
+
+func (o *OpenAIEmbedder) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	out := make([][]float64, 0, len(texts))
+	for start := 0; start < len(texts); start += openAIEmbeddingBatch {
+		end := min(start+openAIEmbeddingBatch, len(texts))
+		vecs, err := o.client.Embed(ctx, o.model, texts[start:end])
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range vecs {
+			out = append(out, normalize(v))
+		}
+	}
+	return out, nil
+}
+
+// normalize scales vec to unit length in place, so that cosine similarity is a dot product.
+func normalize(vec []float64) []float64 {
+	sum := 0.0
+	for _, x := range vec {
+		sum += x * x
+	}
+	if sum == 0 {
+		return vec
+	}
+	norm := math.Sqrt(sum)
+	for i := range vec {
+		vec[i] /= norm
+	}
+	return vec
+}
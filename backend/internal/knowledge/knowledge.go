@@ -0,0 +1,265 @@
+// Package knowledge indexes a directory of curated markdown guides (visa rules, tipping, transit
+// passes, ...) per country or city and finds the passages relevant to a question with an
+// in-process vector index.
+//
+// A guide's place comes from its path: japan.md covers Japan and japan/kyoto.md covers Kyoto
+// in Japan. Guides under general/ apply to every destination.
+package knowledge
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// maxChunkRunes bounds a chunk; longer sections are split between paragraphs.
+	maxChunkRunes = 800
+	// MinScore is the cosine similarity below which a passage is not returned.
+	MinScore = 0.1
+	// generalDir holds guides that apply everywhere.
+	generalDir = "general"
+)
+
+// Chunk is an indexed piece of a guide: a section, or part of a long one.
+type Chunk struct {
+	// ID is the guide's path and the chunk's position in it, e.g. japan/kyoto.md#2.
+	ID      string `json:"id"`
+	Path    string `json:"path"`
+	Title   string `json:"title"`
+	Section string `json:"section,omitempty"`
+	Text    string `json:"text"`
+	// Places are the lower-cased names the guide covers, from its path; empty for general
+	// guides.
+	Places []string `json:"places,omitempty"`
+}
+
+// Passage is a chunk found by Search.
+type Passage struct {
+	Chunk
+	Score float64 `json:"score"`
+}
+
+// Base is an immutable, searchable set of chunks.
+type Base struct {
+	embedder Embedder
+	chunks   []Chunk
+	vectors  [][]float64
+	// parents maps a place to the places it is in, from nested guides: japan/kyoto.md makes
+	// a Kyoto trip match japan.md too.
+	parents map[string][]string
+}
+
+// Load reads every .md file under dir and indexes it with e.
+func Load(ctx context.Context, dir string, e Embedder) (*Base, error) {
+	return LoadFS(ctx, os.DirFS(dir), e)
+}
+
+// LoadFS reads every .md file in fsys and indexes it with e.
+func LoadFS(ctx context.Context, fsys fs.FS, e Embedder) (*Base, error) {
+	chunks := make([]Chunk, 0)
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.EqualFold(path.Ext(p), ".md") {
+			return nil
+		}
+		raw, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		chunks = append(chunks, Split(p, string(raw))...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read knowledge base: %w", err)
+	}
+	return New(ctx, chunks, e)
+}
+
+// New indexes chunks with e.
+func New(ctx context.Context, chunks []Chunk, e Embedder) (*Base, error) {
+	texts := make([]string, len(chunks))
+	for i, c := range chunks {
+		texts[i] = embeddingText(c)
+	}
+	vectors := make([][]float64, 0)
+	if len(texts) > 0 {
+		var err error
+		if vectors, err = e.Embed(ctx, texts); err != nil {
+			return nil, fmt.Errorf("embed knowledge base with %s: %w", e.Name(), err)
+		}
+	}
+	if len(vectors) != len(chunks) {
+		return nil, fmt.Errorf("embed knowledge base with %s: got %d vectors for %d chunks", e.Name(), len(vectors), len(chunks))
+	}
+	parents := make(map[string][]string)
+	for _, c := range chunks {
+		if n := len(c.Places); n > 1 {
+			parents[c.Places[n-1]] = c.Places[:n-1]
+		}
+	}
+	return &Base{embedder: e, chunks: chunks, vectors: vectors, parents: parents}, nil
+}
+
+// Len is the number of indexed chunks.
+func (b *Base) Len() int {
+	return len(b.chunks)
+}
+
+// Embedder is the embedder the base was indexed with.
+func (b *Base) Embedder() Embedder {
+	return b.embedder
+}
+
+func embeddingText(c Chunk) string {
+	return strings.TrimSpace(strings.Join([]string{c.Title, c.Section, c.Text}, "\n"))
+}
+
+// Search returns up to k passages most similar to query, best first, from guides that cover
+// destination (matched by name: "Kyoto" matches japan/kyoto.md and, through it, japan.md) or
+// that are general. Passages scoring below MinScore are left out.
+func (b *Base) Search(ctx context.Context, query, destination string, k int) ([]Passage, error) {
+	if b == nil || len(b.chunks) == 0 || strings.TrimSpace(query) == "" || k <= 0 {
+		return nil, nil
+	}
+	vecs, err := b.embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, err
+	}
+	if len(vecs) != 1 {
+		return nil, fmt.Errorf("embed query with %s: got %d vectors", b.embedder.Name(), len(vecs))
+	}
+	q := vecs[0]
+	dest := " " + normalizePlace(destination) + " "
+	for place, parents := range b.parents {
+		if strings.Contains(dest, " "+place+" ") {
+			dest += strings.Join(parents, " ") + " "
+		}
+	}
+
+	out := make([]Passage, 0)
+	for i, c := range b.chunks {
+		if !covers(c, dest) {
+			continue
+		}
+		score := dot(q, b.vectors[i])
+		if score < MinScore {
+			continue
+		}
+		out = append(out, Passage{Chunk: c, Score: score})
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Score > out[j].Score })
+	if len(out) > k {
+		out = out[:k]
+	}
+	return out, nil
+}
+
+// covers reports whether c's guide is general or about the most specific place it names
+// appearing in dest.
+func covers(c Chunk, dest string) bool {
+	if len(c.Places) == 0 {
+		return true
+	}
+	return strings.Contains(dest, " "+c.Places[len(c.Places)-1]+" ")
+}
+
+func dot(a, b []float64) float64 {
+	sum := 0.0
+	for i := range a {
+		if i < len(b) {
+			sum += a[i] * b[i]
+		}
+	}
+	return sum
+}
+
+// normalizePlace lower-cases s and turns separators into single spaces, so that "new-york"
+// and "New York," compare equal.
+func normalizePlace(s string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return r == '-' || r == '_' || r == ',' || r == '/' || r == ' ' || r == '.'
+	}), " ")
+}
+
+// Split breaks a markdown guide into chunks, one per ## or ### section, splitting sections
+// longer than maxChunkRunes between paragraphs. The title is the first # heading, or the
+// file name.
+func Split(p, markdown string) []Chunk {
+	places := make([]string, 0)
+	for _, part := range strings.Split(strings.TrimSuffix(p, path.Ext(p)), "/") {
+		if name := normalizePlace(part); name != "" && name != generalDir {
+			places = append(places, name)
+		}
+	}
+	if strings.HasPrefix(p, generalDir+"/") {
+		places = nil
+	}
+	title := normalizePlace(path.Base(strings.TrimSuffix(p, path.Ext(p))))
+
+	chunks := make([]Chunk, 0)
+	section := ""
+	paragraphs := make([]string, 0)
+	var para strings.Builder
+	endParagraph := func() {
+		if text := strings.TrimSpace(para.String()); text != "" {
+			paragraphs = append(paragraphs, text)
+		}
+		para.Reset()
+	}
+	flush := func() {
+		endParagraph()
+		var text strings.Builder
+		emit := func() {
+			if text.Len() > 0 {
+				chunks = append(chunks, Chunk{Path: p, Section: section, Text: text.String(), Places: places})
+				text.Reset()
+			}
+		}
+		for _, para := range paragraphs {
+			if text.Len() > 0 && utf8.RuneCountInString(text.String())+utf8.RuneCountInString(para) > maxChunkRunes {
+				emit()
+			}
+			if text.Len() > 0 {
+				text.WriteString("\n\n")
+			}
+			text.WriteString(para)
+		}
+		emit()
+		paragraphs = paragraphs[:0]
+	}
+
+	sawTitle := false
+	for _, line := range strings.Split(strings.ReplaceAll(markdown, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "# ") && !sawTitle:
+			flush()
+			title, sawTitle = strings.TrimSpace(trimmed[2:]), true
+		case strings.HasPrefix(trimmed, "## ") || strings.HasPrefix(trimmed, "### "):
+			flush()
+			section = strings.TrimSpace(strings.TrimLeft(trimmed, "#"))
+		case trimmed == "":
+			endParagraph()
+		default:
+			if para.Len() > 0 {
+				para.WriteString("\n")
+			}
+			para.WriteString(trimmed)
+		}
+	}
+	flush()
+
+	for i := range chunks {
+		chunks[i].ID = fmt.Sprintf("%s#%d", p, i+1)
+		chunks[i].Title = title
+	}
+	return chunks
+}
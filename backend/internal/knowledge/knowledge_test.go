@@ -0,0 +1,94 @@
+package knowledge
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestHashingEmbedder(t *testing.T) {
+	ctx := context.Background()
+	e := NewHashingEmbedder(0)
+	a, _ := e.Embed(ctx, []string{"Japan Rail Pass for shinkansen trains", "Japan rail pass for the shinkansen", "tipping customs in restaurants"})
+	again, _ := NewHashingEmbedder(0).Embed(ctx, []string{"Japan Rail Pass for shinkansen trains"})
+	if len(a[0]) != DefaultHashingDims || dot(a[0], again[0]) < 0.9999 {
+		t.Fatalf("expected the same unit vector for the same text")
+	}
+	if related, unrelated := dot(a[0], a[1]), dot(a[0], a[2]); related < 0.5 || unrelated > 0.2 {
+		t.Fatalf("expected shared words to score higher, got %.2f and %.2f", related, unrelated)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	long := strings.Repeat("word ", 100)
+	chunks := Split("japan/kyoto.md", "# Kyoto\n\nIntro line.\n\n## Temples\n\n"+long+"\n\n"+long+"\n\n### Gardens\n\nMoss.\n")
+	if len(chunks) != 4 {
+		t.Fatalf("expected the intro, two temple chunks and the gardens, got %+v", chunks)
+	}
+	if chunks[0].Title != "Kyoto" || chunks[0].Section != "" || chunks[1].Section != "Temples" || chunks[2].Section != "Temples" || chunks[3].Section != "Gardens" {
+		t.Fatalf("unexpected sections %+v", chunks)
+	}
+	if chunks[3].ID != "japan/kyoto.md#4" || strings.Join(chunks[3].Places, ",") != "japan,kyoto" {
+		t.Fatalf("unexpected chunk %+v", chunks[3])
+	}
+	if general := Split("general/insurance.md", "Cover.\n"); len(general) != 1 || general[0].Places != nil || general[0].Title != "insurance" {
+		t.Fatalf("expected a general guide without places, got %+v", general)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	ctx := context.Background()
+	kb, err := Load(ctx, "testdata", NewHashingEmbedder(0))
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if kb.Len() != 8 {
+		t.Fatalf("expected 8 chunks, got %d", kb.Len())
+	}
+
+	got, err := kb.Search(ctx, "is tipping customary in restaurants?", "Kyoto", 3)
+	if err != nil || len(got) == 0 || got[0].ID != "japan.md#2" {
+		t.Fatalf("expected the Japan tipping section first, got %+v (%v)", got, err)
+	}
+	for _, p := range got {
+		if strings.HasPrefix(p.Path, "portugal") {
+			t.Fatalf("expected guides for other destinations to be left out, got %+v", p)
+		}
+	}
+	got, _ = kb.Search(ctx, "is a rail pass worth it for the shinkansen?", "Japan", 1)
+	if len(got) != 1 || got[0].Section != "Transit passes" {
+		t.Fatalf("expected the transit pass section, got %+v", got)
+	}
+	if got, _ := kb.Search(ctx, "when do temples close?", "Japan", 3); len(got) != 0 {
+		t.Fatalf("expected city guides not to apply to the whole country, got %+v", got)
+	}
+	got, _ = kb.Search(ctx, "does travel insurance cover medical evacuation?", "Lisbon, Portugal", 2)
+	if len(got) == 0 || got[0].Path != "general/travel-insurance.md" {
+		t.Fatalf("expected general guides to apply everywhere, got %+v", got)
+	}
+	if got, _ := kb.Search(ctx, "zebra quantum", "Japan", 3); len(got) != 0 {
+		t.Fatalf("expected unrelated questions to find nothing, got %+v", got)
+	}
+}
+
+type fakeOpenAI struct{ batches []int }
+
+func (f *fakeOpenAI) Embed(_ context.Context, _ string, texts []string) ([][]float64, error) {
+	f.batches = append(f.batches, len(texts))
+	out := make([][]float64, len(texts))
+	for i := range texts {
+		out[i] = []float64{3, 4}
+	}
+	return out, nil
+}
+
+func TestOpenAIEmbedderBatchesAndNormalizes(t *testing.T) {
+	client := &fakeOpenAI{}
+	vecs, err := NewOpenAIEmbedder(client, "text-embedding-3-small").Embed(context.Background(), make([]string, 100))
+	if err != nil || len(vecs) != 100 || vecs[99][0] != 0.6 {
+		t.Fatalf("unexpected vectors %v (%v)", vecs[:1], err)
+	}
+	if len(client.batches) != 2 || client.batches[0] != 64 || client.batches[1] != 36 {
+		t.Fatalf("expected batches of 64, got %v", client.batches)
+	}
+}
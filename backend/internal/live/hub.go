@@ -0,0 +1,156 @@
+package live
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+
+	"triploom/backend/internal/store"
+)
+
+// Frame types exchanged over a trip's live socket.
+const (
+	FramePresence = "presence"
+	FrameEvent    = "event"
+	FrameChange   = "change"
+	FramePing     = "ping"
+	FramePong     = "pong"
+	FrameError    = "error"
+)
+
+// Frame is one JSON message on the socket. Server frames are presence, event, pong and error;
+// clients send change and ping.
+type Frame struct {
+	Type      string           `json:"type"`
+	TripID    string           `json:"tripId,omitempty"`
+	Online    []string         `json:"online,omitempty"`
+	Event     *store.TripEvent `json:"event,omitempty"`
+	EventType string           `json:"eventType,omitempty"`
+	Data      map[string]any   `json:"data,omitempty"`
+	Error     string           `json:"error,omitempty"`
+}
+
+// sendBuffer is how many frames may queue for one connection before it is dropped as too slow.
+const sendBuffer = 32
+
+// Client is one open socket on a trip.
+type Client struct {
+	TripID string
+	UserID string
+	send   chan []byte
+}
+
+// Hub tracks the open sockets of every trip in this process and fans frames out to them.
+type Hub struct {
+	mu    sync.RWMutex
+	trips map[string]map[*Client]struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{trips: make(map[string]map[*Client]struct{})}
+}
+
+// Join registers a connection and tells everyone on the trip who is online now.
+func (h *Hub) Join(tripID, userID string) *Client {
+	c := &Client{TripID: tripID, UserID: userID, send: make(chan []byte, sendBuffer)}
+	h.mu.Lock()
+	if h.trips[tripID] == nil {
+		h.trips[tripID] = make(map[*Client]struct{})
+	}
+	h.trips[tripID][c] = struct{}{}
+	h.mu.Unlock()
+	h.broadcastPresence(tripID)
+	return c
+}
+
+// Leave unregisters a connection. It is safe to call more than once.
+func (h *Hub) Leave(c *Client) {
+	if !h.remove(c) {
+		return
+	}
+	h.broadcastPresence(c.TripID)
+}
+
+// Online lists the distinct users connected to a trip.
+func (h *Hub) Online(tripID string) []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	seen := make(map[string]struct{})
+	out := make([]string, 0)
+	for c := range h.trips[tripID] {
+		if _, ok := seen[c.UserID]; ok {
+			continue
+		}
+		seen[c.UserID] = struct{}{}
+		out = append(out, c.UserID)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Broadcast sends f to every connection on the trip. A connection whose buffer is full is
+// disconnected rather than allowed to hold up the others.
+func (h *Hub) Broadcast(tripID string, f Frame) {
+	f.TripID = tripID
+	msg, err := json.Marshal(f)
+	if err != nil {
+		return
+	}
+	h.mu.RLock()
+	slow := make([]*Client, 0)
+	for c := range h.trips[tripID] {
+		select {
+		case c.send <- msg:
+		default:
+			slow = append(slow, c)
+		}
+	}
+	h.mu.RUnlock()
+	for _, c := range slow {
+		h.Leave(c)
+	}
+}
+
+// HandleTripEvent makes the hub a tripevents.Sink: every recorded event goes live.
+func (h *Hub) HandleTripEvent(_ context.Context, ev store.TripEvent) error {
+	h.Broadcast(ev.TripID, Frame{Type: FrameEvent, Event: &ev})
+	return nil
+}
+
+// sendTo queues a frame for one connection only, dropping it if that connection is backed up.
+func (h *Hub) sendTo(c *Client, f Frame) {
+	f.TripID = c.TripID
+	msg, err := json.Marshal(f)
+	if err != nil {
+		return
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if _, ok := h.trips[c.TripID][c]; !ok {
+		return
+	}
+	select {
+	case c.send <- msg:
+	default:
+	}
+}
+
+func (h *Hub) broadcastPresence(tripID string) {
+	h.Broadcast(tripID, Frame{Type: FramePresence, Online: h.Online(tripID)})
+}
+
+func (h *Hub) remove(c *Client) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	clients := h.trips[c.TripID]
+	if _, ok := clients[c]; !ok {
+		return false
+	}
+	delete(clients, c)
+	if len(clients) == 0 {
+		delete(h.trips, c.TripID)
+	}
+	close(c.send)
+	return true
+}
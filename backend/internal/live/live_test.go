@@ -0,0 +1,166 @@
+package live
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"triploom/backend/internal/store"
+	"triploom/backend/internal/tripevents"
+)
+
+type allowAll struct{}
+
+func (allowAll) IsTripMember(context.Context, string, string) (bool, error) { return true, nil }
+func (allowAll) TripRole(context.Context, string, string) (string, error)   { return "editor", nil }
+
+// roles gives each listed user a role on every trip.
+type roles map[string]string
+
+func (r roles) IsTripMember(_ context.Context, _, userID string) (bool, error) {
+	return r[userID] != "", nil
+}
+func (r roles) TripRole(_ context.Context, _, userID string) (string, error) { return r[userID], nil }
+
+// fakeConn feeds queued client frames to the session and collects what the server writes.
+type fakeConn struct {
+	in     chan []byte
+	mu     sync.Mutex
+	out    []Frame
+	closed chan struct{}
+	once   sync.Once
+}
+
+func newFakeConn() *fakeConn {
+	return &fakeConn{in: make(chan []byte, 8), closed: make(chan struct{})}
+}
+
+func (c *fakeConn) ReadMessage() (int, []byte, error) {
+	select {
+	case msg, ok := <-c.in:
+		if !ok {
+			return 0, nil, errors.New("closed")
+		}
+		return textMessage, msg, nil
+	case <-c.closed:
+		return 0, nil, errors.New("closed")
+	}
+}
+
+func (c *fakeConn) WriteMessage(_ int, data []byte) error {
+	var f Frame
+	_ = json.Unmarshal(data, &f)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.out = append(c.out, f)
+	return nil
+}
+
+func (c *fakeConn) Close() error {
+	c.once.Do(func() { close(c.closed) })
+	return nil
+}
+
+func (c *fakeConn) waitFor(t *testing.T, match func(Frame) bool) Frame {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		c.mu.Lock()
+		for _, f := range c.out {
+			if match(f) {
+				c.mu.Unlock()
+				return f
+			}
+		}
+		c.mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for frame, got %+v", c.out)
+	return Frame{}
+}
+
+func TestHubPresenceAndSlowClients(t *testing.T) {
+	hub := NewHub()
+	a := hub.Join("trip-1", "alice")
+	b := hub.Join("trip-1", "bob")
+	hub.Join("trip-1", "bob")
+	hub.Join("trip-2", "carol")
+
+	if got := hub.Online("trip-1"); len(got) != 2 || got[0] != "alice" || got[1] != "bob" {
+		t.Fatalf("unexpected presence %v", got)
+	}
+
+	hub.Leave(b)
+	hub.Leave(b)
+	if got := hub.Online("trip-1"); len(got) != 2 {
+		t.Fatalf("expected bob to stay online on his second socket, got %v", got)
+	}
+
+	for i := 0; i < sendBuffer+1; i++ {
+		hub.Broadcast("trip-1", Frame{Type: FrameEvent})
+	}
+	if _, open := <-a.send; !open {
+		t.Fatalf("expected queued frames before the drop")
+	}
+	for range a.send {
+	}
+	if got := hub.Online("trip-1"); len(got) != 0 {
+		t.Fatalf("expected slow clients to be dropped, got %v", got)
+	}
+	if got := hub.Online("trip-2"); len(got) != 1 {
+		t.Fatalf("expected other trips untouched, got %v", got)
+	}
+}
+
+func TestServeRelaysChangesThroughRecorder(t *testing.T) {
+	ctx := context.Background()
+	hub := NewHub()
+	recorder := tripevents.NewRecorder(store.NewInMemoryTripEventRepository(nil))
+	recorder.AddSink(hub)
+	srv := NewServer(hub, roles{"alice": "owner", "bob": "editor", "carol": "viewer"}, recorder)
+
+	alice, bob, carol := newFakeConn(), newFakeConn(), newFakeConn()
+	var wg sync.WaitGroup
+	for user, conn := range map[string]*fakeConn{"alice": alice, "bob": bob, "carol": carol} {
+		wg.Add(1)
+		go func(user string, conn *fakeConn) {
+			defer wg.Done()
+			srv.Serve(ctx, conn, "trip-1", user)
+		}(user, conn)
+	}
+
+	alice.waitFor(t, func(f Frame) bool { return f.Type == FramePresence && len(f.Online) == 3 })
+
+	bob.in <- []byte(`{"type":"change","eventType":"itinerary_updated","data":{"itemId":"day-2","source":"server"}}`)
+	bob.waitFor(t, func(f Frame) bool { return f.Type == FrameError })
+	bob.in <- []byte(`{"type":"change","eventType":"itinerary_updated","data":{"itemId":"day-2"}}`)
+	got := alice.waitFor(t, func(f Frame) bool { return f.Type == FrameEvent })
+	if got.Event.Type != tripevents.TypeItineraryUpdated || got.Event.PayloadJSON["userId"] != "bob" || got.Event.PayloadJSON["itemId"] != "day-2" || got.Event.PayloadJSON["source"] != ChangeSource {
+		t.Fatalf("unexpected event frame %+v", got.Event)
+	}
+
+	bob.in <- []byte(`{"type":"change","eventType":"ai_chat_completed"}`)
+	bob.waitFor(t, func(f Frame) bool { return f.Type == FrameError })
+	bob.in <- []byte(`{"type":"change","eventType":"expense_added","data":{"amount":{"value":12}}}`)
+	bob.waitFor(t, func(f Frame) bool { return f.Type == FrameError })
+	carol.in <- []byte(`{"type":"change","eventType":"expense_added","data":{"amount":12}}`)
+	if f := carol.waitFor(t, func(f Frame) bool { return f.Type == FrameError }); f.Error != "only owners and editors may announce changes" {
+		t.Fatalf("unexpected error for a viewer %q", f.Error)
+	}
+	bob.in <- []byte(`{"type":"ping"}`)
+	bob.waitFor(t, func(f Frame) bool { return f.Type == FramePong })
+
+	close(bob.in)
+	close(carol.in)
+	alice.waitFor(t, func(f Frame) bool { return f.Type == FramePresence && len(f.Online) == 1 })
+	close(alice.in)
+	wg.Wait()
+
+	events, _ := recorder.List(ctx, "trip-1", 10)
+	if len(events) != 1 {
+		t.Fatalf("expected only the valid change to be recorded, got %+v", events)
+	}
+}
@@ -0,0 +1,175 @@
+package live
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"triploom/backend/internal/tripevents"
+)
+
+var ErrUnauthorizedTrip = errors.New("unauthorized trip access")
+
+// MaxFrameBytes caps a single client frame; the HTTP layer applies it as the socket read limit.
+const MaxFrameBytes = 64 << 10
+
+// Limits on the data of a change frame. It is stored with the event and sent to every webhook
+// subscriber, so it stays small and flat.
+const (
+	maxChangeDataBytes = 4 << 10
+	maxChangeFields    = 32
+	maxChangeKeyLen    = 64
+)
+
+// ChangeSource marks the payload of events recorded from change frames: the server only
+// checked who sent them, not that the trip tables actually changed.
+const ChangeSource = "client"
+
+// textMessage is the websocket opcode for a text frame (RFC 6455).
+const textMessage = 1
+
+// clientEventTypes are the changes members may announce from the browser after writing to
+// the trip tables directly; server-side events (flight status, AI chat) come from the recorder.
+var clientEventTypes = map[string]bool{
+	tripevents.TypeItineraryUpdated:   true,
+	tripevents.TypeTripFlightsUpdated: true,
+	tripevents.TypeExpenseAdded:       true,
+}
+
+// TripMembership reports whether a user may act on a trip and in which role.
+type TripMembership interface {
+	IsTripMember(ctx context.Context, tripID, userID string) (bool, error)
+	// TripRole returns owner, editor or viewer, or "" for non-members.
+	TripRole(ctx context.Context, tripID, userID string) (string, error)
+}
+
+// Conn is the part of a websocket connection a session needs.
+type Conn interface {
+	ReadMessage() (int, []byte, error)
+	WriteMessage(messageType int, data []byte) error
+	Close() error
+}
+
+// Server runs live sessions: it checks membership, joins sockets to the hub and records the
+// changes members announce so that webhooks and other sinks see them too.
+type Server struct {
+	hub     *Hub
+	members TripMembership
+	events  *tripevents.Recorder
+}
+
+func NewServer(hub *Hub, members TripMembership, events *tripevents.Recorder) *Server {
+	return &Server{hub: hub, members: members, events: events}
+}
+
+func (s *Server) Authorize(ctx context.Context, tripID, userID string) error {
+	ok, err := s.members.IsTripMember(ctx, tripID, userID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrUnauthorizedTrip
+	}
+	return nil
+}
+
+// Serve runs one authorised connection until it closes.
+func (s *Server) Serve(ctx context.Context, conn Conn, tripID, userID string) {
+	client := s.hub.Join(tripID, userID)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for msg := range client.send {
+			if err := conn.WriteMessage(textMessage, msg); err != nil {
+				break
+			}
+		}
+		_ = conn.Close()
+	}()
+
+	for {
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			break
+		}
+		var f Frame
+		if err := json.Unmarshal(msg, &f); err != nil {
+			s.reply(client, Frame{Type: FrameError, Error: "invalid frame"})
+			continue
+		}
+		s.handle(ctx, client, f)
+	}
+
+	s.hub.Leave(client)
+	<-done
+}
+
+func (s *Server) handle(ctx context.Context, client *Client, f Frame) {
+	switch f.Type {
+	case FramePing:
+		s.reply(client, Frame{Type: FramePong})
+	case FrameChange:
+		if !clientEventTypes[f.EventType] {
+			s.reply(client, Frame{Type: FrameError, Error: "unsupported eventType"})
+			return
+		}
+		role, err := s.members.TripRole(ctx, client.TripID, client.UserID)
+		if err != nil {
+			log.Printf("live trip %s: role of %s: %v", client.TripID, client.UserID, err)
+			s.reply(client, Frame{Type: FrameError, Error: "could not record change"})
+			return
+		}
+		if role != "owner" && role != "editor" {
+			s.reply(client, Frame{Type: FrameError, Error: "only owners and editors may announce changes"})
+			return
+		}
+		if msg := validChangeData(f.Data); msg != "" {
+			s.reply(client, Frame{Type: FrameError, Error: msg})
+			return
+		}
+		data := make(map[string]any, len(f.Data)+2)
+		for k, v := range f.Data {
+			data[k] = v
+		}
+		data["userId"] = client.UserID
+		data["source"] = ChangeSource
+		if s.events == nil {
+			s.hub.Broadcast(client.TripID, Frame{Type: FrameChange, EventType: f.EventType, Data: data})
+			return
+		}
+		if _, err := s.events.Record(ctx, client.TripID, f.EventType, data); err != nil {
+			log.Printf("live trip %s: record %s: %v", client.TripID, f.EventType, err)
+			s.reply(client, Frame{Type: FrameError, Error: "could not record change"})
+		}
+	default:
+		s.reply(client, Frame{Type: FrameError, Error: "unsupported frame type"})
+	}
+}
+
+// validChangeData returns why data cannot be recorded, or "" when it can: it must be a small
+// object of short keys and scalar values, without the userId and source the server sets.
+func validChangeData(data map[string]any) string {
+	if len(data) > maxChangeFields {
+		return "too many data fields"
+	}
+	for k, v := range data {
+		if k == "" || len(k) > maxChangeKeyLen || k == "userId" || k == "source" {
+			return fmt.Sprintf("invalid data field %q", k)
+		}
+		switch v.(type) {
+		case nil, string, float64, bool:
+		default:
+			return fmt.Sprintf("data field %q must be a string, number, boolean or null", k)
+		}
+	}
+	if raw, _ := json.Marshal(data); len(raw) > maxChangeDataBytes {
+		return "data too large"
+	}
+	return ""
+}
+
+func (s *Server) reply(client *Client, f Frame) {
+	s.hub.sendTo(client, f)
+}
@@ -0,0 +1,253 @@
+// Package migrate applies the embedded SQL migrations in order and records each one in a
+// schema_migrations table, for both Postgres and SQLite.
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrPending = errors.New("database schema has pending migrations")
+	ErrNoDown  = errors.New("migration has no down script")
+	ErrUnknown = errors.New("database has migrations this binary does not know")
+)
+
+var fileRe = regexp.MustCompile(`^(\d+)_([a-z0-9_]+?)(\.down)?\.sql$`)
+
+// Migration is one NNN_name.sql file and, when present, its NNN_name.down.sql counterpart.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// ID is the file stem, e.g. "006_flight_watches".
+func (m Migration) ID() string {
+	return fmt.Sprintf("%03d_%s", m.Version, m.Name)
+}
+
+// Record is a row of schema_migrations.
+type Record struct {
+	Version   int
+	Name      string
+	AppliedAt time.Time
+}
+
+type Status struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"appliedAt,omitempty"`
+}
+
+// Driver is the database-specific half of the runner.
+type Driver interface {
+	// Lock serialises runners across processes until unlock is called.
+	Lock(ctx context.Context) (unlock func(), err error)
+	// Init creates schema_migrations if it does not exist.
+	Init(ctx context.Context) error
+	Applied(ctx context.Context) ([]Record, error)
+	// Apply runs script and records (up) or forgets (down) the migration in one transaction.
+	// An empty script only updates schema_migrations.
+	Apply(ctx context.Context, m Migration, script string, up bool) error
+}
+
+// Load reads the migrations in fsys, ordered by version.
+func Load(fsys fs.FS) ([]Migration, error) {
+	names, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int]*Migration)
+	for _, name := range names {
+		match := fileRe.FindStringSubmatch(name)
+		if match == nil {
+			return nil, fmt.Errorf("migration %s: name must look like 001_description.sql", name)
+		}
+		version, _ := strconv.Atoi(match[1])
+		raw, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %s: version %d is already used by %s", name, version, m.ID())
+		}
+		if match[3] != "" {
+			m.Down = string(raw)
+		} else {
+			m.Up = string(raw)
+		}
+	}
+	out := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %s: down script without an up script", m.ID())
+		}
+		out = append(out, *m)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
+
+type Runner struct {
+	driver     Driver
+	migrations []Migration
+}
+
+func NewRunner(driver Driver, migrations []Migration) *Runner {
+	return &Runner{driver: driver, migrations: migrations}
+}
+
+// Status lists every known migration plus any applied version missing from this binary.
+func (r *Runner) Status(ctx context.Context) ([]Status, error) {
+	applied, err := r.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Status, 0, len(r.migrations))
+	for _, m := range r.migrations {
+		s := Status{Version: m.Version, Name: m.Name}
+		if rec, ok := applied[m.Version]; ok {
+			at := rec.AppliedAt
+			s.Applied, s.AppliedAt = true, &at
+			delete(applied, m.Version)
+		}
+		out = append(out, s)
+	}
+	for _, rec := range applied {
+		at := rec.AppliedAt
+		out = append(out, Status{Version: rec.Version, Name: rec.Name, Applied: true, AppliedAt: &at})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
+
+// Check returns ErrPending or ErrUnknown unless the database matches this binary exactly.
+func (r *Runner) Check(ctx context.Context) error {
+	statuses, err := r.Status(ctx)
+	if err != nil {
+		return err
+	}
+	known := make(map[int]bool, len(r.migrations))
+	for _, m := range r.migrations {
+		known[m.Version] = true
+	}
+	pending, unknown := make([]string, 0), make([]string, 0)
+	for _, s := range statuses {
+		switch {
+		case !known[s.Version]:
+			unknown = append(unknown, fmt.Sprintf("%03d_%s", s.Version, s.Name))
+		case !s.Applied:
+			pending = append(pending, fmt.Sprintf("%03d_%s", s.Version, s.Name))
+		}
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("%w: %s", ErrUnknown, strings.Join(unknown, ", "))
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%w: %s", ErrPending, strings.Join(pending, ", "))
+	}
+	return nil
+}
+
+// Up applies every pending migration in order and returns the ones it ran.
+func (r *Runner) Up(ctx context.Context) ([]Migration, error) {
+	return r.locked(ctx, func(applied map[int]Record) ([]Migration, error) {
+		done := make([]Migration, 0)
+		for _, m := range r.migrations {
+			if _, ok := applied[m.Version]; ok {
+				continue
+			}
+			if err := r.driver.Apply(ctx, m, m.Up, true); err != nil {
+				return done, fmt.Errorf("apply %s: %w", m.ID(), err)
+			}
+			done = append(done, m)
+		}
+		return done, nil
+	})
+}
+
+// Down reverts the newest steps applied migrations and returns them, newest first.
+func (r *Runner) Down(ctx context.Context, steps int) ([]Migration, error) {
+	return r.locked(ctx, func(applied map[int]Record) ([]Migration, error) {
+		done := make([]Migration, 0)
+		for i := len(r.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+			m := r.migrations[i]
+			if _, ok := applied[m.Version]; !ok {
+				continue
+			}
+			if strings.TrimSpace(m.Down) == "" {
+				return done, fmt.Errorf("revert %s: %w", m.ID(), ErrNoDown)
+			}
+			if err := r.driver.Apply(ctx, m, m.Down, false); err != nil {
+				return done, fmt.Errorf("revert %s: %w", m.ID(), err)
+			}
+			done = append(done, m)
+		}
+		return done, nil
+	})
+}
+
+// Baseline marks every migration up to and including version as applied without running it,
+// for databases whose schema was created by hand before the runner existed.
+func (r *Runner) Baseline(ctx context.Context, version int) ([]Migration, error) {
+	return r.locked(ctx, func(applied map[int]Record) ([]Migration, error) {
+		done := make([]Migration, 0)
+		for _, m := range r.migrations {
+			if m.Version > version {
+				break
+			}
+			if _, ok := applied[m.Version]; ok {
+				continue
+			}
+			if err := r.driver.Apply(ctx, m, "", true); err != nil {
+				return done, fmt.Errorf("baseline %s: %w", m.ID(), err)
+			}
+			done = append(done, m)
+		}
+		return done, nil
+	})
+}
+
+func (r *Runner) locked(ctx context.Context, fn func(applied map[int]Record) ([]Migration, error)) ([]Migration, error) {
+	unlock, err := r.driver.Lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	// Read after taking the lock so a runner that waited sees what the previous one applied.
+	applied, err := r.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return fn(applied)
+}
+
+func (r *Runner) applied(ctx context.Context) (map[int]Record, error) {
+	if err := r.driver.Init(ctx); err != nil {
+		return nil, err
+	}
+	records, err := r.driver.Applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[int]Record, len(records))
+	for _, rec := range records {
+		out[rec.Version] = rec
+	}
+	return out, nil
+}
@@ -0,0 +1,151 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"triploom/backend/internal/store"
+	"triploom/backend/migrations"
+)
+
+func TestLoadPairsUpAndDownScripts(t *testing.T) {
+	all, err := Load(fstest.MapFS{
+		"002_second.sql":      {Data: []byte("CREATE TABLE b (id TEXT);")},
+		"001_first.sql":       {Data: []byte("CREATE TABLE a (id TEXT);")},
+		"001_first.down.sql":  {Data: []byte("DROP TABLE a;")},
+		"002_second.down.sql": {Data: []byte("DROP TABLE b;")},
+	})
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(all) != 2 || all[0].ID() != "001_first" || all[0].Down != "DROP TABLE a;" || all[1].Version != 2 {
+		t.Fatalf("unexpected migrations %+v", all)
+	}
+
+	if _, err := Load(fstest.MapFS{"001_a.sql": {}, "001_b.sql": {}}); err == nil {
+		t.Fatalf("expected duplicate versions to be rejected")
+	}
+	if _, err := Load(fstest.MapFS{"init.sql": {}}); err == nil {
+		t.Fatalf("expected unnumbered files to be rejected")
+	}
+}
+
+func TestSQLiteStatementsDropPostgresOnlyDDL(t *testing.T) {
+	stmts := SQLiteStatements(`
+		-- trips
+		CREATE TABLE t (id TEXT, tags TEXT[], at TIMESTAMPTZ NOT NULL DEFAULT NOW(), day DATE);
+		ALTER TABLE t ENABLE ROW LEVEL SECURITY;
+		CREATE POLICY p ON t FOR SELECT USING (true);
+		DROP POLICY IF EXISTS p ON t;
+		COMMENT ON TABLE t IS 'semicolons; inside quotes';`)
+	if len(stmts) != 1 {
+		t.Fatalf("expected one statement, got %q", stmts)
+	}
+	want := `CREATE TABLE t (id TEXT, tags TEXT, at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%S', 'now') || '.' || substr(strftime('%f', 'now'), 4) || '000000Z'), day TEXT)`
+	if stmts[0] != want {
+		t.Fatalf("unexpected translation\n got: %s\nwant: %s", stmts[0], want)
+	}
+
+	// Defaulted timestamps must have the width of the ones written from Go, or they sort apart.
+	ctx := context.Background()
+	db, err := store.OpenSQLite(ctx, filepath.Join(t.TempDir(), "default.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	var at string
+	if _, err := db.ExecContext(ctx, stmts[0]); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if err := db.QueryRowContext(ctx, `INSERT INTO t (id) VALUES ('a') RETURNING at`).Scan(&at); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	if _, err := time.Parse(sqliteTimeLayout, at); err != nil || len(at) != len(sqliteTimeLayout) {
+		t.Fatalf("expected a %s timestamp, got %q: %v", sqliteTimeLayout, at, err)
+	}
+}
+
+func TestRunnerAgainstSQLite(t *testing.T) {
+	ctx := context.Background()
+	db, err := store.OpenSQLite(ctx, filepath.Join(t.TempDir(), "triploom.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	all, err := Load(migrations.FS)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	runner := NewRunner(NewSQLite(db), all)
+
+	if err := runner.Check(ctx); !errors.Is(err, ErrPending) {
+		t.Fatalf("expected a fresh database to have pending migrations, got %v", err)
+	}
+	applied, err := runner.Up(ctx)
+	if err != nil || len(applied) != len(all) {
+		t.Fatalf("up applied %d of %d: %v", len(applied), len(all), err)
+	}
+	if err := runner.Check(ctx); err != nil {
+		t.Fatalf("expected schema to be current, got %v", err)
+	}
+	if again, _ := runner.Up(ctx); len(again) != 0 {
+		t.Fatalf("expected up to be idempotent, got %+v", again)
+	}
+
+	// Steps back past 008, the migration that creates event_bus_messages.
+	steps := len(all) - 7
+	reverted, err := runner.Down(ctx, steps)
+	if err != nil || len(reverted) != steps || reverted[0].Version != all[len(all)-1].Version {
+		t.Fatalf("unexpected down result %+v: %v", reverted, err)
+	}
+	if _, err := db.ExecContext(ctx, `SELECT 1 FROM event_bus_messages`); err == nil {
+		t.Fatalf("expected event_bus_messages to be dropped")
+	}
+	statuses, _ := runner.Status(ctx)
+	if last := statuses[len(statuses)-1]; last.Applied || statuses[0].AppliedAt == nil {
+		t.Fatalf("unexpected status %+v", statuses)
+	}
+
+	if _, err := runner.Down(ctx, len(all)); err != nil {
+		t.Fatalf("down to empty: %v", err)
+	}
+	if applied, err := runner.Up(ctx); err != nil || len(applied) != len(all) {
+		t.Fatalf("expected a full round trip, got %d: %v", len(applied), err)
+	}
+
+	ahead := NewRunner(NewSQLite(db), all[:len(all)-1])
+	if err := ahead.Check(ctx); !errors.Is(err, ErrUnknown) {
+		t.Fatalf("expected an older binary to notice newer migrations, got %v", err)
+	}
+}
+
+func TestBaselineMarksWithoutRunning(t *testing.T) {
+	ctx := context.Background()
+	db, err := store.OpenSQLite(ctx, filepath.Join(t.TempDir(), "triploom.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	runner := NewRunner(NewSQLite(db), []Migration{
+		{Version: 1, Name: "first", Up: "CREATE TABLE a (id TEXT);"},
+		{Version: 2, Name: "second", Up: "CREATE TABLE b (id TEXT);"},
+	})
+
+	marked, err := runner.Baseline(ctx, 1)
+	if err != nil || len(marked) != 1 {
+		t.Fatalf("baseline: %+v %v", marked, err)
+	}
+	if _, err := db.ExecContext(ctx, `SELECT 1 FROM a`); err == nil {
+		t.Fatalf("expected baseline not to run the script")
+	}
+	if applied, err := runner.Up(ctx); err != nil || len(applied) != 1 || applied[0].Version != 2 {
+		t.Fatalf("expected only the second migration to run, got %+v %v", applied, err)
+	}
+	if _, err := runner.Down(ctx, 1); !errors.Is(err, ErrNoDown) {
+		t.Fatalf("expected ErrNoDown, got %v", err)
+	}
+}
@@ -0,0 +1,90 @@
+package migrate
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// lockName is hashed into the advisory lock key shared by every runner on the database.
+const lockName = "triploom_schema_migrations"
+
+type Postgres struct {
+	db *pgxpool.Pool
+}
+
+func NewPostgres(db *pgxpool.Pool) *Postgres {
+	return &Postgres{db: db}
+}
+
+// Lock holds a session-level advisory lock on a dedicated connection, so replicas starting
+// together apply pending migrations exactly once.
+func (p *Postgres) Lock(ctx context.Context) (func(), error) {
+	conn, err := p.db.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock(hashtext($1))`, lockName); err != nil {
+		conn.Release()
+		return nil, err
+	}
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_, _ = conn.Exec(ctx, `SELECT pg_advisory_unlock(hashtext($1))`, lockName)
+		conn.Release()
+	}, nil
+}
+
+func (p *Postgres) Init(ctx context.Context) error {
+	const q = `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+		  version INT PRIMARY KEY,
+		  name TEXT NOT NULL,
+		  applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)`
+	_, err := p.db.Exec(ctx, q)
+	return err
+}
+
+func (p *Postgres) Applied(ctx context.Context) ([]Record, error) {
+	rows, err := p.db.Query(ctx, `SELECT version, name, applied_at FROM schema_migrations ORDER BY version`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]Record, 0)
+	for rows.Next() {
+		var rec Record
+		if err := rows.Scan(&rec.Version, &rec.Name, &rec.AppliedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, rec)
+	}
+	return out, rows.Err()
+}
+
+func (p *Postgres) Apply(ctx context.Context, m Migration, script string, up bool) error {
+	tx, err := p.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if script != "" {
+		// Without arguments pgx uses the simple protocol, which accepts a multi-statement script.
+		if _, err := tx.Exec(ctx, script); err != nil {
+			return err
+		}
+	}
+	if up {
+		_, err = tx.Exec(ctx, `INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, NOW())`, m.Version, m.Name)
+	} else {
+		_, err = tx.Exec(ctx, `DELETE FROM schema_migrations WHERE version = $1`, m.Version)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
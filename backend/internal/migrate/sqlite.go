@@ -0,0 +1,145 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// sqliteTimeLayout matches the fixed-width timestamps the SQLite repositories write.
+const sqliteTimeLayout = "2006-01-02T15:04:05.000000000Z"
+
+// SQLite runs the same Postgres migrations, translated by SQLiteStatements.
+type SQLite struct {
+	db *sql.DB
+}
+
+func NewSQLite(db *sql.DB) *SQLite {
+	return &SQLite{db: db}
+}
+
+// Lock is a no-op: a SQLite file belongs to a single API process, and each migration runs
+// in a transaction that holds SQLite's write lock anyway.
+func (s *SQLite) Lock(context.Context) (func(), error) {
+	return func() {}, nil
+}
+
+func (s *SQLite) Init(ctx context.Context) error {
+	const q = `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+		  version INTEGER PRIMARY KEY,
+		  name TEXT NOT NULL,
+		  applied_at TEXT NOT NULL
+		)`
+	_, err := s.db.ExecContext(ctx, q)
+	return err
+}
+
+func (s *SQLite) Applied(ctx context.Context) ([]Record, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT version, name, applied_at FROM schema_migrations ORDER BY version`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]Record, 0)
+	for rows.Next() {
+		var rec Record
+		var at string
+		if err := rows.Scan(&rec.Version, &rec.Name, &at); err != nil {
+			return nil, err
+		}
+		rec.AppliedAt, _ = time.Parse(time.RFC3339Nano, at)
+		out = append(out, rec)
+	}
+	return out, rows.Err()
+}
+
+func (s *SQLite) Apply(ctx context.Context, m Migration, script string, up bool) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range SQLiteStatements(script) {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	if up {
+		_, err = tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)`, m.Version, m.Name, time.Now().UTC().Format(sqliteTimeLayout))
+	} else {
+		_, err = tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = $1`, m.Version)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+var (
+	sqlComment     = regexp.MustCompile(`(?m)--.*$`)
+	pgOnlyPrefixes = []string{"CREATE POLICY", "DROP POLICY", "COMMENT ON", "CREATE EXTENSION", "GRANT ", "REVOKE "}
+	pgRLS          = regexp.MustCompile(`(?i)^ALTER TABLE \S+ (ENABLE|DISABLE|FORCE) ROW LEVEL SECURITY$`)
+	// Full-text columns (named *_tsv) and their GIN indexes; SQLite search scans with LIKE.
+	pgFullText = regexp.MustCompile(`(?i)\btsvector\b|\bUSING GIN\b|\w+_tsv\b`)
+	// DATE columns would be decoded to time.Time by the driver; keep them as plain YYYY-MM-DD text.
+	pgDate  = regexp.MustCompile(`\bDATE\b`)
+	pgTypes = strings.NewReplacer(
+		"JSONB", "TEXT",
+		"TIMESTAMPTZ", "TEXT",
+		"TEXT[]", "TEXT",
+		// strftime's %f has milliseconds; pad them to the nine digits of sqliteTimeLayout so
+		// defaulted timestamps sort with the ones the repositories write.
+		"DEFAULT NOW()", "DEFAULT (strftime('%Y-%m-%dT%H:%M:%S', 'now') || '.' || substr(strftime('%f', 'now'), 4) || '000000Z')",
+		// SQLite has no IF [NOT] EXISTS for columns; the runner only applies each file once.
+		"ADD COLUMN IF NOT EXISTS", "ADD COLUMN",
+		"DROP COLUMN IF EXISTS", "DROP COLUMN",
+	)
+)
+
+// SQLiteStatements splits a Postgres migration into statements SQLite can run: row level
+// security, policies, comments and full-text indexing are dropped (the API enforces
+// membership itself) and JSONB, TIMESTAMPTZ, DATE and array columns become TEXT.
+func SQLiteStatements(pgSQL string) []string {
+	out := make([]string, 0)
+	for _, stmt := range splitStatements(sqlComment.ReplaceAllString(pgSQL, "")) {
+		stmt = strings.Join(strings.Fields(stmt), " ")
+		if stmt == "" || pgRLS.MatchString(stmt) || pgFullText.MatchString(stmt) {
+			continue
+		}
+		upper := strings.ToUpper(stmt)
+		skip := false
+		for _, prefix := range pgOnlyPrefixes {
+			if strings.HasPrefix(upper, prefix) {
+				skip = true
+				break
+			}
+		}
+		if !skip {
+			out = append(out, pgDate.ReplaceAllString(pgTypes.Replace(stmt), "TEXT"))
+		}
+	}
+	return out
+}
+
+// splitStatements splits on semicolons outside single-quoted literals.
+func splitStatements(script string) []string {
+	out := make([]string, 0)
+	var b strings.Builder
+	quoted := false
+	for _, r := range script {
+		switch {
+		case r == '\'':
+			quoted = !quoted
+		case r == ';' && !quoted:
+			out = append(out, b.String())
+			b.Reset()
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return append(out, b.String())
+}
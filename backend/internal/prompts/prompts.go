@@ -0,0 +1,216 @@
+// Package prompts holds the assistant's system prompts as versioned text/template files and
+// assigns each user to one version of a prompt, so that wording can change (and be A/B
+// tested) without touching Go code.
+package prompts
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// bundled holds the default templates, named <name>.v<N>.tmpl.
+//
+//go:embed templates/*.tmpl
+var bundled embed.FS
+
+// Definition is the source of one template version, from a file or the database.
+type Definition struct {
+	Name    string
+	Version string
+	Body    string
+	// Weight is the share of users assigned to this version. When every version of a name
+	// has weight 0, the newest version serves everyone.
+	Weight int
+}
+
+// ID is the name@version recorded with each answer.
+func (d Definition) ID() string {
+	return d.Name + "@" + d.Version
+}
+
+// Template is a parsed Definition.
+type Template struct {
+	Definition
+	tmpl *template.Template
+}
+
+// Render executes the template with data.
+func (t *Template) Render(data any) (string, error) {
+	var b bytes.Buffer
+	if err := t.tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("render prompt %s: %w", t.ID(), err)
+	}
+	return b.String(), nil
+}
+
+// Registry serves the current template versions. It is safe for concurrent use and can be
+// replaced wholesale while serving.
+type Registry struct {
+	mu     sync.RWMutex
+	byName map[string][]*Template
+}
+
+var (
+	fileName    = regexp.MustCompile(`^([a-z0-9_-]+)\.(v[0-9]+)\.tmpl$`)
+	weightLine  = regexp.MustCompile(`^\{\{-?\s*/\*\s*weight:\s*([0-9]+)\s*\*/\s*-?\}\}`)
+	versionLike = regexp.MustCompile(`^v[0-9]+$`)
+)
+
+// Bundled returns the definitions compiled into the binary.
+func Bundled() []Definition {
+	defs, err := ParseFS(bundled)
+	if err != nil {
+		panic(err)
+	}
+	return defs
+}
+
+// Default returns a registry of the bundled templates.
+func Default() *Registry {
+	r, err := New(Bundled())
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// ParseFS reads every <name>.v<N>.tmpl file in fsys, recursively. A first line of the form
+// {{/* weight: 50 */}} sets the version's weight.
+func ParseFS(fsys fs.FS) ([]Definition, error) {
+	defs := make([]Definition, 0)
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || path.Ext(p) != ".tmpl" {
+			return err
+		}
+		m := fileName.FindStringSubmatch(path.Base(p))
+		if m == nil {
+			return fmt.Errorf("prompt file %s: want <name>.v<N>.tmpl", p)
+		}
+		body, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		def := Definition{Name: m[1], Version: m[2], Body: string(body)}
+		if w := weightLine.FindStringSubmatch(def.Body); w != nil {
+			def.Weight, _ = strconv.Atoi(w[1])
+		}
+		defs = append(defs, def)
+		return nil
+	})
+	return defs, err
+}
+
+// Pin returns defs with the version id (name@vN) serving every user of its name, for
+// comparing one version against another offline.
+func Pin(defs []Definition, id string) ([]Definition, error) {
+	name, _, _ := strings.Cut(id, "@")
+	found := false
+	out := make([]Definition, len(defs))
+	for i, def := range defs {
+		if def.Name == name {
+			def.Weight = 0
+			if def.ID() == id {
+				def.Weight = 1
+				found = true
+			}
+		}
+		out[i] = def
+	}
+	if !found {
+		return nil, fmt.Errorf("prompt %s is not defined", id)
+	}
+	return out, nil
+}
+
+// New parses defs into a registry. A later definition replaces an earlier one with the same
+// name and version, so callers list bundled files first and overrides after them.
+func New(defs []Definition) (*Registry, error) {
+	r := &Registry{}
+	if err := r.Replace(defs); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Replace swaps in a new set of definitions. On error the registry keeps serving the old set.
+func (r *Registry) Replace(defs []Definition) error {
+	latest := make(map[string]Definition)
+	for _, def := range defs {
+		if def.Name == "" || !versionLike.MatchString(def.Version) || def.Weight < 0 {
+			return fmt.Errorf("prompt %s: invalid name, version or weight", def.ID())
+		}
+		latest[def.ID()] = def
+	}
+	byName := make(map[string][]*Template)
+	for _, def := range latest {
+		tmpl, err := template.New(def.ID()).Option("missingkey=error").Parse(def.Body)
+		if err != nil {
+			return fmt.Errorf("parse prompt %s: %w", def.ID(), err)
+		}
+		byName[def.Name] = append(byName[def.Name], &Template{Definition: def, tmpl: tmpl})
+	}
+	for _, versions := range byName {
+		sort.Slice(versions, func(i, j int) bool {
+			return versionNumber(versions[i].Version) < versionNumber(versions[j].Version)
+		})
+	}
+	r.mu.Lock()
+	r.byName = byName
+	r.mu.Unlock()
+	return nil
+}
+
+// Versions lists the loaded versions of name, oldest first.
+func (r *Registry) Versions(name string) []Definition {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]Definition, 0, len(r.byName[name]))
+	for _, t := range r.byName[name] {
+		out = append(out, t.Definition)
+	}
+	return out
+}
+
+// Pick returns the version of name that userID is assigned to. Assignment hashes the user
+// and prompt name, so a user keeps their variant across requests and instances for as long
+// as the weights stay the same.
+func (r *Registry) Pick(name, userID string) (*Template, error) {
+	r.mu.RLock()
+	versions := r.byName[name]
+	r.mu.RUnlock()
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("prompt %q is not loaded", name)
+	}
+	total := 0
+	for _, t := range versions {
+		total += t.Weight
+	}
+	if total == 0 {
+		return versions[len(versions)-1], nil
+	}
+	h := fnv.New32a()
+	h.Write([]byte(name + "\x00" + userID))
+	bucket := int(h.Sum32() % uint32(total))
+	for _, t := range versions {
+		if bucket < t.Weight {
+			return t, nil
+		}
+		bucket -= t.Weight
+	}
+	return versions[len(versions)-1], nil
+}
+
+func versionNumber(v string) int {
+	n, _ := strconv.Atoi(strings.TrimPrefix(v, "v"))
+	return n
+}
@@ -0,0 +1,119 @@
+package prompts
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestBundledTemplatesRender(t *testing.T) {
+	r := Default()
+	for _, name := range []string{"copilot", "planner"} {
+		tmpl, err := r.Pick(name, "alice")
+		if err != nil {
+			t.Fatalf("pick %s: %v", name, err)
+		}
+		out, err := tmpl.Render(struct {
+			PageKey, ContextJSON string
+			Degraded             bool
+		}{"flights", `{"trip":{}}`, true})
+		if err != nil {
+			t.Fatalf("render %s: %v", name, err)
+		}
+		if !strings.Contains(out, `{"trip":{}}`) || !strings.HasSuffix(out, "DegradedMode:\ntrue\n") {
+			t.Fatalf("unexpected %s prompt:\n%s", name, out)
+		}
+	}
+	copilot, _ := r.Pick("copilot", "alice")
+	out, _ := copilot.Render(struct {
+		PageKey, ContextJSON string
+		Degraded             bool
+	}{"hotels", "{}", false})
+	if !strings.Contains(out, "Page playbook:\n- Hotels:") || !strings.HasPrefix(out, "You are TripLoom AI Copilot.") {
+		t.Fatalf("expected the hotels playbook, got:\n%s", out)
+	}
+}
+
+func TestParseFSAndOverrides(t *testing.T) {
+	defs, err := ParseFS(fstest.MapFS{
+		"copilot.v2.tmpl":     {Data: []byte("{{/* weight: 30 */ -}}\nv2 {{.Name}}")},
+		"sub/copilot.v3.tmpl": {Data: []byte("v3 {{.Name}}")},
+		"README.md":           {Data: []byte("ignored")},
+	})
+	if err != nil || len(defs) != 2 {
+		t.Fatalf("parse: %+v %v", defs, err)
+	}
+	if _, err := ParseFS(fstest.MapFS{"Copilot-final.tmpl": {}}); err == nil {
+		t.Fatalf("expected badly named files to be rejected")
+	}
+
+	r, err := New(append(defs, Definition{Name: "copilot", Version: "v3", Body: "db v3 {{.Name}}", Weight: 70}))
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	versions := r.Versions("copilot")
+	if len(versions) != 2 || versions[0].Weight != 30 || versions[1].Body != "db v3 {{.Name}}" {
+		t.Fatalf("expected the later v3 to win, got %+v", versions)
+	}
+
+	if err := r.Replace([]Definition{{Name: "copilot", Version: "v4", Body: "{{.Broken"}}); err == nil {
+		t.Fatalf("expected a parse error")
+	}
+	if len(r.Versions("copilot")) != 2 {
+		t.Fatalf("expected a failed replace to keep the old set")
+	}
+	if _, err := r.Pick("missing", "alice"); err == nil {
+		t.Fatalf("expected an error for an unknown prompt")
+	}
+}
+
+func TestPickIsStickyAndWeighted(t *testing.T) {
+	r, _ := New([]Definition{
+		{Name: "copilot", Version: "v1", Body: "a", Weight: 75},
+		{Name: "copilot", Version: "v2", Body: "b", Weight: 25},
+	})
+	counts := map[string]int{}
+	for i := 0; i < 2000; i++ {
+		user := fmt.Sprintf("user-%d", i)
+		first, _ := r.Pick("copilot", user)
+		again, _ := r.Pick("copilot", user)
+		if first.ID() != again.ID() {
+			t.Fatalf("expected %s to keep their variant", user)
+		}
+		counts[first.Version]++
+	}
+	if counts["v1"] < 1350 || counts["v1"] > 1650 {
+		t.Fatalf("expected roughly a 75/25 split, got %v", counts)
+	}
+
+	// Without weights the newest version serves everyone.
+	r, _ = New([]Definition{{Name: "copilot", Version: "v9", Body: "a"}, {Name: "copilot", Version: "v10", Body: "b"}})
+	if got, _ := r.Pick("copilot", "alice"); got.Version != "v10" {
+		t.Fatalf("expected v10, got %s", got.Version)
+	}
+}
+
+func TestPin(t *testing.T) {
+	defs := []Definition{
+		{Name: "copilot", Version: "v1", Body: "one", Weight: 90},
+		{Name: "copilot", Version: "v2", Body: "two", Weight: 10},
+		{Name: "planner", Version: "v1", Body: "plan", Weight: 5},
+	}
+	pinned, err := Pin(defs, "copilot@v2")
+	if err != nil {
+		t.Fatalf("pin: %v", err)
+	}
+	if pinned[0].Weight != 0 || pinned[1].Weight != 1 || pinned[2].Weight != 5 || defs[0].Weight != 90 {
+		t.Fatalf("unexpected weights %+v (input %+v)", pinned, defs)
+	}
+	r, _ := New(pinned)
+	for _, user := range []string{"alice", "bob", "carol"} {
+		if tmpl, _ := r.Pick("copilot", user); tmpl.Version != "v2" {
+			t.Fatalf("expected every user on v2, %s got %s", user, tmpl.Version)
+		}
+	}
+	if _, err := Pin(defs, "copilot@v9"); err == nil {
+		t.Fatalf("expected an unknown version to be rejected")
+	}
+}
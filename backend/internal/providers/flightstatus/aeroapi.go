@@ -0,0 +1,256 @@
+package flightstatus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const defaultAeroAPIBaseURL = "https://aeroapi.flightaware.com/aeroapi"
+
+// AeroAPI looks flights up on FlightAware AeroAPI. Live flights come from /flights/{ident};
+// dates with no live record yet fall back to /schedules.
+type AeroAPI struct {
+	baseURL string
+	apiKey  string
+	http    *http.Client
+}
+
+func NewAeroAPI(baseURL, apiKey string, httpClient *http.Client) *AeroAPI {
+	if strings.TrimSpace(baseURL) == "" {
+		baseURL = defaultAeroAPIBaseURL
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &AeroAPI{baseURL: strings.TrimRight(baseURL, "/"), apiKey: apiKey, http: httpClient}
+}
+
+func (a *AeroAPI) Name() string { return "aeroapi" }
+
+type aeroAirport struct {
+	Code     string `json:"code"`
+	CodeIATA string `json:"code_iata"`
+	Name     string `json:"name"`
+	Timezone string `json:"timezone"`
+}
+
+type aeroFlight struct {
+	Ident               string       `json:"ident"`
+	IdentIATA           string       `json:"ident_iata"`
+	Operator            string       `json:"operator"`
+	OperatorIATA        string       `json:"operator_iata"`
+	Origin              *aeroAirport `json:"origin"`
+	Destination         *aeroAirport `json:"destination"`
+	ScheduledOut        string       `json:"scheduled_out"`
+	EstimatedOut        string       `json:"estimated_out"`
+	ActualOut           string       `json:"actual_out"`
+	ScheduledIn         string       `json:"scheduled_in"`
+	EstimatedIn         string       `json:"estimated_in"`
+	ActualIn            string       `json:"actual_in"`
+	DepartureDelay      int          `json:"departure_delay"`
+	GateOrigin          string       `json:"gate_origin"`
+	GateDestination     string       `json:"gate_destination"`
+	TerminalOrigin      string       `json:"terminal_origin"`
+	TerminalDestination string       `json:"terminal_destination"`
+	Cancelled           bool         `json:"cancelled"`
+	Diverted            bool         `json:"diverted"`
+	Status              string       `json:"status"`
+}
+
+type aeroSchedule struct {
+	Ident           string `json:"ident"`
+	IdentIATA       string `json:"ident_iata"`
+	ActualIdentIATA string `json:"actual_ident_iata"`
+	OriginIATA      string `json:"origin_iata"`
+	DestinationIATA string `json:"destination_iata"`
+	ScheduledOut    string `json:"scheduled_out"`
+	ScheduledIn     string `json:"scheduled_in"`
+}
+
+func (a *AeroAPI) Lookup(ctx context.Context, flightNumber, departureDate string) (*FlightStatus, error) {
+	normalized, day, err := validateInput(flightNumber, departureDate)
+	if err != nil {
+		return nil, err
+	}
+
+	q := url.Values{}
+	q.Set("start", day.Format(time.RFC3339))
+	q.Set("end", day.Add(24*time.Hour).Format(time.RFC3339))
+	var flights struct {
+		Flights []aeroFlight `json:"flights"`
+	}
+	if err := a.get(ctx, "/flights/"+url.PathEscape(normalized)+"?"+q.Encode(), &flights); err != nil {
+		return nil, err
+	}
+	if best := pickAeroFlight(flights.Flights, normalized, day); best != nil {
+		return aeroFlightToStatus(best, normalized), nil
+	}
+
+	carrier, number := SplitFlightNumber(normalized)
+	if carrier == "" {
+		return nil, ErrNotFound
+	}
+	sq := url.Values{}
+	sq.Set("airline", carrier)
+	sq.Set("flight_number", number)
+	sq.Set("max_pages", "1")
+	var schedules struct {
+		Scheduled []aeroSchedule `json:"scheduled"`
+	}
+	path := fmt.Sprintf("/schedules/%s/%s?%s", departureDate, day.Add(24*time.Hour).Format("2006-01-02"), sq.Encode())
+	if err := a.get(ctx, path, &schedules); err != nil {
+		return nil, err
+	}
+	if best := pickAeroSchedule(schedules.Scheduled, normalized, day); best != nil {
+		return aeroScheduleToStatus(best, normalized), nil
+	}
+	return nil, ErrNotFound
+}
+
+func (a *AeroAPI) get(ctx context.Context, path string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("x-apikey", a.apiKey)
+	req.Header.Set("Accept", "application/json")
+	res, err := a.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	body, _ := io.ReadAll(res.Body)
+	if res.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("aeroapi error (%d): %s", res.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return json.Unmarshal(body, out)
+}
+
+// pickAeroFlight returns the earliest row for flightNumber departing on day (UTC, like the
+// query window), or nil when none is: another flight's status must never stand in for it.
+func pickAeroFlight(rows []aeroFlight, flightNumber string, day time.Time) *aeroFlight {
+	candidates := make([]aeroFlight, 0, len(rows))
+	for _, r := range rows {
+		if (NormalizeFlightNumber(r.IdentIATA) == flightNumber || NormalizeFlightNumber(r.Ident) == flightNumber) &&
+			onDay(firstNonEmpty(r.ScheduledOut, r.EstimatedOut, r.ActualOut), day) {
+			candidates = append(candidates, r)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return sortKey(firstNonEmpty(candidates[i].ScheduledOut, candidates[i].EstimatedOut, candidates[i].ActualOut)).
+			Before(sortKey(firstNonEmpty(candidates[j].ScheduledOut, candidates[j].EstimatedOut, candidates[j].ActualOut)))
+	})
+	return &candidates[0]
+}
+
+// pickAeroSchedule is pickAeroFlight for /schedules rows, whose window spans two days.
+func pickAeroSchedule(rows []aeroSchedule, flightNumber string, day time.Time) *aeroSchedule {
+	candidates := make([]aeroSchedule, 0, len(rows))
+	for _, r := range rows {
+		if (NormalizeFlightNumber(r.IdentIATA) == flightNumber || NormalizeFlightNumber(r.ActualIdentIATA) == flightNumber) && onDay(r.ScheduledOut, day) {
+			candidates = append(candidates, r)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return sortKey(candidates[i].ScheduledOut).Before(sortKey(candidates[j].ScheduledOut))
+	})
+	return &candidates[0]
+}
+
+func aeroFlightToStatus(f *aeroFlight, flightNumber string) *FlightStatus {
+	dep := Endpoint{
+		Terminal:  f.TerminalOrigin,
+		Gate:      f.GateOrigin,
+		Scheduled: parseTime(f.ScheduledOut),
+		Estimated: parseTime(f.EstimatedOut),
+		Actual:    parseTime(f.ActualOut),
+	}
+	if f.Origin != nil {
+		dep.IATA = strings.ToUpper(firstNonEmpty(f.Origin.CodeIATA, f.Origin.Code))
+		dep.AirportName = f.Origin.Name
+		dep.Timezone = f.Origin.Timezone
+	}
+	arr := Endpoint{
+		Terminal:  f.TerminalDestination,
+		Gate:      f.GateDestination,
+		Scheduled: parseTime(f.ScheduledIn),
+		Estimated: parseTime(f.EstimatedIn),
+		Actual:    parseTime(f.ActualIn),
+	}
+	if f.Destination != nil {
+		arr.IATA = strings.ToUpper(firstNonEmpty(f.Destination.CodeIATA, f.Destination.Code))
+		arr.AirportName = f.Destination.Name
+		arr.Timezone = f.Destination.Timezone
+	}
+
+	code := strings.ToUpper(f.OperatorIATA)
+	if code == "" {
+		code, _ = SplitFlightNumber(firstNonEmpty(f.IdentIATA, f.Ident, flightNumber))
+	}
+	status := &FlightStatus{
+		FlightNumber: firstNonEmpty(f.IdentIATA, f.Ident, flightNumber),
+		Airline:      firstNonEmpty(f.Operator, code, "Airline"),
+		AirlineCode:  code,
+		Status:       aeroStatus(f),
+		Departure:    dep,
+		Arrival:      arr,
+		DelayMinutes: departureDelayMinutes(f.DepartureDelay/60, dep),
+		Cancelled:    f.Cancelled,
+		Provider:     "aeroapi",
+	}
+	return status
+}
+
+func aeroScheduleToStatus(s *aeroSchedule, flightNumber string) *FlightStatus {
+	number := firstNonEmpty(s.IdentIATA, s.Ident, flightNumber)
+	code, _ := SplitFlightNumber(number)
+	return &FlightStatus{
+		FlightNumber: number,
+		Airline:      firstNonEmpty(code, "Airline"),
+		AirlineCode:  code,
+		Status:       StatusScheduled,
+		Departure:    Endpoint{IATA: strings.ToUpper(s.OriginIATA), Scheduled: parseTime(s.ScheduledOut)},
+		Arrival:      Endpoint{IATA: strings.ToUpper(s.DestinationIATA), Scheduled: parseTime(s.ScheduledIn)},
+		Provider:     "aeroapi",
+	}
+}
+
+func aeroStatus(f *aeroFlight) string {
+	switch {
+	case f.Cancelled:
+		return StatusCancelled
+	case f.Diverted:
+		return StatusDiverted
+	case f.ActualIn != "":
+		return StatusLanded
+	case f.ActualOut != "":
+		return StatusActive
+	case f.ScheduledOut != "":
+		return StatusScheduled
+	}
+	return StatusUnknown
+}
+
+func sortKey(raw string) time.Time {
+	if t := parseTime(raw); t != nil {
+		return *t
+	}
+	return time.Unix(1<<62, 0)
+}
@@ -0,0 +1,174 @@
+package flightstatus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+const defaultAviationstackBaseURL = "https://api.aviationstack.com/v1"
+
+type Aviationstack struct {
+	baseURL   string
+	accessKey string
+	http      *http.Client
+}
+
+func NewAviationstack(baseURL, accessKey string, httpClient *http.Client) *Aviationstack {
+	if strings.TrimSpace(baseURL) == "" {
+		baseURL = defaultAviationstackBaseURL
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Aviationstack{baseURL: strings.TrimRight(baseURL, "/"), accessKey: accessKey, http: httpClient}
+}
+
+func (a *Aviationstack) Name() string { return "aviationstack" }
+
+type aviationNode struct {
+	Airport   string `json:"airport"`
+	IATA      string `json:"iata"`
+	Timezone  string `json:"timezone"`
+	Terminal  string `json:"terminal"`
+	Gate      string `json:"gate"`
+	Delay     *int   `json:"delay"`
+	Scheduled string `json:"scheduled"`
+	Estimated string `json:"estimated"`
+	Actual    string `json:"actual"`
+}
+
+type aviationRow struct {
+	FlightDate   string `json:"flight_date"`
+	FlightStatus string `json:"flight_status"`
+	Airline      struct {
+		Name string `json:"name"`
+		IATA string `json:"iata"`
+	} `json:"airline"`
+	Flight struct {
+		IATA   string `json:"iata"`
+		Number string `json:"number"`
+	} `json:"flight"`
+	Departure aviationNode `json:"departure"`
+	Arrival   aviationNode `json:"arrival"`
+}
+
+func (a *Aviationstack) Lookup(ctx context.Context, flightNumber, departureDate string) (*FlightStatus, error) {
+	normalized, _, err := validateInput(flightNumber, departureDate)
+	if err != nil {
+		return nil, err
+	}
+
+	q := url.Values{}
+	q.Set("access_key", a.accessKey)
+	q.Set("flight_iata", normalized)
+	q.Set("flight_date", departureDate)
+	q.Set("limit", "20")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.baseURL+"/flights?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := a.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	body, _ := io.ReadAll(res.Body)
+	var payload struct {
+		Data  []aviationRow `json:"data"`
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	_ = json.Unmarshal(body, &payload)
+	if res.StatusCode >= 300 || payload.Error != nil {
+		msg := strings.TrimSpace(string(body))
+		if payload.Error != nil && payload.Error.Message != "" {
+			msg = payload.Error.Message
+		}
+		return nil, fmt.Errorf("aviationstack error (%d): %s", res.StatusCode, msg)
+	}
+
+	best := pickAviationRow(payload.Data, normalized, departureDate)
+	if best == nil {
+		return nil, ErrNotFound
+	}
+	return aviationRowToStatus(best, normalized), nil
+}
+
+// pickAviationRow returns the earliest row for flightNumber on departureDate, or nil when
+// none matches both.
+func pickAviationRow(rows []aviationRow, flightNumber, departureDate string) *aviationRow {
+	candidates := make([]aviationRow, 0, len(rows))
+	for _, r := range rows {
+		if NormalizeFlightNumber(r.Flight.IATA) == flightNumber && r.FlightDate == departureDate {
+			candidates = append(candidates, r)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return sortKey(candidates[i].Departure.Scheduled).Before(sortKey(candidates[j].Departure.Scheduled))
+	})
+	return &candidates[0]
+}
+
+func aviationRowToStatus(r *aviationRow, flightNumber string) *FlightStatus {
+	toEndpoint := func(n aviationNode) Endpoint {
+		return Endpoint{
+			IATA:        strings.ToUpper(n.IATA),
+			AirportName: n.Airport,
+			Timezone:    n.Timezone,
+			Terminal:    n.Terminal,
+			Gate:        n.Gate,
+			Scheduled:   parseTime(n.Scheduled),
+			Estimated:   parseTime(n.Estimated),
+			Actual:      parseTime(n.Actual),
+		}
+	}
+	dep := toEndpoint(r.Departure)
+	reported := 0
+	if r.Departure.Delay != nil {
+		reported = *r.Departure.Delay
+	}
+
+	code := strings.ToUpper(r.Airline.IATA)
+	if code == "" {
+		code, _ = SplitFlightNumber(flightNumber)
+	}
+	status := aviationStatus(r.FlightStatus)
+	return &FlightStatus{
+		FlightNumber: firstNonEmpty(r.Flight.IATA, flightNumber),
+		Airline:      firstNonEmpty(r.Airline.Name, code, "Airline"),
+		AirlineCode:  code,
+		Status:       status,
+		Departure:    dep,
+		Arrival:      toEndpoint(r.Arrival),
+		DelayMinutes: departureDelayMinutes(reported, dep),
+		Cancelled:    status == StatusCancelled,
+		Provider:     "aviationstack",
+	}
+}
+
+func aviationStatus(raw string) string {
+	switch strings.ToLower(raw) {
+	case "scheduled":
+		return StatusScheduled
+	case "active":
+		return StatusActive
+	case "landed":
+		return StatusLanded
+	case "cancelled":
+		return StatusCancelled
+	case "diverted", "incident":
+		return StatusDiverted
+	}
+	return StatusUnknown
+}
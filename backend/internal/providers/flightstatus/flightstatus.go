@@ -0,0 +1,214 @@
+package flightstatus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotFound     = errors.New("flight not found")
+	ErrInvalidInput = errors.New("invalid flight lookup input")
+)
+
+const (
+	StatusScheduled = "scheduled"
+	StatusActive    = "active"
+	StatusLanded    = "landed"
+	StatusCancelled = "cancelled"
+	StatusDiverted  = "diverted"
+	StatusUnknown   = "unknown"
+)
+
+// Provider looks up the live status of a single flight on a departure date (YYYY-MM-DD).
+type Provider interface {
+	Name() string
+	Lookup(ctx context.Context, flightNumber, departureDate string) (*FlightStatus, error)
+}
+
+type Endpoint struct {
+	IATA        string     `json:"iata"`
+	AirportName string     `json:"airportName,omitempty"`
+	Timezone    string     `json:"timezone,omitempty"`
+	Terminal    string     `json:"terminal,omitempty"`
+	Gate        string     `json:"gate,omitempty"`
+	Scheduled   *time.Time `json:"scheduled,omitempty"`
+	Estimated   *time.Time `json:"estimated,omitempty"`
+	Actual      *time.Time `json:"actual,omitempty"`
+}
+
+type FlightStatus struct {
+	FlightNumber string   `json:"flightNumber"`
+	Airline      string   `json:"airline"`
+	AirlineCode  string   `json:"airlineCode,omitempty"`
+	Status       string   `json:"status"`
+	Departure    Endpoint `json:"departure"`
+	Arrival      Endpoint `json:"arrival"`
+	DelayMinutes int      `json:"delayMinutes"`
+	Cancelled    bool     `json:"cancelled"`
+	Provider     string   `json:"provider"`
+}
+
+type Config struct {
+	Provider             string
+	AeroAPIKey           string
+	AeroAPIBaseURL       string
+	AviationstackKey     string
+	AviationstackBaseURL string
+}
+
+// New builds the provider named in cfg.Provider. An empty name or "auto" chains every
+// configured provider (AeroAPI first, like the Next route), and nil is returned when none is configured.
+func New(cfg Config) (Provider, error) {
+	httpClient := &http.Client{Timeout: 15 * time.Second}
+	switch strings.ToLower(strings.TrimSpace(cfg.Provider)) {
+	case "aeroapi":
+		if cfg.AeroAPIKey == "" {
+			return nil, fmt.Errorf("AERO_API_KEY is required for flight status provider aeroapi")
+		}
+		return NewAeroAPI(cfg.AeroAPIBaseURL, cfg.AeroAPIKey, httpClient), nil
+	case "aviationstack":
+		if cfg.AviationstackKey == "" {
+			return nil, fmt.Errorf("FLIGHT_STATUS_API_KEY is required for flight status provider aviationstack")
+		}
+		return NewAviationstack(cfg.AviationstackBaseURL, cfg.AviationstackKey, httpClient), nil
+	case "", "auto":
+		providers := make([]Provider, 0, 2)
+		if cfg.AeroAPIKey != "" {
+			providers = append(providers, NewAeroAPI(cfg.AeroAPIBaseURL, cfg.AeroAPIKey, httpClient))
+		}
+		if cfg.AviationstackKey != "" {
+			providers = append(providers, NewAviationstack(cfg.AviationstackBaseURL, cfg.AviationstackKey, httpClient))
+		}
+		switch len(providers) {
+		case 0:
+			return nil, nil
+		case 1:
+			return providers[0], nil
+		default:
+			return NewChain(providers...), nil
+		}
+	default:
+		return nil, fmt.Errorf("unknown flight status provider %q", cfg.Provider)
+	}
+}
+
+// Chain tries each provider in order and returns the first successful lookup.
+type Chain struct {
+	providers []Provider
+}
+
+func NewChain(providers ...Provider) *Chain {
+	return &Chain{providers: providers}
+}
+
+func (c *Chain) Name() string {
+	names := make([]string, 0, len(c.providers))
+	for _, p := range c.providers {
+		names = append(names, p.Name())
+	}
+	return strings.Join(names, "+")
+}
+
+func (c *Chain) Lookup(ctx context.Context, flightNumber, departureDate string) (*FlightStatus, error) {
+	var lastErr error = ErrNotFound
+	for _, p := range c.providers {
+		status, err := p.Lookup(ctx, flightNumber, departureDate)
+		if err == nil {
+			return status, nil
+		}
+		if errors.Is(err, ErrInvalidInput) {
+			return nil, err
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+var (
+	flightNumberRe = regexp.MustCompile(`^([A-Z0-9]{2,3}?)(\d{1,4}[A-Z]?)$`)
+	dateRe         = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+)
+
+func NormalizeFlightNumber(v string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(v), ""))
+}
+
+// SplitFlightNumber splits "AC856" into carrier "AC" and number "856".
+func SplitFlightNumber(flightNumber string) (string, string) {
+	m := flightNumberRe.FindStringSubmatch(NormalizeFlightNumber(flightNumber))
+	if len(m) != 3 {
+		return "", ""
+	}
+	return m[1], m[2]
+}
+
+func validateInput(flightNumber, departureDate string) (string, time.Time, error) {
+	normalized := NormalizeFlightNumber(flightNumber)
+	if normalized == "" {
+		return "", time.Time{}, fmt.Errorf("%w: flight number is required", ErrInvalidInput)
+	}
+	if !dateRe.MatchString(departureDate) {
+		return "", time.Time{}, fmt.Errorf("%w: departure date must be YYYY-MM-DD", ErrInvalidInput)
+	}
+	day, err := time.Parse("2006-01-02", departureDate)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	return normalized, day, nil
+}
+
+func parseTime(raw string) *time.Time {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05"} {
+		if t, err := time.Parse(layout, raw); err == nil {
+			t = t.UTC()
+			return &t
+		}
+	}
+	return nil
+}
+
+// onDay reports whether the timestamp raw falls on the UTC day starting at day.
+func onDay(raw string, day time.Time) bool {
+	t := parseTime(raw)
+	return t != nil && !t.Before(day) && t.Before(day.Add(24*time.Hour))
+}
+
+// departureDelayMinutes prefers the provider-reported delay and otherwise derives it from
+// the best known departure time against the schedule.
+func departureDelayMinutes(reported int, dep Endpoint) int {
+	if reported > 0 {
+		return reported
+	}
+	if dep.Scheduled == nil {
+		return 0
+	}
+	best := dep.Actual
+	if best == nil {
+		best = dep.Estimated
+	}
+	if best == nil {
+		return 0
+	}
+	if d := int(best.Sub(*dep.Scheduled).Minutes()); d > 0 {
+		return d
+	}
+	return 0
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return strings.TrimSpace(v)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,149 @@
+package flightstatus_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"triploom/backend/internal/providers/flightstatus"
+	"triploom/backend/internal/providers/flightstatus/flightstatustest"
+)
+
+func TestAeroAPILookup(t *testing.T) {
+	srv := flightstatustest.NewServer(t, map[string]flightstatustest.Fixture{
+		"/flights/AC856": {File: "testdata/aeroapi_flights_AC856.json"},
+	})
+	p := flightstatus.NewAeroAPI(srv.URL, "test-key", srv.Client())
+
+	got, err := p.Lookup(context.Background(), "ac 856", "2026-11-02")
+	if err != nil {
+		t.Fatalf("lookup: %v", err)
+	}
+	if got.FlightNumber != "AC856" || got.Airline != "Air Canada" || got.Provider != "aeroapi" {
+		t.Fatalf("unexpected identity: %+v", got)
+	}
+	if got.Departure.IATA != "YYZ" || got.Arrival.IATA != "LHR" {
+		t.Fatalf("unexpected route %s-%s", got.Departure.IATA, got.Arrival.IATA)
+	}
+	if got.Departure.Gate != "D41" || got.Departure.Terminal != "1" || got.Arrival.Terminal != "2" {
+		t.Fatalf("unexpected gate/terminal: %+v / %+v", got.Departure, got.Arrival)
+	}
+	if got.DelayMinutes != 45 || got.Cancelled || got.Status != flightstatus.StatusScheduled {
+		t.Fatalf("unexpected delay/status: %d %v %s", got.DelayMinutes, got.Cancelled, got.Status)
+	}
+	if got.Departure.Estimated == nil || got.Departure.Actual != nil {
+		t.Fatalf("expected estimated departure only, got %+v", got.Departure)
+	}
+	if key := srv.Requests()[0].Header.Get("x-apikey"); key != "test-key" {
+		t.Fatalf("expected api key header, got %q", key)
+	}
+}
+
+func TestAeroAPIFallsBackToSchedules(t *testing.T) {
+	srv := flightstatustest.NewServer(t, map[string]flightstatustest.Fixture{
+		"/schedules/2027-03-10/2027-03-11": {File: "testdata/aeroapi_schedules_AC856.json"},
+	})
+	p := flightstatus.NewAeroAPI(srv.URL, "test-key", srv.Client())
+
+	got, err := p.Lookup(context.Background(), "AC856", "2027-03-10")
+	if err != nil {
+		t.Fatalf("lookup: %v", err)
+	}
+	if got.Status != flightstatus.StatusScheduled || got.Departure.IATA != "YYZ" || got.Departure.Scheduled == nil {
+		t.Fatalf("unexpected schedule result: %+v", got)
+	}
+	if q := srv.Requests()[1].URL.Query(); q.Get("airline") != "AC" || q.Get("flight_number") != "856" {
+		t.Fatalf("unexpected schedules query: %v", q)
+	}
+}
+
+func TestAviationstackLookupCancelled(t *testing.T) {
+	srv := flightstatustest.NewServer(t, map[string]flightstatustest.Fixture{
+		"/flights": {File: "testdata/aviationstack_flights_BA92.json"},
+	})
+	p := flightstatus.NewAviationstack(srv.URL, "access", srv.Client())
+
+	got, err := p.Lookup(context.Background(), "BA92", "2026-11-02")
+	if err != nil {
+		t.Fatalf("lookup: %v", err)
+	}
+	if !got.Cancelled || got.Status != flightstatus.StatusCancelled {
+		t.Fatalf("expected cancelled flight, got %+v", got)
+	}
+	if got.Airline != "British Airways" || got.Departure.Gate != "B36" || got.Arrival.Terminal != "3" {
+		t.Fatalf("unexpected normalisation: %+v", got)
+	}
+}
+
+func TestLookupIgnoresOtherFlightsAndDates(t *testing.T) {
+	srv := flightstatustest.NewServer(t, map[string]flightstatustest.Fixture{
+		"/flights/AC856":                   {File: "testdata/aeroapi_flights_AC856.json"},
+		"/schedules/2027-03-09/2027-03-10": {File: "testdata/aeroapi_schedules_AC856.json"},
+		"/flights":                         {File: "testdata/aviationstack_flights_BA92.json"},
+	})
+	aero := flightstatus.NewAeroAPI(srv.URL, "test-key", srv.Client())
+	// The flight rows are for 2026-11-02 and the schedule for 2027-03-10, the end of the window.
+	for _, date := range []string{"2026-11-03", "2027-03-09"} {
+		if _, err := aero.Lookup(context.Background(), "AC856", date); !errors.Is(err, flightstatus.ErrNotFound) {
+			t.Fatalf("aeroapi %s: expected not found, got %v", date, err)
+		}
+	}
+	aviation := flightstatus.NewAviationstack(srv.URL, "access", srv.Client())
+	for _, c := range []struct{ flight, date string }{{"BA92", "2026-11-03"}, {"BA93", "2026-11-02"}} {
+		if _, err := aviation.Lookup(context.Background(), c.flight, c.date); !errors.Is(err, flightstatus.ErrNotFound) {
+			t.Fatalf("aviationstack %s on %s: expected not found, got %v", c.flight, c.date, err)
+		}
+	}
+}
+
+func TestAviationstackError(t *testing.T) {
+	srv := flightstatustest.NewServer(t, map[string]flightstatustest.Fixture{
+		"/flights": {Status: http.StatusOK, File: "testdata/aviationstack_error.json"},
+	})
+	p := flightstatus.NewAviationstack(srv.URL, "access", srv.Client())
+
+	if _, err := p.Lookup(context.Background(), "BA92", "2026-11-02"); err == nil {
+		t.Fatalf("expected provider error")
+	}
+}
+
+func TestChainFallsThrough(t *testing.T) {
+	empty := flightstatustest.NewServer(t, nil)
+	srv := flightstatustest.NewServer(t, map[string]flightstatustest.Fixture{
+		"/flights": {File: "testdata/aviationstack_flights_BA92.json"},
+	})
+	chain := flightstatus.NewChain(
+		flightstatus.NewAeroAPI(empty.URL, "k", empty.Client()),
+		flightstatus.NewAviationstack(srv.URL, "k", srv.Client()),
+	)
+
+	got, err := chain.Lookup(context.Background(), "BA92", "2026-11-02")
+	if err != nil {
+		t.Fatalf("lookup: %v", err)
+	}
+	if got.Provider != "aviationstack" {
+		t.Fatalf("expected aviationstack result, got %s", got.Provider)
+	}
+}
+
+func TestLookupValidatesInput(t *testing.T) {
+	p := flightstatus.NewAeroAPI("http://127.0.0.1:0", "k", nil)
+	if _, err := p.Lookup(context.Background(), "AC856", "next tuesday"); !errors.Is(err, flightstatus.ErrInvalidInput) {
+		t.Fatalf("expected invalid input, got %v", err)
+	}
+}
+
+func TestNewSelectsProvider(t *testing.T) {
+	p, err := flightstatus.New(flightstatus.Config{})
+	if err != nil || p != nil {
+		t.Fatalf("expected no provider without keys, got %v %v", p, err)
+	}
+	p, err = flightstatus.New(flightstatus.Config{AeroAPIKey: "a", AviationstackKey: "b"})
+	if err != nil || p.Name() != "aeroapi+aviationstack" {
+		t.Fatalf("expected chain, got %v %v", p, err)
+	}
+	if _, err := flightstatus.New(flightstatus.Config{Provider: "aviationstack"}); err == nil {
+		t.Fatalf("expected missing key error")
+	}
+}
@@ -0,0 +1,122 @@
+// Package flightstatustest provides fakes for exercising flight status providers without
+// reaching AeroAPI or Aviationstack.
+package flightstatustest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+
+	"triploom/backend/internal/providers/flightstatus"
+)
+
+// Fixture is a recorded provider response served for one request path.
+type Fixture struct {
+	Status int
+	File   string
+}
+
+// Server is an httptest server that answers provider requests from recorded JSON fixtures,
+// keyed by URL path. Unknown paths return 404 with an empty JSON object.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []*http.Request
+}
+
+func NewServer(tb testing.TB, fixtures map[string]Fixture) *Server {
+	tb.Helper()
+	bodies := make(map[string][]byte, len(fixtures))
+	for path, f := range fixtures {
+		b, err := os.ReadFile(f.File)
+		if err != nil {
+			tb.Fatalf("read fixture %s: %v", f.File, err)
+		}
+		bodies[path] = b
+	}
+
+	s := &Server{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests = append(s.requests, r.Clone(context.Background()))
+		s.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		f, ok := fixtures[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{}`))
+			return
+		}
+		status := f.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		w.WriteHeader(status)
+		_, _ = w.Write(bodies[r.URL.Path])
+	}))
+	tb.Cleanup(s.Close)
+	return s
+}
+
+// Requests returns every request received so far, in arrival order.
+func (s *Server) Requests() []*http.Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]*http.Request, len(s.requests))
+	copy(out, s.requests)
+	return out
+}
+
+// Provider is an in-memory flightstatus.Provider whose answers can be changed between calls.
+type Provider struct {
+	mu       sync.Mutex
+	statuses map[string]*flightstatus.FlightStatus
+	err      error
+	calls    int
+}
+
+func NewProvider() *Provider {
+	return &Provider{statuses: make(map[string]*flightstatus.FlightStatus)}
+}
+
+func (p *Provider) Name() string { return "fake" }
+
+// Set stores the status returned for flightNumber on departureDate.
+func (p *Provider) Set(flightNumber, departureDate string, status flightstatus.FlightStatus) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.statuses[flightstatus.NormalizeFlightNumber(flightNumber)+"|"+departureDate] = &status
+}
+
+// SetError makes every subsequent lookup fail with err until it is reset with nil.
+func (p *Provider) SetError(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.err = err
+}
+
+func (p *Provider) Calls() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.calls
+}
+
+func (p *Provider) Lookup(_ context.Context, flightNumber, departureDate string) (*flightstatus.FlightStatus, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls++
+	if p.err != nil {
+		return nil, p.err
+	}
+	status, ok := p.statuses[flightstatus.NormalizeFlightNumber(flightNumber)+"|"+departureDate]
+	if !ok {
+		return nil, flightstatus.ErrNotFound
+	}
+	out := *status
+	return &out, nil
+}
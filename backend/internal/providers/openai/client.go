@@ -3,6 +3,7 @@ package openai
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -26,6 +27,19 @@ type ChatResult struct {
 	TokenUsage map[string]any
 }
 
+type schemaKey struct{}
+
+type jsonSchema struct {
+	name   string
+	schema map[string]any
+}
+
+// WithJSONSchema makes ResponsesChat calls made with the returned context ask for structured
+// output matching schema, in strict mode. The reply's Text is then the JSON document.
+func WithJSONSchema(ctx context.Context, name string, schema map[string]any) context.Context {
+	return context.WithValue(ctx, schemaKey{}, jsonSchema{name: name, schema: schema})
+}
+
 func NewClient(apiKey string) *Client {
 	return &Client{
 		client: openai.NewClient(
@@ -36,6 +50,29 @@ func NewClient(apiKey string) *Client {
 	}
 }
 
+// NewCompatibleClient talks to another provider's OpenAI-compatible Responses API at baseURL,
+// e.g. a gateway or a self-hosted model server.
+func NewCompatibleClient(baseURL, apiKey string) *Client {
+	return &Client{
+		client: openai.NewClient(
+			option.WithBaseURL(baseURL),
+			option.WithAPIKey(apiKey),
+			option.WithRequestTimeout(45*time.Second),
+			option.WithMaxRetries(2),
+		),
+	}
+}
+
+// StatusCode is the HTTP status of the API response err reports, or 0 when the call failed
+// without one, e.g. on a network error or timeout.
+func StatusCode(err error) int {
+	var apiErr *openai.Error
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode
+	}
+	return 0
+}
+
 func (c *Client) ResponsesChat(ctx context.Context, model string, systemPrompt string, messages []Message) (*ChatResult, error) {
 	var transcript strings.Builder
 	for _, m := range messages {
@@ -51,13 +88,25 @@ func (c *Client) ResponsesChat(ctx context.Context, model string, systemPrompt s
 		transcript.WriteString(strings.TrimSpace(m.Content))
 	}
 
-	resp, err := c.client.Responses.New(ctx, responses.ResponseNewParams{
+	params := responses.ResponseNewParams{
 		Instructions: openai.String(systemPrompt),
 		Model:        model,
 		Input: responses.ResponseNewParamsInputUnion{
 			OfString: openai.String(transcript.String()),
 		},
-	})
+	}
+	if schema, ok := ctx.Value(schemaKey{}).(jsonSchema); ok {
+		params.Text = responses.ResponseTextConfigParam{
+			Format: responses.ResponseFormatTextConfigUnionParam{
+				OfJSONSchema: &responses.ResponseFormatTextJSONSchemaConfigParam{
+					Name:   schema.name,
+					Schema: schema.schema,
+					Strict: openai.Bool(true),
+				},
+			},
+		}
+	}
+	resp, err := c.client.Responses.New(ctx, params)
 	if err != nil {
 		return nil, fmt.Errorf("openai responses error: %w", err)
 	}
@@ -74,3 +123,22 @@ func (c *Client) ResponsesChat(ctx context.Context, model string, systemPrompt s
 
 	return &ChatResult{Text: text, TokenUsage: usage}, nil
 }
+
+// Embed returns one embedding per text, in the order given.
+func (c *Client) Embed(ctx context.Context, model string, texts []string) ([][]float64, error) {
+	resp, err := c.client.Embeddings.New(ctx, openai.EmbeddingNewParams{
+		Model: model,
+		Input: openai.EmbeddingNewParamsInputUnion{OfArrayOfStrings: texts},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("openai embeddings error: %w", err)
+	}
+	out := make([][]float64, len(texts))
+	for _, d := range resp.Data {
+		if d.Index < 0 || int(d.Index) >= len(out) {
+			return nil, fmt.Errorf("openai embeddings error: unexpected index %d", d.Index)
+		}
+		out[d.Index] = d.Embedding
+	}
+	return out, nil
+}
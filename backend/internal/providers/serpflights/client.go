@@ -0,0 +1,309 @@
+package serpflights
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultBaseURL = "https://serpapi.com/search"
+	currency       = "USD"
+)
+
+var (
+	ErrInvalidInput = errors.New("invalid flight search input")
+	ErrNoBooking    = errors.New("no booking option available for this flight")
+)
+
+var (
+	iataRe = regexp.MustCompile(`^[A-Z]{3}$`)
+	dateRe = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+)
+
+// Client searches Google Flights through SerpAPI (engine=google_flights). See docs/api.md for
+// how search, departure_token and booking_token requests fit together.
+type Client struct {
+	baseURL string
+	apiKey  string
+	http    *http.Client
+}
+
+func NewClient(baseURL, apiKey string, httpClient *http.Client) *Client {
+	if strings.TrimSpace(baseURL) == "" {
+		baseURL = defaultBaseURL
+	}
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 20 * time.Second}
+	}
+	return &Client{baseURL: strings.TrimRight(baseURL, "/"), apiKey: apiKey, http: httpClient}
+}
+
+type Slice struct {
+	Origin        string `json:"origin"`
+	Destination   string `json:"destination"`
+	DepartureDate string `json:"departure_date"`
+}
+
+type SearchRequest struct {
+	TripID        string  `json:"trip_id,omitempty"`
+	Slices        []Slice `json:"slices,omitempty"`
+	Origin        string  `json:"origin,omitempty"`
+	Destination   string  `json:"destination,omitempty"`
+	DepartureDate string  `json:"departure_date,omitempty"`
+	ReturnDate    string  `json:"return_date,omitempty"`
+	Adults        int     `json:"adults,omitempty"`
+}
+
+type ReturnFlightsRequest struct {
+	TripID          string `json:"trip_id,omitempty"`
+	DepartureToken  string `json:"departure_token"`
+	OutboundDate    string `json:"outbound_date"`
+	ReturnDate      string `json:"return_date"`
+	DepartureID     string `json:"departure_id"`
+	ArrivalID       string `json:"arrival_id"`
+	Adults          int    `json:"adults,omitempty"`
+	OutboundOfferID string `json:"outbound_offer_id,omitempty"`
+}
+
+type BookingOptionsRequest struct {
+	BookingToken   string `json:"booking_token"`
+	DepartureToken string `json:"departure_token"`
+}
+
+type BookingOptions struct {
+	URL      string  `json:"url"`
+	PostData *string `json:"post_data"`
+}
+
+// Search runs the initial one-way or round-trip search. A round trip is one slice with
+// ReturnDate, or two slices where the second returns from the first's destination.
+func (c *Client) Search(ctx context.Context, req SearchRequest) ([]Offer, error) {
+	slices, err := normalizeSlices(req)
+	if err != nil {
+		return nil, err
+	}
+	returnDate := strings.TrimSpace(req.ReturnDate)
+	if len(slices) == 2 {
+		returnDate = slices[1].DepartureDate
+	}
+	oneWay := returnDate == ""
+	p := searchParams{
+		departureID:  slices[0].Origin,
+		arrivalID:    slices[0].Destination,
+		outboundDate: slices[0].DepartureDate,
+		oneWay:       oneWay,
+	}
+
+	q := c.baseQuery()
+	q.Set("departure_id", p.departureID)
+	q.Set("arrival_id", p.arrivalID)
+	q.Set("outbound_date", p.outboundDate)
+	q.Set("adults", strconv.Itoa(clampAdults(req.Adults)))
+	if oneWay {
+		q.Set("type", "2")
+	} else {
+		q.Set("type", "1")
+		if dateRe.MatchString(returnDate) {
+			q.Set("return_date", returnDate)
+			p.returnDate = returnDate
+		}
+	}
+
+	var resp serpResponse
+	if err := c.get(ctx, q, &resp); err != nil {
+		return nil, err
+	}
+	source := SourceOutbound
+	if oneWay {
+		source = SourceOneWay
+	}
+	all := append(resp.BestFlights, resp.OtherFlights...)
+	out := make([]Offer, 0, len(all))
+	for i, o := range all {
+		offer := searchOfferFromSerp(o, i, p)
+		offer.TripFlight = tripFlightFromOffer(req.TripID, source, offer)
+		out = append(out, offer)
+	}
+	return out, nil
+}
+
+// ReturnFlights fetches return options for a selected outbound offer. DepartureID/ArrivalID
+// describe the return leg (from destination back to origin); SerpAPI wants the original
+// round-trip convention, so they are swapped in the upstream request.
+func (c *Client) ReturnFlights(ctx context.Context, req ReturnFlightsRequest) ([]Offer, error) {
+	token := strings.TrimSpace(req.DepartureToken)
+	outboundDate := strings.TrimSpace(req.OutboundDate)
+	returnDate := strings.TrimSpace(req.ReturnDate)
+	departureID := strings.ToUpper(strings.TrimSpace(req.DepartureID))
+	arrivalID := strings.ToUpper(strings.TrimSpace(req.ArrivalID))
+	switch {
+	case token == "":
+		return nil, fmt.Errorf("%w: missing departure_token", ErrInvalidInput)
+	case !dateRe.MatchString(outboundDate):
+		return nil, fmt.Errorf("%w: outbound_date must be YYYY-MM-DD", ErrInvalidInput)
+	case !dateRe.MatchString(returnDate):
+		return nil, fmt.Errorf("%w: return_date must be YYYY-MM-DD", ErrInvalidInput)
+	case !iataRe.MatchString(departureID) || !iataRe.MatchString(arrivalID):
+		return nil, fmt.Errorf("%w: departure_id and arrival_id must be 3-letter IATA codes", ErrInvalidInput)
+	}
+
+	q := c.baseQuery()
+	q.Set("type", "1")
+	q.Set("departure_token", token)
+	q.Set("outbound_date", outboundDate)
+	q.Set("return_date", returnDate)
+	q.Set("departure_id", arrivalID)
+	q.Set("arrival_id", departureID)
+	q.Set("adults", strconv.Itoa(clampAdults(req.Adults)))
+
+	var resp serpResponse
+	if err := c.get(ctx, q, &resp); err != nil {
+		return nil, err
+	}
+	all := append(resp.BestFlights, resp.OtherFlights...)
+	out := make([]Offer, 0, len(all))
+	for i, o := range all {
+		offer := returnOfferFromSerp(o, i, req.OutboundOfferID)
+		offer.TripFlight = tripFlightFromOffer(req.TripID, SourceInbound, offer)
+		out = append(out, offer)
+	}
+	return out, nil
+}
+
+// BookingOptions resolves a booking_token (or, as a fallback, a departure_token) to the
+// Google Flights URL and POST data that open the pre-selected itinerary.
+func (c *Client) BookingOptions(ctx context.Context, req BookingOptionsRequest) (*BookingOptions, error) {
+	q := c.baseQuery()
+	switch {
+	case strings.TrimSpace(req.BookingToken) != "":
+		q.Set("booking_token", strings.TrimSpace(req.BookingToken))
+	case strings.TrimSpace(req.DepartureToken) != "":
+		q.Set("departure_token", strings.TrimSpace(req.DepartureToken))
+	default:
+		return nil, fmt.Errorf("%w: missing booking_token or departure_token", ErrInvalidInput)
+	}
+
+	var resp serpBookingResponse
+	if err := c.get(ctx, q, &resp); err != nil {
+		return nil, err
+	}
+	for _, opt := range resp.BookingOptions {
+		br := opt.BookingRequest
+		if opt.Together != nil && opt.Together.BookingRequest != nil {
+			br = opt.Together.BookingRequest
+		}
+		if br != nil && br.URL != "" {
+			return &BookingOptions{URL: br.URL, PostData: br.PostData}, nil
+		}
+	}
+	return nil, ErrNoBooking
+}
+
+func (c *Client) baseQuery() url.Values {
+	q := url.Values{}
+	q.Set("engine", "google_flights")
+	q.Set("api_key", c.apiKey)
+	q.Set("hl", "en")
+	q.Set("currency", currency)
+	return q
+}
+
+func (c *Client) get(ctx context.Context, q url.Values, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"?"+q.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	res, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	body, _ := io.ReadAll(res.Body)
+	var envelope struct {
+		SearchMetadata struct {
+			Status string `json:"status"`
+		} `json:"search_metadata"`
+		Error string `json:"error"`
+	}
+	_ = json.Unmarshal(body, &envelope)
+	if res.StatusCode >= 300 {
+		msg := envelope.Error
+		if msg == "" {
+			msg = fmt.Sprintf("status %d", res.StatusCode)
+		}
+		return fmt.Errorf("serpapi error (%d): %s", res.StatusCode, msg)
+	}
+	if envelope.SearchMetadata.Status == "Error" && envelope.Error != "" {
+		return fmt.Errorf("serpapi error: %s", envelope.Error)
+	}
+	return json.Unmarshal(body, out)
+}
+
+func normalizeSlices(req SearchRequest) ([]Slice, error) {
+	var slices []Slice
+	switch {
+	case len(req.Slices) > 2:
+		return nil, fmt.Errorf("%w: multi-city searches are not supported; send one slice, or two for a round trip", ErrInvalidInput)
+	case len(req.Slices) > 0:
+		slices = make([]Slice, 0, len(req.Slices))
+		for _, s := range req.Slices {
+			slices = append(slices, Slice{
+				Origin:        strings.ToUpper(strings.TrimSpace(s.Origin)),
+				Destination:   strings.ToUpper(strings.TrimSpace(s.Destination)),
+				DepartureDate: strings.TrimSpace(s.DepartureDate),
+			})
+		}
+	case req.Origin != "" && req.Destination != "" && req.DepartureDate != "":
+		slices = []Slice{{
+			Origin:        strings.ToUpper(strings.TrimSpace(req.Origin)),
+			Destination:   strings.ToUpper(strings.TrimSpace(req.Destination)),
+			DepartureDate: strings.TrimSpace(req.DepartureDate),
+		}}
+	default:
+		return nil, fmt.Errorf("%w: provide either slices[] or origin, destination, and departure_date", ErrInvalidInput)
+	}
+	for _, s := range slices {
+		if !iataRe.MatchString(s.Origin) {
+			return nil, fmt.Errorf("%w: each slice needs a 3-letter IATA origin", ErrInvalidInput)
+		}
+		if !iataRe.MatchString(s.Destination) {
+			return nil, fmt.Errorf("%w: each slice needs a 3-letter IATA destination", ErrInvalidInput)
+		}
+		if !dateRe.MatchString(s.DepartureDate) {
+			return nil, fmt.Errorf("%w: each slice needs departure_date YYYY-MM-DD", ErrInvalidInput)
+		}
+	}
+	if len(slices) == 2 {
+		out, back := slices[0], slices[1]
+		if back.Origin != out.Destination || back.Destination != out.Origin {
+			return nil, fmt.Errorf("%w: the second slice must return from the first slice's destination to its origin", ErrInvalidInput)
+		}
+		if back.DepartureDate < out.DepartureDate {
+			return nil, fmt.Errorf("%w: the return slice departs before the outbound one", ErrInvalidInput)
+		}
+		if rd := strings.TrimSpace(req.ReturnDate); rd != "" && rd != back.DepartureDate {
+			return nil, fmt.Errorf("%w: return_date conflicts with the second slice", ErrInvalidInput)
+		}
+	}
+	return slices, nil
+}
+
+func clampAdults(n int) int {
+	if n < 1 {
+		return 1
+	}
+	if n > 10 {
+		return 10
+	}
+	return n
+}
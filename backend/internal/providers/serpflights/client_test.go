@@ -0,0 +1,187 @@
+package serpflights
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+)
+
+// fixtureServer answers SerpAPI requests with recorded responses, chosen by which token the
+// request carries. The last query seen is written to *last.
+func fixtureServer(t *testing.T, last *url.Values) *Client {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		*last = q
+		file := "testdata/search_round_trip.json"
+		switch {
+		case q.Get("booking_token") != "":
+			file = "testdata/booking_options.json"
+		case q.Get("departure_token") != "":
+			file = "testdata/return_flights.json"
+		case q.Get("departure_id") == "XXX":
+			file = "testdata/error.json"
+		}
+		b, err := os.ReadFile(file)
+		if err != nil {
+			t.Errorf("read fixture: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(b)
+	}))
+	t.Cleanup(srv.Close)
+	return NewClient(srv.URL, "serp-key", srv.Client())
+}
+
+func TestSearchNormalisesOffers(t *testing.T) {
+	var q url.Values
+	c := fixtureServer(t, &q)
+
+	offers, err := c.Search(context.Background(), SearchRequest{
+		TripID:        "trip-1",
+		Origin:        "yyz",
+		Destination:   "ber",
+		DepartureDate: "2026-11-02",
+		ReturnDate:    "2026-11-12",
+		Adults:        2,
+	})
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if q.Get("type") != "1" || q.Get("return_date") != "2026-11-12" || q.Get("adults") != "2" || q.Get("api_key") != "serp-key" {
+		t.Fatalf("unexpected upstream query: %v", q)
+	}
+	if len(offers) != 2 {
+		t.Fatalf("expected best+other offers, got %d", len(offers))
+	}
+
+	first := offers[0]
+	if first.ID != "serp-0-WyJDalJJ==" || first.DepartureToken != "WyJDalJJ==" || first.TotalAmount != "912" {
+		t.Fatalf("unexpected offer identity: %+v", first)
+	}
+	if len(first.Slices) != 1 || len(first.Slices[0].Segments) != 2 || first.Slices[0].Duration != "10h 15m" {
+		t.Fatalf("unexpected slices: %+v", first.Slices)
+	}
+
+	tf := first.TripFlight
+	want := TripFlight{
+		ID:         "trip-1:outbound:serp-0-WyJDalJJ==:Mon, Nov 2",
+		TripID:     "trip-1",
+		Source:     SourceOutbound,
+		Route:      "YYZ → BER",
+		FlightDate: "Mon, Nov 2",
+		Departure:  "6:10 PM",
+		Arrival:    "7:40 AM",
+		Duration:   "10h 15m",
+		Stops:      "1 stop",
+		Airline:    "Lufthansa",
+		Cost:       "912 USD",
+		OfferID:    "serp-0-WyJDalJJ==",
+		BookURL:    first.BookURL,
+	}
+	if tf != want {
+		t.Fatalf("unexpected trip flight row:\n got %+v\nwant %+v", tf, want)
+	}
+	if offers[1].TripFlight.Stops != "Non-stop" || offers[1].TripFlight.Cost != "734.5 USD" {
+		t.Fatalf("unexpected second row: %+v", offers[1].TripFlight)
+	}
+}
+
+func TestSearchOneWayAndValidation(t *testing.T) {
+	var q url.Values
+	c := fixtureServer(t, &q)
+
+	offers, err := c.Search(context.Background(), SearchRequest{Slices: []Slice{{Origin: "YYZ", Destination: "BER", DepartureDate: "2026-11-02"}}})
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if q.Get("type") != "2" || offers[0].TripFlight.Source != SourceOneWay || offers[0].TripFlight.ID != "" {
+		t.Fatalf("expected one-way search without trip id, got type=%s row=%+v", q.Get("type"), offers[0].TripFlight)
+	}
+
+	if _, err := c.Search(context.Background(), SearchRequest{Origin: "Toronto", Destination: "BER", DepartureDate: "2026-11-02"}); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("expected invalid input for non-IATA origin, got %v", err)
+	}
+	if _, err := c.Search(context.Background(), SearchRequest{Origin: "XXX", Destination: "BER", DepartureDate: "2026-11-02"}); err == nil {
+		t.Fatalf("expected upstream error")
+	}
+}
+
+func TestSearchSlices(t *testing.T) {
+	var q url.Values
+	c := fixtureServer(t, &q)
+
+	out := Slice{Origin: "YYZ", Destination: "BER", DepartureDate: "2026-11-02"}
+	back := Slice{Origin: "BER", Destination: "YYZ", DepartureDate: "2026-11-12"}
+	if _, err := c.Search(context.Background(), SearchRequest{Slices: []Slice{out, back}}); err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if q.Get("type") != "1" || q.Get("outbound_date") != "2026-11-02" || q.Get("return_date") != "2026-11-12" {
+		t.Fatalf("expected a round trip from two slices, got %v", q)
+	}
+
+	onward := Slice{Origin: "BER", Destination: "PRG", DepartureDate: "2026-11-06"}
+	bad := [][]Slice{
+		{out, onward},
+		{out, onward, {Origin: "PRG", Destination: "YYZ", DepartureDate: "2026-11-12"}},
+		{out, {Origin: "BER", Destination: "YYZ", DepartureDate: "2026-11-01"}},
+	}
+	for _, slices := range bad {
+		q = nil
+		if _, err := c.Search(context.Background(), SearchRequest{Slices: slices}); !errors.Is(err, ErrInvalidInput) {
+			t.Fatalf("expected %d slices %+v to be rejected, got %v", len(slices), slices, err)
+		}
+		if q != nil {
+			t.Fatalf("expected no upstream request, got %v", q)
+		}
+	}
+	if _, err := c.Search(context.Background(), SearchRequest{Slices: []Slice{out, back}, ReturnDate: "2026-11-10"}); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("expected a conflicting return_date to be rejected, got %v", err)
+	}
+}
+
+func TestReturnFlightsSwapsAirports(t *testing.T) {
+	var q url.Values
+	c := fixtureServer(t, &q)
+
+	offers, err := c.ReturnFlights(context.Background(), ReturnFlightsRequest{
+		DepartureToken:  "WyJDalJK==",
+		OutboundDate:    "2026-11-02",
+		ReturnDate:      "2026-11-12",
+		DepartureID:     "BER",
+		ArrivalID:       "YYZ",
+		OutboundOfferID: "serp-1-WyJDalJK==",
+	})
+	if err != nil {
+		t.Fatalf("return flights: %v", err)
+	}
+	if q.Get("departure_id") != "YYZ" || q.Get("arrival_id") != "BER" {
+		t.Fatalf("expected swapped airports, got %v", q)
+	}
+	if len(offers) != 1 || offers[0].BookingToken != "WyJCb29rMSJd" || offers[0].TripFlight.Source != SourceInbound {
+		t.Fatalf("unexpected return offers: %+v", offers)
+	}
+	if offers[0].ID != "serp-return-serp-1-WyJDalJK==-0-WyJCb29rMSJd" {
+		t.Fatalf("unexpected return offer id %s", offers[0].ID)
+	}
+}
+
+func TestBookingOptions(t *testing.T) {
+	var q url.Values
+	c := fixtureServer(t, &q)
+
+	got, err := c.BookingOptions(context.Background(), BookingOptionsRequest{BookingToken: "WyJCb29rMSJd"})
+	if err != nil {
+		t.Fatalf("booking options: %v", err)
+	}
+	if got.URL != "https://www.google.com/travel/clk/f" || got.PostData == nil || *got.PostData != "u=EncodedTokenFixture" {
+		t.Fatalf("unexpected booking options: %+v", got)
+	}
+	if _, err := c.BookingOptions(context.Background(), BookingOptionsRequest{}); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("expected invalid input, got %v", err)
+	}
+}
@@ -0,0 +1,346 @@
+package serpflights
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// trip_flights.source values.
+const (
+	SourceOutbound = "outbound"
+	SourceInbound  = "inbound"
+	SourceOneWay   = "one_way"
+)
+
+type Place struct {
+	Name     string `json:"name"`
+	IATACode string `json:"iataCode"`
+	CityName string `json:"cityName,omitempty"`
+}
+
+type Carrier struct {
+	Name     string `json:"name"`
+	IATACode string `json:"iataCode"`
+}
+
+type Segment struct {
+	OperatingCarrier Carrier `json:"operatingCarrier"`
+	MarketingCarrier Carrier `json:"marketingCarrier"`
+	FlightNumber     string  `json:"flightNumber"`
+	DepartingAt      string  `json:"departingAt"`
+	ArrivingAt       string  `json:"arrivingAt"`
+	Origin           Place   `json:"origin"`
+	Destination      Place   `json:"destination"`
+	Duration         string  `json:"duration"`
+}
+
+type OfferSlice struct {
+	Origin      Place     `json:"origin"`
+	Destination Place     `json:"destination"`
+	Duration    string    `json:"duration"`
+	Segments    []Segment `json:"segments"`
+}
+
+// Offer mirrors the frontend FlightOffer type, plus TripFlight: the same offer flattened to
+// trip_flights columns so the client can save it without re-deriving labels.
+type Offer struct {
+	ID             string       `json:"id"`
+	TotalAmount    string       `json:"totalAmount"`
+	TotalCurrency  string       `json:"totalCurrency"`
+	Owner          Carrier      `json:"owner"`
+	BookURL        string       `json:"bookUrl,omitempty"`
+	BookingToken   string       `json:"bookingToken,omitempty"`
+	DepartureToken string       `json:"departureToken,omitempty"`
+	Slices         []OfferSlice `json:"slices"`
+	ExpiresAt      string       `json:"expiresAt"`
+	TripFlight     TripFlight   `json:"tripFlight"`
+}
+
+// TripFlight matches a trip_flights row (see migrations/003_trip_flights.sql).
+type TripFlight struct {
+	ID         string `json:"id,omitempty"`
+	TripID     string `json:"trip_id,omitempty"`
+	Source     string `json:"source"`
+	Route      string `json:"route"`
+	FlightDate string `json:"flight_date"`
+	Departure  string `json:"departure"`
+	Arrival    string `json:"arrival"`
+	Duration   string `json:"duration"`
+	Stops      string `json:"stops"`
+	Airline    string `json:"airline"`
+	Cost       string `json:"cost"`
+	OfferID    string `json:"offer_id"`
+	BookURL    string `json:"book_url,omitempty"`
+}
+
+type serpAirport struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Time string `json:"time"`
+}
+
+type serpFlight struct {
+	DepartureAirport serpAirport `json:"departure_airport"`
+	ArrivalAirport   serpAirport `json:"arrival_airport"`
+	Duration         *int        `json:"duration"`
+	Airline          string      `json:"airline"`
+	FlightNumber     string      `json:"flight_number"`
+}
+
+type serpOffer struct {
+	Flights        []serpFlight `json:"flights"`
+	TotalDuration  *int         `json:"total_duration"`
+	Price          float64      `json:"price"`
+	Type           string       `json:"type"`
+	DepartureToken string       `json:"departure_token"`
+	BookingToken   string       `json:"booking_token"`
+}
+
+type serpResponse struct {
+	BestFlights  []serpOffer `json:"best_flights"`
+	OtherFlights []serpOffer `json:"other_flights"`
+}
+
+type serpBookingRequest struct {
+	URL      string  `json:"url"`
+	PostData *string `json:"post_data"`
+}
+
+type serpBookingResponse struct {
+	BookingOptions []struct {
+		BookingRequest *serpBookingRequest `json:"booking_request"`
+		Together       *struct {
+			BookingRequest *serpBookingRequest `json:"booking_request"`
+		} `json:"together"`
+	} `json:"booking_options"`
+}
+
+type searchParams struct {
+	departureID  string
+	arrivalID    string
+	outboundDate string
+	returnDate   string
+	oneWay       bool
+}
+
+func searchOfferFromSerp(serp serpOffer, index int, p searchParams) Offer {
+	flights := serp.Flights
+	tokenOrIndex := serp.DepartureToken
+	if tokenOrIndex == "" {
+		tokenOrIndex = fmt.Sprint(index)
+	}
+
+	slices := []OfferSlice{sliceFromFlights(flights, totalDuration(serp))}
+	// Round-trip results sometimes carry both legs in one flights[] list; split them at the
+	// first arrival into the trip destination.
+	if !p.oneWay && p.returnDate != "" && p.arrivalID != "" && len(flights) > 1 {
+		split := -1
+		for i, f := range flights {
+			if strings.EqualFold(f.ArrivalAirport.ID, p.arrivalID) {
+				split = i
+				break
+			}
+		}
+		if split >= 0 && split < len(flights)-1 {
+			outbound, inbound := flights[:split+1], flights[split+1:]
+			slices = []OfferSlice{
+				sliceFromFlights(outbound, durationLabel(sumDurations(outbound))),
+				sliceFromFlights(inbound, durationLabel(sumDurations(inbound))),
+			}
+		}
+	}
+
+	return Offer{
+		ID:             fmt.Sprintf("serp-%d-%s", index, tokenOrIndex),
+		TotalAmount:    formatAmount(serp.Price),
+		TotalCurrency:  currency,
+		Owner:          Carrier{Name: ownerName(flights)},
+		BookURL:        googleFlightsURL(p),
+		BookingToken:   serp.BookingToken,
+		DepartureToken: serp.DepartureToken,
+		Slices:         slices,
+	}
+}
+
+func returnOfferFromSerp(serp serpOffer, index int, outboundOfferID string) Offer {
+	tokenOrIndex := serp.BookingToken
+	if tokenOrIndex == "" {
+		tokenOrIndex = serp.DepartureToken
+	}
+	if tokenOrIndex == "" {
+		tokenOrIndex = fmt.Sprint(index)
+	}
+	return Offer{
+		ID:             fmt.Sprintf("serp-return-%s-%d-%s", outboundOfferID, index, tokenOrIndex),
+		TotalAmount:    formatAmount(serp.Price),
+		TotalCurrency:  currency,
+		Owner:          Carrier{Name: ownerName(serp.Flights)},
+		BookingToken:   serp.BookingToken,
+		DepartureToken: serp.DepartureToken,
+		Slices:         []OfferSlice{sliceFromFlights(serp.Flights, totalDuration(serp))},
+	}
+}
+
+func sliceFromFlights(flights []serpFlight, duration string) OfferSlice {
+	s := OfferSlice{Duration: duration, Segments: make([]Segment, 0, len(flights))}
+	if len(flights) > 0 {
+		first, last := flights[0], flights[len(flights)-1]
+		s.Origin = Place{Name: first.DepartureAirport.Name, IATACode: first.DepartureAirport.ID}
+		s.Destination = Place{Name: last.ArrivalAirport.Name, IATACode: last.ArrivalAirport.ID}
+	}
+	for _, f := range flights {
+		seg := Segment{
+			OperatingCarrier: Carrier{Name: f.Airline},
+			MarketingCarrier: Carrier{Name: f.Airline},
+			FlightNumber:     f.FlightNumber,
+			DepartingAt:      f.DepartureAirport.Time,
+			ArrivingAt:       f.ArrivalAirport.Time,
+			Origin:           Place{Name: f.DepartureAirport.Name, IATACode: f.DepartureAirport.ID},
+			Destination:      Place{Name: f.ArrivalAirport.Name, IATACode: f.ArrivalAirport.ID},
+		}
+		if f.Duration != nil {
+			seg.Duration = durationLabel(*f.Duration)
+		}
+		s.Segments = append(s.Segments, seg)
+	}
+	return s
+}
+
+// tripFlightFromOffer flattens an offer the same way the flights page does before saving
+// (getOfferRow/getStopsLabel in the frontend).
+func tripFlightFromOffer(tripID, source string, o Offer) TripFlight {
+	routes := make([]string, 0, len(o.Slices))
+	dates := make([]string, 0, len(o.Slices))
+	stops := make([]string, 0, len(o.Slices))
+	for _, s := range o.Slices {
+		routes = append(routes, s.Origin.IATACode+" → "+s.Destination.IATACode)
+		if len(s.Segments) > 0 {
+			if d := dateLabel(s.Segments[0].DepartingAt); d != "" {
+				dates = append(dates, d)
+			}
+		}
+		stops = append(stops, stopsLabel(len(s.Segments)-1))
+	}
+
+	tf := TripFlight{
+		TripID:     tripID,
+		Source:     source,
+		Route:      strings.Join(routes, " · "),
+		FlightDate: strings.Join(dates, " · "),
+		Stops:      strings.Join(stops, " · "),
+		Airline:    o.Owner.Name,
+		Cost:       o.TotalAmount + " " + o.TotalCurrency,
+		OfferID:    o.ID,
+		BookURL:    o.BookURL,
+	}
+	if len(o.Slices) > 0 {
+		first := o.Slices[0]
+		tf.Duration = first.Duration
+		if len(first.Segments) > 0 {
+			tf.Departure = timeLabel(first.Segments[0].DepartingAt)
+			tf.Arrival = timeLabel(first.Segments[0].ArrivingAt)
+		}
+	}
+	if tripID != "" {
+		tf.ID = fmt.Sprintf("%s:%s:%s:%s", tripID, source, o.ID, tf.FlightDate)
+	}
+	return tf
+}
+
+func googleFlightsURL(p searchParams) string {
+	q := url.Values{}
+	q.Set("hl", "en")
+	q.Set("departure_id", p.departureID)
+	q.Set("arrival_id", p.arrivalID)
+	q.Set("outbound_date", p.outboundDate)
+	if p.oneWay {
+		q.Set("type", "2")
+	} else {
+		q.Set("type", "1")
+		if p.returnDate != "" {
+			q.Set("return_date", p.returnDate)
+		}
+	}
+	return "https://www.google.com/travel/flights?" + q.Encode()
+}
+
+func ownerName(flights []serpFlight) string {
+	if len(flights) > 0 && flights[0].Airline != "" {
+		return flights[0].Airline
+	}
+	return "Airline"
+}
+
+func totalDuration(o serpOffer) string {
+	if o.TotalDuration == nil {
+		return ""
+	}
+	return durationLabel(*o.TotalDuration)
+}
+
+func sumDurations(flights []serpFlight) int {
+	total := 0
+	for _, f := range flights {
+		if f.Duration != nil {
+			total += *f.Duration
+		}
+	}
+	return total
+}
+
+func durationLabel(min int) string {
+	if min < 60 {
+		return fmt.Sprintf("%dm", min)
+	}
+	h, m := min/60, min%60
+	if m > 0 {
+		return fmt.Sprintf("%dh %dm", h, m)
+	}
+	return fmt.Sprintf("%dh", h)
+}
+
+func stopsLabel(n int) string {
+	switch {
+	case n <= 0:
+		return "Non-stop"
+	case n == 1:
+		return "1 stop"
+	default:
+		return fmt.Sprintf("%d stops", n)
+	}
+}
+
+func formatAmount(price float64) string {
+	return fmt.Sprint(price)
+}
+
+// SerpAPI reports local airport times as "2006-01-02 15:04".
+func parseLocal(raw string) (time.Time, bool) {
+	for _, layout := range []string{"2006-01-02 15:04", time.RFC3339, "2006-01-02T15:04:05"} {
+		if t, err := time.Parse(layout, strings.TrimSpace(raw)); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func timeLabel(raw string) string {
+	t, ok := parseLocal(raw)
+	if !ok {
+		return raw
+	}
+	return t.Format("3:04 PM")
+}
+
+func dateLabel(raw string) string {
+	if len(raw) < 10 {
+		return ""
+	}
+	t, err := time.Parse("2006-01-02", raw[:10])
+	if err != nil {
+		return ""
+	}
+	return t.Format("Mon, Jan 2")
+}
@@ -0,0 +1,138 @@
+// Package retrieval ranks a trip's persisted data against a question with BM25 and picks what
+// fits in a prompt's token budget.
+package retrieval
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// BM25 parameters: k1 saturates term frequency, b normalises for document length.
+const (
+	k1 = 1.2
+	b  = 0.75
+)
+
+// Document is one retrievable item, e.g. a saved flight or an itinerary item.
+type Document struct {
+	// ID is stable across requests, e.g. "itinerary:<item id>".
+	ID    string `json:"id"`
+	Kind  string `json:"kind"`
+	Title string `json:"title"`
+	Text  string `json:"text"`
+}
+
+// Result is a ranked document.
+type Result struct {
+	Document
+	Score  float64 `json:"score"`
+	Tokens int     `json:"-"`
+}
+
+var stopwords = map[string]bool{}
+
+func init() {
+	for _, w := range strings.Fields(`a an and are as at be by can do does for from has have how i in is it
+		me my of on or our should so that the their them there this to us was we what when where which
+		who will with would you your`) {
+		stopwords[w] = true
+	}
+}
+
+// Terms lower-cases text and splits it into words, dropping stopwords and plural endings.
+func Terms(text string) []string {
+	out := make([]string, 0)
+	for _, w := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if stopwords[w] {
+			continue
+		}
+		out = append(out, stem(w))
+	}
+	return out
+}
+
+// stem strips a plural "s", so that "flights" matches "flight".
+func stem(w string) string {
+	if len(w) > 3 && strings.HasSuffix(w, "s") && !strings.HasSuffix(w, "ss") {
+		return w[:len(w)-1]
+	}
+	return w
+}
+
+// Tokens estimates the prompt tokens text takes, at four bytes a token.
+func Tokens(text string) int {
+	return (len(text) + 3) / 4
+}
+
+// Rank scores docs against query with BM25 over their title and text, best first. Documents
+// that match no term score 0 and keep their order after the matches.
+func Rank(query string, docs []Document) []Result {
+	terms := Terms(query)
+	tf := make([]map[string]int, len(docs))
+	lengths := make([]int, len(docs))
+	df := make(map[string]int)
+	total := 0
+	for i, d := range docs {
+		words := Terms(d.Title + " " + d.Text)
+		tf[i] = make(map[string]int, len(words))
+		for _, w := range words {
+			if tf[i][w] == 0 {
+				df[w]++
+			}
+			tf[i][w]++
+		}
+		lengths[i] = len(words)
+		total += len(words)
+	}
+	avg := 1.0
+	if len(docs) > 0 && total > 0 {
+		avg = float64(total) / float64(len(docs))
+	}
+
+	out := make([]Result, len(docs))
+	n := float64(len(docs))
+	for i, d := range docs {
+		score := 0.0
+		for _, t := range uniq(terms) {
+			f := float64(tf[i][t])
+			if f == 0 {
+				continue
+			}
+			idf := math.Log(1 + (n-float64(df[t])+0.5)/(float64(df[t])+0.5))
+			score += idf * f * (k1 + 1) / (f + k1*(1-b+b*float64(lengths[i])/avg))
+		}
+		out[i] = Result{Document: d, Score: score, Tokens: Tokens(d.Title) + Tokens(d.Text)}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Score > out[j].Score })
+	return out
+}
+
+func uniq(terms []string) []string {
+	seen := make(map[string]bool, len(terms))
+	out := make([]string, 0, len(terms))
+	for _, t := range terms {
+		if !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// Budget keeps results in order while they fit in maxTokens, skipping any that would not fit.
+func Budget(results []Result, maxTokens int) []Result {
+	out := make([]Result, 0)
+	used := 0
+	for _, r := range results {
+		if used+r.Tokens > maxTokens {
+			continue
+		}
+		used += r.Tokens
+		out = append(out, r)
+	}
+	return out
+}
@@ -0,0 +1,40 @@
+package retrieval
+
+import "testing"
+
+func TestRank(t *testing.T) {
+	docs := []Document{
+		{ID: "itinerary:1", Title: "Fushimi Inari", Text: "Day 2 morning, activities"},
+		{ID: "flight:1", Title: "JL1 outbound", Text: "SFO to HND on 2026-11-01, departs 11:00"},
+		{ID: "expense:1", Title: "Ryokan deposit", Text: "hotels, 300 USD on 2026-10-01"},
+		{ID: "flight:2", Title: "JL2 inbound", Text: "HND to SFO on 2026-11-07, departs 18:00"},
+	}
+	got := Rank("When does our outbound flight depart?", docs)
+	if got[0].ID != "flight:1" || got[1].ID != "flight:2" || got[0].Score <= got[1].Score {
+		t.Fatalf("expected the outbound flight first, then the other flight, got %+v", got)
+	}
+	if got[2].Score != 0 || got[2].ID != "itinerary:1" || got[3].ID != "expense:1" {
+		t.Fatalf("expected unmatched documents last in their original order, got %+v", got)
+	}
+
+	kept := Budget(got, got[0].Tokens+got[2].Tokens)
+	if len(kept) != 2 || kept[0].ID != "flight:1" || kept[1].ID != "itinerary:1" {
+		t.Fatalf("expected documents that do not fit to be skipped, got %+v", kept)
+	}
+	if len(Rank("anything", nil)) != 0 {
+		t.Fatalf("expected no results without documents")
+	}
+}
+
+func TestTerms(t *testing.T) {
+	got := Terms("What are the Flights' costs, and the pass?")
+	want := []string{"flight", "cost", "pass"}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+	}
+}
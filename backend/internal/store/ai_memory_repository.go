@@ -0,0 +1,459 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MemoryAIRepository is the in-process AIStore used when no database is configured. Trips
+// added with CreateTrip behave like rows in trips/trip_members; any other trip is treated as a
+// test trip every user belongs to.
+type MemoryAIRepository struct {
+	mu                sync.RWMutex
+	trips             map[string]Trip
+	members           map[string]map[string]string
+	conversationsByID map[string]Conversation
+	messagesByConvID  map[string][]Message
+	feedbackByMsgID   map[string]map[string]Feedback
+	contextByMsgID    map[string]map[string]any
+	toolSnapshots     []ToolSnapshot
+	contextSnapshots  []ContextSnapshot
+	auditLogs         []AuditLog
+}
+
+func NewInMemoryAIRepository() *MemoryAIRepository {
+	return &MemoryAIRepository{
+		trips:             make(map[string]Trip),
+		members:           make(map[string]map[string]string),
+		conversationsByID: make(map[string]Conversation),
+		messagesByConvID:  make(map[string][]Message),
+		feedbackByMsgID:   make(map[string]map[string]Feedback),
+		contextByMsgID:    make(map[string]map[string]any),
+	}
+}
+
+func (r *MemoryAIRepository) CreateTrip(_ context.Context, trip Trip, ownerID string) (*Trip, error) {
+	if trip.ID == "" {
+		trip.ID = uuid.NewString()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.trips[trip.ID] = trip
+	r.members[trip.ID] = map[string]string{ownerID: "owner"}
+	return &trip, nil
+}
+
+func (r *MemoryAIRepository) AddTripMember(_ context.Context, tripID, userID, role string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.trips[tripID]; !ok {
+		return ErrNotFound
+	}
+	r.members[tripID][userID] = role
+	return nil
+}
+
+func (r *MemoryAIRepository) IsTripMember(_ context.Context, tripID, userID string) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if members, ok := r.members[tripID]; ok {
+		_, member := members[userID]
+		return member, nil
+	}
+	return tripID != "" && userID != "", nil
+}
+
+// TripRole treats every user as the owner of test trips.
+func (r *MemoryAIRepository) TripRole(_ context.Context, tripID, userID string) (string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if members, ok := r.members[tripID]; ok {
+		return members[userID], nil
+	}
+	if tripID != "" && userID != "" {
+		return "owner", nil
+	}
+	return "", nil
+}
+
+func (r *MemoryAIRepository) GetTripByID(_ context.Context, tripID string) (*Trip, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if t, ok := r.trips[tripID]; ok {
+		return &t, nil
+	}
+	now := time.Now().UTC()
+	return &Trip{
+		ID:          tripID,
+		Destination: "Test Destination",
+		StartDate:   now,
+		EndDate:     now.Add(72 * time.Hour),
+		Timezone:    "UTC",
+	}, nil
+}
+
+func (r *MemoryAIRepository) UpsertConversation(_ context.Context, tripID, userID, title string) (string, error) {
+	now := time.Now().UTC()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var latest *Conversation
+	for _, c := range r.conversationsByID {
+		if c.TripID == tripID && c.UserID == userID && c.ArchivedAt == nil && (latest == nil || c.UpdatedAt.After(latest.UpdatedAt)) {
+			c := c
+			latest = &c
+		}
+	}
+	if latest != nil {
+		latest.UpdatedAt = now
+		r.conversationsByID[latest.ID] = *latest
+		return latest.ID, nil
+	}
+
+	conv := Conversation{ID: uuid.NewString(), TripID: tripID, UserID: userID, Title: title, CreatedAt: now, UpdatedAt: now}
+	r.conversationsByID[conv.ID] = conv
+	return conv.ID, nil
+}
+
+func (r *MemoryAIRepository) CreateConversation(_ context.Context, tripID, userID, title string) (*Conversation, error) {
+	now := time.Now().UTC()
+	conv := Conversation{ID: uuid.NewString(), TripID: tripID, UserID: userID, Title: title, CreatedAt: now, UpdatedAt: now}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.conversationsByID[conv.ID] = conv
+	return &conv, nil
+}
+
+func (r *MemoryAIRepository) GetConversation(_ context.Context, conversationID string) (*Conversation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	conv, ok := r.conversationsByID[conversationID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &conv, nil
+}
+
+func (r *MemoryAIRepository) RenameConversation(_ context.Context, conversationID, title string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	conv, ok := r.conversationsByID[conversationID]
+	if !ok {
+		return ErrNotFound
+	}
+	conv.Title = title
+	r.conversationsByID[conversationID] = conv
+	return nil
+}
+
+func (r *MemoryAIRepository) SetConversationArchived(_ context.Context, conversationID string, archived bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	conv, ok := r.conversationsByID[conversationID]
+	if !ok {
+		return ErrNotFound
+	}
+	switch {
+	case !archived:
+		conv.ArchivedAt = nil
+	case conv.ArchivedAt == nil:
+		now := time.Now().UTC()
+		conv.ArchivedAt = &now
+	}
+	r.conversationsByID[conversationID] = conv
+	return nil
+}
+
+func (r *MemoryAIRepository) DeleteConversation(_ context.Context, conversationID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.conversationsByID[conversationID]; !ok {
+		return ErrNotFound
+	}
+	delete(r.conversationsByID, conversationID)
+	for _, m := range r.messagesByConvID[conversationID] {
+		delete(r.feedbackByMsgID, m.ID)
+		delete(r.contextByMsgID, m.ID)
+	}
+	delete(r.messagesByConvID, conversationID)
+	kept := r.toolSnapshots[:0]
+	for _, snap := range r.toolSnapshots {
+		if snap.ConversationID != conversationID {
+			kept = append(kept, snap)
+		}
+	}
+	r.toolSnapshots = kept
+	return nil
+}
+
+func (r *MemoryAIRepository) InsertMessage(_ context.Context, msg Message) (*Message, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	msg.ID = uuid.NewString()
+	msg.TokenUsageJSON = copyMap(msg.TokenUsageJSON)
+	var latest time.Time
+	for _, m := range r.messagesByConvID[msg.ConversationID] {
+		if m.CreatedAt.After(latest) {
+			latest = m.CreatedAt
+		}
+	}
+	msg.CreatedAt = messageTime(time.Now().UTC(), latest)
+	if msg.ContextJSON != nil {
+		r.contextByMsgID[msg.ID] = copyMap(msg.ContextJSON)
+	}
+	stored := msg
+	stored.ContextJSON = nil
+	r.messagesByConvID[msg.ConversationID] = append(r.messagesByConvID[msg.ConversationID], stored)
+	if conv, ok := r.conversationsByID[msg.ConversationID]; ok {
+		conv.UpdatedAt = msg.CreatedAt
+		r.conversationsByID[msg.ConversationID] = conv
+	}
+	return &msg, nil
+}
+
+func (r *MemoryAIRepository) GetMessage(_ context.Context, messageID string) (*Message, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, msgs := range r.messagesByConvID {
+		for _, m := range msgs {
+			if m.ID == messageID {
+				return &m, nil
+			}
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *MemoryAIRepository) MessageContext(_ context.Context, messageID string) (map[string]any, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	payload, ok := r.contextByMsgID[messageID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return copyMap(payload), nil
+}
+
+func (r *MemoryAIRepository) ListConversations(_ context.Context, tripID, userID string, includeArchived bool, page PageRequest) ([]Conversation, string, error) {
+	after, err := decodeCursor(page.Cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]Conversation, 0)
+	for _, c := range r.conversationsByID {
+		if c.TripID == tripID && c.UserID == userID && (includeArchived || c.ArchivedAt == nil) && after.before(c.CreatedAt, c.ID) {
+			out = append(out, c)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return descendingKey(out[i].CreatedAt, out[i].ID, out[j].CreatedAt, out[j].ID)
+	})
+	out, next := trimPage(out, page.Limit, conversationKey)
+	return out, next, nil
+}
+
+func (r *MemoryAIRepository) ConversationBelongsToUser(_ context.Context, conversationID, userID string) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	conv, ok := r.conversationsByID[conversationID]
+	return ok && conv.UserID == userID, nil
+}
+
+func (r *MemoryAIRepository) ListMessages(_ context.Context, conversationID string, page PageRequest) ([]Message, string, error) {
+	after, err := decodeCursor(page.Cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]Message, 0)
+	for _, m := range r.messagesByConvID[conversationID] {
+		if after.before(m.CreatedAt, m.ID) {
+			out = append(out, m)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return descendingKey(out[i].CreatedAt, out[i].ID, out[j].CreatedAt, out[j].ID)
+	})
+	out, next := trimPage(out, page.Limit, messageKey)
+	return out, next, nil
+}
+
+func (r *MemoryAIRepository) SearchMessages(_ context.Context, userID, tripID, query string, limit int) ([]MessageHit, error) {
+	terms := SearchTerms(query)
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	hits := make([]MessageHit, 0)
+	for _, conv := range r.conversationsByID {
+		if conv.UserID != userID || (tripID != "" && conv.TripID != tripID) {
+			continue
+		}
+		for _, m := range r.messagesByConvID[conv.ID] {
+			rank, snippet, ok := matchContent(m.Content, terms)
+			if !ok {
+				continue
+			}
+			hits = append(hits, MessageHit{
+				MessageID:         m.ID,
+				ConversationID:    conv.ID,
+				ConversationTitle: conv.Title,
+				TripID:            conv.TripID,
+				Role:              m.Role,
+				Snippet:           snippet,
+				Rank:              rank,
+				CreatedAt:         m.CreatedAt,
+			})
+		}
+	}
+	return sortHits(hits, limit), nil
+}
+
+func (r *MemoryAIRepository) UpsertFeedback(_ context.Context, f Feedback) (*Feedback, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now().UTC()
+	byUser := r.feedbackByMsgID[f.MessageID]
+	if byUser == nil {
+		byUser = make(map[string]Feedback)
+		r.feedbackByMsgID[f.MessageID] = byUser
+	}
+	f.CreatedAt, f.UpdatedAt = now, now
+	if prev, ok := byUser[f.UserID]; ok {
+		f.CreatedAt = prev.CreatedAt
+	}
+	f.Reasons = append([]string{}, f.Reasons...)
+	byUser[f.UserID] = f
+	return &f, nil
+}
+
+func (r *MemoryAIRepository) FeedbackReport(_ context.Context, tripID string, since time.Time) ([]FeedbackSummary, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	report := newFeedbackReport()
+	for _, conv := range r.conversationsByID {
+		if conv.TripID != tripID {
+			continue
+		}
+		for _, m := range r.messagesByConvID[conv.ID] {
+			for _, f := range r.feedbackByMsgID[m.ID] {
+				if !f.UpdatedAt.Before(since) {
+					report.add(m.Model, m.PageKey, m.PromptVersion, f.Rating, f.Reasons)
+				}
+			}
+		}
+	}
+	return report.summaries(), nil
+}
+
+func (r *MemoryAIRepository) InsertToolSnapshot(_ context.Context, conversationID, pageKey, toolName, status string, payload map[string]any) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.toolSnapshots = append(r.toolSnapshots, ToolSnapshot{
+		ID:             uuid.NewString(),
+		ConversationID: conversationID,
+		PageKey:        pageKey,
+		ToolName:       toolName,
+		Status:         status,
+		PayloadJSON:    copyMap(payload),
+		FetchedAt:      time.Now().UTC(),
+	})
+	return nil
+}
+
+func (r *MemoryAIRepository) InsertContextSnapshot(_ context.Context, tripID, pageKey string, payload map[string]any) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.contextSnapshots = append(r.contextSnapshots, ContextSnapshot{
+		ID:          uuid.NewString(),
+		TripID:      tripID,
+		PageKey:     pageKey,
+		ContextJSON: copyMap(payload),
+		GeneratedAt: time.Now().UTC(),
+	})
+	return nil
+}
+
+func (r *MemoryAIRepository) ListToolSnapshots(_ context.Context, conversationID string) ([]ToolSnapshot, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]ToolSnapshot, 0)
+	for _, snap := range r.toolSnapshots {
+		if snap.ConversationID == conversationID {
+			out = append(out, snap)
+		}
+	}
+	return out, nil
+}
+
+func (r *MemoryAIRepository) LatestContextSnapshot(_ context.Context, tripID, pageKey string) (*ContextSnapshot, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for i := len(r.contextSnapshots) - 1; i >= 0; i-- {
+		if snap := r.contextSnapshots[i]; snap.TripID == tripID && snap.PageKey == pageKey {
+			return &snap, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *MemoryAIRepository) InsertAuditLog(_ context.Context, userID, tripID, action string, metadata map[string]any) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.auditLogs = append(r.auditLogs, AuditLog{
+		ID:           uuid.NewString(),
+		UserID:       userID,
+		TripID:       tripID,
+		Action:       action,
+		MetadataJSON: copyMap(metadata),
+		CreatedAt:    time.Now().UTC(),
+	})
+	return nil
+}
+
+func (r *MemoryAIRepository) ListAuditLogs(_ context.Context, tripID string, limit int) ([]AuditLog, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]AuditLog, 0)
+	for i := len(r.auditLogs) - 1; i >= 0 && len(out) < limit; i-- {
+		if r.auditLogs[i].TripID == tripID {
+			out = append(out, r.auditLogs[i])
+		}
+	}
+	return out, nil
+}
+
+func (r *MemoryAIRepository) ListPromptTemplates(context.Context) ([]PromptTemplate, error) {
+	return []PromptTemplate{}, nil
+}
+
+func (r *MemoryAIRepository) Mode() string {
+	return "in-memory"
+}
+
+// copyMap keeps callers from mutating stored JSON after the fact. A nil map stays nil.
+// messageTime is now, or just after latest when the clock has not moved past it, so that no
+// two messages of a conversation share a created_at.
+func messageTime(now, latest time.Time) time.Time {
+	if now.After(latest) {
+		return now
+	}
+	return latest.Add(time.Nanosecond)
+}
+
+func copyMap(m map[string]any) map[string]any {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]any, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
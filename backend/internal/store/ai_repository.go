@@ -3,68 +3,59 @@ package store
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"sort"
-	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// AIRepository is the Postgres implementation of AIStore.
 type AIRepository struct {
 	db *pgxpool.Pool
-
-	mu                  sync.RWMutex
-	conversationsByID   map[string]Conversation
-	conversationByOwner map[string]string
-	messagesByConvID    map[string][]Message
-}
-
-type Trip struct {
-	ID          string    `json:"id"`
-	Destination string    `json:"destination"`
-	StartDate   time.Time `json:"startDate"`
-	EndDate     time.Time `json:"endDate"`
-	Timezone    string    `json:"timezone"`
-}
-
-type Conversation struct {
-	ID        string    `json:"id"`
-	TripID    string    `json:"tripId"`
-	UserID    string    `json:"userId"`
-	Title     string    `json:"title"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
-}
-
-type Message struct {
-	ID             string         `json:"id"`
-	ConversationID string         `json:"conversationId"`
-	Role           string         `json:"role"`
-	Content        string         `json:"content"`
-	Model          string         `json:"model"`
-	TokenUsageJSON map[string]any `json:"tokenUsageJson"`
-	CreatedAt      time.Time      `json:"createdAt"`
 }
 
 func NewAIRepository(db *pgxpool.Pool) *AIRepository {
 	return &AIRepository{db: db}
 }
 
-func NewInMemoryAIRepository() *AIRepository {
-	return &AIRepository{
-		conversationsByID:   make(map[string]Conversation),
-		conversationByOwner: make(map[string]string),
-		messagesByConvID:    make(map[string][]Message),
+func (r *AIRepository) CreateTrip(ctx context.Context, trip Trip, ownerID string) (*Trip, error) {
+	if trip.ID == "" {
+		trip.ID = uuid.NewString()
 	}
-}
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
 
-func (r *AIRepository) IsTripMember(ctx context.Context, tripID, userID string) (bool, error) {
-	if r.db == nil {
-		return tripID != "" && userID != "", nil
+	const insertTrip = `
+		INSERT INTO trips (id, destination, start_date, end_date, timezone, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NOW(), NOW())`
+	if _, err := tx.Exec(ctx, insertTrip, trip.ID, trip.Destination, trip.StartDate, trip.EndDate, trip.Timezone); err != nil {
+		return nil, err
+	}
+	const insertOwner = `INSERT INTO trip_members (trip_id, user_id, role, created_at) VALUES ($1, $2, 'owner', NOW())`
+	if _, err := tx.Exec(ctx, insertOwner, trip.ID, ownerID); err != nil {
+		return nil, err
 	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return &trip, nil
+}
 
+func (r *AIRepository) AddTripMember(ctx context.Context, tripID, userID, role string) error {
+	const q = `
+		INSERT INTO trip_members (trip_id, user_id, role, created_at) VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (trip_id, user_id) DO UPDATE SET role = EXCLUDED.role`
+	_, err := r.db.Exec(ctx, q, tripID, userID, role)
+	return err
+}
+
+func (r *AIRepository) IsTripMember(ctx context.Context, tripID, userID string) (bool, error) {
 	const q = `SELECT EXISTS(SELECT 1 FROM trip_members WHERE trip_id = $1 AND user_id = $2)`
 	var exists bool
 	if err := r.db.QueryRow(ctx, q, tripID, userID).Scan(&exists); err != nil {
@@ -73,103 +64,162 @@ func (r *AIRepository) IsTripMember(ctx context.Context, tripID, userID string)
 	return exists, nil
 }
 
-func (r *AIRepository) GetTripByID(ctx context.Context, tripID string) (*Trip, error) {
-	if r.db == nil {
-		now := time.Now().UTC()
-		return &Trip{
-			ID:          tripID,
-			Destination: "Test Destination",
-			StartDate:   now,
-			EndDate:     now.Add(72 * time.Hour),
-			Timezone:    "UTC",
-		}, nil
+func (r *AIRepository) TripRole(ctx context.Context, tripID, userID string) (string, error) {
+	const q = `SELECT role FROM trip_members WHERE trip_id = $1 AND user_id = $2`
+	var role string
+	if err := r.db.QueryRow(ctx, q, tripID, userID).Scan(&role); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", nil
+		}
+		return "", err
 	}
+	return role, nil
+}
 
+func (r *AIRepository) GetTripByID(ctx context.Context, tripID string) (*Trip, error) {
 	const q = `SELECT id, destination, start_date, end_date, COALESCE(timezone, '') FROM trips WHERE id = $1`
 	var t Trip
 	if err := r.db.QueryRow(ctx, q, tripID).Scan(&t.ID, &t.Destination, &t.StartDate, &t.EndDate, &t.Timezone); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
 		return nil, err
 	}
 	return &t, nil
 }
 
 func (r *AIRepository) UpsertConversation(ctx context.Context, tripID, userID, title string) (string, error) {
-	if r.db == nil {
-		ownerKey := tripID + ":" + userID
-		now := time.Now().UTC()
-
-		r.mu.Lock()
-		defer r.mu.Unlock()
-
-		if id, ok := r.conversationByOwner[ownerKey]; ok {
-			conv := r.conversationsByID[id]
-			conv.UpdatedAt = now
-			r.conversationsByID[id] = conv
-			return id, nil
-		}
-
-		id := uuid.NewString()
-		conv := Conversation{ID: id, TripID: tripID, UserID: userID, Title: title, CreatedAt: now, UpdatedAt: now}
-		r.conversationsByID[id] = conv
-		r.conversationByOwner[ownerKey] = id
-		return id, nil
-	}
-
-	const findLatest = `SELECT id FROM ai_conversations WHERE trip_id = $1 AND user_id = $2 ORDER BY updated_at DESC LIMIT 1`
+	const findLatest = `
+		SELECT id FROM ai_conversations
+		WHERE trip_id = $1 AND user_id = $2 AND archived_at IS NULL
+		ORDER BY updated_at DESC LIMIT 1`
 	var id string
 	if err := r.db.QueryRow(ctx, findLatest, tripID, userID).Scan(&id); err == nil {
 		_, _ = r.db.Exec(ctx, `UPDATE ai_conversations SET updated_at = NOW() WHERE id = $1`, id)
 		return id, nil
 	}
 
-	id = uuid.NewString()
-	const insert = `
-		INSERT INTO ai_conversations (id, trip_id, user_id, title, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, NOW(), NOW())`
-	if _, err := r.db.Exec(ctx, insert, id, tripID, userID, title); err != nil {
+	conv, err := r.CreateConversation(ctx, tripID, userID, title)
+	if err != nil {
 		return "", err
 	}
-	return id, nil
+	return conv.ID, nil
 }
 
-func (r *AIRepository) InsertMessage(ctx context.Context, conversationID, role, content, model string, usage map[string]any) error {
-	if r.db == nil {
-		r.mu.Lock()
-		defer r.mu.Unlock()
-		copiedUsage := map[string]any{}
-		for k, v := range usage {
-			copiedUsage[k] = v
-		}
-		msg := Message{
-			ID:             uuid.NewString(),
-			ConversationID: conversationID,
-			Role:           role,
-			Content:        content,
-			Model:          model,
-			TokenUsageJSON: copiedUsage,
-			CreatedAt:      time.Now().UTC(),
-		}
-		r.messagesByConvID[conversationID] = append(r.messagesByConvID[conversationID], msg)
-		if conv, ok := r.conversationsByID[conversationID]; ok {
-			conv.UpdatedAt = msg.CreatedAt
-			r.conversationsByID[conversationID] = conv
+func (r *AIRepository) CreateConversation(ctx context.Context, tripID, userID, title string) (*Conversation, error) {
+	conv := Conversation{ID: uuid.NewString(), TripID: tripID, UserID: userID, Title: title}
+	const q = `
+		INSERT INTO ai_conversations (id, trip_id, user_id, title, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, NOW(), NOW())
+		RETURNING created_at, updated_at`
+	if err := r.db.QueryRow(ctx, q, conv.ID, tripID, userID, title).Scan(&conv.CreatedAt, &conv.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &conv, nil
+}
+
+func (r *AIRepository) GetConversation(ctx context.Context, conversationID string) (*Conversation, error) {
+	q := `SELECT ` + conversationColumns + ` FROM ai_conversations WHERE id = $1`
+	var c Conversation
+	if err := r.db.QueryRow(ctx, q, conversationID).Scan(&c.ID, &c.TripID, &c.UserID, &c.Title, &c.CreatedAt, &c.UpdatedAt, &c.ArchivedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
 		}
-		return nil
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *AIRepository) RenameConversation(ctx context.Context, conversationID, title string) error {
+	return r.execConversation(ctx, `UPDATE ai_conversations SET title = $2 WHERE id = $1`, conversationID, title)
+}
+
+func (r *AIRepository) SetConversationArchived(ctx context.Context, conversationID string, archived bool) error {
+	const q = `UPDATE ai_conversations SET archived_at = CASE WHEN $2 THEN COALESCE(archived_at, NOW()) END WHERE id = $1`
+	return r.execConversation(ctx, q, conversationID, archived)
+}
+
+func (r *AIRepository) DeleteConversation(ctx context.Context, conversationID string) error {
+	return r.execConversation(ctx, `DELETE FROM ai_conversations WHERE id = $1`, conversationID)
+}
+
+func (r *AIRepository) execConversation(ctx context.Context, q string, args ...any) error {
+	tag, err := r.db.Exec(ctx, q, args...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
 	}
+	return nil
+}
 
-	usageJSON, _ := json.Marshal(usage)
+func (r *AIRepository) InsertMessage(ctx context.Context, msg Message) (*Message, error) {
+	usageJSON, _ := json.Marshal(msg.TokenUsageJSON)
+	msg.ID = uuid.NewString()
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	// Locking the conversation serializes its inserts, so the latest created_at read below
+	// is final.
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM ai_conversations WHERE id = $1 FOR UPDATE`, msg.ConversationID); err != nil {
+		return nil, err
+	}
 	const q = `
-		INSERT INTO ai_messages (id, conversation_id, role, content, model, token_usage_json, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6::jsonb, NOW())`
-	_, err := r.db.Exec(ctx, q, uuid.NewString(), conversationID, role, content, model, string(usageJSON))
-	return err
+		INSERT INTO ai_messages (id, conversation_id, role, content, model, token_usage_json, page_key, prompt_version, regenerated_from, context_json, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6::jsonb, NULLIF($7, ''), NULLIF($8, ''), NULLIF($9, ''), $10::jsonb,
+			GREATEST(NOW(), (SELECT MAX(created_at) + INTERVAL '1 microsecond' FROM ai_messages WHERE conversation_id = $2)))
+		RETURNING created_at`
+	err = tx.QueryRow(ctx, q, msg.ID, msg.ConversationID, msg.Role, msg.Content, msg.Model, string(usageJSON), msg.PageKey, msg.PromptVersion, msg.RegeneratedFrom, contextJSON(msg.ContextJSON)).Scan(&msg.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE ai_conversations SET updated_at = $2 WHERE id = $1`, msg.ConversationID, msg.CreatedAt); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return &msg, nil
 }
 
-func (r *AIRepository) InsertToolSnapshot(ctx context.Context, conversationID, pageKey, toolName, status string, payload map[string]any) error {
-	if r.db == nil {
-		return nil
+func (r *AIRepository) GetMessage(ctx context.Context, messageID string) (*Message, error) {
+	q := `SELECT ` + messageColumns + ` FROM ai_messages WHERE id = $1`
+	m, err := scanMessage(r.db.QueryRow(ctx, q, messageID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return m, err
+}
+
+func (r *AIRepository) MessageContext(ctx context.Context, messageID string) (map[string]any, error) {
+	var raw []byte
+	err := r.db.QueryRow(ctx, `SELECT context_json FROM ai_messages WHERE id = $1`, messageID).Scan(&raw)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodeMessageContext(raw)
+}
+
+// scanMessage reads messageColumns.
+func scanMessage(row pgx.Row) (*Message, error) {
+	var m Message
+	var usageBytes []byte
+	if err := row.Scan(&m.ID, &m.ConversationID, &m.Role, &m.Content, &m.Model, &usageBytes, &m.PageKey, &m.PromptVersion, &m.RegeneratedFrom, &m.CreatedAt); err != nil {
+		return nil, err
 	}
+	if len(usageBytes) > 0 {
+		_ = json.Unmarshal(usageBytes, &m.TokenUsageJSON)
+	}
+	return &m, nil
+}
 
+func (r *AIRepository) InsertToolSnapshot(ctx context.Context, conversationID, pageKey, toolName, status string, payload map[string]any) error {
 	payloadJSON, _ := json.Marshal(payload)
 	const q = `
 		INSERT INTO ai_tool_snapshots (id, conversation_id, page_key, tool_name, status, payload_json, fetched_at)
@@ -179,10 +229,6 @@ func (r *AIRepository) InsertToolSnapshot(ctx context.Context, conversationID, p
 }
 
 func (r *AIRepository) InsertContextSnapshot(ctx context.Context, tripID, pageKey string, payload map[string]any) error {
-	if r.db == nil {
-		return nil
-	}
-
 	payloadJSON, _ := json.Marshal(payload)
 	const q = `
 		INSERT INTO ai_context_snapshots (id, trip_id, page_key, context_json, generated_at)
@@ -192,10 +238,6 @@ func (r *AIRepository) InsertContextSnapshot(ctx context.Context, tripID, pageKe
 }
 
 func (r *AIRepository) InsertAuditLog(ctx context.Context, userID, tripID, action string, metadata map[string]any) error {
-	if r.db == nil {
-		return nil
-	}
-
 	meta, _ := json.Marshal(metadata)
 	const q = `
 		INSERT INTO ai_audit_logs (id, user_id, trip_id, action, metadata_json, created_at)
@@ -204,55 +246,49 @@ func (r *AIRepository) InsertAuditLog(ctx context.Context, userID, tripID, actio
 	return err
 }
 
-func (r *AIRepository) ListConversations(ctx context.Context, tripID, userID string) ([]Conversation, error) {
-	if r.db == nil {
-		r.mu.RLock()
-		defer r.mu.RUnlock()
-		out := make([]Conversation, 0)
-		for _, c := range r.conversationsByID {
-			if c.TripID == tripID && c.UserID == userID {
-				out = append(out, c)
-			}
-		}
-		sort.Slice(out, func(i, j int) bool {
-			return out[i].UpdatedAt.After(out[j].UpdatedAt)
-		})
-		if len(out) > 50 {
-			out = out[:50]
-		}
-		return out, nil
+// ListConversations and ListMessages compare ids with the "C" collation so that ties on
+// created_at break in the same byte order as the cursor.
+func (r *AIRepository) ListConversations(ctx context.Context, tripID, userID string, includeArchived bool, page PageRequest) ([]Conversation, string, error) {
+	after, err := decodeCursor(page.Cursor)
+	if err != nil {
+		return nil, "", err
 	}
-
-	const q = `
-		SELECT id, trip_id, user_id, title, created_at, updated_at
+	createdBefore, idBefore := pgCursorArgs(after)
+	q := `
+		SELECT ` + conversationColumns + `
 		FROM ai_conversations
-		WHERE trip_id = $1 AND user_id = $2
-		ORDER BY updated_at DESC
-		LIMIT 50`
-	rows, err := r.db.Query(ctx, q, tripID, userID)
+		WHERE trip_id = $1 AND user_id = $2 AND ($3 OR archived_at IS NULL)
+		  AND ($4::timestamptz IS NULL OR created_at < $4 OR (created_at = $4 AND id COLLATE "C" < $5))
+		ORDER BY created_at DESC, id COLLATE "C" DESC
+		LIMIT $6`
+	rows, err := r.db.Query(ctx, q, tripID, userID, includeArchived, createdBefore, idBefore, page.Limit+1)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	defer rows.Close()
 	out := make([]Conversation, 0)
 	for rows.Next() {
 		var c Conversation
-		if err := rows.Scan(&c.ID, &c.TripID, &c.UserID, &c.Title, &c.CreatedAt, &c.UpdatedAt); err != nil {
-			return nil, err
+		if err := rows.Scan(&c.ID, &c.TripID, &c.UserID, &c.Title, &c.CreatedAt, &c.UpdatedAt, &c.ArchivedAt); err != nil {
+			return nil, "", err
 		}
 		out = append(out, c)
 	}
-	return out, rows.Err()
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+	out, next := trimPage(out, page.Limit, conversationKey)
+	return out, next, nil
 }
 
-func (r *AIRepository) ConversationBelongsToUser(ctx context.Context, conversationID, userID string) (bool, error) {
-	if r.db == nil {
-		r.mu.RLock()
-		defer r.mu.RUnlock()
-		conv, ok := r.conversationsByID[conversationID]
-		return ok && conv.UserID == userID, nil
+func pgCursorArgs(after *cursorKey) (*time.Time, string) {
+	if after == nil {
+		return nil, ""
 	}
+	return &after.CreatedAt, after.ID
+}
 
+func (r *AIRepository) ConversationBelongsToUser(ctx context.Context, conversationID, userID string) (bool, error) {
 	const q = `SELECT EXISTS(SELECT 1 FROM ai_conversations WHERE id = $1 AND user_id = $2)`
 	var ok bool
 	if err := r.db.QueryRow(ctx, q, conversationID, userID).Scan(&ok); err != nil {
@@ -261,55 +297,232 @@ func (r *AIRepository) ConversationBelongsToUser(ctx context.Context, conversati
 	return ok, nil
 }
 
-func (r *AIRepository) ListMessages(ctx context.Context, conversationID string, limit int) ([]Message, error) {
-	if r.db == nil {
-		r.mu.RLock()
-		defer r.mu.RUnlock()
-		msgs := r.messagesByConvID[conversationID]
-		if len(msgs) == 0 {
-			return []Message{}, nil
-		}
-		start := len(msgs) - limit
-		if start < 0 {
-			start = 0
+func (r *AIRepository) ListMessages(ctx context.Context, conversationID string, page PageRequest) ([]Message, string, error) {
+	after, err := decodeCursor(page.Cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	createdBefore, idBefore := pgCursorArgs(after)
+	q := `
+		SELECT ` + messageColumns + `
+		FROM ai_messages
+		WHERE conversation_id = $1
+		  AND ($2::timestamptz IS NULL OR created_at < $2 OR (created_at = $2 AND id COLLATE "C" < $3))
+		ORDER BY created_at DESC, id COLLATE "C" DESC
+		LIMIT $4`
+	rows, err := r.db.Query(ctx, q, conversationID, createdBefore, idBefore, page.Limit+1)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+	out := make([]Message, 0)
+	for rows.Next() {
+		m, err := scanMessage(rows)
+		if err != nil {
+			return nil, "", err
 		}
-		selected := msgs[start:]
-		out := make([]Message, 0, len(selected))
-		for i := len(selected) - 1; i >= 0; i-- {
-			out = append(out, selected[i])
+		out = append(out, *m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+	out, next := trimPage(out, page.Limit, messageKey)
+	return out, next, nil
+}
+
+// SearchMessages uses the content_tsv index from migration 010. The content is HTML-escaped
+// before ts_headline adds the <mark> tags.
+func (r *AIRepository) SearchMessages(ctx context.Context, userID, tripID, query string, limit int) ([]MessageHit, error) {
+	const q = `
+		SELECT m.id, m.conversation_id, c.title, c.trip_id, m.role, m.created_at,
+		       ts_rank(m.content_tsv, query) AS rank,
+		       ts_headline('english',
+		                   replace(replace(replace(m.content, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'),
+		                   query, 'StartSel=<mark>, StopSel=</mark>, MaxWords=24, MinWords=8, MaxFragments=2')
+		FROM ai_messages m
+		JOIN ai_conversations c ON c.id = m.conversation_id,
+		     websearch_to_tsquery('english', $3) AS query
+		WHERE c.user_id = $1 AND ($2 = '' OR c.trip_id = $2) AND m.content_tsv @@ query
+		ORDER BY rank DESC, m.created_at DESC
+		LIMIT $4`
+	rows, err := r.db.Query(ctx, q, userID, tripID, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]MessageHit, 0)
+	for rows.Next() {
+		var h MessageHit
+		var rank float32
+		if err := rows.Scan(&h.MessageID, &h.ConversationID, &h.ConversationTitle, &h.TripID, &h.Role, &h.CreatedAt, &rank, &h.Snippet); err != nil {
+			return nil, err
 		}
-		return out, nil
+		h.Rank = float64(rank)
+		out = append(out, h)
 	}
+	return out, rows.Err()
+}
 
+func (r *AIRepository) ListToolSnapshots(ctx context.Context, conversationID string) ([]ToolSnapshot, error) {
 	const q = `
-		SELECT id, conversation_id, role, content, model, token_usage_json, created_at
-		FROM ai_messages
+		SELECT id, conversation_id, page_key, tool_name, status, payload_json, fetched_at
+		FROM ai_tool_snapshots
 		WHERE conversation_id = $1
+		ORDER BY fetched_at ASC`
+	rows, err := r.db.Query(ctx, q, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]ToolSnapshot, 0)
+	for rows.Next() {
+		var snap ToolSnapshot
+		var payloadBytes []byte
+		if err := rows.Scan(&snap.ID, &snap.ConversationID, &snap.PageKey, &snap.ToolName, &snap.Status, &payloadBytes, &snap.FetchedAt); err != nil {
+			return nil, err
+		}
+		if len(payloadBytes) > 0 {
+			_ = json.Unmarshal(payloadBytes, &snap.PayloadJSON)
+		}
+		out = append(out, snap)
+	}
+	return out, rows.Err()
+}
+
+func (r *AIRepository) LatestContextSnapshot(ctx context.Context, tripID, pageKey string) (*ContextSnapshot, error) {
+	const q = `
+		SELECT id, trip_id, page_key, context_json, generated_at
+		FROM ai_context_snapshots
+		WHERE trip_id = $1 AND page_key = $2
+		ORDER BY generated_at DESC
+		LIMIT 1`
+	var snap ContextSnapshot
+	var contextBytes []byte
+	if err := r.db.QueryRow(ctx, q, tripID, pageKey).Scan(&snap.ID, &snap.TripID, &snap.PageKey, &contextBytes, &snap.GeneratedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	_ = json.Unmarshal(contextBytes, &snap.ContextJSON)
+	return &snap, nil
+}
+
+func (r *AIRepository) ListAuditLogs(ctx context.Context, tripID string, limit int) ([]AuditLog, error) {
+	const q = `
+		SELECT id, user_id, trip_id, action, metadata_json, created_at
+		FROM ai_audit_logs
+		WHERE trip_id = $1
 		ORDER BY created_at DESC
 		LIMIT $2`
-	rows, err := r.db.Query(ctx, q, conversationID, limit)
+	rows, err := r.db.Query(ctx, q, tripID, limit)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	out := make([]Message, 0)
+	out := make([]AuditLog, 0)
 	for rows.Next() {
-		var m Message
-		var usageBytes []byte
-		if err := rows.Scan(&m.ID, &m.ConversationID, &m.Role, &m.Content, &m.Model, &usageBytes, &m.CreatedAt); err != nil {
+		var entry AuditLog
+		var metaBytes []byte
+		if err := rows.Scan(&entry.ID, &entry.UserID, &entry.TripID, &entry.Action, &metaBytes, &entry.CreatedAt); err != nil {
 			return nil, err
 		}
-		if len(usageBytes) > 0 {
-			_ = json.Unmarshal(usageBytes, &m.TokenUsageJSON)
+		if len(metaBytes) > 0 {
+			_ = json.Unmarshal(metaBytes, &entry.MetadataJSON)
 		}
-		out = append(out, m)
+		out = append(out, entry)
 	}
 	return out, rows.Err()
 }
 
-func (r *AIRepository) Mode() string {
-	if r.db == nil {
-		return "in-memory"
+const conversationColumns = `id, trip_id, user_id, title, created_at, updated_at, archived_at`
+
+// contextJSON encodes a message's ContextJSON, or NULL when it has none.
+func contextJSON(payload map[string]any) any {
+	if payload == nil {
+		return nil
+	}
+	raw, _ := json.Marshal(payload)
+	return string(raw)
+}
+
+func decodeMessageContext(raw []byte) (map[string]any, error) {
+	var payload map[string]any
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &payload); err != nil {
+			return nil, err
+		}
+	}
+	if payload == nil {
+		return nil, ErrNotFound
+	}
+	return payload, nil
+}
+
+const messageColumns = `id, conversation_id, role, content, COALESCE(model, ''), token_usage_json,
+	COALESCE(page_key, ''), COALESCE(prompt_version, ''), COALESCE(regenerated_from, ''), created_at`
+
+func (r *AIRepository) UpsertFeedback(ctx context.Context, f Feedback) (*Feedback, error) {
+	const q = `
+		INSERT INTO ai_message_feedback (message_id, user_id, rating, reasons, comment, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
+		ON CONFLICT (message_id, user_id) DO UPDATE
+		SET rating = EXCLUDED.rating, reasons = EXCLUDED.reasons, comment = EXCLUDED.comment, updated_at = NOW()
+		RETURNING created_at, updated_at`
+	if f.Reasons == nil {
+		f.Reasons = []string{}
+	}
+	if err := r.db.QueryRow(ctx, q, f.MessageID, f.UserID, f.Rating, f.Reasons, f.Comment).Scan(&f.CreatedAt, &f.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+func (r *AIRepository) FeedbackReport(ctx context.Context, tripID string, since time.Time) ([]FeedbackSummary, error) {
+	const q = `
+		SELECT COALESCE(m.model, ''), COALESCE(m.page_key, ''), COALESCE(m.prompt_version, ''), f.rating, f.reasons
+		FROM ai_message_feedback f
+		JOIN ai_messages m ON m.id = f.message_id
+		JOIN ai_conversations c ON c.id = m.conversation_id
+		WHERE c.trip_id = $1 AND f.updated_at >= $2`
+	rows, err := r.db.Query(ctx, q, tripID, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	report := newFeedbackReport()
+	for rows.Next() {
+		var model, pageKey, promptVersion, rating string
+		var reasons []string
+		if err := rows.Scan(&model, &pageKey, &promptVersion, &rating, &reasons); err != nil {
+			return nil, err
+		}
+		report.add(model, pageKey, promptVersion, rating, reasons)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return report.summaries(), nil
+}
+
+func (r *AIRepository) ListPromptTemplates(ctx context.Context) ([]PromptTemplate, error) {
+	const q = `SELECT name, version, body, weight, created_at FROM ai_prompt_templates ORDER BY name, version`
+	rows, err := r.db.Query(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]PromptTemplate, 0)
+	for rows.Next() {
+		var t PromptTemplate
+		if err := rows.Scan(&t.Name, &t.Version, &t.Body, &t.Weight, &t.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, t)
 	}
+	return out, rows.Err()
+}
+
+func (r *AIRepository) Mode() string {
 	return fmt.Sprintf("postgres:%T", r.db)
 }
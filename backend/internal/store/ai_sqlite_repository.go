@@ -0,0 +1,538 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SQLiteAIRepository is the AIStore for self-hosted single-binary deployments.
+type SQLiteAIRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteAIRepository(db *sql.DB) *SQLiteAIRepository {
+	return &SQLiteAIRepository{db: db}
+}
+
+func (r *SQLiteAIRepository) CreateTrip(ctx context.Context, trip Trip, ownerID string) (*Trip, error) {
+	if trip.ID == "" {
+		trip.ID = uuid.NewString()
+	}
+	now := sqliteTime(time.Now())
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	const insertTrip = `
+		INSERT INTO trips (id, destination, start_date, end_date, timezone, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $6)`
+	if _, err := tx.ExecContext(ctx, insertTrip, trip.ID, trip.Destination, trip.StartDate.Format("2006-01-02"), trip.EndDate.Format("2006-01-02"), trip.Timezone, now); err != nil {
+		return nil, err
+	}
+	const insertOwner = `INSERT INTO trip_members (trip_id, user_id, role, created_at) VALUES ($1, $2, 'owner', $3)`
+	if _, err := tx.ExecContext(ctx, insertOwner, trip.ID, ownerID, now); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &trip, nil
+}
+
+func (r *SQLiteAIRepository) AddTripMember(ctx context.Context, tripID, userID, role string) error {
+	const q = `
+		INSERT INTO trip_members (trip_id, user_id, role, created_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (trip_id, user_id) DO UPDATE SET role = excluded.role`
+	_, err := r.db.ExecContext(ctx, q, tripID, userID, role, sqliteTime(time.Now()))
+	return err
+}
+
+func (r *SQLiteAIRepository) IsTripMember(ctx context.Context, tripID, userID string) (bool, error) {
+	const q = `SELECT EXISTS(SELECT 1 FROM trip_members WHERE trip_id = $1 AND user_id = $2)`
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, q, tripID, userID).Scan(&exists); err != nil {
+		return false, err
+	}
+	return exists, nil
+}
+
+func (r *SQLiteAIRepository) TripRole(ctx context.Context, tripID, userID string) (string, error) {
+	const q = `SELECT role FROM trip_members WHERE trip_id = $1 AND user_id = $2`
+	var role string
+	if err := r.db.QueryRowContext(ctx, q, tripID, userID).Scan(&role); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil
+		}
+		return "", err
+	}
+	return role, nil
+}
+
+func (r *SQLiteAIRepository) GetTripByID(ctx context.Context, tripID string) (*Trip, error) {
+	const q = `SELECT id, destination, start_date, end_date, COALESCE(timezone, '') FROM trips WHERE id = $1`
+	var t Trip
+	var start, end string
+	if err := r.db.QueryRowContext(ctx, q, tripID).Scan(&t.ID, &t.Destination, &start, &end, &t.Timezone); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	t.StartDate, _ = time.Parse("2006-01-02", start)
+	t.EndDate, _ = time.Parse("2006-01-02", end)
+	return &t, nil
+}
+
+func (r *SQLiteAIRepository) UpsertConversation(ctx context.Context, tripID, userID, title string) (string, error) {
+	const findLatest = `
+		SELECT id FROM ai_conversations
+		WHERE trip_id = $1 AND user_id = $2 AND archived_at IS NULL
+		ORDER BY updated_at DESC, rowid DESC LIMIT 1`
+	var id string
+	if err := r.db.QueryRowContext(ctx, findLatest, tripID, userID).Scan(&id); err == nil {
+		_, _ = r.db.ExecContext(ctx, `UPDATE ai_conversations SET updated_at = $2 WHERE id = $1`, id, sqliteTime(time.Now()))
+		return id, nil
+	}
+
+	conv, err := r.CreateConversation(ctx, tripID, userID, title)
+	if err != nil {
+		return "", err
+	}
+	return conv.ID, nil
+}
+
+func (r *SQLiteAIRepository) CreateConversation(ctx context.Context, tripID, userID, title string) (*Conversation, error) {
+	now := time.Now().UTC()
+	conv := Conversation{ID: uuid.NewString(), TripID: tripID, UserID: userID, Title: title, CreatedAt: now, UpdatedAt: now}
+	const q = `
+		INSERT INTO ai_conversations (id, trip_id, user_id, title, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $5)`
+	if _, err := r.db.ExecContext(ctx, q, conv.ID, tripID, userID, title, sqliteTime(now)); err != nil {
+		return nil, err
+	}
+	return &conv, nil
+}
+
+func (r *SQLiteAIRepository) GetConversation(ctx context.Context, conversationID string) (*Conversation, error) {
+	q := `SELECT ` + conversationColumns + ` FROM ai_conversations WHERE id = $1`
+	c, err := scanSQLiteConversation(r.db.QueryRowContext(ctx, q, conversationID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return c, err
+}
+
+func (r *SQLiteAIRepository) RenameConversation(ctx context.Context, conversationID, title string) error {
+	return r.execConversation(ctx, `UPDATE ai_conversations SET title = $2 WHERE id = $1`, conversationID, title)
+}
+
+func (r *SQLiteAIRepository) SetConversationArchived(ctx context.Context, conversationID string, archived bool) error {
+	const q = `UPDATE ai_conversations SET archived_at = CASE WHEN $2 THEN COALESCE(archived_at, $3) END WHERE id = $1`
+	return r.execConversation(ctx, q, conversationID, archived, sqliteTime(time.Now()))
+}
+
+func (r *SQLiteAIRepository) DeleteConversation(ctx context.Context, conversationID string) error {
+	return r.execConversation(ctx, `DELETE FROM ai_conversations WHERE id = $1`, conversationID)
+}
+
+func (r *SQLiteAIRepository) execConversation(ctx context.Context, q string, args ...any) error {
+	res, err := r.db.ExecContext(ctx, q, args...)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *SQLiteAIRepository) InsertMessage(ctx context.Context, msg Message) (*Message, error) {
+	msg.ID = uuid.NewString()
+	usageJSON, _ := json.Marshal(msg.TokenUsageJSON)
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	var latest sql.NullString
+	if err := tx.QueryRowContext(ctx, `SELECT MAX(created_at) FROM ai_messages WHERE conversation_id = $1`, msg.ConversationID).Scan(&latest); err != nil {
+		return nil, err
+	}
+	msg.CreatedAt = messageTime(time.Now().UTC(), parseSQLiteTime(latest.String))
+	now := sqliteTime(msg.CreatedAt)
+	const q = `
+		INSERT INTO ai_messages (id, conversation_id, role, content, model, token_usage_json, page_key, prompt_version, regenerated_from, context_json, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), NULLIF($8, ''), NULLIF($9, ''), $10, $11)`
+	if _, err := tx.ExecContext(ctx, q, msg.ID, msg.ConversationID, msg.Role, msg.Content, msg.Model, string(usageJSON), msg.PageKey, msg.PromptVersion, msg.RegeneratedFrom, contextJSON(msg.ContextJSON), now); err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE ai_conversations SET updated_at = $2 WHERE id = $1`, msg.ConversationID, now); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+func (r *SQLiteAIRepository) GetMessage(ctx context.Context, messageID string) (*Message, error) {
+	q := `SELECT ` + messageColumns + ` FROM ai_messages WHERE id = $1`
+	m, err := scanSQLiteMessage(r.db.QueryRowContext(ctx, q, messageID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return m, err
+}
+
+func (r *SQLiteAIRepository) MessageContext(ctx context.Context, messageID string) (map[string]any, error) {
+	var raw sql.NullString
+	err := r.db.QueryRowContext(ctx, `SELECT context_json FROM ai_messages WHERE id = $1`, messageID).Scan(&raw)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodeMessageContext([]byte(raw.String))
+}
+
+func scanSQLiteMessage(row interface{ Scan(...any) error }) (*Message, error) {
+	var m Message
+	var usage sql.NullString
+	var created string
+	if err := row.Scan(&m.ID, &m.ConversationID, &m.Role, &m.Content, &m.Model, &usage, &m.PageKey, &m.PromptVersion, &m.RegeneratedFrom, &created); err != nil {
+		return nil, err
+	}
+	if usage.Valid {
+		_ = json.Unmarshal([]byte(usage.String), &m.TokenUsageJSON)
+	}
+	m.CreatedAt = parseSQLiteTime(created)
+	return &m, nil
+}
+
+func (r *SQLiteAIRepository) ListConversations(ctx context.Context, tripID, userID string, includeArchived bool, page PageRequest) ([]Conversation, string, error) {
+	after, err := decodeCursor(page.Cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	createdBefore, idBefore := sqliteCursorArgs(after)
+	q := `
+		SELECT ` + conversationColumns + `
+		FROM ai_conversations
+		WHERE trip_id = $1 AND user_id = $2 AND ($3 OR archived_at IS NULL)
+		  AND ($4 = '' OR created_at < $4 OR (created_at = $4 AND id < $5))
+		ORDER BY created_at DESC, id DESC
+		LIMIT $6`
+	rows, err := r.db.QueryContext(ctx, q, tripID, userID, includeArchived, createdBefore, idBefore, page.Limit+1)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+	out := make([]Conversation, 0)
+	for rows.Next() {
+		c, err := scanSQLiteConversation(rows)
+		if err != nil {
+			return nil, "", err
+		}
+		out = append(out, *c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+	out, next := trimPage(out, page.Limit, conversationKey)
+	return out, next, nil
+}
+
+// sqliteCursorArgs formats the cursor like the stored TEXT timestamps so they compare as strings.
+func sqliteCursorArgs(after *cursorKey) (string, string) {
+	if after == nil {
+		return "", ""
+	}
+	return sqliteTime(after.CreatedAt), after.ID
+}
+
+func scanSQLiteConversation(row interface{ Scan(...any) error }) (*Conversation, error) {
+	var c Conversation
+	var created, updated string
+	var archived sql.NullString
+	if err := row.Scan(&c.ID, &c.TripID, &c.UserID, &c.Title, &created, &updated, &archived); err != nil {
+		return nil, err
+	}
+	c.CreatedAt, c.UpdatedAt = parseSQLiteTime(created), parseSQLiteTime(updated)
+	c.ArchivedAt = sqliteNullTime(archived)
+	return &c, nil
+}
+
+func (r *SQLiteAIRepository) ConversationBelongsToUser(ctx context.Context, conversationID, userID string) (bool, error) {
+	const q = `SELECT EXISTS(SELECT 1 FROM ai_conversations WHERE id = $1 AND user_id = $2)`
+	var ok bool
+	if err := r.db.QueryRowContext(ctx, q, conversationID, userID).Scan(&ok); err != nil {
+		return false, err
+	}
+	return ok, nil
+}
+
+func (r *SQLiteAIRepository) ListMessages(ctx context.Context, conversationID string, page PageRequest) ([]Message, string, error) {
+	after, err := decodeCursor(page.Cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	createdBefore, idBefore := sqliteCursorArgs(after)
+	q := `
+		SELECT ` + messageColumns + `
+		FROM ai_messages
+		WHERE conversation_id = $1
+		  AND ($2 = '' OR created_at < $2 OR (created_at = $2 AND id < $3))
+		ORDER BY created_at DESC, id DESC
+		LIMIT $4`
+	rows, err := r.db.QueryContext(ctx, q, conversationID, createdBefore, idBefore, page.Limit+1)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+	out := make([]Message, 0)
+	for rows.Next() {
+		m, err := scanSQLiteMessage(rows)
+		if err != nil {
+			return nil, "", err
+		}
+		out = append(out, *m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+	out, next := trimPage(out, page.Limit, messageKey)
+	return out, next, nil
+}
+
+// SearchMessages narrows candidates with LIKE (case-insensitive for ASCII) and ranks them
+// in Go, the same way as the in-memory store.
+func (r *SQLiteAIRepository) SearchMessages(ctx context.Context, userID, tripID, query string, limit int) ([]MessageHit, error) {
+	terms := SearchTerms(query)
+	if len(terms) == 0 {
+		return []MessageHit{}, nil
+	}
+	q := `
+		SELECT m.id, m.conversation_id, c.title, c.trip_id, m.role, m.content, m.created_at
+		FROM ai_messages m
+		JOIN ai_conversations c ON c.id = m.conversation_id
+		WHERE c.user_id = $1 AND ($2 = '' OR c.trip_id = $2)`
+	args := []any{userID, tripID}
+	for _, term := range terms {
+		args = append(args, "%"+escapeLike(term)+"%")
+		q += fmt.Sprintf(` AND m.content LIKE $%d ESCAPE '\'`, len(args))
+	}
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	hits := make([]MessageHit, 0)
+	for rows.Next() {
+		var h MessageHit
+		var content, created string
+		if err := rows.Scan(&h.MessageID, &h.ConversationID, &h.ConversationTitle, &h.TripID, &h.Role, &content, &created); err != nil {
+			return nil, err
+		}
+		rank, snippet, ok := matchContent(content, terms)
+		if !ok {
+			continue
+		}
+		h.Rank, h.Snippet, h.CreatedAt = rank, snippet, parseSQLiteTime(created)
+		hits = append(hits, h)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return sortHits(hits, limit), nil
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// UpsertFeedback stores reasons as a JSON array; SQLite has no arrays.
+func (r *SQLiteAIRepository) UpsertFeedback(ctx context.Context, f Feedback) (*Feedback, error) {
+	if f.Reasons == nil {
+		f.Reasons = []string{}
+	}
+	reasons, _ := json.Marshal(f.Reasons)
+	now := sqliteTime(time.Now())
+	const q = `
+		INSERT INTO ai_message_feedback (message_id, user_id, rating, reasons, comment, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $6)
+		ON CONFLICT (message_id, user_id) DO UPDATE
+		SET rating = excluded.rating, reasons = excluded.reasons, comment = excluded.comment, updated_at = excluded.updated_at
+		RETURNING created_at, updated_at`
+	var created, updated string
+	if err := r.db.QueryRowContext(ctx, q, f.MessageID, f.UserID, f.Rating, string(reasons), f.Comment, now).Scan(&created, &updated); err != nil {
+		return nil, err
+	}
+	f.CreatedAt, f.UpdatedAt = parseSQLiteTime(created), parseSQLiteTime(updated)
+	return &f, nil
+}
+
+func (r *SQLiteAIRepository) FeedbackReport(ctx context.Context, tripID string, since time.Time) ([]FeedbackSummary, error) {
+	const q = `
+		SELECT COALESCE(m.model, ''), COALESCE(m.page_key, ''), COALESCE(m.prompt_version, ''), f.rating, f.reasons
+		FROM ai_message_feedback f
+		JOIN ai_messages m ON m.id = f.message_id
+		JOIN ai_conversations c ON c.id = m.conversation_id
+		WHERE c.trip_id = $1 AND f.updated_at >= $2`
+	rows, err := r.db.QueryContext(ctx, q, tripID, sqliteTime(since))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	report := newFeedbackReport()
+	for rows.Next() {
+		var model, pageKey, promptVersion, rating, reasonsJSON string
+		if err := rows.Scan(&model, &pageKey, &promptVersion, &rating, &reasonsJSON); err != nil {
+			return nil, err
+		}
+		var reasons []string
+		_ = json.Unmarshal([]byte(reasonsJSON), &reasons)
+		report.add(model, pageKey, promptVersion, rating, reasons)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return report.summaries(), nil
+}
+
+func (r *SQLiteAIRepository) ListPromptTemplates(ctx context.Context) ([]PromptTemplate, error) {
+	const q = `SELECT name, version, body, weight, created_at FROM ai_prompt_templates ORDER BY name, version`
+	rows, err := r.db.QueryContext(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]PromptTemplate, 0)
+	for rows.Next() {
+		var t PromptTemplate
+		var created string
+		if err := rows.Scan(&t.Name, &t.Version, &t.Body, &t.Weight, &created); err != nil {
+			return nil, err
+		}
+		t.CreatedAt = parseSQLiteTime(created)
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+func (r *SQLiteAIRepository) InsertToolSnapshot(ctx context.Context, conversationID, pageKey, toolName, status string, payload map[string]any) error {
+	payloadJSON, _ := json.Marshal(payload)
+	const q = `
+		INSERT INTO ai_tool_snapshots (id, conversation_id, page_key, tool_name, status, payload_json, fetched_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	_, err := r.db.ExecContext(ctx, q, uuid.NewString(), conversationID, pageKey, toolName, status, string(payloadJSON), sqliteTime(time.Now()))
+	return err
+}
+
+func (r *SQLiteAIRepository) InsertContextSnapshot(ctx context.Context, tripID, pageKey string, payload map[string]any) error {
+	payloadJSON, _ := json.Marshal(payload)
+	const q = `
+		INSERT INTO ai_context_snapshots (id, trip_id, page_key, context_json, generated_at)
+		VALUES ($1, $2, $3, $4, $5)`
+	_, err := r.db.ExecContext(ctx, q, uuid.NewString(), tripID, pageKey, string(payloadJSON), sqliteTime(time.Now()))
+	return err
+}
+
+func (r *SQLiteAIRepository) ListToolSnapshots(ctx context.Context, conversationID string) ([]ToolSnapshot, error) {
+	const q = `
+		SELECT id, conversation_id, page_key, tool_name, status, payload_json, fetched_at
+		FROM ai_tool_snapshots
+		WHERE conversation_id = $1
+		ORDER BY fetched_at ASC, rowid ASC`
+	rows, err := r.db.QueryContext(ctx, q, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]ToolSnapshot, 0)
+	for rows.Next() {
+		var snap ToolSnapshot
+		var payload sql.NullString
+		var fetched string
+		if err := rows.Scan(&snap.ID, &snap.ConversationID, &snap.PageKey, &snap.ToolName, &snap.Status, &payload, &fetched); err != nil {
+			return nil, err
+		}
+		if payload.Valid {
+			_ = json.Unmarshal([]byte(payload.String), &snap.PayloadJSON)
+		}
+		snap.FetchedAt = parseSQLiteTime(fetched)
+		out = append(out, snap)
+	}
+	return out, rows.Err()
+}
+
+func (r *SQLiteAIRepository) LatestContextSnapshot(ctx context.Context, tripID, pageKey string) (*ContextSnapshot, error) {
+	const q = `
+		SELECT id, trip_id, page_key, context_json, generated_at
+		FROM ai_context_snapshots
+		WHERE trip_id = $1 AND page_key = $2
+		ORDER BY generated_at DESC, rowid DESC
+		LIMIT 1`
+	var snap ContextSnapshot
+	var contextJSON, generated string
+	if err := r.db.QueryRowContext(ctx, q, tripID, pageKey).Scan(&snap.ID, &snap.TripID, &snap.PageKey, &contextJSON, &generated); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	_ = json.Unmarshal([]byte(contextJSON), &snap.ContextJSON)
+	snap.GeneratedAt = parseSQLiteTime(generated)
+	return &snap, nil
+}
+
+func (r *SQLiteAIRepository) InsertAuditLog(ctx context.Context, userID, tripID, action string, metadata map[string]any) error {
+	meta, _ := json.Marshal(metadata)
+	const q = `
+		INSERT INTO ai_audit_logs (id, user_id, trip_id, action, metadata_json, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`
+	_, err := r.db.ExecContext(ctx, q, uuid.NewString(), userID, tripID, action, string(meta), sqliteTime(time.Now()))
+	return err
+}
+
+func (r *SQLiteAIRepository) ListAuditLogs(ctx context.Context, tripID string, limit int) ([]AuditLog, error) {
+	const q = `
+		SELECT id, user_id, trip_id, action, metadata_json, created_at
+		FROM ai_audit_logs
+		WHERE trip_id = $1
+		ORDER BY created_at DESC, rowid DESC
+		LIMIT $2`
+	rows, err := r.db.QueryContext(ctx, q, tripID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]AuditLog, 0)
+	for rows.Next() {
+		var entry AuditLog
+		var meta sql.NullString
+		var created string
+		if err := rows.Scan(&entry.ID, &entry.UserID, &entry.TripID, &entry.Action, &meta, &created); err != nil {
+			return nil, err
+		}
+		if meta.Valid {
+			_ = json.Unmarshal([]byte(meta.String), &entry.MetadataJSON)
+		}
+		entry.CreatedAt = parseSQLiteTime(created)
+		out = append(out, entry)
+	}
+	return out, rows.Err()
+}
+
+func (r *SQLiteAIRepository) Mode() string {
+	return "sqlite"
+}
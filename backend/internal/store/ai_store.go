@@ -0,0 +1,195 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+type Trip struct {
+	ID          string    `json:"id"`
+	Destination string    `json:"destination"`
+	StartDate   time.Time `json:"startDate"`
+	EndDate     time.Time `json:"endDate"`
+	Timezone    string    `json:"timezone"`
+}
+
+type Conversation struct {
+	ID         string     `json:"id"`
+	TripID     string     `json:"tripId"`
+	UserID     string     `json:"userId"`
+	Title      string     `json:"title"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	UpdatedAt  time.Time  `json:"updatedAt"`
+	ArchivedAt *time.Time `json:"archivedAt,omitempty"`
+}
+
+type Message struct {
+	ID             string         `json:"id"`
+	ConversationID string         `json:"conversationId"`
+	Role           string         `json:"role"`
+	Content        string         `json:"content"`
+	Model          string         `json:"model"`
+	TokenUsageJSON map[string]any `json:"tokenUsageJson"`
+	PageKey        string         `json:"pageKey,omitempty"`
+	// PromptVersion is the name@version of the system prompt an answer was generated with.
+	PromptVersion string `json:"promptVersion,omitempty"`
+	// RegeneratedFrom is the original answer this one was regenerated from. Every version
+	// points at the original, never at another regeneration.
+	RegeneratedFrom string    `json:"regeneratedFrom,omitempty"`
+	CreatedAt       time.Time `json:"createdAt"`
+	// ContextJSON is the context an answer was generated from. InsertMessage stores it, but
+	// only MessageContext reads it back: listings do not need it.
+	ContextJSON map[string]any `json:"-"`
+}
+
+// Feedback is one user's rating of an assistant message.
+type Feedback struct {
+	MessageID string    `json:"messageId"`
+	UserID    string    `json:"userId"`
+	Rating    string    `json:"rating"`
+	Reasons   []string  `json:"reasons"`
+	Comment   string    `json:"comment"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// FeedbackSummary aggregates the ratings of the answers one model gave on one page with one
+// prompt version.
+type FeedbackSummary struct {
+	Model         string         `json:"model"`
+	PageKey       string         `json:"pageKey"`
+	PromptVersion string         `json:"promptVersion"`
+	Up            int            `json:"up"`
+	Down          int            `json:"down"`
+	Reasons       map[string]int `json:"reasons"`
+}
+
+// PromptTemplate is a prompt version stored in the database (see internal/prompts).
+type PromptTemplate struct {
+	Name      string    `json:"name"`
+	Version   string    `json:"version"`
+	Body      string    `json:"body"`
+	Weight    int       `json:"weight"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type ToolSnapshot struct {
+	ID             string         `json:"id"`
+	ConversationID string         `json:"conversationId"`
+	PageKey        string         `json:"pageKey"`
+	ToolName       string         `json:"toolName"`
+	Status         string         `json:"status"`
+	PayloadJSON    map[string]any `json:"payload"`
+	FetchedAt      time.Time      `json:"fetchedAt"`
+}
+
+type ContextSnapshot struct {
+	ID          string         `json:"id"`
+	TripID      string         `json:"tripId"`
+	PageKey     string         `json:"pageKey"`
+	ContextJSON map[string]any `json:"context"`
+	GeneratedAt time.Time      `json:"generatedAt"`
+}
+
+type AuditLog struct {
+	ID           string         `json:"id"`
+	UserID       string         `json:"userId"`
+	TripID       string         `json:"tripId"`
+	Action       string         `json:"action"`
+	MetadataJSON map[string]any `json:"metadata"`
+	CreatedAt    time.Time      `json:"createdAt"`
+}
+
+// TripStore answers who may see a trip and what it is.
+type TripStore interface {
+	// CreateTrip stores trip (generating an ID when empty) with ownerID as its owner.
+	CreateTrip(ctx context.Context, trip Trip, ownerID string) (*Trip, error)
+	// AddTripMember adds userID to the trip, or changes their role if already a member.
+	AddTripMember(ctx context.Context, tripID, userID, role string) error
+	IsTripMember(ctx context.Context, tripID, userID string) (bool, error)
+	// TripRole returns userID's role on the trip (owner, editor or viewer), or "" for
+	// non-members.
+	TripRole(ctx context.Context, tripID, userID string) (string, error)
+	GetTripByID(ctx context.Context, tripID string) (*Trip, error)
+}
+
+// ConversationStore keeps assistant conversations and their messages.
+type ConversationStore interface {
+	// UpsertConversation returns the user's most recently active unarchived conversation on
+	// the trip, creating one titled title if there is none.
+	UpsertConversation(ctx context.Context, tripID, userID, title string) (string, error)
+	CreateConversation(ctx context.Context, tripID, userID, title string) (*Conversation, error)
+	// GetConversation returns ErrNotFound for unknown IDs.
+	GetConversation(ctx context.Context, conversationID string) (*Conversation, error)
+	RenameConversation(ctx context.Context, conversationID, title string) error
+	SetConversationArchived(ctx context.Context, conversationID string, archived bool) error
+	// DeleteConversation removes the conversation with its messages and tool snapshots.
+	DeleteConversation(ctx context.Context, conversationID string) error
+	// InsertMessage stores msg, assigning its ID and a CreatedAt after that of every earlier
+	// message in the conversation, so that a turn's answer always sorts after its question.
+	InsertMessage(ctx context.Context, msg Message) (*Message, error)
+	// GetMessage returns ErrNotFound for unknown IDs.
+	GetMessage(ctx context.Context, messageID string) (*Message, error)
+	// MessageContext returns the ContextJSON stored with a message, or ErrNotFound when the
+	// message is unknown or was stored without one.
+	MessageContext(ctx context.Context, messageID string) (map[string]any, error)
+	// ListConversations returns one page of conversations, newest first by (created_at, id),
+	// and the cursor of the next page ("" after the last one).
+	ListConversations(ctx context.Context, tripID, userID string, includeArchived bool, page PageRequest) ([]Conversation, string, error)
+	ConversationBelongsToUser(ctx context.Context, conversationID, userID string) (bool, error)
+	// ListMessages pages through a conversation's messages newest first, ordered like
+	// ListConversations.
+	ListMessages(ctx context.Context, conversationID string, page PageRequest) ([]Message, string, error)
+	// SearchMessages returns messages from userID's conversations that contain every word of
+	// query, best match first. A non-empty tripID narrows the search to that trip.
+	SearchMessages(ctx context.Context, userID, tripID, query string, limit int) ([]MessageHit, error)
+}
+
+// SnapshotStore records the tool results and trip context each answer was built from.
+type SnapshotStore interface {
+	InsertToolSnapshot(ctx context.Context, conversationID, pageKey, toolName, status string, payload map[string]any) error
+	InsertContextSnapshot(ctx context.Context, tripID, pageKey string, payload map[string]any) error
+	// ListToolSnapshots returns a conversation's snapshots oldest first.
+	ListToolSnapshots(ctx context.Context, conversationID string) ([]ToolSnapshot, error)
+	// LatestContextSnapshot returns ErrNotFound when the page has no snapshot yet.
+	LatestContextSnapshot(ctx context.Context, tripID, pageKey string) (*ContextSnapshot, error)
+}
+
+// AuditStore is the append-only log of assistant actions.
+type AuditStore interface {
+	InsertAuditLog(ctx context.Context, userID, tripID, action string, metadata map[string]any) error
+	// ListAuditLogs returns a trip's newest entries first.
+	ListAuditLogs(ctx context.Context, tripID string, limit int) ([]AuditLog, error)
+}
+
+// FeedbackStore keeps ratings of assistant answers.
+type FeedbackStore interface {
+	// UpsertFeedback stores f, replacing the user's earlier rating of the same message.
+	UpsertFeedback(ctx context.Context, f Feedback) (*Feedback, error)
+	// FeedbackReport summarises ratings of answers in the trip's conversations given since
+	// since, ordered by model, page and prompt version.
+	FeedbackReport(ctx context.Context, tripID string, since time.Time) ([]FeedbackSummary, error)
+}
+
+// PromptStore holds prompt template versions managed in the database.
+type PromptStore interface {
+	// ListPromptTemplates returns every stored version. The in-memory store has none.
+	ListPromptTemplates(ctx context.Context) ([]PromptTemplate, error)
+}
+
+// AIStore is everything the assistant persists. AIRepository (Postgres),
+// SQLiteAIRepository and MemoryAIRepository implement it.
+type AIStore interface {
+	TripStore
+	ConversationStore
+	SnapshotStore
+	AuditStore
+	FeedbackStore
+	PromptStore
+}
+
+var (
+	_ AIStore = (*AIRepository)(nil)
+	_ AIStore = (*SQLiteAIRepository)(nil)
+	_ AIStore = (*MemoryAIRepository)(nil)
+)
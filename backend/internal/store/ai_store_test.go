@@ -0,0 +1,41 @@
+package store_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"triploom/backend/internal/store"
+	"triploom/backend/internal/store/storetest"
+)
+
+func TestMemoryAIRepositoryConformance(t *testing.T) {
+	storetest.RunAIStore(t, storetest.Harness{
+		New: func(*testing.T) store.AIStore { return store.NewInMemoryAIRepository() },
+	})
+}
+
+func TestSQLiteAIRepositoryConformance(t *testing.T) {
+	storetest.RunAIStore(t, storetest.Harness{
+		New: func(t *testing.T) store.AIStore { return store.NewSQLiteAIRepository(openSQLite(t)) },
+	})
+}
+
+// TestPostgresAIRepositoryConformance runs against a migrated database named by
+// TEST_DATABASE_URL and is skipped without one.
+func TestPostgresAIRepositoryConformance(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	db, err := store.NewPostgres(context.Background(), dsn)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(db.Close)
+
+	repo := store.NewAIRepository(db)
+	storetest.RunAIStore(t, storetest.Harness{
+		New: func(*testing.T) store.AIStore { return repo },
+	})
+}
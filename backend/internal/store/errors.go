@@ -0,0 +1,8 @@
+package store
+
+import "errors"
+
+var ErrNotFound = errors.New("not found")
+
+// ErrInvalidCursor is returned for pagination cursors the store did not issue.
+var ErrInvalidCursor = errors.New("invalid cursor")
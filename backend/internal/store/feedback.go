@@ -0,0 +1,47 @@
+package store
+
+import "sort"
+
+// feedbackReport accumulates FeedbackSummary rows in Go so that every store reports the same
+// shape, including the per-reason counts.
+type feedbackReport struct {
+	byKey map[[3]string]*FeedbackSummary
+}
+
+func newFeedbackReport() *feedbackReport {
+	return &feedbackReport{byKey: make(map[[3]string]*FeedbackSummary)}
+}
+
+func (r *feedbackReport) add(model, pageKey, promptVersion, rating string, reasons []string) {
+	key := [3]string{model, pageKey, promptVersion}
+	sum, ok := r.byKey[key]
+	if !ok {
+		sum = &FeedbackSummary{Model: model, PageKey: pageKey, PromptVersion: promptVersion, Reasons: make(map[string]int)}
+		r.byKey[key] = sum
+	}
+	if rating == "up" {
+		sum.Up++
+	} else {
+		sum.Down++
+	}
+	for _, reason := range reasons {
+		sum.Reasons[reason]++
+	}
+}
+
+func (r *feedbackReport) summaries() []FeedbackSummary {
+	out := make([]FeedbackSummary, 0, len(r.byKey))
+	for _, sum := range r.byKey {
+		out = append(out, *sum)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Model != out[j].Model {
+			return out[i].Model < out[j].Model
+		}
+		if out[i].PageKey != out[j].PageKey {
+			return out[i].PageKey < out[j].PageKey
+		}
+		return out[i].PromptVersion < out[j].PromptVersion
+	})
+	return out
+}
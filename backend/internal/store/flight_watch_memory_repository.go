@@ -0,0 +1,97 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MemoryFlightWatchRepository is the in-process FlightWatchStore used when no database is
+// configured.
+type MemoryFlightWatchRepository struct {
+	mu      sync.RWMutex
+	watches map[string]FlightWatch
+}
+
+func NewInMemoryFlightWatchRepository() *MemoryFlightWatchRepository {
+	return &MemoryFlightWatchRepository{watches: make(map[string]FlightWatch)}
+}
+
+func (r *MemoryFlightWatchRepository) CreateFlightWatch(_ context.Context, tripID, userID, flightNumber, departureDate string) (*FlightWatch, error) {
+	now := time.Now().UTC()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, w := range r.watches {
+		if w.TripID == tripID && w.FlightNumber == flightNumber && w.DepartureDate == departureDate {
+			w.Active = true
+			w.NextPollAt = now
+			w.UpdatedAt = now
+			r.watches[id] = w
+			return &w, nil
+		}
+	}
+	w := FlightWatch{
+		ID:            uuid.NewString(),
+		TripID:        tripID,
+		UserID:        userID,
+		FlightNumber:  flightNumber,
+		DepartureDate: departureDate,
+		Active:        true,
+		NextPollAt:    now,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	r.watches[w.ID] = w
+	return &w, nil
+}
+
+func (r *MemoryFlightWatchRepository) ListFlightWatches(_ context.Context, tripID string) ([]FlightWatch, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]FlightWatch, 0)
+	for _, w := range r.watches {
+		if w.TripID == tripID {
+			out = append(out, w)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	return out, nil
+}
+
+func (r *MemoryFlightWatchRepository) ListDueFlightWatches(_ context.Context, now time.Time, limit int) ([]FlightWatch, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]FlightWatch, 0)
+	for _, w := range r.watches {
+		if w.Active && !w.NextPollAt.After(now) {
+			out = append(out, w)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].NextPollAt.Before(out[j].NextPollAt) })
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func (r *MemoryFlightWatchRepository) RecordFlightWatchPoll(_ context.Context, id string, status map[string]any, nextPollAt time.Time, active bool) error {
+	now := time.Now().UTC()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	w, ok := r.watches[id]
+	if !ok {
+		return nil
+	}
+	if status != nil {
+		w.LastStatusJSON = status
+	}
+	w.LastPolledAt = &now
+	w.NextPollAt = nextPollAt
+	w.Active = active
+	w.UpdatedAt = now
+	r.watches[id] = w
+	return nil
+}
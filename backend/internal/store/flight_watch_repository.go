@@ -0,0 +1,88 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// FlightWatchRepository is the Postgres implementation of FlightWatchStore.
+type FlightWatchRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewFlightWatchRepository(db *pgxpool.Pool) *FlightWatchRepository {
+	return &FlightWatchRepository{db: db}
+}
+
+func (r *FlightWatchRepository) CreateFlightWatch(ctx context.Context, tripID, userID, flightNumber, departureDate string) (*FlightWatch, error) {
+	const q = `
+		INSERT INTO flight_watches (id, trip_id, user_id, flight_number, departure_date, active, next_poll_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, TRUE, NOW(), NOW(), NOW())
+		ON CONFLICT (trip_id, flight_number, departure_date)
+		DO UPDATE SET active = TRUE, next_poll_at = NOW(), updated_at = NOW()
+		RETURNING ` + flightWatchColumns
+	return scanFlightWatch(r.db.QueryRow(ctx, q, uuid.NewString(), tripID, userID, flightNumber, departureDate))
+}
+
+func (r *FlightWatchRepository) ListFlightWatches(ctx context.Context, tripID string) ([]FlightWatch, error) {
+	q := `SELECT ` + flightWatchColumns + ` FROM flight_watches WHERE trip_id = $1 ORDER BY created_at DESC`
+	return r.queryFlightWatches(ctx, q, tripID)
+}
+
+func (r *FlightWatchRepository) ListDueFlightWatches(ctx context.Context, now time.Time, limit int) ([]FlightWatch, error) {
+	q := `SELECT ` + flightWatchColumns + ` FROM flight_watches WHERE active AND next_poll_at <= $1 ORDER BY next_poll_at ASC LIMIT $2`
+	return r.queryFlightWatches(ctx, q, now, limit)
+}
+
+func (r *FlightWatchRepository) RecordFlightWatchPoll(ctx context.Context, id string, status map[string]any, nextPollAt time.Time, active bool) error {
+	var statusJSON *string
+	if status != nil {
+		b, _ := json.Marshal(status)
+		s := string(b)
+		statusJSON = &s
+	}
+	const q = `
+		UPDATE flight_watches
+		SET last_status_json = COALESCE($2::jsonb, last_status_json), last_polled_at = NOW(), next_poll_at = $3, active = $4, updated_at = NOW()
+		WHERE id = $1`
+	_, err := r.db.Exec(ctx, q, id, statusJSON, nextPollAt, active)
+	return err
+}
+
+func (r *FlightWatchRepository) queryFlightWatches(ctx context.Context, q string, args ...any) ([]FlightWatch, error) {
+	rows, err := r.db.Query(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]FlightWatch, 0)
+	for rows.Next() {
+		w, err := scanFlightWatch(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *w)
+	}
+	return out, rows.Err()
+}
+
+func scanFlightWatch(row pgx.Row) (*FlightWatch, error) {
+	var w FlightWatch
+	var statusBytes []byte
+	if err := row.Scan(&w.ID, &w.TripID, &w.UserID, &w.FlightNumber, &w.DepartureDate, &w.Active, &statusBytes, &w.LastPolledAt, &w.NextPollAt, &w.CreatedAt, &w.UpdatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if len(statusBytes) > 0 {
+		_ = json.Unmarshal(statusBytes, &w.LastStatusJSON)
+	}
+	return &w, nil
+}
@@ -0,0 +1,91 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SQLiteFlightWatchRepository is the FlightWatchStore for self-hosted deployments.
+type SQLiteFlightWatchRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteFlightWatchRepository(db *sql.DB) *SQLiteFlightWatchRepository {
+	return &SQLiteFlightWatchRepository{db: db}
+}
+
+func (r *SQLiteFlightWatchRepository) CreateFlightWatch(ctx context.Context, tripID, userID, flightNumber, departureDate string) (*FlightWatch, error) {
+	now := sqliteTime(time.Now())
+	const q = `
+		INSERT INTO flight_watches (id, trip_id, user_id, flight_number, departure_date, active, next_poll_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, TRUE, $6, $6, $6)
+		ON CONFLICT (trip_id, flight_number, departure_date)
+		DO UPDATE SET active = TRUE, next_poll_at = excluded.next_poll_at, updated_at = excluded.updated_at
+		RETURNING ` + flightWatchColumns
+	return scanSQLiteFlightWatch(r.db.QueryRowContext(ctx, q, uuid.NewString(), tripID, userID, flightNumber, departureDate, now))
+}
+
+func (r *SQLiteFlightWatchRepository) ListFlightWatches(ctx context.Context, tripID string) ([]FlightWatch, error) {
+	q := `SELECT ` + flightWatchColumns + ` FROM flight_watches WHERE trip_id = $1 ORDER BY created_at DESC, rowid DESC`
+	return r.queryFlightWatches(ctx, q, tripID)
+}
+
+func (r *SQLiteFlightWatchRepository) ListDueFlightWatches(ctx context.Context, now time.Time, limit int) ([]FlightWatch, error) {
+	q := `SELECT ` + flightWatchColumns + ` FROM flight_watches WHERE active AND next_poll_at <= $1 ORDER BY next_poll_at ASC LIMIT $2`
+	return r.queryFlightWatches(ctx, q, sqliteTime(now), limit)
+}
+
+func (r *SQLiteFlightWatchRepository) RecordFlightWatchPoll(ctx context.Context, id string, status map[string]any, nextPollAt time.Time, active bool) error {
+	var statusJSON *string
+	if status != nil {
+		b, _ := json.Marshal(status)
+		s := string(b)
+		statusJSON = &s
+	}
+	const q = `
+		UPDATE flight_watches
+		SET last_status_json = COALESCE($2, last_status_json), last_polled_at = $5, next_poll_at = $3, active = $4, updated_at = $5
+		WHERE id = $1`
+	_, err := r.db.ExecContext(ctx, q, id, statusJSON, sqliteTime(nextPollAt), active, sqliteTime(time.Now()))
+	return err
+}
+
+func (r *SQLiteFlightWatchRepository) queryFlightWatches(ctx context.Context, q string, args ...any) ([]FlightWatch, error) {
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]FlightWatch, 0)
+	for rows.Next() {
+		w, err := scanSQLiteFlightWatch(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *w)
+	}
+	return out, rows.Err()
+}
+
+func scanSQLiteFlightWatch(row interface{ Scan(...any) error }) (*FlightWatch, error) {
+	var w FlightWatch
+	var status, polled sql.NullString
+	var next, created, updated string
+	if err := row.Scan(&w.ID, &w.TripID, &w.UserID, &w.FlightNumber, &w.DepartureDate, &w.Active, &status, &polled, &next, &created, &updated); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if status.Valid {
+		_ = json.Unmarshal([]byte(status.String), &w.LastStatusJSON)
+	}
+	w.LastPolledAt = sqliteNullTime(polled)
+	w.NextPollAt, w.CreatedAt, w.UpdatedAt = parseSQLiteTime(next), parseSQLiteTime(created), parseSQLiteTime(updated)
+	return &w, nil
+}
@@ -0,0 +1,44 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+type FlightWatch struct {
+	ID             string         `json:"id"`
+	TripID         string         `json:"tripId"`
+	UserID         string         `json:"userId"`
+	FlightNumber   string         `json:"flightNumber"`
+	DepartureDate  string         `json:"departureDate"`
+	Active         bool           `json:"active"`
+	LastStatusJSON map[string]any `json:"lastStatus,omitempty"`
+	LastPolledAt   *time.Time     `json:"lastPolledAt,omitempty"`
+	NextPollAt     time.Time      `json:"nextPollAt"`
+	CreatedAt      time.Time      `json:"createdAt"`
+	UpdatedAt      time.Time      `json:"updatedAt"`
+}
+
+// FlightWatchStore keeps the flights the poller checks for status changes.
+// FlightWatchRepository (Postgres), SQLiteFlightWatchRepository and
+// MemoryFlightWatchRepository implement it.
+type FlightWatchStore interface {
+	// CreateFlightWatch registers a watch, or reactivates the existing one for the same trip,
+	// flight and date.
+	CreateFlightWatch(ctx context.Context, tripID, userID, flightNumber, departureDate string) (*FlightWatch, error)
+	// ListFlightWatches returns a trip's watches newest first.
+	ListFlightWatches(ctx context.Context, tripID string) ([]FlightWatch, error)
+	// ListDueFlightWatches returns active watches whose next poll time has passed, oldest first.
+	ListDueFlightWatches(ctx context.Context, now time.Time, limit int) ([]FlightWatch, error)
+	// RecordFlightWatchPoll stores the latest status (nil keeps the previous one) and schedules
+	// the next poll.
+	RecordFlightWatchPoll(ctx context.Context, id string, status map[string]any, nextPollAt time.Time, active bool) error
+}
+
+var (
+	_ FlightWatchStore = (*FlightWatchRepository)(nil)
+	_ FlightWatchStore = (*SQLiteFlightWatchRepository)(nil)
+	_ FlightWatchStore = (*MemoryFlightWatchRepository)(nil)
+)
+
+const flightWatchColumns = `id, trip_id, user_id, flight_number, departure_date, active, last_status_json, last_polled_at, next_poll_at, created_at, updated_at`
@@ -0,0 +1,78 @@
+package store
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"time"
+)
+
+// PageRequest asks for up to Limit rows after Cursor, the NextCursor of the previous page.
+// An empty Cursor starts from the newest row.
+type PageRequest struct {
+	Cursor string
+	Limit  int
+}
+
+// cursorKey is the (created_at, id) position of the last row on a page. Rows are listed in
+// descending key order, so the next page holds the keys strictly below it.
+type cursorKey struct {
+	CreatedAt time.Time `json:"t"`
+	ID        string    `json:"id"`
+}
+
+func (k cursorKey) encode() string {
+	b, _ := json.Marshal(k)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeCursor returns nil for the first page and ErrInvalidCursor for tokens it did not issue.
+func decodeCursor(cursor string) (*cursorKey, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var k cursorKey
+	if err := json.Unmarshal(b, &k); err != nil || k.ID == "" || k.CreatedAt.IsZero() {
+		return nil, ErrInvalidCursor
+	}
+	return &k, nil
+}
+
+// before reports whether (t, id) sorts after k in a descending listing.
+func (k *cursorKey) before(t time.Time, id string) bool {
+	return k == nil || t.Before(k.CreatedAt) || (t.Equal(k.CreatedAt) && id < k.ID)
+}
+
+// trimPage drops the extra row that callers fetch beyond limit to learn whether another page
+// follows, and returns the cursor of that page ("" after the last one).
+func trimPage[T any](rows []T, limit int, key func(T) cursorKey) ([]T, string) {
+	if limit <= 0 {
+		return rows[:0], ""
+	}
+	if len(rows) <= limit {
+		return rows, ""
+	}
+	rows = rows[:limit]
+	return rows, key(rows[limit-1]).encode()
+}
+
+func conversationKey(c Conversation) cursorKey { return cursorKey{CreatedAt: c.CreatedAt, ID: c.ID} }
+
+func messageKey(m Message) cursorKey { return cursorKey{CreatedAt: m.CreatedAt, ID: m.ID} }
+
+// descendingKey orders two rows newest first, breaking ties by ID.
+func descendingKey(ti time.Time, idi string, tj time.Time, idj string) bool {
+	if !ti.Equal(tj) {
+		return ti.After(tj)
+	}
+	return idi > idj
+}
+
+// MessageCursor is the cursor of the page that starts just after m, i.e. the messages older
+// than m.
+func MessageCursor(m Message) string {
+	return messageKey(m).encode()
+}
@@ -0,0 +1,36 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// Postgres and SQLite break created_at ties in SQL; the memory store has to agree.
+func TestMemoryPaginationBreaksTiesByID(t *testing.T) {
+	ctx := context.Background()
+	r := NewInMemoryAIRepository()
+	at := time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC)
+	for _, id := range []string{"b", "d", "a", "c"} {
+		r.messagesByConvID["conv"] = append(r.messagesByConvID["conv"], Message{ID: id, ConversationID: "conv", CreatedAt: at})
+	}
+
+	var got string
+	page := PageRequest{Limit: 3}
+	for {
+		msgs, next, err := r.ListMessages(ctx, "conv", page)
+		if err != nil {
+			t.Fatalf("list: %v", err)
+		}
+		for _, m := range msgs {
+			got += m.ID
+		}
+		if next == "" {
+			break
+		}
+		page.Cursor = next
+	}
+	if got != "dcba" {
+		t.Fatalf("expected ids in descending order across pages, got %q", got)
+	}
+}
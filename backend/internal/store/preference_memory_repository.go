@@ -0,0 +1,121 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MemoryPreferenceRepository is the in-process PreferenceStore used when no database is
+// configured.
+type MemoryPreferenceRepository struct {
+	mu          sync.Mutex
+	preferences map[string]UserPreferences
+	updates     map[string]PreferenceUpdate
+}
+
+func NewInMemoryPreferenceRepository() *MemoryPreferenceRepository {
+	return &MemoryPreferenceRepository{preferences: make(map[string]UserPreferences), updates: make(map[string]PreferenceUpdate)}
+}
+
+func (r *MemoryPreferenceRepository) GetPreferences(_ context.Context, userID string) (*UserPreferences, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.current(userID), nil
+}
+
+func (r *MemoryPreferenceRepository) SavePreferences(_ context.Context, userID string, prefs map[string]string) (*UserPreferences, error) {
+	now := time.Now().UTC()
+	r.mu.Lock()
+	r.preferences[userID] = UserPreferences{UserID: userID, Preferences: copyPreferences(prefs), UpdatedAt: &now}
+	r.mu.Unlock()
+	return &UserPreferences{UserID: userID, Preferences: copyPreferences(prefs), UpdatedAt: &now}, nil
+}
+
+func (r *MemoryPreferenceRepository) CreatePreferenceUpdate(_ context.Context, u PreferenceUpdate) (*PreferenceUpdate, error) {
+	u.ID = uuid.NewString()
+	u.Status = PreferenceUpdatePending
+	u.CreatedAt = time.Now().UTC()
+	r.mu.Lock()
+	r.updates[u.ID] = u
+	r.mu.Unlock()
+	return &u, nil
+}
+
+func (r *MemoryPreferenceRepository) GetPreferenceUpdate(_ context.Context, id string) (*PreferenceUpdate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, ok := r.updates[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &u, nil
+}
+
+func (r *MemoryPreferenceRepository) ListPreferenceUpdates(_ context.Context, userID, status string, limit int) ([]PreferenceUpdate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]PreferenceUpdate, 0)
+	for _, u := range r.updates {
+		if u.UserID == userID && (status == "" || u.Status == status) {
+			out = append(out, u)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].CreatedAt.After(out[j].CreatedAt)
+		}
+		return out[i].ID > out[j].ID
+	})
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func (r *MemoryPreferenceRepository) ApprovePreferenceUpdate(_ context.Context, id string) (*PreferenceUpdate, *UserPreferences, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, ok := r.updates[id]
+	if !ok {
+		return nil, nil, ErrNotFound
+	}
+	if u.Status != PreferenceUpdatePending {
+		return nil, nil, ErrPreferenceUpdateDecided
+	}
+	now := time.Now().UTC()
+	prefs := applyPreferenceUpdate(r.current(u.UserID).Preferences, &u)
+	r.preferences[u.UserID] = UserPreferences{UserID: u.UserID, Preferences: prefs, UpdatedAt: &now}
+	u.Status, u.DecidedAt = PreferenceUpdateApproved, &now
+	r.updates[id] = u
+	return &u, &UserPreferences{UserID: u.UserID, Preferences: copyPreferences(prefs), UpdatedAt: &now}, nil
+}
+
+func (r *MemoryPreferenceRepository) RejectPreferenceUpdate(_ context.Context, id string) (*PreferenceUpdate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, ok := r.updates[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if u.Status != PreferenceUpdatePending {
+		return nil, ErrPreferenceUpdateDecided
+	}
+	now := time.Now().UTC()
+	u.Status, u.DecidedAt = PreferenceUpdateRejected, &now
+	r.updates[id] = u
+	return &u, nil
+}
+
+// current returns a copy of a user's preferences. It is called with r.mu held.
+func (r *MemoryPreferenceRepository) current(userID string) *UserPreferences {
+	p, ok := r.preferences[userID]
+	if !ok {
+		return &UserPreferences{UserID: userID, Preferences: map[string]string{}}
+	}
+	p.Preferences = copyPreferences(p.Preferences)
+	return &p
+}
@@ -0,0 +1,150 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PreferenceRepository is the Postgres implementation of PreferenceStore.
+type PreferenceRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPreferenceRepository(db *pgxpool.Pool) *PreferenceRepository {
+	return &PreferenceRepository{db: db}
+}
+
+func (r *PreferenceRepository) GetPreferences(ctx context.Context, userID string) (*UserPreferences, error) {
+	const q = `SELECT preferences_json, updated_at FROM user_preferences WHERE user_id = $1`
+	return scanPreferences(userID, r.db.QueryRow(ctx, q, userID))
+}
+
+func (r *PreferenceRepository) SavePreferences(ctx context.Context, userID string, prefs map[string]string) (*UserPreferences, error) {
+	now := time.Now().UTC()
+	raw, _ := json.Marshal(prefs)
+	if _, err := r.db.Exec(ctx, upsertPreferences, userID, raw, now); err != nil {
+		return nil, err
+	}
+	return &UserPreferences{UserID: userID, Preferences: copyPreferences(prefs), UpdatedAt: &now}, nil
+}
+
+func (r *PreferenceRepository) CreatePreferenceUpdate(ctx context.Context, u PreferenceUpdate) (*PreferenceUpdate, error) {
+	u.ID = uuid.NewString()
+	u.Status = PreferenceUpdatePending
+	u.CreatedAt = time.Now().UTC()
+	const q = `
+		INSERT INTO user_preference_updates (id, user_id, conversation_id, message_id, preference, value, previous, status, created_at)
+		VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), $5, $6, $7, $8, $9)`
+	if _, err := r.db.Exec(ctx, q, u.ID, u.UserID, u.ConversationID, u.MessageID, u.Preference, u.Value, u.Previous, u.Status, u.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+func (r *PreferenceRepository) GetPreferenceUpdate(ctx context.Context, id string) (*PreferenceUpdate, error) {
+	q := `SELECT ` + preferenceUpdateColumns + ` FROM user_preference_updates WHERE id = $1`
+	return scanPreferenceUpdate(r.db.QueryRow(ctx, q, id))
+}
+
+func (r *PreferenceRepository) ListPreferenceUpdates(ctx context.Context, userID, status string, limit int) ([]PreferenceUpdate, error) {
+	q := `SELECT ` + preferenceUpdateColumns + ` FROM user_preference_updates WHERE user_id = $1 AND ($2 = '' OR status = $2) ORDER BY created_at DESC, id DESC LIMIT $3`
+	rows, err := r.db.Query(ctx, q, userID, status, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]PreferenceUpdate, 0)
+	for rows.Next() {
+		u, err := scanPreferenceUpdate(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *u)
+	}
+	return out, rows.Err()
+}
+
+func (r *PreferenceRepository) ApprovePreferenceUpdate(ctx context.Context, id string) (*PreferenceUpdate, *UserPreferences, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback(ctx)
+	u, err := scanPreferenceUpdate(tx.QueryRow(ctx, `SELECT `+preferenceUpdateColumns+` FROM user_preference_updates WHERE id = $1 FOR UPDATE`, id))
+	if err != nil {
+		return nil, nil, err
+	}
+	if u.Status != PreferenceUpdatePending {
+		return nil, nil, ErrPreferenceUpdateDecided
+	}
+	// FOR UPDATE locks nothing while the user has no row yet, so create an empty one first:
+	// concurrent approvals for the same user then queue on it.
+	if _, err := tx.Exec(ctx, `INSERT INTO user_preferences (user_id, preferences_json, updated_at) VALUES ($1, '{}', NOW()) ON CONFLICT (user_id) DO NOTHING`, u.UserID); err != nil {
+		return nil, nil, err
+	}
+	current, err := scanPreferences(u.UserID, tx.QueryRow(ctx, `SELECT preferences_json, updated_at FROM user_preferences WHERE user_id = $1 FOR UPDATE`, u.UserID))
+	if err != nil {
+		return nil, nil, err
+	}
+	now := time.Now().UTC()
+	prefs := applyPreferenceUpdate(current.Preferences, u)
+	raw, _ := json.Marshal(prefs)
+	if _, err := tx.Exec(ctx, upsertPreferences, u.UserID, raw, now); err != nil {
+		return nil, nil, err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE user_preference_updates SET status = $2, decided_at = $3 WHERE id = $1`, id, PreferenceUpdateApproved, now); err != nil {
+		return nil, nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, err
+	}
+	u.Status, u.DecidedAt = PreferenceUpdateApproved, &now
+	return u, &UserPreferences{UserID: u.UserID, Preferences: prefs, UpdatedAt: &now}, nil
+}
+
+func (r *PreferenceRepository) RejectPreferenceUpdate(ctx context.Context, id string) (*PreferenceUpdate, error) {
+	const q = `UPDATE user_preference_updates SET status = $2, decided_at = $3 WHERE id = $1 AND status = 'pending'`
+	tag, err := r.db.Exec(ctx, q, id, PreferenceUpdateRejected, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	u, err := r.GetPreferenceUpdate(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, ErrPreferenceUpdateDecided
+	}
+	return u, nil
+}
+
+func scanPreferences(userID string, row pgx.Row) (*UserPreferences, error) {
+	var raw []byte
+	var updated time.Time
+	if err := row.Scan(&raw, &updated); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return &UserPreferences{UserID: userID, Preferences: map[string]string{}}, nil
+		}
+		return nil, err
+	}
+	p := &UserPreferences{UserID: userID, Preferences: map[string]string{}, UpdatedAt: &updated}
+	_ = json.Unmarshal(raw, &p.Preferences)
+	return p, nil
+}
+
+func scanPreferenceUpdate(row pgx.Row) (*PreferenceUpdate, error) {
+	var u PreferenceUpdate
+	if err := row.Scan(&u.ID, &u.UserID, &u.ConversationID, &u.MessageID, &u.Preference, &u.Value, &u.Previous, &u.Status, &u.DecidedAt, &u.CreatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &u, nil
+}
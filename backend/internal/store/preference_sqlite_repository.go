@@ -0,0 +1,154 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SQLitePreferenceRepository is the PreferenceStore for self-hosted deployments.
+type SQLitePreferenceRepository struct {
+	db *sql.DB
+}
+
+func NewSQLitePreferenceRepository(db *sql.DB) *SQLitePreferenceRepository {
+	return &SQLitePreferenceRepository{db: db}
+}
+
+func (r *SQLitePreferenceRepository) GetPreferences(ctx context.Context, userID string) (*UserPreferences, error) {
+	const q = `SELECT preferences_json, updated_at FROM user_preferences WHERE user_id = $1`
+	return scanSQLitePreferences(userID, r.db.QueryRowContext(ctx, q, userID))
+}
+
+func (r *SQLitePreferenceRepository) SavePreferences(ctx context.Context, userID string, prefs map[string]string) (*UserPreferences, error) {
+	now := time.Now().UTC()
+	raw, _ := json.Marshal(prefs)
+	if _, err := r.db.ExecContext(ctx, upsertPreferences, userID, string(raw), sqliteTime(now)); err != nil {
+		return nil, err
+	}
+	return &UserPreferences{UserID: userID, Preferences: copyPreferences(prefs), UpdatedAt: &now}, nil
+}
+
+func (r *SQLitePreferenceRepository) CreatePreferenceUpdate(ctx context.Context, u PreferenceUpdate) (*PreferenceUpdate, error) {
+	u.ID = uuid.NewString()
+	u.Status = PreferenceUpdatePending
+	u.CreatedAt = time.Now().UTC()
+	const q = `
+		INSERT INTO user_preference_updates (id, user_id, conversation_id, message_id, preference, value, previous, status, created_at)
+		VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), $5, $6, $7, $8, $9)`
+	if _, err := r.db.ExecContext(ctx, q, u.ID, u.UserID, u.ConversationID, u.MessageID, u.Preference, u.Value, u.Previous, u.Status, sqliteTime(u.CreatedAt)); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+func (r *SQLitePreferenceRepository) GetPreferenceUpdate(ctx context.Context, id string) (*PreferenceUpdate, error) {
+	q := `SELECT ` + preferenceUpdateColumns + ` FROM user_preference_updates WHERE id = $1`
+	return scanSQLitePreferenceUpdate(r.db.QueryRowContext(ctx, q, id))
+}
+
+func (r *SQLitePreferenceRepository) ListPreferenceUpdates(ctx context.Context, userID, status string, limit int) ([]PreferenceUpdate, error) {
+	q := `SELECT ` + preferenceUpdateColumns + ` FROM user_preference_updates WHERE user_id = $1 AND ($2 = '' OR status = $2) ORDER BY created_at DESC, id DESC LIMIT $3`
+	rows, err := r.db.QueryContext(ctx, q, userID, status, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]PreferenceUpdate, 0)
+	for rows.Next() {
+		u, err := scanSQLitePreferenceUpdate(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *u)
+	}
+	return out, rows.Err()
+}
+
+// ApprovePreferenceUpdate relies on SQLite serialising write transactions in place of
+// SELECT ... FOR UPDATE: the status update only succeeds while the update is still pending.
+func (r *SQLitePreferenceRepository) ApprovePreferenceUpdate(ctx context.Context, id string) (*PreferenceUpdate, *UserPreferences, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+	u, err := scanSQLitePreferenceUpdate(tx.QueryRowContext(ctx, `SELECT `+preferenceUpdateColumns+` FROM user_preference_updates WHERE id = $1`, id))
+	if err != nil {
+		return nil, nil, err
+	}
+	if u.Status != PreferenceUpdatePending {
+		return nil, nil, ErrPreferenceUpdateDecided
+	}
+	current, err := scanSQLitePreferences(u.UserID, tx.QueryRowContext(ctx, `SELECT preferences_json, updated_at FROM user_preferences WHERE user_id = $1`, u.UserID))
+	if err != nil {
+		return nil, nil, err
+	}
+	now := time.Now().UTC()
+	prefs := applyPreferenceUpdate(current.Preferences, u)
+	raw, _ := json.Marshal(prefs)
+	if _, err := tx.ExecContext(ctx, upsertPreferences, u.UserID, string(raw), sqliteTime(now)); err != nil {
+		return nil, nil, err
+	}
+	res, err := tx.ExecContext(ctx, `UPDATE user_preference_updates SET status = $2, decided_at = $3 WHERE id = $1 AND status = 'pending'`, id, PreferenceUpdateApproved, sqliteTime(now))
+	if err != nil {
+		return nil, nil, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, nil, ErrPreferenceUpdateDecided
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+	u.Status, u.DecidedAt = PreferenceUpdateApproved, &now
+	return u, &UserPreferences{UserID: u.UserID, Preferences: prefs, UpdatedAt: &now}, nil
+}
+
+func (r *SQLitePreferenceRepository) RejectPreferenceUpdate(ctx context.Context, id string) (*PreferenceUpdate, error) {
+	const q = `UPDATE user_preference_updates SET status = $2, decided_at = $3 WHERE id = $1 AND status = 'pending'`
+	res, err := r.db.ExecContext(ctx, q, id, PreferenceUpdateRejected, sqliteTime(time.Now()))
+	if err != nil {
+		return nil, err
+	}
+	u, err := r.GetPreferenceUpdate(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, ErrPreferenceUpdateDecided
+	}
+	return u, nil
+}
+
+func scanSQLitePreferences(userID string, row interface{ Scan(...any) error }) (*UserPreferences, error) {
+	var raw, updated string
+	if err := row.Scan(&raw, &updated); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return &UserPreferences{UserID: userID, Preferences: map[string]string{}}, nil
+		}
+		return nil, err
+	}
+	at := parseSQLiteTime(updated)
+	p := &UserPreferences{UserID: userID, Preferences: map[string]string{}, UpdatedAt: &at}
+	_ = json.Unmarshal([]byte(raw), &p.Preferences)
+	return p, nil
+}
+
+func scanSQLitePreferenceUpdate(row interface{ Scan(...any) error }) (*PreferenceUpdate, error) {
+	var u PreferenceUpdate
+	var created string
+	var decided sql.NullString
+	if err := row.Scan(&u.ID, &u.UserID, &u.ConversationID, &u.MessageID, &u.Preference, &u.Value, &u.Previous, &u.Status, &decided, &created); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	u.DecidedAt = sqliteNullTime(decided)
+	u.CreatedAt = parseSQLiteTime(created)
+	return &u, nil
+}
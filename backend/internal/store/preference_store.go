@@ -0,0 +1,98 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Preference update statuses.
+const (
+	PreferenceUpdatePending  = "pending"
+	PreferenceUpdateApproved = "approved"
+	PreferenceUpdateRejected = "rejected"
+)
+
+// ErrPreferenceUpdateDecided is returned when approving or rejecting a preference update that
+// is no longer pending.
+var ErrPreferenceUpdateDecided = errors.New("preference update already decided")
+
+// UserPreferences are the travel preferences kept for a user across trips, keyed by
+// preference, e.g. {"seat": "aisle", "budget": "mid-range"}.
+type UserPreferences struct {
+	UserID      string            `json:"userId"`
+	Preferences map[string]string `json:"preferences"`
+	// UpdatedAt is nil until the preferences are first saved.
+	UpdatedAt *time.Time `json:"updatedAt,omitempty"`
+}
+
+// PreferenceUpdate is a preference change the assistant suggested. It is written to the
+// user's preferences only once they approve it; an empty Value removes the preference.
+type PreferenceUpdate struct {
+	ID             string `json:"id"`
+	UserID         string `json:"userId"`
+	ConversationID string `json:"conversationId,omitempty"`
+	MessageID      string `json:"messageId,omitempty"`
+	Preference     string `json:"preference"`
+	Value          string `json:"value"`
+	// Previous is the preference's value when the update was suggested.
+	Previous  string     `json:"previous,omitempty"`
+	Status    string     `json:"status"`
+	DecidedAt *time.Time `json:"decidedAt,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+// PreferenceStore keeps user preferences and the updates the assistant suggests to them.
+// PreferenceRepository (Postgres),
+// SQLitePreferenceRepository and MemoryPreferenceRepository implement it.
+type PreferenceStore interface {
+	// GetPreferences returns a user's preferences, empty when none were saved.
+	GetPreferences(ctx context.Context, userID string) (*UserPreferences, error)
+	// SavePreferences replaces a user's preferences.
+	SavePreferences(ctx context.Context, userID string, prefs map[string]string) (*UserPreferences, error)
+	// CreatePreferenceUpdate stores u as pending, assigning its ID and CreatedAt.
+	CreatePreferenceUpdate(ctx context.Context, u PreferenceUpdate) (*PreferenceUpdate, error)
+	// GetPreferenceUpdate returns ErrNotFound for unknown IDs.
+	GetPreferenceUpdate(ctx context.Context, id string) (*PreferenceUpdate, error)
+	// ListPreferenceUpdates returns a user's updates newest first, only those with status when
+	// it is not empty.
+	ListPreferenceUpdates(ctx context.Context, userID, status string, limit int) ([]PreferenceUpdate, error)
+	// ApprovePreferenceUpdate writes a pending update to the user's preferences and marks it
+	// approved, in one transaction. It returns ErrPreferenceUpdateDecided if the update is no
+	// longer pending.
+	ApprovePreferenceUpdate(ctx context.Context, id string) (*PreferenceUpdate, *UserPreferences, error)
+	// RejectPreferenceUpdate marks a pending update rejected without changing the preferences.
+	RejectPreferenceUpdate(ctx context.Context, id string) (*PreferenceUpdate, error)
+}
+
+var (
+	_ PreferenceStore = (*PreferenceRepository)(nil)
+	_ PreferenceStore = (*SQLitePreferenceRepository)(nil)
+	_ PreferenceStore = (*MemoryPreferenceRepository)(nil)
+)
+
+const preferenceUpdateColumns = `id, user_id, COALESCE(conversation_id, ''), COALESCE(message_id, ''), preference, value,
+	previous, status, decided_at, created_at`
+
+const upsertPreferences = `
+	INSERT INTO user_preferences (user_id, preferences_json, updated_at) VALUES ($1, $2, $3)
+	ON CONFLICT (user_id) DO UPDATE SET preferences_json = excluded.preferences_json, updated_at = excluded.updated_at`
+
+// applyPreferenceUpdate returns prefs with u applied.
+func applyPreferenceUpdate(prefs map[string]string, u *PreferenceUpdate) map[string]string {
+	out := copyPreferences(prefs)
+	if u.Value == "" {
+		delete(out, u.Preference)
+	} else {
+		out[u.Preference] = u.Value
+	}
+	return out
+}
+
+func copyPreferences(prefs map[string]string) map[string]string {
+	out := make(map[string]string, len(prefs))
+	for k, v := range prefs {
+		out[k] = v
+	}
+	return out
+}
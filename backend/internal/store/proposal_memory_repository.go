@@ -0,0 +1,191 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MemoryProposalRepository is the in-process ProposalStore used when no database is
+// configured. It keeps its own itinerary items, expenses and saved flights.
+type MemoryProposalRepository struct {
+	mu        sync.Mutex
+	proposals map[string]Proposal
+	items     map[string]ItineraryItem
+	expenses  []Expense
+	flights   []SavedFlight
+}
+
+func NewInMemoryProposalRepository() *MemoryProposalRepository {
+	return &MemoryProposalRepository{proposals: make(map[string]Proposal), items: make(map[string]ItineraryItem)}
+}
+
+func (r *MemoryProposalRepository) CreateProposal(_ context.Context, p Proposal) (*Proposal, error) {
+	p.ID = uuid.NewString()
+	p.Status = ProposalPending
+	p.CreatedAt = time.Now().UTC()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.proposals[p.ID] = p
+	return &p, nil
+}
+
+func (r *MemoryProposalRepository) GetProposal(_ context.Context, id string) (*Proposal, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p, ok := r.proposals[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &p, nil
+}
+
+func (r *MemoryProposalRepository) ListProposals(_ context.Context, tripID, status string, limit int) ([]Proposal, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]Proposal, 0)
+	for _, p := range r.proposals {
+		if p.TripID == tripID && (status == "" || p.Status == status) {
+			out = append(out, p)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].CreatedAt.After(out[j].CreatedAt)
+		}
+		return out[i].ID > out[j].ID
+	})
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func (r *MemoryProposalRepository) ApproveProposal(_ context.Context, id, userID string) (*Proposal, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, ok := r.proposals[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	p := &stored
+	if p.Status != ProposalPending {
+		return nil, ErrProposalDecided
+	}
+	now := time.Now().UTC()
+	resultID, err := r.apply(p, now)
+	if err != nil {
+		return nil, err
+	}
+	decide(p, ProposalApproved, resultID, userID, now)
+	r.proposals[id] = *p
+	return p, nil
+}
+
+func (r *MemoryProposalRepository) RejectProposal(_ context.Context, id, userID string) (*Proposal, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p, ok := r.proposals[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if p.Status != ProposalPending {
+		return nil, ErrProposalDecided
+	}
+	decide(&p, ProposalRejected, "", userID, time.Now().UTC())
+	r.proposals[id] = p
+	return &p, nil
+}
+
+func (r *MemoryProposalRepository) ListItineraryItems(_ context.Context, tripID string) ([]ItineraryItem, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]ItineraryItem, 0)
+	for _, it := range r.items {
+		if it.TripID == tripID {
+			out = append(out, it)
+		}
+	}
+	blocks := map[string]int{"morning": 1, "afternoon": 2, "evening": 3}
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.DayIndex != b.DayIndex {
+			return a.DayIndex < b.DayIndex
+		}
+		if blocks[a.TimeBlock] != blocks[b.TimeBlock] {
+			return blocks[a.TimeBlock] < blocks[b.TimeBlock]
+		}
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+		return a.ID < b.ID
+	})
+	return out, nil
+}
+
+func (r *MemoryProposalRepository) ListExpenses(_ context.Context, tripID string) ([]Expense, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]Expense, 0)
+	for _, e := range r.expenses {
+		if e.TripID == tripID {
+			out = append(out, e)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Date < out[j].Date })
+	return out, nil
+}
+
+func (r *MemoryProposalRepository) ListSavedFlights(_ context.Context, tripID string) ([]SavedFlight, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]SavedFlight, 0)
+	for _, f := range r.flights {
+		if f.TripID == tripID {
+			out = append(out, f)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].FlightDate < out[j].FlightDate })
+	return out, nil
+}
+
+func (r *MemoryProposalRepository) AddItineraryItem(_ context.Context, it ItineraryItem) (*ItineraryItem, error) {
+	now := time.Now().UTC()
+	r.mu.Lock()
+	id, err := r.apply(itineraryItemProposal(it), now)
+	r.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	it.ID, it.Status, it.CreatedAt, it.UpdatedAt = id, "planned", now, now
+	return &it, nil
+}
+
+// apply is applyProposal for the in-memory tables. It is called with r.mu held.
+func (r *MemoryProposalRepository) apply(p *Proposal, now time.Time) (string, error) {
+	c := p.Change
+	id := uuid.NewString()
+	switch p.Kind {
+	case ProposalAddItineraryItem:
+		r.items[id] = ItineraryItem{ID: id, TripID: p.TripID, DayIndex: c.DayIndex, TimeBlock: c.TimeBlock, Status: "planned", Category: c.Category, Title: c.Title, Notes: c.Notes, CreatedAt: now, UpdatedAt: now}
+	case ProposalMoveItineraryItem:
+		it, ok := r.items[c.ItemID]
+		if !ok || it.TripID != p.TripID || it.DayIndex != c.FromDayIndex || it.TimeBlock != c.FromTimeBlock {
+			return "", ErrProposalStale
+		}
+		it.DayIndex, it.TimeBlock, it.UpdatedAt = c.DayIndex, c.TimeBlock, now
+		r.items[c.ItemID] = it
+		return c.ItemID, nil
+	case ProposalSaveFlight:
+		r.flights = append(r.flights, SavedFlight{ID: id, TripID: p.TripID, Source: c.Source, FlightNumber: c.FlightNumber, FlightDate: c.Date})
+	case ProposalAddExpense:
+		r.expenses = append(r.expenses, Expense{ID: id, TripID: p.TripID, Date: c.Date, Category: c.Category, Title: c.Title, Amount: c.Amount, Currency: c.Currency, Notes: c.Notes, CreatedAt: now})
+	default:
+		return "", errors.New("unknown proposal kind " + p.Kind)
+	}
+	return id, nil
+}
@@ -0,0 +1,197 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ProposalRepository is the Postgres implementation of ProposalStore.
+type ProposalRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewProposalRepository(db *pgxpool.Pool) *ProposalRepository {
+	return &ProposalRepository{db: db}
+}
+
+func (r *ProposalRepository) CreateProposal(ctx context.Context, p Proposal) (*Proposal, error) {
+	p.ID = uuid.NewString()
+	p.Status = ProposalPending
+	p.CreatedAt = time.Now().UTC()
+	change, _ := json.Marshal(p.Change)
+	preview, _ := json.Marshal(p.Preview)
+	const q = `
+		INSERT INTO ai_proposals (id, trip_id, conversation_id, message_id, user_id, kind, label, change_json, preview_json, status, created_at)
+		VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), $5, $6, $7, $8, $9, $10, $11)`
+	if _, err := r.db.Exec(ctx, q, p.ID, p.TripID, p.ConversationID, p.MessageID, p.UserID, p.Kind, p.Label, change, preview, p.Status, p.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r *ProposalRepository) GetProposal(ctx context.Context, id string) (*Proposal, error) {
+	q := `SELECT ` + proposalColumns + ` FROM ai_proposals WHERE id = $1`
+	return scanProposal(r.db.QueryRow(ctx, q, id))
+}
+
+func (r *ProposalRepository) ListProposals(ctx context.Context, tripID, status string, limit int) ([]Proposal, error) {
+	q := `SELECT ` + proposalColumns + ` FROM ai_proposals WHERE trip_id = $1 AND ($2 = '' OR status = $2) ORDER BY created_at DESC, id DESC LIMIT $3`
+	rows, err := r.db.Query(ctx, q, tripID, status, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]Proposal, 0)
+	for rows.Next() {
+		p, err := scanProposal(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *p)
+	}
+	return out, rows.Err()
+}
+
+func (r *ProposalRepository) ApproveProposal(ctx context.Context, id, userID string) (*Proposal, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	p, err := scanProposal(tx.QueryRow(ctx, `SELECT `+proposalColumns+` FROM ai_proposals WHERE id = $1 FOR UPDATE`, id))
+	if err != nil {
+		return nil, err
+	}
+	if p.Status != ProposalPending {
+		return nil, ErrProposalDecided
+	}
+	now := time.Now().UTC()
+	resultID, err := applyProposal(ctx, pgxTx{tx}, p, now)
+	if err != nil {
+		return nil, err
+	}
+	const q = `UPDATE ai_proposals SET status = $2, result_id = $3, decided_by = $4, decided_at = $5 WHERE id = $1`
+	if _, err := tx.Exec(ctx, q, id, ProposalApproved, resultID, userID, now); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	decide(p, ProposalApproved, resultID, userID, now)
+	return p, nil
+}
+
+func (r *ProposalRepository) RejectProposal(ctx context.Context, id, userID string) (*Proposal, error) {
+	now := time.Now().UTC()
+	const q = `UPDATE ai_proposals SET status = $2, decided_by = $3, decided_at = $4 WHERE id = $1 AND status = 'pending'`
+	tag, err := r.db.Exec(ctx, q, id, ProposalRejected, userID, now)
+	if err != nil {
+		return nil, err
+	}
+	p, err := r.GetProposal(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, ErrProposalDecided
+	}
+	return p, nil
+}
+
+func (r *ProposalRepository) ListItineraryItems(ctx context.Context, tripID string) ([]ItineraryItem, error) {
+	rows, err := r.db.Query(ctx, listItineraryItems, tripID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]ItineraryItem, 0)
+	for rows.Next() {
+		var it ItineraryItem
+		if err := rows.Scan(&it.ID, &it.TripID, &it.DayIndex, &it.TimeBlock, &it.Status, &it.Category, &it.Title, &it.LocationLabel, &it.Notes, &it.CreatedAt, &it.UpdatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, it)
+	}
+	return out, rows.Err()
+}
+
+func (r *ProposalRepository) ListExpenses(ctx context.Context, tripID string) ([]Expense, error) {
+	rows, err := r.db.Query(ctx, listExpenses, tripID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]Expense, 0)
+	for rows.Next() {
+		var e Expense
+		if err := rows.Scan(&e.ID, &e.TripID, &e.Date, &e.Category, &e.Title, &e.Amount, &e.Currency, &e.PayerName, &e.Notes, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+func (r *ProposalRepository) ListSavedFlights(ctx context.Context, tripID string) ([]SavedFlight, error) {
+	rows, err := r.db.Query(ctx, listSavedFlights, tripID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]SavedFlight, 0)
+	for rows.Next() {
+		var f SavedFlight
+		if err := rows.Scan(&f.ID, &f.TripID, &f.Source, &f.FlightNumber, &f.FlightDate, &f.Route, &f.Departure, &f.Arrival, &f.Airline, &f.Cost); err != nil {
+			return nil, err
+		}
+		out = append(out, f)
+	}
+	return out, rows.Err()
+}
+
+func (r *ProposalRepository) AddItineraryItem(ctx context.Context, it ItineraryItem) (*ItineraryItem, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	now := time.Now().UTC()
+	id, err := applyProposal(ctx, pgxTx{tx}, itineraryItemProposal(it), now)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	it.ID, it.Status, it.CreatedAt, it.UpdatedAt = id, "planned", now, now
+	return &it, nil
+}
+
+type pgxTx struct{ tx pgx.Tx }
+
+func (t pgxTx) exec(ctx context.Context, q string, args ...any) (int64, error) {
+	tag, err := t.tx.Exec(ctx, q, args...)
+	return tag.RowsAffected(), err
+}
+
+func (pgxTx) timestamp(t time.Time) any { return t }
+
+func scanProposal(row pgx.Row) (*Proposal, error) {
+	var p Proposal
+	var change, preview []byte
+	if err := row.Scan(&p.ID, &p.TripID, &p.ConversationID, &p.MessageID, &p.UserID, &p.Kind, &p.Label, &change, &preview, &p.Status, &p.ResultID, &p.DecidedBy, &p.DecidedAt, &p.CreatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	_ = json.Unmarshal(change, &p.Change)
+	_ = json.Unmarshal(preview, &p.Preview)
+	return &p, nil
+}
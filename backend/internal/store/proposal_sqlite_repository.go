@@ -0,0 +1,211 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SQLiteProposalRepository is the ProposalStore for self-hosted deployments.
+type SQLiteProposalRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteProposalRepository(db *sql.DB) *SQLiteProposalRepository {
+	return &SQLiteProposalRepository{db: db}
+}
+
+func (r *SQLiteProposalRepository) CreateProposal(ctx context.Context, p Proposal) (*Proposal, error) {
+	p.ID = uuid.NewString()
+	p.Status = ProposalPending
+	p.CreatedAt = time.Now().UTC()
+	change, _ := json.Marshal(p.Change)
+	preview, _ := json.Marshal(p.Preview)
+	const q = `
+		INSERT INTO ai_proposals (id, trip_id, conversation_id, message_id, user_id, kind, label, change_json, preview_json, status, created_at)
+		VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), $5, $6, $7, $8, $9, $10, $11)`
+	if _, err := r.db.ExecContext(ctx, q, p.ID, p.TripID, p.ConversationID, p.MessageID, p.UserID, p.Kind, p.Label, string(change), string(preview), p.Status, sqliteTime(p.CreatedAt)); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r *SQLiteProposalRepository) GetProposal(ctx context.Context, id string) (*Proposal, error) {
+	q := `SELECT ` + proposalColumns + ` FROM ai_proposals WHERE id = $1`
+	return scanSQLiteProposal(r.db.QueryRowContext(ctx, q, id))
+}
+
+func (r *SQLiteProposalRepository) ListProposals(ctx context.Context, tripID, status string, limit int) ([]Proposal, error) {
+	q := `SELECT ` + proposalColumns + ` FROM ai_proposals WHERE trip_id = $1 AND ($2 = '' OR status = $2) ORDER BY created_at DESC, id DESC LIMIT $3`
+	rows, err := r.db.QueryContext(ctx, q, tripID, status, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]Proposal, 0)
+	for rows.Next() {
+		p, err := scanSQLiteProposal(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *p)
+	}
+	return out, rows.Err()
+}
+
+// ApproveProposal relies on SQLite serialising write transactions in place of
+// SELECT ... FOR UPDATE: the status update only succeeds while the proposal is still pending.
+func (r *SQLiteProposalRepository) ApproveProposal(ctx context.Context, id, userID string) (*Proposal, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	p, err := scanSQLiteProposal(tx.QueryRowContext(ctx, `SELECT `+proposalColumns+` FROM ai_proposals WHERE id = $1`, id))
+	if err != nil {
+		return nil, err
+	}
+	if p.Status != ProposalPending {
+		return nil, ErrProposalDecided
+	}
+	now := time.Now().UTC()
+	resultID, err := applyProposal(ctx, sqlTx{tx}, p, now)
+	if err != nil {
+		return nil, err
+	}
+	const q = `UPDATE ai_proposals SET status = $2, result_id = $3, decided_by = $4, decided_at = $5 WHERE id = $1 AND status = 'pending'`
+	res, err := tx.ExecContext(ctx, q, id, ProposalApproved, resultID, userID, sqliteTime(now))
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, ErrProposalDecided
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	decide(p, ProposalApproved, resultID, userID, now)
+	return p, nil
+}
+
+func (r *SQLiteProposalRepository) RejectProposal(ctx context.Context, id, userID string) (*Proposal, error) {
+	const q = `UPDATE ai_proposals SET status = $2, decided_by = $3, decided_at = $4 WHERE id = $1 AND status = 'pending'`
+	res, err := r.db.ExecContext(ctx, q, id, ProposalRejected, userID, sqliteTime(time.Now()))
+	if err != nil {
+		return nil, err
+	}
+	p, err := r.GetProposal(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, ErrProposalDecided
+	}
+	return p, nil
+}
+
+func (r *SQLiteProposalRepository) ListItineraryItems(ctx context.Context, tripID string) ([]ItineraryItem, error) {
+	rows, err := r.db.QueryContext(ctx, listItineraryItems, tripID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]ItineraryItem, 0)
+	for rows.Next() {
+		var it ItineraryItem
+		var created, updated string
+		if err := rows.Scan(&it.ID, &it.TripID, &it.DayIndex, &it.TimeBlock, &it.Status, &it.Category, &it.Title, &it.LocationLabel, &it.Notes, &created, &updated); err != nil {
+			return nil, err
+		}
+		it.CreatedAt, it.UpdatedAt = parseSQLiteTime(created), parseSQLiteTime(updated)
+		out = append(out, it)
+	}
+	return out, rows.Err()
+}
+
+func (r *SQLiteProposalRepository) ListExpenses(ctx context.Context, tripID string) ([]Expense, error) {
+	rows, err := r.db.QueryContext(ctx, listExpenses, tripID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]Expense, 0)
+	for rows.Next() {
+		var e Expense
+		var created string
+		if err := rows.Scan(&e.ID, &e.TripID, &e.Date, &e.Category, &e.Title, &e.Amount, &e.Currency, &e.PayerName, &e.Notes, &created); err != nil {
+			return nil, err
+		}
+		e.CreatedAt = parseSQLiteTime(created)
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+func (r *SQLiteProposalRepository) ListSavedFlights(ctx context.Context, tripID string) ([]SavedFlight, error) {
+	rows, err := r.db.QueryContext(ctx, listSavedFlights, tripID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]SavedFlight, 0)
+	for rows.Next() {
+		var f SavedFlight
+		if err := rows.Scan(&f.ID, &f.TripID, &f.Source, &f.FlightNumber, &f.FlightDate, &f.Route, &f.Departure, &f.Arrival, &f.Airline, &f.Cost); err != nil {
+			return nil, err
+		}
+		out = append(out, f)
+	}
+	return out, rows.Err()
+}
+
+func (r *SQLiteProposalRepository) AddItineraryItem(ctx context.Context, it ItineraryItem) (*ItineraryItem, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	now := time.Now().UTC()
+	id, err := applyProposal(ctx, sqlTx{tx}, itineraryItemProposal(it), now)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	it.ID, it.Status, it.CreatedAt, it.UpdatedAt = id, "planned", now, now
+	return &it, nil
+}
+
+type sqlTx struct{ tx *sql.Tx }
+
+func (t sqlTx) exec(ctx context.Context, q string, args ...any) (int64, error) {
+	res, err := t.tx.ExecContext(ctx, q, args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (sqlTx) timestamp(t time.Time) any { return sqliteTime(t) }
+
+func scanSQLiteProposal(row interface{ Scan(...any) error }) (*Proposal, error) {
+	var p Proposal
+	var change, preview, created string
+	var decided sql.NullString
+	if err := row.Scan(&p.ID, &p.TripID, &p.ConversationID, &p.MessageID, &p.UserID, &p.Kind, &p.Label, &change, &preview, &p.Status, &p.ResultID, &p.DecidedBy, &decided, &created); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	_ = json.Unmarshal([]byte(change), &p.Change)
+	_ = json.Unmarshal([]byte(preview), &p.Preview)
+	p.DecidedAt = sqliteNullTime(decided)
+	p.CreatedAt = parseSQLiteTime(created)
+	return &p, nil
+}
@@ -0,0 +1,236 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Proposal kinds: the trip changes the assistant may propose.
+const (
+	ProposalAddItineraryItem  = "add_itinerary_item"
+	ProposalMoveItineraryItem = "move_itinerary_item"
+	ProposalSaveFlight        = "save_flight"
+	ProposalAddExpense        = "add_expense"
+)
+
+// Proposal statuses.
+const (
+	ProposalPending  = "pending"
+	ProposalApproved = "approved"
+	ProposalRejected = "rejected"
+)
+
+var (
+	// ErrProposalDecided is returned when approving or rejecting a proposal that is no longer
+	// pending.
+	ErrProposalDecided = errors.New("proposal already decided")
+	// ErrProposalStale is returned when the row a proposal changes no longer matches its
+	// preview, e.g. an itinerary item that was moved or deleted since.
+	ErrProposalStale = errors.New("proposal is out of date")
+)
+
+// Proposal is a trip change suggested by the assistant. Nothing is written to the trip until
+// a member approves it.
+type Proposal struct {
+	ID             string          `json:"id"`
+	TripID         string          `json:"tripId"`
+	ConversationID string          `json:"conversationId,omitempty"`
+	MessageID      string          `json:"messageId,omitempty"`
+	UserID         string          `json:"userId"`
+	Kind           string          `json:"kind"`
+	Label          string          `json:"label"`
+	Change         ProposalChange  `json:"change"`
+	Preview        ProposalPreview `json:"preview"`
+	Status         string          `json:"status"`
+	// ResultID is the row an approved proposal created or changed.
+	ResultID  string     `json:"resultId,omitempty"`
+	DecidedBy string     `json:"decidedBy,omitempty"`
+	DecidedAt *time.Time `json:"decidedAt,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+// ProposalChange is what an approved proposal writes; which fields apply depends on the kind.
+type ProposalChange struct {
+	// ItemID, FromDayIndex and FromTimeBlock identify the item a move applies to and where it
+	// was when the move was proposed.
+	ItemID        string `json:"itemId,omitempty"`
+	FromDayIndex  int    `json:"fromDayIndex,omitempty"`
+	FromTimeBlock string `json:"fromTimeBlock,omitempty"`
+
+	DayIndex  int    `json:"dayIndex,omitempty"`
+	TimeBlock string `json:"timeBlock,omitempty"`
+	Title     string `json:"title,omitempty"`
+	Category  string `json:"category,omitempty"`
+	Notes     string `json:"notes,omitempty"`
+
+	FlightNumber string `json:"flightNumber,omitempty"`
+	// Source is the trip_flights source: outbound, inbound or one_way.
+	Source string `json:"source,omitempty"`
+
+	// Date is the flight date or the expense date (YYYY-MM-DD).
+	Date     string  `json:"date,omitempty"`
+	Amount   float64 `json:"amount,omitempty"`
+	Currency string  `json:"currency,omitempty"`
+}
+
+// ProposalPreview is the diff shown before approval: the affected row as it is (nil for
+// additions) and as it would be.
+type ProposalPreview struct {
+	Table  string         `json:"table"`
+	Before map[string]any `json:"before,omitempty"`
+	After  map[string]any `json:"after"`
+}
+
+// ItineraryItem is a trip_itinerary_items row.
+type ItineraryItem struct {
+	ID            string    `json:"id"`
+	TripID        string    `json:"tripId"`
+	DayIndex      int       `json:"dayIndex"`
+	TimeBlock     string    `json:"timeBlock"`
+	Status        string    `json:"status"`
+	Category      string    `json:"category"`
+	Title         string    `json:"title"`
+	LocationLabel string    `json:"locationLabel"`
+	Notes         string    `json:"notes"`
+	CreatedAt     time.Time `json:"createdAt"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+// Expense is a trip_expenses row.
+type Expense struct {
+	ID        string    `json:"id"`
+	TripID    string    `json:"tripId"`
+	Date      string    `json:"date"`
+	Category  string    `json:"category"`
+	Title     string    `json:"title"`
+	Amount    float64   `json:"amount"`
+	Currency  string    `json:"currency"`
+	PayerName string    `json:"payerName"`
+	Notes     string    `json:"notes"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// SavedFlight is a trip_flights row, without its booking links.
+type SavedFlight struct {
+	ID           string `json:"id"`
+	TripID       string `json:"tripId"`
+	Source       string `json:"source"`
+	FlightNumber string `json:"flightNumber"`
+	FlightDate   string `json:"flightDate"`
+	Route        string `json:"route"`
+	Departure    string `json:"departure"`
+	Arrival      string `json:"arrival"`
+	Airline      string `json:"airline"`
+	Cost         string `json:"cost"`
+}
+
+// ProposalStore keeps assistant proposals and applies approved ones to the trip tables in the
+// same transaction that marks them approved. ProposalRepository (Postgres),
+// SQLiteProposalRepository and MemoryProposalRepository implement it.
+type ProposalStore interface {
+	// CreateProposal stores p as pending, assigning its ID and CreatedAt.
+	CreateProposal(ctx context.Context, p Proposal) (*Proposal, error)
+	// GetProposal returns ErrNotFound for unknown IDs.
+	GetProposal(ctx context.Context, id string) (*Proposal, error)
+	// ListProposals returns a trip's proposals newest first, only those with status when it
+	// is not empty.
+	ListProposals(ctx context.Context, tripID, status string, limit int) ([]Proposal, error)
+	// ApproveProposal applies a pending proposal and marks it approved, in one transaction. It
+	// returns ErrProposalDecided if the proposal is no longer pending and ErrProposalStale if
+	// the row it changes no longer matches.
+	ApproveProposal(ctx context.Context, id, userID string) (*Proposal, error)
+	// RejectProposal marks a pending proposal rejected without changing the trip.
+	RejectProposal(ctx context.Context, id, userID string) (*Proposal, error)
+
+	// ListItineraryItems returns a trip's items by day and time block.
+	ListItineraryItems(ctx context.Context, tripID string) ([]ItineraryItem, error)
+	// ListExpenses returns a trip's expenses by date.
+	ListExpenses(ctx context.Context, tripID string) ([]Expense, error)
+	// ListSavedFlights returns a trip's saved flights by date.
+	ListSavedFlights(ctx context.Context, tripID string) ([]SavedFlight, error)
+	// AddItineraryItem stores an item directly, without a proposal, for seeding and tests.
+	AddItineraryItem(ctx context.Context, it ItineraryItem) (*ItineraryItem, error)
+}
+
+var (
+	_ ProposalStore = (*ProposalRepository)(nil)
+	_ ProposalStore = (*SQLiteProposalRepository)(nil)
+	_ ProposalStore = (*MemoryProposalRepository)(nil)
+)
+
+const proposalColumns = `id, trip_id, COALESCE(conversation_id, ''), COALESCE(message_id, ''), user_id, kind, label,
+	change_json, preview_json, status, COALESCE(result_id, ''), COALESCE(decided_by, ''), decided_at, created_at`
+
+const (
+	listItineraryItems = `
+		SELECT id, trip_id, day_index, time_block, status, category, title, location_label, notes, created_at, updated_at
+		FROM trip_itinerary_items WHERE trip_id = $1
+		ORDER BY day_index, CASE time_block WHEN 'morning' THEN 1 WHEN 'afternoon' THEN 2 ELSE 3 END, created_at, id`
+	listExpenses = `
+		SELECT id, trip_id, expense_date, category, title, amount, currency, payer_name, notes, created_at
+		FROM trip_expenses WHERE trip_id = $1 ORDER BY expense_date, created_at, id`
+	listSavedFlights = `
+		SELECT id, trip_id, source, flight_number, flight_date, route, departure, arrival, airline, cost
+		FROM trip_flights WHERE trip_id = $1 ORDER BY flight_date, created_at, id`
+)
+
+func decide(p *Proposal, status, resultID, userID string, at time.Time) {
+	p.Status, p.ResultID, p.DecidedBy, p.DecidedAt = status, resultID, userID, &at
+}
+
+// proposalExecer is the part of a pgx or database/sql transaction applyProposal needs.
+type proposalExecer interface {
+	exec(ctx context.Context, q string, args ...any) (int64, error)
+	// timestamp converts t to the driver's column representation.
+	timestamp(t time.Time) any
+}
+
+// applyProposal writes p's change and returns the ID of the row it created or changed.
+func applyProposal(ctx context.Context, tx proposalExecer, p *Proposal, now time.Time) (string, error) {
+	c := p.Change
+	ts := tx.timestamp(now)
+	switch p.Kind {
+	case ProposalAddItineraryItem:
+		id := uuid.NewString()
+		const q = `
+			INSERT INTO trip_itinerary_items (id, trip_id, day_index, time_block, category, title, notes, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8)`
+		_, err := tx.exec(ctx, q, id, p.TripID, c.DayIndex, c.TimeBlock, c.Category, c.Title, c.Notes, ts)
+		return id, err
+	case ProposalMoveItineraryItem:
+		const q = `
+			UPDATE trip_itinerary_items SET day_index = $3, time_block = $4, updated_at = $5
+			WHERE id = $1 AND trip_id = $2 AND day_index = $6 AND time_block = $7`
+		n, err := tx.exec(ctx, q, c.ItemID, p.TripID, c.DayIndex, c.TimeBlock, ts, c.FromDayIndex, c.FromTimeBlock)
+		if err != nil {
+			return "", err
+		}
+		if n == 0 {
+			return "", ErrProposalStale
+		}
+		return c.ItemID, nil
+	case ProposalSaveFlight:
+		id := uuid.NewString()
+		const q = `
+			INSERT INTO trip_flights (id, trip_id, source, flight_number, flight_date, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $6)`
+		_, err := tx.exec(ctx, q, id, p.TripID, c.Source, c.FlightNumber, c.Date, ts)
+		return id, err
+	case ProposalAddExpense:
+		id := uuid.NewString()
+		const q = `
+			INSERT INTO trip_expenses (id, trip_id, expense_date, category, title, amount, currency, notes, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $9)`
+		_, err := tx.exec(ctx, q, id, p.TripID, c.Date, c.Category, c.Title, c.Amount, c.Currency, c.Notes, ts)
+		return id, err
+	}
+	return "", errors.New("unknown proposal kind " + p.Kind)
+}
+
+// itineraryItemProposal is the proposal AddItineraryItem applies.
+func itineraryItemProposal(it ItineraryItem) *Proposal {
+	return &Proposal{TripID: it.TripID, Kind: ProposalAddItineraryItem, Change: ProposalChange{DayIndex: it.DayIndex, TimeBlock: it.TimeBlock, Category: it.Category, Title: it.Title, Notes: it.Notes}}
+}
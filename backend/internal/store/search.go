@@ -0,0 +1,139 @@
+package store
+
+import (
+	"html"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// MessageHit is one search result. Snippet is HTML-escaped with the matched words wrapped in
+// <mark></mark>.
+type MessageHit struct {
+	MessageID         string    `json:"messageId"`
+	ConversationID    string    `json:"conversationId"`
+	ConversationTitle string    `json:"conversationTitle"`
+	TripID            string    `json:"tripId"`
+	Role              string    `json:"role"`
+	Snippet           string    `json:"snippet"`
+	Rank              float64   `json:"rank"`
+	CreatedAt         time.Time `json:"createdAt"`
+}
+
+const snippetWords = 24
+
+// SearchTerms lower-cases query and splits it into words, dropping duplicates.
+func SearchTerms(query string) []string {
+	seen := make(map[string]bool)
+	out := make([]string, 0)
+	for _, w := range strings.FieldsFunc(strings.ToLower(query), isNotWordRune) {
+		if !seen[w] {
+			seen[w] = true
+			out = append(out, w)
+		}
+	}
+	return out
+}
+
+func isNotWordRune(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+type wordSpan struct {
+	start, end int
+	lower      string
+}
+
+func splitWords(content string) []wordSpan {
+	out := make([]wordSpan, 0)
+	start := -1
+	for i, r := range content {
+		if isNotWordRune(r) {
+			if start >= 0 {
+				out = append(out, wordSpan{start, i, strings.ToLower(content[start:i])})
+				start = -1
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		out = append(out, wordSpan{start, len(content), strings.ToLower(content[start:])})
+	}
+	return out
+}
+
+// matchContent is the search used by the in-memory and SQLite stores, standing in for
+// Postgres full-text search: every term must prefix some word ("ferr" finds "ferries"), rank
+// grows with hits and shrinks with message length, and the snippet is centred on the first hit.
+func matchContent(content string, terms []string) (float64, string, bool) {
+	if len(terms) == 0 {
+		return 0, "", false
+	}
+	words := splitWords(content)
+	hit := make([]bool, len(words))
+	matched := make(map[string]bool, len(terms))
+	hits, first := 0, -1
+	for i, w := range words {
+		for _, term := range terms {
+			if strings.HasPrefix(w.lower, term) {
+				hit[i] = true
+				matched[term] = true
+				hits++
+				if first < 0 {
+					first = i
+				}
+				break
+			}
+		}
+	}
+	if len(matched) < len(terms) {
+		return 0, "", false
+	}
+	rank := float64(hits) / (1 + math.Log(float64(len(words))))
+
+	from := first - snippetWords/3
+	if from < 0 {
+		from = 0
+	}
+	to := from + snippetWords
+	if to > len(words) {
+		to = len(words)
+	}
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString("… ")
+	}
+	for i := from; i < to; i++ {
+		if i > from {
+			b.WriteString(html.EscapeString(content[words[i-1].end:words[i].start]))
+		}
+		word := html.EscapeString(content[words[i].start:words[i].end])
+		if hit[i] {
+			word = "<mark>" + word + "</mark>"
+		}
+		b.WriteString(word)
+	}
+	if to < len(words) {
+		b.WriteString(" …")
+	}
+	return rank, b.String(), true
+}
+
+// sortHits orders hits best first, newest first among equals, and applies limit.
+func sortHits(hits []MessageHit, limit int) []MessageHit {
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].Rank != hits[j].Rank {
+			return hits[i].Rank > hits[j].Rank
+		}
+		return hits[i].CreatedAt.After(hits[j].CreatedAt)
+	})
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits
+}
@@ -0,0 +1,47 @@
+package store_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"triploom/backend/internal/migrate"
+	"triploom/backend/internal/store"
+	"triploom/backend/migrations"
+)
+
+// openSQLite returns a migrated database in a temporary directory.
+func openSQLite(t *testing.T) *sql.DB {
+	t.Helper()
+	ctx := context.Background()
+	db, err := store.OpenSQLite(ctx, filepath.Join(t.TempDir(), "triploom.db"))
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	all, err := migrate.Load(migrations.FS)
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+	if _, err := migrate.NewRunner(migrate.NewSQLite(db), all).Up(ctx); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+func TestSQLitePromptTemplates(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t)
+	repo := store.NewSQLiteAIRepository(db)
+	if got, err := repo.ListPromptTemplates(ctx); err != nil || len(got) != 0 {
+		t.Fatalf("expected no templates, got %+v %v", got, err)
+	}
+	if _, err := db.ExecContext(ctx, `INSERT INTO ai_prompt_templates (name, version, body, weight) VALUES ('copilot', 'v2', 'Hi {{.PageKey}}', 50)`); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	got, err := repo.ListPromptTemplates(ctx)
+	if err != nil || len(got) != 1 || got[0].Version != "v2" || got[0].Weight != 50 || got[0].CreatedAt.IsZero() {
+		t.Fatalf("unexpected templates %+v %v", got, err)
+	}
+}
@@ -0,0 +1,468 @@
+// Package storetest holds the behaviour every implementation of the store interfaces must
+// share.
+package storetest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"triploom/backend/internal/store"
+)
+
+// Harness adapts one AIStore implementation to the suite.
+type Harness struct {
+	// New returns a store for a subtest. Stores may be shared: the suite uses fresh IDs.
+	New func(t *testing.T) store.AIStore
+}
+
+// RunAIStore runs the conformance suite against h.
+func RunAIStore(t *testing.T, h Harness) {
+	t.Run("trips", func(t *testing.T) { testTrips(t, h) })
+	t.Run("conversations", func(t *testing.T) { testConversations(t, h) })
+	t.Run("threads", func(t *testing.T) { testThreads(t, h) })
+	t.Run("pagination", func(t *testing.T) { testPagination(t, h) })
+	t.Run("search", func(t *testing.T) { testSearch(t, h) })
+	t.Run("feedback", func(t *testing.T) { testFeedback(t, h) })
+	t.Run("snapshots", func(t *testing.T) { testSnapshots(t, h) })
+	t.Run("audit", func(t *testing.T) { testAudit(t, h) })
+}
+
+// seed creates a trip owned by the first member and adds the rest as viewers.
+func seed(t *testing.T, s store.AIStore, members ...string) store.Trip {
+	t.Helper()
+	ctx := context.Background()
+	trip, err := s.CreateTrip(ctx, store.Trip{
+		ID:          "trip-" + uuid.NewString(),
+		Destination: "Lisbon",
+		StartDate:   time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC),
+		EndDate:     time.Date(2026, 5, 8, 0, 0, 0, 0, time.UTC),
+		Timezone:    "Europe/Lisbon",
+	}, members[0])
+	if err != nil {
+		t.Fatalf("create trip: %v", err)
+	}
+	for _, userID := range members[1:] {
+		if err := s.AddTripMember(ctx, trip.ID, userID, "viewer"); err != nil {
+			t.Fatalf("add member: %v", err)
+		}
+	}
+	return *trip
+}
+
+func testTrips(t *testing.T, h Harness) {
+	ctx := context.Background()
+	s := h.New(t)
+	trip := seed(t, s, "alice", "bob")
+
+	for _, member := range []string{"alice", "bob"} {
+		if ok, err := s.IsTripMember(ctx, trip.ID, member); err != nil || !ok {
+			t.Fatalf("expected %s to be a member: ok=%v err=%v", member, ok, err)
+		}
+	}
+	if ok, err := s.IsTripMember(ctx, trip.ID, "mallory"); err != nil || ok {
+		t.Fatalf("expected mallory not to be a member: ok=%v err=%v", ok, err)
+	}
+	for member, want := range map[string]string{"alice": "owner", "bob": "viewer", "mallory": ""} {
+		if role, err := s.TripRole(ctx, trip.ID, member); err != nil || role != want {
+			t.Fatalf("expected %s to have role %q, got %q (%v)", member, want, role, err)
+		}
+	}
+	got, err := s.GetTripByID(ctx, trip.ID)
+	if err != nil {
+		t.Fatalf("get trip: %v", err)
+	}
+	if got.Destination != "Lisbon" || got.Timezone != "Europe/Lisbon" || !got.StartDate.Equal(trip.StartDate) {
+		t.Fatalf("unexpected trip %+v", got)
+	}
+	if !got.EndDate.Equal(trip.EndDate) {
+		t.Fatalf("expected end date %s, got %s", trip.EndDate, got.EndDate)
+	}
+}
+
+func testConversations(t *testing.T, h Harness) {
+	ctx := context.Background()
+	s := h.New(t)
+	trip := seed(t, s, "alice", "bob")
+
+	first, err := s.UpsertConversation(ctx, trip.ID, "alice", "Itinerary assistant")
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	again, _ := s.UpsertConversation(ctx, trip.ID, "alice", "ignored")
+	if again != first {
+		t.Fatalf("expected upsert to reuse %s, got %s", first, again)
+	}
+	bobs, _ := s.UpsertConversation(ctx, trip.ID, "bob", "Itinerary assistant")
+	if bobs == first {
+		t.Fatalf("expected a separate conversation per user")
+	}
+
+	for i, content := range []string{"first", "second", "third"} {
+		role := "user"
+		if i%2 == 1 {
+			role = "assistant"
+		}
+		msg := store.Message{ConversationID: first, Role: role, Content: content, Model: "gpt-5-mini", TokenUsageJSON: map[string]any{"total": "12"}}
+		if _, err := s.InsertMessage(ctx, msg); err != nil {
+			t.Fatalf("insert message: %v", err)
+		}
+	}
+	msgs, _, err := s.ListMessages(ctx, first, store.PageRequest{Limit: 2})
+	if err != nil {
+		t.Fatalf("list messages: %v", err)
+	}
+	if len(msgs) != 2 || msgs[0].Content != "third" || msgs[1].Content != "second" || msgs[1].Role != "assistant" {
+		t.Fatalf("expected the two newest messages newest first, got %+v", msgs)
+	}
+	if msgs[0].TokenUsageJSON["total"] != "12" || msgs[0].ConversationID != first {
+		t.Fatalf("message fields not round-tripped: %+v", msgs[0])
+	}
+	if empty, _, err := s.ListMessages(ctx, uuid.NewString(), store.PageRequest{Limit: 10}); err != nil || len(empty) != 0 {
+		t.Fatalf("expected no messages for unknown conversation: %v %v", empty, err)
+	}
+
+	if ok, _ := s.ConversationBelongsToUser(ctx, first, "alice"); !ok {
+		t.Fatalf("expected alice to own her conversation")
+	}
+	if ok, _ := s.ConversationBelongsToUser(ctx, first, "bob"); ok {
+		t.Fatalf("expected bob not to own alice's conversation")
+	}
+
+	convs, _, err := s.ListConversations(ctx, trip.ID, "alice", false, store.PageRequest{Limit: 50})
+	if err != nil {
+		t.Fatalf("list conversations: %v", err)
+	}
+	if len(convs) != 1 || convs[0].ID != first || convs[0].Title != "Itinerary assistant" {
+		t.Fatalf("unexpected conversations %+v", convs)
+	}
+}
+
+func testThreads(t *testing.T, h Harness) {
+	ctx := context.Background()
+	s := h.New(t)
+	trip := seed(t, s, "alice")
+
+	older, err := s.CreateConversation(ctx, trip.ID, "alice", "")
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	time.Sleep(2 * time.Millisecond)
+	newer, _ := s.CreateConversation(ctx, trip.ID, "alice", "Day trips")
+	if older.ID == newer.ID || newer.Title != "Day trips" || newer.CreatedAt.IsZero() {
+		t.Fatalf("unexpected conversations %+v %+v", older, newer)
+	}
+	if got, _ := s.UpsertConversation(ctx, trip.ID, "alice", "ignored"); got != newer.ID {
+		t.Fatalf("expected upsert to pick the most recent thread %s, got %s", newer.ID, got)
+	}
+
+	if err := s.RenameConversation(ctx, older.ID, "Restaurants"); err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+	if err := s.SetConversationArchived(ctx, newer.ID, true); err != nil {
+		t.Fatalf("archive: %v", err)
+	}
+	got, err := s.GetConversation(ctx, newer.ID)
+	if err != nil || got.ArchivedAt == nil || got.TripID != trip.ID {
+		t.Fatalf("expected an archived conversation, got %+v %v", got, err)
+	}
+	if id, _ := s.UpsertConversation(ctx, trip.ID, "alice", "ignored"); id != older.ID {
+		t.Fatalf("expected upsert to skip archived threads, got %s", id)
+	}
+	active, _, _ := s.ListConversations(ctx, trip.ID, "alice", false, store.PageRequest{Limit: 50})
+	if len(active) != 1 || active[0].ID != older.ID || active[0].Title != "Restaurants" {
+		t.Fatalf("unexpected active conversations %+v", active)
+	}
+	if all, _, _ := s.ListConversations(ctx, trip.ID, "alice", true, store.PageRequest{Limit: 50}); len(all) != 2 {
+		t.Fatalf("expected archived threads on request, got %+v", all)
+	}
+	if err := s.SetConversationArchived(ctx, newer.ID, false); err != nil {
+		t.Fatalf("unarchive: %v", err)
+	}
+	if got, _ := s.GetConversation(ctx, newer.ID); got.ArchivedAt != nil {
+		t.Fatalf("expected archivedAt to be cleared, got %+v", got)
+	}
+
+	_, _ = s.InsertMessage(ctx, store.Message{ConversationID: older.ID, Role: "user", Content: "hello"})
+	_ = s.InsertToolSnapshot(ctx, older.ID, "itinerary", "trip_db_context", "ok", nil)
+	if err := s.DeleteConversation(ctx, older.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := s.GetConversation(ctx, older.ID); err != store.ErrNotFound {
+		t.Fatalf("expected ErrNotFound after delete, got %v", err)
+	}
+	if msgs, _, _ := s.ListMessages(ctx, older.ID, store.PageRequest{Limit: 10}); len(msgs) != 0 {
+		t.Fatalf("expected messages to be deleted, got %+v", msgs)
+	}
+	if snaps, _ := s.ListToolSnapshots(ctx, older.ID); len(snaps) != 0 {
+		t.Fatalf("expected tool snapshots to be deleted, got %+v", snaps)
+	}
+	for _, err := range []error{
+		s.RenameConversation(ctx, older.ID, "x"),
+		s.SetConversationArchived(ctx, older.ID, true),
+		s.DeleteConversation(ctx, older.ID),
+	} {
+		if err != store.ErrNotFound {
+			t.Fatalf("expected ErrNotFound for a deleted conversation, got %v", err)
+		}
+	}
+}
+
+func testPagination(t *testing.T, h Harness) {
+	ctx := context.Background()
+	s := h.New(t)
+	trip := seed(t, s, "alice")
+
+	convIDs := make([]string, 0)
+	for _, title := range []string{"one", "two", "three"} {
+		conv, err := s.CreateConversation(ctx, trip.ID, "alice", title)
+		if err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		convIDs = append([]string{conv.ID}, convIDs...)
+		time.Sleep(2 * time.Millisecond)
+	}
+	// Activity must not reorder the listing: pages are keyed by creation time.
+	_, _ = s.InsertMessage(ctx, store.Message{ConversationID: convIDs[2], Role: "user", Content: "bump"})
+
+	var got []string
+	cursor := ""
+	for pages := 0; ; pages++ {
+		convs, next, err := s.ListConversations(ctx, trip.ID, "alice", false, store.PageRequest{Cursor: cursor, Limit: 2})
+		if err != nil {
+			t.Fatalf("list conversations: %v", err)
+		}
+		for _, c := range convs {
+			got = append(got, c.ID)
+		}
+		if next == "" {
+			break
+		}
+		if pages > 3 {
+			t.Fatalf("pagination did not terminate")
+		}
+		cursor = next
+	}
+	if strings.Join(got, ",") != strings.Join(convIDs, ",") {
+		t.Fatalf("expected conversations newest first %v, got %v", convIDs, got)
+	}
+	if _, next, _ := s.ListConversations(ctx, trip.ID, "alice", false, store.PageRequest{Limit: 3}); next != "" {
+		t.Fatalf("expected no cursor after an exactly full last page, got %q", next)
+	}
+
+	conv := convIDs[0]
+	for _, content := range []string{"m1", "m2", "m3", "m4", "m5"} {
+		_, _ = s.InsertMessage(ctx, store.Message{ConversationID: conv, Role: "user", Content: content})
+	}
+	first, cursor, err := s.ListMessages(ctx, conv, store.PageRequest{Limit: 2})
+	if err != nil || len(first) != 2 || first[0].Content != "m5" || first[1].Content != "m4" || cursor == "" {
+		t.Fatalf("unexpected first page %+v %q %v", first, cursor, err)
+	}
+	// New messages arriving between requests do not shift later pages.
+	_, _ = s.InsertMessage(ctx, store.Message{ConversationID: conv, Role: "user", Content: "m6"})
+	second, cursor, _ := s.ListMessages(ctx, conv, store.PageRequest{Cursor: cursor, Limit: 2})
+	if len(second) != 2 || second[0].Content != "m3" || second[1].Content != "m2" || cursor == "" {
+		t.Fatalf("unexpected second page %+v %q", second, cursor)
+	}
+	last, cursor, _ := s.ListMessages(ctx, conv, store.PageRequest{Cursor: cursor, Limit: 2})
+	if len(last) != 1 || last[0].Content != "m1" || cursor != "" {
+		t.Fatalf("unexpected last page %+v %q", last, cursor)
+	}
+
+	for _, bad := range []string{"not-a-cursor", "e30"} {
+		if _, _, err := s.ListMessages(ctx, conv, store.PageRequest{Cursor: bad, Limit: 2}); err != store.ErrInvalidCursor {
+			t.Fatalf("expected ErrInvalidCursor for %q, got %v", bad, err)
+		}
+	}
+
+	// Turns stored back to back must not tie, or an answer could sort before its question.
+	turns := convIDs[1]
+	var want []string
+	var prev time.Time
+	for i := 0; i < 20; i++ {
+		role := "user"
+		if i%2 == 1 {
+			role = "assistant"
+		}
+		msg, err := s.InsertMessage(ctx, store.Message{ConversationID: turns, Role: role, Content: fmt.Sprintf("t%d", i)})
+		if err != nil || !msg.CreatedAt.After(prev) {
+			t.Fatalf("expected message %d after %v, got %+v %v", i, prev, msg, err)
+		}
+		prev = msg.CreatedAt
+		want = append([]string{msg.Content}, want...)
+	}
+	listed, _, _ := s.ListMessages(ctx, turns, store.PageRequest{Limit: 50})
+	got = got[:0]
+	for _, m := range listed {
+		got = append(got, m.Content)
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected messages in insertion order %v, got %v", want, got)
+	}
+}
+
+func testSearch(t *testing.T, h Harness) {
+	ctx := context.Background()
+	s := h.New(t)
+	// A unique word keeps hits from other subtests sharing the store out of the results.
+	word := "zq" + strings.ReplaceAll(uuid.NewString()[:8], "-", "")
+	lisbon := seed(t, s, "alice", "bob")
+	porto := seed(t, s, "alice")
+
+	first, _ := s.CreateConversation(ctx, lisbon.ID, "alice", "Ferries")
+	second, _ := s.CreateConversation(ctx, porto.ID, "alice", "Wine")
+	bobs, _ := s.CreateConversation(ctx, lisbon.ID, "bob", "Bob's")
+	_, _ = s.InsertMessage(ctx, store.Message{ConversationID: first.ID, Role: "user", Content: "Which " + word + " ferries leave Cais do Sodré?"})
+	_, _ = s.InsertMessage(ctx, store.Message{ConversationID: first.ID, Role: "assistant", Content: "Trains run to Cascais every 20 minutes."})
+	_, _ = s.InsertMessage(ctx, store.Message{ConversationID: second.ID, Role: "assistant", Content: "Book a <b>" + word + "</b> port cellar tour in Gaia, then take the ferries."})
+	_, _ = s.InsertMessage(ctx, store.Message{ConversationID: bobs.ID, Role: "user", Content: word + " ferries for bob"})
+
+	hits, err := s.SearchMessages(ctx, "alice", "", word+" ferries", 10)
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if len(hits) != 2 {
+		t.Fatalf("expected alice's two matching messages, got %+v", hits)
+	}
+	for _, hit := range hits {
+		if hit.ConversationID == bobs.ID {
+			t.Fatalf("expected bob's messages to be excluded, got %+v", hit)
+		}
+		if !strings.Contains(hit.Snippet, "<mark>") || strings.Contains(hit.Snippet, "<b>") {
+			t.Fatalf("expected an escaped, highlighted snippet, got %q", hit.Snippet)
+		}
+	}
+
+	hits, _ = s.SearchMessages(ctx, "alice", porto.ID, word, 10)
+	if len(hits) != 1 || hits[0].ConversationID != second.ID || hits[0].TripID != porto.ID || hits[0].ConversationTitle != "Wine" || hits[0].Role != "assistant" {
+		t.Fatalf("expected the trip filter to keep only the Porto message, got %+v", hits)
+	}
+	if hits, _ := s.SearchMessages(ctx, "alice", "", word+" gondolas", 10); len(hits) != 0 {
+		t.Fatalf("expected every word to be required, got %+v", hits)
+	}
+	if hits, _ := s.SearchMessages(ctx, "alice", "", word, 1); len(hits) != 1 {
+		t.Fatalf("expected limit to apply, got %+v", hits)
+	}
+}
+
+func testFeedback(t *testing.T, h Harness) {
+	ctx := context.Background()
+	s := h.New(t)
+	trip := seed(t, s, "alice", "bob")
+	conv, _ := s.CreateConversation(ctx, trip.ID, "alice", "Food")
+
+	original, err := s.InsertMessage(ctx, store.Message{ConversationID: conv.ID, Role: "assistant", Content: "Try the pastéis.", Model: "model-a", PageKey: "itinerary", PromptVersion: "copilot@v1"})
+	if err != nil || original.ID == "" || original.CreatedAt.IsZero() {
+		t.Fatalf("insert: %+v %v", original, err)
+	}
+	if _, err := s.MessageContext(ctx, original.ID); err != store.ErrNotFound {
+		t.Fatalf("expected no context for a message stored without one, got %v", err)
+	}
+	retry, _ := s.InsertMessage(ctx, store.Message{ConversationID: conv.ID, Role: "assistant", Content: "Try the bifanas.", Model: "model-b", PageKey: "itinerary", PromptVersion: "copilot@v2", RegeneratedFrom: original.ID, ContextJSON: map[string]any{"pageKey": "itinerary"}})
+	if payload, err := s.MessageContext(ctx, retry.ID); err != nil || payload["pageKey"] != "itinerary" {
+		t.Fatalf("expected the stored context, got %v %v", payload, err)
+	}
+	got, err := s.GetMessage(ctx, retry.ID)
+	if err != nil || got.RegeneratedFrom != original.ID || got.PageKey != "itinerary" || got.PromptVersion != "copilot@v2" || got.Model != "model-b" || got.Content != "Try the bifanas." {
+		t.Fatalf("unexpected message %+v %v", got, err)
+	}
+	if _, err := s.GetMessage(ctx, uuid.NewString()); err != store.ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	since := time.Now().Add(-time.Minute)
+	first, err := s.UpsertFeedback(ctx, store.Feedback{MessageID: original.ID, UserID: "alice", Rating: "up"})
+	if err != nil || first.CreatedAt.IsZero() || first.Reasons == nil {
+		t.Fatalf("upsert feedback: %+v %v", first, err)
+	}
+	time.Sleep(2 * time.Millisecond)
+	again, _ := s.UpsertFeedback(ctx, store.Feedback{MessageID: original.ID, UserID: "alice", Rating: "down", Reasons: []string{"outdated", "incorrect"}, Comment: "closed"})
+	if again.Rating != "down" || !again.UpdatedAt.After(first.CreatedAt) {
+		t.Fatalf("expected the rating to be replaced, got %+v", again)
+	}
+	_, _ = s.UpsertFeedback(ctx, store.Feedback{MessageID: retry.ID, UserID: "alice", Rating: "up"})
+	_, _ = s.UpsertFeedback(ctx, store.Feedback{MessageID: retry.ID, UserID: "bob", Rating: "down", Reasons: []string{"outdated"}})
+
+	report, err := s.FeedbackReport(ctx, trip.ID, since)
+	if err != nil {
+		t.Fatalf("report: %v", err)
+	}
+	if len(report) != 2 {
+		t.Fatalf("expected one row per model, got %+v", report)
+	}
+	a, b := report[0], report[1]
+	if a.Model != "model-a" || a.PageKey != "itinerary" || a.PromptVersion != "copilot@v1" || a.Up != 0 || a.Down != 1 || a.Reasons["incorrect"] != 1 {
+		t.Fatalf("unexpected model-a summary %+v", a)
+	}
+	if b.Model != "model-b" || b.Up != 1 || b.Down != 1 || b.Reasons["outdated"] != 1 {
+		t.Fatalf("unexpected model-b summary %+v", b)
+	}
+	if later, _ := s.FeedbackReport(ctx, trip.ID, time.Now().Add(time.Minute)); len(later) != 0 {
+		t.Fatalf("expected since to exclude older ratings, got %+v", later)
+	}
+
+	if err := s.DeleteConversation(ctx, conv.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if report, _ := s.FeedbackReport(ctx, trip.ID, since); len(report) != 0 {
+		t.Fatalf("expected feedback to be deleted with the conversation, got %+v", report)
+	}
+}
+
+func testSnapshots(t *testing.T, h Harness) {
+	ctx := context.Background()
+	s := h.New(t)
+	trip := seed(t, s, "alice")
+	conv, err := s.UpsertConversation(ctx, trip.ID, "alice", "Flights assistant")
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+
+	_ = s.InsertToolSnapshot(ctx, conv, "flights", "flight_status", "ok", map[string]any{"detail": "on time"})
+	_ = s.InsertToolSnapshot(ctx, conv, "flights", "transit", "error", map[string]any{"detail": "timeout"})
+	snaps, err := s.ListToolSnapshots(ctx, conv)
+	if err != nil {
+		t.Fatalf("list tool snapshots: %v", err)
+	}
+	if len(snaps) != 2 || snaps[0].ToolName != "flight_status" || snaps[1].Status != "error" || snaps[1].PayloadJSON["detail"] != "timeout" {
+		t.Fatalf("unexpected tool snapshots %+v", snaps)
+	}
+
+	if _, err := s.LatestContextSnapshot(ctx, trip.ID, "flights"); err != store.ErrNotFound {
+		t.Fatalf("expected ErrNotFound before any snapshot, got %v", err)
+	}
+	_ = s.InsertContextSnapshot(ctx, trip.ID, "flights", map[string]any{"version": "1"})
+	_ = s.InsertContextSnapshot(ctx, trip.ID, "flights", map[string]any{"version": "2"})
+	_ = s.InsertContextSnapshot(ctx, trip.ID, "expenses", map[string]any{"version": "3"})
+	latest, err := s.LatestContextSnapshot(ctx, trip.ID, "flights")
+	if err != nil {
+		t.Fatalf("latest context snapshot: %v", err)
+	}
+	if latest.ContextJSON["version"] != "2" || latest.PageKey != "flights" {
+		t.Fatalf("unexpected latest snapshot %+v", latest)
+	}
+}
+
+func testAudit(t *testing.T, h Harness) {
+	ctx := context.Background()
+	s := h.New(t)
+	trip := seed(t, s, "alice")
+	other := seed(t, s, "alice")
+
+	_ = s.InsertAuditLog(ctx, "alice", trip.ID, "ai_chat", map[string]any{"model": "a"})
+	_ = s.InsertAuditLog(ctx, "alice", other.ID, "ai_chat", map[string]any{"model": "b"})
+	_ = s.InsertAuditLog(ctx, "alice", trip.ID, "ai_context_refresh", map[string]any{"model": "c"})
+	_ = s.InsertAuditLog(ctx, "alice", trip.ID, "ai_chat", map[string]any{"model": "d"})
+
+	logs, err := s.ListAuditLogs(ctx, trip.ID, 2)
+	if err != nil {
+		t.Fatalf("list audit logs: %v", err)
+	}
+	if len(logs) != 2 || logs[0].MetadataJSON["model"] != "d" || logs[1].Action != "ai_context_refresh" {
+		t.Fatalf("expected the trip's two newest entries, got %+v", logs)
+	}
+}
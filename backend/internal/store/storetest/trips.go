@@ -0,0 +1,404 @@
+package storetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"triploom/backend/internal/store"
+)
+
+// Stores is one backend's trip data stores. They share a database, so trips created through
+// AI exist for the others.
+type Stores struct {
+	AI            store.AIStore
+	FlightWatches store.FlightWatchStore
+	TripEvents    store.TripEventStore
+	Webhooks      store.WebhookStore
+	Proposals     store.ProposalStore
+	Preferences   store.PreferenceStore
+}
+
+// TripHarness adapts one backend to RunTripStores.
+type TripHarness struct {
+	// New returns the stores for a subtest. Stores may be shared: the suite uses fresh IDs.
+	New func(t *testing.T) Stores
+}
+
+// RunTripStores runs the conformance suite for the stores behind flight watches, trip events,
+// webhooks, proposals and preferences against h.
+func RunTripStores(t *testing.T, h TripHarness) {
+	t.Run("flight watches", func(t *testing.T) { testFlightWatches(t, h) })
+	t.Run("trip events", func(t *testing.T) { testTripEvents(t, h) })
+	t.Run("webhooks", func(t *testing.T) { testWebhooks(t, h) })
+	t.Run("proposals", func(t *testing.T) { testProposals(t, h) })
+	t.Run("preferences", func(t *testing.T) { testPreferences(t, h) })
+}
+
+func testFlightWatches(t *testing.T, h TripHarness) {
+	ctx := context.Background()
+	s := h.New(t)
+	trip := seed(t, s.AI, "alice")
+	watches := s.FlightWatches
+
+	w, err := watches.CreateFlightWatch(ctx, trip.ID, "alice", "BA117", "2026-05-01")
+	if err != nil {
+		t.Fatalf("create watch: %v", err)
+	}
+	if !w.Active || w.FlightNumber != "BA117" || w.NextPollAt.IsZero() {
+		t.Fatalf("unexpected watch %+v", w)
+	}
+	if again, err := watches.CreateFlightWatch(ctx, trip.ID, "alice", "BA117", "2026-05-01"); err != nil || again.ID != w.ID {
+		t.Fatalf("expected the same watch to be reactivated, got %+v (%v)", again, err)
+	}
+	if _, err := watches.CreateFlightWatch(ctx, trip.ID, "alice", "BA119", "2026-05-08"); err != nil {
+		t.Fatalf("create second watch: %v", err)
+	}
+	if list, err := watches.ListFlightWatches(ctx, trip.ID); err != nil || len(list) != 2 {
+		t.Fatalf("expected two watches, got %+v (%v)", list, err)
+	}
+
+	next := time.Now().Add(time.Hour)
+	if err := watches.RecordFlightWatchPoll(ctx, w.ID, map[string]any{"status": "scheduled"}, next, true); err != nil {
+		t.Fatalf("record poll: %v", err)
+	}
+	if find(t, watches, time.Now(), w.ID) != nil {
+		t.Fatalf("expected the polled watch not to be due yet")
+	}
+	due := find(t, watches, next.Add(time.Second), w.ID)
+	if due == nil || due.LastStatusJSON["status"] != "scheduled" || due.LastPolledAt == nil {
+		t.Fatalf("unexpected due watch %+v", due)
+	}
+	if err := watches.RecordFlightWatchPoll(ctx, w.ID, nil, next, false); err != nil {
+		t.Fatalf("record poll: %v", err)
+	}
+	if find(t, watches, next.Add(time.Second), w.ID) != nil {
+		t.Fatalf("expected an inactive watch not to be due")
+	}
+	list, _ := watches.ListFlightWatches(ctx, trip.ID)
+	for _, got := range list {
+		if got.ID == w.ID && (got.Active || got.LastStatusJSON["status"] != "scheduled") {
+			t.Fatalf("expected a nil status to keep the last one, got %+v", got)
+		}
+	}
+}
+
+// find returns the watch with id among those due at now, or nil. Other tests' watches may be
+// due too when the store is shared.
+func find(t *testing.T, watches store.FlightWatchStore, now time.Time, id string) *store.FlightWatch {
+	t.Helper()
+	due, err := watches.ListDueFlightWatches(context.Background(), now, 1000)
+	if err != nil {
+		t.Fatalf("list due: %v", err)
+	}
+	for i := range due {
+		if due[i].ID == id {
+			return &due[i]
+		}
+	}
+	return nil
+}
+
+func testTripEvents(t *testing.T, h TripHarness) {
+	ctx := context.Background()
+	s := h.New(t)
+	trip := seed(t, s.AI, "alice")
+	other := seed(t, s.AI, "alice")
+
+	for _, typ := range []string{"first", "second", "third"} {
+		ev, err := s.TripEvents.InsertTripEvent(ctx, trip.ID, typ, map[string]any{"n": typ})
+		if err != nil {
+			t.Fatalf("insert event: %v", err)
+		}
+		if ev.ID == "" || ev.CreatedAt.IsZero() || ev.TripID != trip.ID {
+			t.Fatalf("unexpected event %+v", ev)
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+	_, _ = s.TripEvents.InsertTripEvent(ctx, other.ID, "elsewhere", nil)
+
+	got, err := s.TripEvents.ListTripEvents(ctx, trip.ID, 2)
+	if err != nil || len(got) != 2 || got[0].Type != "third" || got[1].Type != "second" || got[0].PayloadJSON["n"] != "third" {
+		t.Fatalf("expected the two newest events newest first, got %+v (%v)", got, err)
+	}
+}
+
+func testWebhooks(t *testing.T, h TripHarness) {
+	ctx := context.Background()
+	s := h.New(t)
+	trip := seed(t, s.AI, "alice")
+	hooks := s.Webhooks
+
+	sub, err := hooks.CreateWebhookSubscription(ctx, trip.ID, "alice", "https://example.com/hook", "whsec_x", []string{"expense_added"})
+	if err != nil {
+		t.Fatalf("create subscription: %v", err)
+	}
+	if list, _ := hooks.ListWebhookSubscriptions(ctx, trip.ID); len(list) != 1 || list[0].ID != sub.ID || list[0].EventTypes[0] != "expense_added" {
+		t.Fatalf("unexpected subscriptions %+v", list)
+	}
+	if subs, _ := hooks.ListWebhookSubscriptionsForEvent(ctx, trip.ID, "itinerary_updated"); len(subs) != 0 {
+		t.Fatalf("expected no subscribers for other events, got %+v", subs)
+	}
+	if subs, _ := hooks.ListWebhookSubscriptionsForEvent(ctx, trip.ID, "expense_added"); len(subs) != 1 || subs[0].Secret != "whsec_x" {
+		t.Fatalf("unexpected subscribers %+v", subs)
+	}
+
+	ev, err := s.TripEvents.InsertTripEvent(ctx, trip.ID, "expense_added", map[string]any{"amount": 3.0})
+	if err != nil {
+		t.Fatalf("insert event: %v", err)
+	}
+	ignored, _ := s.TripEvents.InsertTripEvent(ctx, trip.ID, "itinerary_updated", nil)
+	var queued, unwanted *store.WebhookOutbox
+	due, _ := hooks.ClaimDueWebhooks(ctx, time.Now().Add(time.Second), time.Minute, 1000)
+	for i := range due {
+		switch due[i].EventID {
+		case ev.ID:
+			queued = &due[i]
+		case ignored.ID:
+			unwanted = &due[i]
+		}
+	}
+	if queued == nil || queued.SubscriptionID != sub.ID || queued.PayloadJSON["id"] != ev.ID || unwanted != nil {
+		t.Fatalf("expected inserting an event to queue it for its subscribers only, got %+v and %+v", queued, unwanted)
+	}
+	if data, _ := queued.PayloadJSON["data"].(map[string]any); data["amount"] != 3.0 {
+		t.Fatalf("expected the outbox row to carry the envelope, got %+v", queued.PayloadJSON)
+	}
+
+	o, err := hooks.EnqueueWebhook(ctx, sub.ID, trip.ID, "ev-1", "expense_added", map[string]any{"amount": 12.5})
+	if err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	now := time.Now().Add(time.Second)
+	claimed := claim(t, hooks, now, o.ID)
+	if claimed == nil || claimed.PayloadJSON["amount"] != 12.5 || claimed.EventID != "ev-1" {
+		t.Fatalf("unexpected claim %+v", claimed)
+	}
+	if claim(t, hooks, now, o.ID) != nil {
+		t.Fatalf("expected the lease to hide the claimed row")
+	}
+
+	code := 500
+	retryAt := now.Add(time.Minute)
+	if _, err := hooks.RecordWebhookAttempt(ctx, o.ID, store.WebhookDelivery{SubscriptionID: sub.ID, EventType: "expense_added", Attempt: 1, StatusCode: &code, Error: "HTTP 500"}, store.WebhookPending, retryAt); err != nil {
+		t.Fatalf("record failed attempt: %v", err)
+	}
+	if got, _ := hooks.GetWebhookOutbox(ctx, o.ID); got.Status != store.WebhookPending || got.Attempts != 1 || got.LastError != "HTTP 500" {
+		t.Fatalf("unexpected outbox row after a failure %+v", got)
+	}
+	if claim(t, hooks, retryAt.Add(time.Second), o.ID) == nil {
+		t.Fatalf("expected the retry to be claimable once due")
+	}
+	code = 200
+	d, err := hooks.RecordWebhookAttempt(ctx, o.ID, store.WebhookDelivery{SubscriptionID: sub.ID, EventType: "expense_added", Attempt: 2, StatusCode: &code}, store.WebhookDelivered, now)
+	if err != nil {
+		t.Fatalf("record attempt: %v", err)
+	}
+	if got, err := hooks.GetWebhookDelivery(ctx, sub.ID, d.ID); err != nil || got.StatusCode == nil || *got.StatusCode != 200 || got.Attempt != 2 {
+		t.Fatalf("unexpected delivery %+v (%v)", got, err)
+	}
+	if got, _ := hooks.GetWebhookOutbox(ctx, o.ID); got.Status != store.WebhookDelivered || got.DeliveredAt == nil || got.LastError != "" {
+		t.Fatalf("unexpected outbox row %+v", got)
+	}
+	if list, _ := hooks.ListWebhookDeliveries(ctx, sub.ID, 10); len(list) != 2 || list[0].ID != d.ID {
+		t.Fatalf("expected both attempts newest first, got %+v", list)
+	}
+	if _, err := hooks.RecordWebhookAttempt(ctx, uuid.NewString(), store.WebhookDelivery{SubscriptionID: sub.ID}, store.WebhookFailed, now); !errors.Is(err, store.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for an unknown outbox row, got %v", err)
+	}
+
+	if err := hooks.DeleteWebhookSubscription(ctx, uuid.NewString(), sub.ID); !errors.Is(err, store.ErrNotFound) {
+		t.Fatalf("expected deleting through another trip to fail, got %v", err)
+	}
+	if err := hooks.DeleteWebhookSubscription(ctx, trip.ID, sub.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := hooks.GetWebhookSubscription(ctx, sub.ID); !errors.Is(err, store.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound after delete, got %v", err)
+	}
+	if _, err := hooks.GetWebhookOutbox(ctx, o.ID); !errors.Is(err, store.ErrNotFound) {
+		t.Fatalf("expected the outbox row to be deleted, got %v", err)
+	}
+	if list, _ := hooks.ListWebhookDeliveries(ctx, sub.ID, 10); len(list) != 0 {
+		t.Fatalf("expected the delivery log to be deleted, got %+v", list)
+	}
+}
+
+// claim returns the outbox row with id if ClaimDueWebhooks hands it out at now, or nil.
+func claim(t *testing.T, hooks store.WebhookStore, now time.Time, id string) *store.WebhookOutbox {
+	t.Helper()
+	claimed, err := hooks.ClaimDueWebhooks(context.Background(), now, time.Minute, 1000)
+	if err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+	for i := range claimed {
+		if claimed[i].ID == id {
+			return &claimed[i]
+		}
+	}
+	return nil
+}
+
+func testProposals(t *testing.T, h TripHarness) {
+	ctx := context.Background()
+	s := h.New(t)
+	tripID := seed(t, s.AI, "alice", "bob").ID
+	repo := s.Proposals
+
+	propose := func(kind string, change store.ProposalChange) *store.Proposal {
+		t.Helper()
+		p, err := repo.CreateProposal(ctx, store.Proposal{TripID: tripID, UserID: "alice", Kind: kind, Label: kind, Change: change,
+			Preview: store.ProposalPreview{Table: "test", After: map[string]any{"title": change.Title}}})
+		if err != nil {
+			t.Fatalf("create proposal: %v", err)
+		}
+		time.Sleep(2 * time.Millisecond)
+		return p
+	}
+
+	add := propose(store.ProposalAddItineraryItem, store.ProposalChange{DayIndex: 2, TimeBlock: "morning", Category: "activities", Title: "Fushimi Inari"})
+	if got, err := repo.GetProposal(ctx, add.ID); err != nil || got.Status != store.ProposalPending || got.Change.Title != "Fushimi Inari" || got.Preview.After["title"] != "Fushimi Inari" {
+		t.Fatalf("unexpected stored proposal %+v (%v)", got, err)
+	}
+	approved, err := repo.ApproveProposal(ctx, add.ID, "bob")
+	if err != nil || approved.Status != store.ProposalApproved || approved.DecidedBy != "bob" || approved.DecidedAt == nil || approved.ResultID == "" {
+		t.Fatalf("unexpected approval %+v (%v)", approved, err)
+	}
+	if _, err := repo.ApproveProposal(ctx, add.ID, "bob"); !errors.Is(err, store.ErrProposalDecided) {
+		t.Fatalf("expected a second approval to fail, got %v", err)
+	}
+	items, _ := repo.ListItineraryItems(ctx, tripID)
+	if len(items) != 1 || items[0].ID != approved.ResultID || items[0].DayIndex != 2 || items[0].Status != "planned" {
+		t.Fatalf("unexpected items %+v", items)
+	}
+
+	move := propose(store.ProposalMoveItineraryItem, store.ProposalChange{ItemID: items[0].ID, FromDayIndex: 2, FromTimeBlock: "morning", DayIndex: 3, TimeBlock: "evening"})
+	stale := propose(store.ProposalMoveItineraryItem, store.ProposalChange{ItemID: items[0].ID, FromDayIndex: 2, FromTimeBlock: "morning", DayIndex: 1, TimeBlock: "afternoon"})
+	if _, err := repo.ApproveProposal(ctx, move.ID, "alice"); err != nil {
+		t.Fatalf("approve move: %v", err)
+	}
+	if _, err := repo.ApproveProposal(ctx, stale.ID, "alice"); !errors.Is(err, store.ErrProposalStale) {
+		t.Fatalf("expected the second move to be stale, got %v", err)
+	}
+	if got, _ := repo.GetProposal(ctx, stale.ID); got.Status != store.ProposalPending {
+		t.Fatalf("expected a failed approval to leave the proposal pending, got %+v", got)
+	}
+	items, _ = repo.ListItineraryItems(ctx, tripID)
+	if items[0].DayIndex != 3 || items[0].TimeBlock != "evening" {
+		t.Fatalf("expected the item to be moved, got %+v", items[0])
+	}
+	seeded, err := repo.AddItineraryItem(ctx, store.ItineraryItem{TripID: tripID, DayIndex: 1, TimeBlock: "afternoon", Category: "food", Title: "Nishiki Market"})
+	if err != nil || seeded.ID == "" || seeded.Status != "planned" {
+		t.Fatalf("unexpected seeded item %+v (%v)", seeded, err)
+	}
+	if items, _ = repo.ListItineraryItems(ctx, tripID); len(items) != 2 || items[0].ID != seeded.ID {
+		t.Fatalf("expected items ordered by day, got %+v", items)
+	}
+
+	flight := propose(store.ProposalSaveFlight, store.ProposalChange{FlightNumber: "JL1", Date: "2026-11-01", Source: "outbound"})
+	expense := propose(store.ProposalAddExpense, store.ProposalChange{Title: "Temple entry", Category: "activities", Amount: 12.5, Currency: "USD", Date: "2026-11-03"})
+	for _, p := range []*store.Proposal{flight, expense} {
+		if _, err := repo.ApproveProposal(ctx, p.ID, "alice"); err != nil {
+			t.Fatalf("approve %s: %v", p.Kind, err)
+		}
+	}
+	if flights, _ := repo.ListSavedFlights(ctx, tripID); len(flights) != 1 || flights[0].FlightNumber != "JL1" || flights[0].Source != "outbound" {
+		t.Fatalf("unexpected flights %+v", flights)
+	}
+	if expenses, _ := repo.ListExpenses(ctx, tripID); len(expenses) != 1 || expenses[0].Amount != 12.5 || expenses[0].Date != "2026-11-03" {
+		t.Fatalf("unexpected expenses %+v", expenses)
+	}
+
+	rejected, err := repo.RejectProposal(ctx, stale.ID, "bob")
+	if err != nil || rejected.Status != store.ProposalRejected || rejected.DecidedBy != "bob" || rejected.ResultID != "" {
+		t.Fatalf("unexpected rejection %+v (%v)", rejected, err)
+	}
+	if _, err := repo.RejectProposal(ctx, stale.ID, "bob"); !errors.Is(err, store.ErrProposalDecided) {
+		t.Fatalf("expected a second rejection to fail, got %v", err)
+	}
+	if _, err := repo.RejectProposal(ctx, uuid.NewString(), "bob"); !errors.Is(err, store.ErrNotFound) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+
+	pending := propose(store.ProposalAddExpense, store.ProposalChange{Title: "Tea", Category: "food", Amount: 4, Currency: "USD", Date: "2026-11-04"})
+	if got, _ := repo.ListProposals(ctx, tripID, store.ProposalPending, 10); len(got) != 1 || got[0].ID != pending.ID {
+		t.Fatalf("expected only the pending proposal, got %+v", got)
+	}
+	if got, _ := repo.ListProposals(ctx, tripID, "", 10); len(got) != 6 || got[0].ID != pending.ID {
+		t.Fatalf("expected every proposal newest first, got %d", len(got))
+	}
+	if got, _ := repo.ListProposals(ctx, tripID, "", 2); len(got) != 2 {
+		t.Fatalf("expected the limit to apply, got %d", len(got))
+	}
+}
+
+func testPreferences(t *testing.T, h TripHarness) {
+	ctx := context.Background()
+	repo := h.New(t).Preferences
+	alice, bob := "alice-"+uuid.NewString(), "bob-"+uuid.NewString()
+
+	empty, err := repo.GetPreferences(ctx, alice)
+	if err != nil || len(empty.Preferences) != 0 || empty.UpdatedAt != nil {
+		t.Fatalf("expected no preferences yet, got %+v (%v)", empty, err)
+	}
+	if _, err := repo.SavePreferences(ctx, alice, map[string]string{"seat": "aisle", "diet": "vegetarian"}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if got, _ := repo.GetPreferences(ctx, alice); got.Preferences["seat"] != "aisle" || got.UpdatedAt == nil {
+		t.Fatalf("unexpected saved preferences %+v", got)
+	}
+
+	suggest := func(userID, preference, value string) *store.PreferenceUpdate {
+		t.Helper()
+		u, err := repo.CreatePreferenceUpdate(ctx, store.PreferenceUpdate{UserID: userID, Preference: preference, Value: value})
+		if err != nil {
+			t.Fatalf("create update: %v", err)
+		}
+		time.Sleep(2 * time.Millisecond)
+		return u
+	}
+	budget := suggest(alice, "budget", "mid-range")
+	diet := suggest(alice, "diet", "")
+	seat := suggest(alice, "seat", "window")
+
+	u, prefs, err := repo.ApprovePreferenceUpdate(ctx, budget.ID)
+	if err != nil || u.Status != store.PreferenceUpdateApproved || u.DecidedAt == nil || prefs.Preferences["budget"] != "mid-range" || prefs.Preferences["seat"] != "aisle" {
+		t.Fatalf("unexpected approval %+v %+v (%v)", u, prefs, err)
+	}
+	if _, _, err := repo.ApprovePreferenceUpdate(ctx, budget.ID); !errors.Is(err, store.ErrPreferenceUpdateDecided) {
+		t.Fatalf("expected a second approval to fail, got %v", err)
+	}
+	if _, _, err := repo.ApprovePreferenceUpdate(ctx, diet.ID); err != nil {
+		t.Fatalf("approve removal: %v", err)
+	}
+	if _, err := repo.RejectPreferenceUpdate(ctx, seat.ID); err != nil {
+		t.Fatalf("reject: %v", err)
+	}
+	if _, err := repo.RejectPreferenceUpdate(ctx, seat.ID); !errors.Is(err, store.ErrPreferenceUpdateDecided) {
+		t.Fatalf("expected a second rejection to fail, got %v", err)
+	}
+	if _, err := repo.RejectPreferenceUpdate(ctx, uuid.NewString()); !errors.Is(err, store.ErrNotFound) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+	got, _ := repo.GetPreferences(ctx, alice)
+	if len(got.Preferences) != 2 || got.Preferences["seat"] != "aisle" || got.Preferences["budget"] != "mid-range" {
+		t.Fatalf("expected the approved updates only, got %+v", got.Preferences)
+	}
+
+	first := suggest(bob, "pace", "packed")
+	if _, prefs, err := repo.ApprovePreferenceUpdate(ctx, first.ID); err != nil || prefs.Preferences["pace"] != "packed" {
+		t.Fatalf("expected an update to create a user's first preferences, got %+v (%v)", prefs, err)
+	}
+
+	pending := suggest(alice, "pace", "relaxed")
+	_ = suggest(bob, "pace", "slow")
+	if list, _ := repo.ListPreferenceUpdates(ctx, alice, store.PreferenceUpdatePending, 10); len(list) != 1 || list[0].ID != pending.ID {
+		t.Fatalf("expected only alice's pending update, got %+v", list)
+	}
+	if list, _ := repo.ListPreferenceUpdates(ctx, alice, "", 10); len(list) != 4 || list[0].ID != pending.ID {
+		t.Fatalf("expected every update newest first, got %d", len(list))
+	}
+}
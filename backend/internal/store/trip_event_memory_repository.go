@@ -0,0 +1,44 @@
+package store
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MemoryTripEventRepository is the in-process TripEventStore used when no database is
+// configured. Outbox rows go to webhooks, which may be nil when nothing delivers them.
+type MemoryTripEventRepository struct {
+	mu       sync.RWMutex
+	events   []TripEvent
+	webhooks *MemoryWebhookRepository
+}
+
+func NewInMemoryTripEventRepository(webhooks *MemoryWebhookRepository) *MemoryTripEventRepository {
+	return &MemoryTripEventRepository{webhooks: webhooks}
+}
+
+func (r *MemoryTripEventRepository) InsertTripEvent(_ context.Context, tripID, eventType string, payload map[string]any) (*TripEvent, error) {
+	ev := TripEvent{ID: uuid.NewString(), TripID: tripID, Type: eventType, PayloadJSON: payload, CreatedAt: time.Now().UTC()}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, ev)
+	if r.webhooks != nil {
+		r.webhooks.enqueueEvent(ev)
+	}
+	return &ev, nil
+}
+
+func (r *MemoryTripEventRepository) ListTripEvents(_ context.Context, tripID string, limit int) ([]TripEvent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]TripEvent, 0)
+	for i := len(r.events) - 1; i >= 0 && len(out) < limit; i-- {
+		if r.events[i].TripID == tripID {
+			out = append(out, r.events[i])
+		}
+	}
+	return out, nil
+}
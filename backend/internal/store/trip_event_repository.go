@@ -0,0 +1,86 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// TripEventRepository is the Postgres implementation of TripEventStore.
+type TripEventRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewTripEventRepository(db *pgxpool.Pool) *TripEventRepository {
+	return &TripEventRepository{db: db}
+}
+
+func (r *TripEventRepository) InsertTripEvent(ctx context.Context, tripID, eventType string, payload map[string]any) (*TripEvent, error) {
+	ev := TripEvent{ID: uuid.NewString(), TripID: tripID, Type: eventType, PayloadJSON: payload, CreatedAt: time.Now().UTC()}
+	payloadJSON, _ := json.Marshal(payload)
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	const insertQ = `
+		INSERT INTO trip_events (id, trip_id, type, payload_json, created_at)
+		VALUES ($1, $2, $3, $4::jsonb, NOW())
+		RETURNING created_at`
+	if err := tx.QueryRow(ctx, insertQ, ev.ID, tripID, eventType, string(payloadJSON)).Scan(&ev.CreatedAt); err != nil {
+		return nil, err
+	}
+	rows, err := tx.Query(ctx, `SELECT id FROM webhook_subscriptions WHERE trip_id = $1 AND active AND $2 = ANY(event_types)`, tripID, eventType)
+	if err != nil {
+		return nil, err
+	}
+	subs, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, err
+	}
+	envelope, _ := json.Marshal(WebhookEnvelope(ev))
+	const enqueueQ = `
+		INSERT INTO webhook_outbox (id, subscription_id, trip_id, event_id, event_type, payload_json, status, attempts, next_attempt_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6::jsonb, 'pending', 0, NOW(), NOW())`
+	for _, subID := range subs {
+		if _, err := tx.Exec(ctx, enqueueQ, uuid.NewString(), subID, tripID, ev.ID, eventType, string(envelope)); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return &ev, nil
+}
+
+func (r *TripEventRepository) ListTripEvents(ctx context.Context, tripID string, limit int) ([]TripEvent, error) {
+	const q = `
+		SELECT id, trip_id, type, payload_json, created_at
+		FROM trip_events
+		WHERE trip_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2`
+	rows, err := r.db.Query(ctx, q, tripID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]TripEvent, 0)
+	for rows.Next() {
+		var ev TripEvent
+		var payloadBytes []byte
+		if err := rows.Scan(&ev.ID, &ev.TripID, &ev.Type, &payloadBytes, &ev.CreatedAt); err != nil {
+			return nil, err
+		}
+		if len(payloadBytes) > 0 {
+			_ = json.Unmarshal(payloadBytes, &ev.PayloadJSON)
+		}
+		out = append(out, ev)
+	}
+	return out, rows.Err()
+}
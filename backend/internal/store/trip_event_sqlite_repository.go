@@ -0,0 +1,106 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SQLiteTripEventRepository is the TripEventStore for self-hosted deployments.
+type SQLiteTripEventRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteTripEventRepository(db *sql.DB) *SQLiteTripEventRepository {
+	return &SQLiteTripEventRepository{db: db}
+}
+
+func (r *SQLiteTripEventRepository) InsertTripEvent(ctx context.Context, tripID, eventType string, payload map[string]any) (*TripEvent, error) {
+	ev := TripEvent{ID: uuid.NewString(), TripID: tripID, Type: eventType, PayloadJSON: payload, CreatedAt: time.Now().UTC()}
+	payloadJSON, _ := json.Marshal(payload)
+	now := sqliteTime(ev.CreatedAt)
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	const insertQ = `
+		INSERT INTO trip_events (id, trip_id, type, payload_json, created_at)
+		VALUES ($1, $2, $3, $4, $5)`
+	if _, err := tx.ExecContext(ctx, insertQ, ev.ID, tripID, eventType, string(payloadJSON), now); err != nil {
+		return nil, err
+	}
+	subs, err := sqliteSubscribers(ctx, tx, tripID, eventType)
+	if err != nil {
+		return nil, err
+	}
+	envelope, _ := json.Marshal(WebhookEnvelope(ev))
+	const enqueueQ = `
+		INSERT INTO webhook_outbox (id, subscription_id, trip_id, event_id, event_type, payload_json, status, attempts, next_attempt_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, 'pending', 0, $7, $7)`
+	for _, subID := range subs {
+		if _, err := tx.ExecContext(ctx, enqueueQ, uuid.NewString(), subID, tripID, ev.ID, eventType, string(envelope), now); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &ev, nil
+}
+
+// sqliteSubscribers returns the IDs of the active subscriptions on a trip that want eventType.
+// event_types is a JSON array, so the match happens in Go.
+func sqliteSubscribers(ctx context.Context, tx *sql.Tx, tripID, eventType string) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT id, event_types FROM webhook_subscriptions WHERE trip_id = $1 AND active`, tripID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []string
+	for rows.Next() {
+		var s WebhookSubscription
+		var types string
+		if err := rows.Scan(&s.ID, &types); err != nil {
+			return nil, err
+		}
+		_ = json.Unmarshal([]byte(types), &s.EventTypes)
+		if s.Subscribes(eventType) {
+			out = append(out, s.ID)
+		}
+	}
+	return out, rows.Err()
+}
+
+func (r *SQLiteTripEventRepository) ListTripEvents(ctx context.Context, tripID string, limit int) ([]TripEvent, error) {
+	const q = `
+		SELECT id, trip_id, type, payload_json, created_at
+		FROM trip_events
+		WHERE trip_id = $1
+		ORDER BY created_at DESC, rowid DESC
+		LIMIT $2`
+	rows, err := r.db.QueryContext(ctx, q, tripID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]TripEvent, 0)
+	for rows.Next() {
+		var ev TripEvent
+		var payload sql.NullString
+		var created string
+		if err := rows.Scan(&ev.ID, &ev.TripID, &ev.Type, &payload, &created); err != nil {
+			return nil, err
+		}
+		if payload.Valid {
+			_ = json.Unmarshal([]byte(payload.String), &ev.PayloadJSON)
+		}
+		ev.CreatedAt = parseSQLiteTime(created)
+		out = append(out, ev)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,30 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+type TripEvent struct {
+	ID          string         `json:"id"`
+	TripID      string         `json:"tripId"`
+	Type        string         `json:"type"`
+	PayloadJSON map[string]any `json:"payload"`
+	CreatedAt   time.Time      `json:"createdAt"`
+}
+
+// TripEventStore is the append-only log of trip changes. TripEventRepository (Postgres),
+// SQLiteTripEventRepository and MemoryTripEventRepository implement it.
+type TripEventStore interface {
+	// InsertTripEvent records an event and, in the same transaction, queues a webhook outbox
+	// row carrying WebhookEnvelope for every active subscription on the trip that wants it.
+	InsertTripEvent(ctx context.Context, tripID, eventType string, payload map[string]any) (*TripEvent, error)
+	// ListTripEvents returns the newest events for a trip first.
+	ListTripEvents(ctx context.Context, tripID string, limit int) ([]TripEvent, error)
+}
+
+var (
+	_ TripEventStore = (*TripEventRepository)(nil)
+	_ TripEventStore = (*SQLiteTripEventRepository)(nil)
+	_ TripEventStore = (*MemoryTripEventRepository)(nil)
+)
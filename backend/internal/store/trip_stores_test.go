@@ -0,0 +1,68 @@
+package store_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"triploom/backend/internal/store"
+	"triploom/backend/internal/store/storetest"
+)
+
+func TestMemoryTripStoresConformance(t *testing.T) {
+	storetest.RunTripStores(t, storetest.TripHarness{
+		New: func(*testing.T) storetest.Stores {
+			webhooks := store.NewInMemoryWebhookRepository()
+			return storetest.Stores{
+				AI:            store.NewInMemoryAIRepository(),
+				FlightWatches: store.NewInMemoryFlightWatchRepository(),
+				TripEvents:    store.NewInMemoryTripEventRepository(webhooks),
+				Webhooks:      webhooks,
+				Proposals:     store.NewInMemoryProposalRepository(),
+				Preferences:   store.NewInMemoryPreferenceRepository(),
+			}
+		},
+	})
+}
+
+func TestSQLiteTripStoresConformance(t *testing.T) {
+	storetest.RunTripStores(t, storetest.TripHarness{
+		New: func(t *testing.T) storetest.Stores {
+			db := openSQLite(t)
+			return storetest.Stores{
+				AI:            store.NewSQLiteAIRepository(db),
+				FlightWatches: store.NewSQLiteFlightWatchRepository(db),
+				TripEvents:    store.NewSQLiteTripEventRepository(db),
+				Webhooks:      store.NewSQLiteWebhookRepository(db),
+				Proposals:     store.NewSQLiteProposalRepository(db),
+				Preferences:   store.NewSQLitePreferenceRepository(db),
+			}
+		},
+	})
+}
+
+// TestPostgresTripStoresConformance runs against a migrated database named by
+// TEST_DATABASE_URL and is skipped without one.
+func TestPostgresTripStoresConformance(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	db, err := store.NewPostgres(context.Background(), dsn)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(db.Close)
+
+	stores := storetest.Stores{
+		AI:            store.NewAIRepository(db),
+		FlightWatches: store.NewFlightWatchRepository(db),
+		TripEvents:    store.NewTripEventRepository(db),
+		Webhooks:      store.NewWebhookRepository(db),
+		Proposals:     store.NewProposalRepository(db),
+		Preferences:   store.NewPreferenceRepository(db),
+	}
+	storetest.RunTripStores(t, storetest.TripHarness{
+		New: func(*testing.T) storetest.Stores { return stores },
+	})
+}
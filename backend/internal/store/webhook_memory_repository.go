@@ -0,0 +1,220 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MemoryWebhookRepository is the in-process WebhookStore used when no database is configured.
+type MemoryWebhookRepository struct {
+	mu            sync.RWMutex
+	subscriptions map[string]WebhookSubscription
+	outbox        map[string]WebhookOutbox
+	deliveries    []WebhookDelivery
+}
+
+func NewInMemoryWebhookRepository() *MemoryWebhookRepository {
+	return &MemoryWebhookRepository{
+		subscriptions: make(map[string]WebhookSubscription),
+		outbox:        make(map[string]WebhookOutbox),
+	}
+}
+
+func (r *MemoryWebhookRepository) CreateWebhookSubscription(_ context.Context, tripID, userID, url, secret string, eventTypes []string) (*WebhookSubscription, error) {
+	sub := WebhookSubscription{
+		ID:         uuid.NewString(),
+		TripID:     tripID,
+		UserID:     userID,
+		URL:        url,
+		Secret:     secret,
+		EventTypes: eventTypes,
+		Active:     true,
+		CreatedAt:  time.Now().UTC(),
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.subscriptions[sub.ID] = sub
+	return &sub, nil
+}
+
+func (r *MemoryWebhookRepository) ListWebhookSubscriptions(_ context.Context, tripID string) ([]WebhookSubscription, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]WebhookSubscription, 0)
+	for _, s := range r.subscriptions {
+		if s.TripID == tripID {
+			out = append(out, s)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	return out, nil
+}
+
+func (r *MemoryWebhookRepository) ListWebhookSubscriptionsForEvent(_ context.Context, tripID, eventType string) ([]WebhookSubscription, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]WebhookSubscription, 0)
+	for _, s := range r.subscriptions {
+		if s.TripID == tripID && s.Active && s.Subscribes(eventType) {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+func (r *MemoryWebhookRepository) GetWebhookSubscription(_ context.Context, id string) (*WebhookSubscription, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	s, ok := r.subscriptions[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &s, nil
+}
+
+func (r *MemoryWebhookRepository) DeleteWebhookSubscription(_ context.Context, tripID, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, ok := r.subscriptions[id]
+	if !ok || s.TripID != tripID {
+		return ErrNotFound
+	}
+	delete(r.subscriptions, id)
+	for oid, o := range r.outbox {
+		if o.SubscriptionID == id {
+			delete(r.outbox, oid)
+		}
+	}
+	kept := r.deliveries[:0]
+	for _, d := range r.deliveries {
+		if d.SubscriptionID != id {
+			kept = append(kept, d)
+		}
+	}
+	r.deliveries = kept
+	return nil
+}
+
+func (r *MemoryWebhookRepository) EnqueueWebhook(_ context.Context, subscriptionID, tripID, eventID, eventType string, payload map[string]any) (*WebhookOutbox, error) {
+	now := time.Now().UTC()
+	o := WebhookOutbox{
+		ID:             uuid.NewString(),
+		SubscriptionID: subscriptionID,
+		TripID:         tripID,
+		EventID:        eventID,
+		EventType:      eventType,
+		PayloadJSON:    payload,
+		Status:         WebhookPending,
+		NextAttemptAt:  now,
+		CreatedAt:      now,
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.outbox[o.ID] = o
+	return &o, nil
+}
+
+// enqueueEvent queues ev for every matching subscription under one lock, which is the memory
+// store's stand-in for InsertTripEvent's transaction.
+func (r *MemoryWebhookRepository) enqueueEvent(ev TripEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	envelope := WebhookEnvelope(ev)
+	for _, s := range r.subscriptions {
+		if s.TripID != ev.TripID || !s.Active || !s.Subscribes(ev.Type) {
+			continue
+		}
+		o := WebhookOutbox{
+			ID:             uuid.NewString(),
+			SubscriptionID: s.ID,
+			TripID:         ev.TripID,
+			EventID:        ev.ID,
+			EventType:      ev.Type,
+			PayloadJSON:    envelope,
+			Status:         WebhookPending,
+			NextAttemptAt:  ev.CreatedAt,
+			CreatedAt:      ev.CreatedAt,
+		}
+		r.outbox[o.ID] = o
+	}
+}
+
+func (r *MemoryWebhookRepository) GetWebhookOutbox(_ context.Context, id string) (*WebhookOutbox, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	o, ok := r.outbox[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &o, nil
+}
+
+func (r *MemoryWebhookRepository) ClaimDueWebhooks(_ context.Context, now time.Time, lease time.Duration, limit int) ([]WebhookOutbox, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]WebhookOutbox, 0)
+	for _, o := range r.outbox {
+		if o.Status == WebhookPending && !o.NextAttemptAt.After(now) {
+			out = append(out, o)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].NextAttemptAt.Before(out[j].NextAttemptAt) })
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	for _, o := range out {
+		o.NextAttemptAt = now.Add(lease)
+		r.outbox[o.ID] = o
+	}
+	return out, nil
+}
+
+func (r *MemoryWebhookRepository) RecordWebhookAttempt(_ context.Context, outboxID string, d WebhookDelivery, status string, nextAttemptAt time.Time) (*WebhookDelivery, error) {
+	now := time.Now().UTC()
+	d.ID = uuid.NewString()
+	d.OutboxID = outboxID
+	d.CreatedAt = now
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	o, ok := r.outbox[outboxID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	o.Attempts = d.Attempt
+	o.Status = status
+	o.NextAttemptAt = nextAttemptAt
+	o.LastError = d.Error
+	if status == WebhookDelivered {
+		o.DeliveredAt = &now
+	}
+	r.outbox[outboxID] = o
+	r.deliveries = append(r.deliveries, d)
+	return &d, nil
+}
+
+func (r *MemoryWebhookRepository) ListWebhookDeliveries(_ context.Context, subscriptionID string, limit int) ([]WebhookDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]WebhookDelivery, 0)
+	for i := len(r.deliveries) - 1; i >= 0 && len(out) < limit; i-- {
+		if r.deliveries[i].SubscriptionID == subscriptionID {
+			out = append(out, r.deliveries[i])
+		}
+	}
+	return out, nil
+}
+
+func (r *MemoryWebhookRepository) GetWebhookDelivery(_ context.Context, subscriptionID, id string) (*WebhookDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, d := range r.deliveries {
+		if d.ID == id && d.SubscriptionID == subscriptionID {
+			return &d, nil
+		}
+	}
+	return nil, ErrNotFound
+}
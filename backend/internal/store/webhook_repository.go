@@ -0,0 +1,237 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// WebhookRepository is the Postgres implementation of WebhookStore.
+type WebhookRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewWebhookRepository(db *pgxpool.Pool) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+func (r *WebhookRepository) CreateWebhookSubscription(ctx context.Context, tripID, userID, url, secret string, eventTypes []string) (*WebhookSubscription, error) {
+	sub := WebhookSubscription{
+		ID:         uuid.NewString(),
+		TripID:     tripID,
+		UserID:     userID,
+		URL:        url,
+		Secret:     secret,
+		EventTypes: eventTypes,
+		Active:     true,
+		CreatedAt:  time.Now().UTC(),
+	}
+	const q = `
+		INSERT INTO webhook_subscriptions (id, trip_id, user_id, url, secret, event_types, active, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, TRUE, NOW())
+		RETURNING created_at`
+	if err := r.db.QueryRow(ctx, q, sub.ID, tripID, userID, url, secret, eventTypes).Scan(&sub.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+func (r *WebhookRepository) ListWebhookSubscriptions(ctx context.Context, tripID string) ([]WebhookSubscription, error) {
+	q := `SELECT ` + webhookSubscriptionColumns + ` FROM webhook_subscriptions WHERE trip_id = $1 ORDER BY created_at DESC`
+	return r.queryWebhookSubscriptions(ctx, q, tripID)
+}
+
+func (r *WebhookRepository) ListWebhookSubscriptionsForEvent(ctx context.Context, tripID, eventType string) ([]WebhookSubscription, error) {
+	q := `SELECT ` + webhookSubscriptionColumns + ` FROM webhook_subscriptions WHERE trip_id = $1 AND active AND $2 = ANY(event_types)`
+	return r.queryWebhookSubscriptions(ctx, q, tripID, eventType)
+}
+
+func (r *WebhookRepository) GetWebhookSubscription(ctx context.Context, id string) (*WebhookSubscription, error) {
+	q := `SELECT ` + webhookSubscriptionColumns + ` FROM webhook_subscriptions WHERE id = $1`
+	return scanWebhookSubscription(r.db.QueryRow(ctx, q, id))
+}
+
+func (r *WebhookRepository) DeleteWebhookSubscription(ctx context.Context, tripID, id string) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM webhook_subscriptions WHERE id = $1 AND trip_id = $2`, id, tripID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *WebhookRepository) EnqueueWebhook(ctx context.Context, subscriptionID, tripID, eventID, eventType string, payload map[string]any) (*WebhookOutbox, error) {
+	now := time.Now().UTC()
+	o := WebhookOutbox{
+		ID:             uuid.NewString(),
+		SubscriptionID: subscriptionID,
+		TripID:         tripID,
+		EventID:        eventID,
+		EventType:      eventType,
+		PayloadJSON:    payload,
+		Status:         WebhookPending,
+		NextAttemptAt:  now,
+		CreatedAt:      now,
+	}
+	payloadJSON, _ := json.Marshal(payload)
+	const q = `
+		INSERT INTO webhook_outbox (id, subscription_id, trip_id, event_id, event_type, payload_json, status, attempts, next_attempt_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6::jsonb, 'pending', 0, NOW(), NOW())
+		RETURNING next_attempt_at, created_at`
+	if err := r.db.QueryRow(ctx, q, o.ID, subscriptionID, tripID, eventID, eventType, string(payloadJSON)).Scan(&o.NextAttemptAt, &o.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+func (r *WebhookRepository) GetWebhookOutbox(ctx context.Context, id string) (*WebhookOutbox, error) {
+	q := `SELECT ` + webhookOutboxColumns + ` FROM webhook_outbox WHERE id = $1`
+	return scanWebhookOutbox(r.db.QueryRow(ctx, q, id))
+}
+
+func (r *WebhookRepository) ClaimDueWebhooks(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]WebhookOutbox, error) {
+	q := `
+		UPDATE webhook_outbox SET next_attempt_at = $2
+		WHERE id IN (
+			SELECT id FROM webhook_outbox
+			WHERE status = 'pending' AND next_attempt_at <= $1
+			ORDER BY next_attempt_at ASC
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + webhookOutboxColumns
+	rows, err := r.db.Query(ctx, q, now, now.Add(lease), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]WebhookOutbox, 0)
+	for rows.Next() {
+		o, err := scanWebhookOutbox(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *o)
+	}
+	return out, rows.Err()
+}
+
+func (r *WebhookRepository) RecordWebhookAttempt(ctx context.Context, outboxID string, d WebhookDelivery, status string, nextAttemptAt time.Time) (*WebhookDelivery, error) {
+	now := time.Now().UTC()
+	d.ID = uuid.NewString()
+	d.OutboxID = outboxID
+	d.CreatedAt = now
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	const updateQ = `
+		UPDATE webhook_outbox
+		SET attempts = $2, status = $3, next_attempt_at = $4, last_error = NULLIF($5, ''),
+		    delivered_at = CASE WHEN $3 = 'delivered' THEN NOW() ELSE delivered_at END
+		WHERE id = $1`
+	tag, err := tx.Exec(ctx, updateQ, outboxID, d.Attempt, status, nextAttemptAt, d.Error)
+	if err != nil {
+		return nil, err
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, ErrNotFound
+	}
+	const insertQ = `
+		INSERT INTO webhook_deliveries (id, outbox_id, subscription_id, event_type, attempt, status_code, error, duration_ms, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8, NOW())
+		RETURNING created_at`
+	if err := tx.QueryRow(ctx, insertQ, d.ID, outboxID, d.SubscriptionID, d.EventType, d.Attempt, d.StatusCode, d.Error, d.DurationMS).Scan(&d.CreatedAt); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (r *WebhookRepository) ListWebhookDeliveries(ctx context.Context, subscriptionID string, limit int) ([]WebhookDelivery, error) {
+	q := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries WHERE subscription_id = $1 ORDER BY created_at DESC LIMIT $2`
+	rows, err := r.db.Query(ctx, q, subscriptionID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]WebhookDelivery, 0)
+	for rows.Next() {
+		d, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *d)
+	}
+	return out, rows.Err()
+}
+
+func (r *WebhookRepository) GetWebhookDelivery(ctx context.Context, subscriptionID, id string) (*WebhookDelivery, error) {
+	q := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries WHERE id = $1 AND subscription_id = $2`
+	return scanWebhookDelivery(r.db.QueryRow(ctx, q, id, subscriptionID))
+}
+
+func (r *WebhookRepository) queryWebhookSubscriptions(ctx context.Context, q string, args ...any) ([]WebhookSubscription, error) {
+	rows, err := r.db.Query(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]WebhookSubscription, 0)
+	for rows.Next() {
+		s, err := scanWebhookSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *s)
+	}
+	return out, rows.Err()
+}
+
+func scanWebhookSubscription(row pgx.Row) (*WebhookSubscription, error) {
+	var s WebhookSubscription
+	if err := row.Scan(&s.ID, &s.TripID, &s.UserID, &s.URL, &s.Secret, &s.EventTypes, &s.Active, &s.CreatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &s, nil
+}
+
+func scanWebhookOutbox(row pgx.Row) (*WebhookOutbox, error) {
+	var o WebhookOutbox
+	var payloadBytes []byte
+	if err := row.Scan(&o.ID, &o.SubscriptionID, &o.TripID, &o.EventID, &o.EventType, &payloadBytes, &o.Status, &o.Attempts, &o.NextAttemptAt, &o.LastError, &o.CreatedAt, &o.DeliveredAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if len(payloadBytes) > 0 {
+		_ = json.Unmarshal(payloadBytes, &o.PayloadJSON)
+	}
+	return &o, nil
+}
+
+func scanWebhookDelivery(row pgx.Row) (*WebhookDelivery, error) {
+	var d WebhookDelivery
+	if err := row.Scan(&d.ID, &d.OutboxID, &d.SubscriptionID, &d.EventType, &d.Attempt, &d.StatusCode, &d.Error, &d.DurationMS, &d.CreatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &d, nil
+}
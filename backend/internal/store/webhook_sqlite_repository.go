@@ -0,0 +1,258 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SQLiteWebhookRepository is the WebhookStore for self-hosted deployments. SQLite has no
+// arrays, so event_types holds a JSON array and is filtered in Go.
+type SQLiteWebhookRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteWebhookRepository(db *sql.DB) *SQLiteWebhookRepository {
+	return &SQLiteWebhookRepository{db: db}
+}
+
+func (r *SQLiteWebhookRepository) CreateWebhookSubscription(ctx context.Context, tripID, userID, url, secret string, eventTypes []string) (*WebhookSubscription, error) {
+	sub := WebhookSubscription{
+		ID:         uuid.NewString(),
+		TripID:     tripID,
+		UserID:     userID,
+		URL:        url,
+		Secret:     secret,
+		EventTypes: eventTypes,
+		Active:     true,
+		CreatedAt:  time.Now().UTC(),
+	}
+	typesJSON, _ := json.Marshal(eventTypes)
+	const q = `
+		INSERT INTO webhook_subscriptions (id, trip_id, user_id, url, secret, event_types, active, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, TRUE, $7)`
+	if _, err := r.db.ExecContext(ctx, q, sub.ID, tripID, userID, url, secret, string(typesJSON), sqliteTime(sub.CreatedAt)); err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+func (r *SQLiteWebhookRepository) ListWebhookSubscriptions(ctx context.Context, tripID string) ([]WebhookSubscription, error) {
+	q := `SELECT ` + webhookSubscriptionColumns + ` FROM webhook_subscriptions WHERE trip_id = $1 ORDER BY created_at DESC, rowid DESC`
+	return r.queryWebhookSubscriptions(ctx, q, tripID)
+}
+
+func (r *SQLiteWebhookRepository) ListWebhookSubscriptionsForEvent(ctx context.Context, tripID, eventType string) ([]WebhookSubscription, error) {
+	q := `SELECT ` + webhookSubscriptionColumns + ` FROM webhook_subscriptions WHERE trip_id = $1 AND active`
+	subs, err := r.queryWebhookSubscriptions(ctx, q, tripID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]WebhookSubscription, 0, len(subs))
+	for _, s := range subs {
+		if s.Subscribes(eventType) {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+func (r *SQLiteWebhookRepository) GetWebhookSubscription(ctx context.Context, id string) (*WebhookSubscription, error) {
+	q := `SELECT ` + webhookSubscriptionColumns + ` FROM webhook_subscriptions WHERE id = $1`
+	return scanSQLiteWebhookSubscription(r.db.QueryRowContext(ctx, q, id))
+}
+
+func (r *SQLiteWebhookRepository) DeleteWebhookSubscription(ctx context.Context, tripID, id string) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM webhook_subscriptions WHERE id = $1 AND trip_id = $2`, id, tripID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *SQLiteWebhookRepository) EnqueueWebhook(ctx context.Context, subscriptionID, tripID, eventID, eventType string, payload map[string]any) (*WebhookOutbox, error) {
+	now := time.Now().UTC()
+	o := WebhookOutbox{
+		ID:             uuid.NewString(),
+		SubscriptionID: subscriptionID,
+		TripID:         tripID,
+		EventID:        eventID,
+		EventType:      eventType,
+		PayloadJSON:    payload,
+		Status:         WebhookPending,
+		NextAttemptAt:  now,
+		CreatedAt:      now,
+	}
+	payloadJSON, _ := json.Marshal(payload)
+	const q = `
+		INSERT INTO webhook_outbox (id, subscription_id, trip_id, event_id, event_type, payload_json, status, attempts, next_attempt_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, 'pending', 0, $7, $7)`
+	if _, err := r.db.ExecContext(ctx, q, o.ID, subscriptionID, tripID, eventID, eventType, string(payloadJSON), sqliteTime(now)); err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+func (r *SQLiteWebhookRepository) GetWebhookOutbox(ctx context.Context, id string) (*WebhookOutbox, error) {
+	q := `SELECT ` + webhookOutboxColumns + ` FROM webhook_outbox WHERE id = $1`
+	return scanSQLiteWebhookOutbox(r.db.QueryRowContext(ctx, q, id))
+}
+
+// ClaimDueWebhooks relies on SQLite's single writer instead of SKIP LOCKED: the UPDATE
+// both selects and leases the rows atomically.
+func (r *SQLiteWebhookRepository) ClaimDueWebhooks(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]WebhookOutbox, error) {
+	q := `
+		UPDATE webhook_outbox SET next_attempt_at = $2
+		WHERE id IN (
+			SELECT id FROM webhook_outbox
+			WHERE status = 'pending' AND next_attempt_at <= $1
+			ORDER BY next_attempt_at ASC
+			LIMIT $3
+		)
+		RETURNING ` + webhookOutboxColumns
+	rows, err := r.db.QueryContext(ctx, q, sqliteTime(now), sqliteTime(now.Add(lease)), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]WebhookOutbox, 0)
+	for rows.Next() {
+		o, err := scanSQLiteWebhookOutbox(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *o)
+	}
+	return out, rows.Err()
+}
+
+func (r *SQLiteWebhookRepository) RecordWebhookAttempt(ctx context.Context, outboxID string, d WebhookDelivery, status string, nextAttemptAt time.Time) (*WebhookDelivery, error) {
+	d.ID = uuid.NewString()
+	d.OutboxID = outboxID
+	d.CreatedAt = time.Now().UTC()
+	now := sqliteTime(d.CreatedAt)
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	const updateQ = `
+		UPDATE webhook_outbox
+		SET attempts = $2, status = $3, next_attempt_at = $4, last_error = NULLIF($5, ''),
+		    delivered_at = CASE WHEN $3 = 'delivered' THEN $6 ELSE delivered_at END
+		WHERE id = $1`
+	res, err := tx.ExecContext(ctx, updateQ, outboxID, d.Attempt, status, sqliteTime(nextAttemptAt), d.Error, now)
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, ErrNotFound
+	}
+	const insertQ = `
+		INSERT INTO webhook_deliveries (id, outbox_id, subscription_id, event_type, attempt, status_code, error, duration_ms, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8, $9)`
+	if _, err := tx.ExecContext(ctx, insertQ, d.ID, outboxID, d.SubscriptionID, d.EventType, d.Attempt, d.StatusCode, d.Error, d.DurationMS, now); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (r *SQLiteWebhookRepository) ListWebhookDeliveries(ctx context.Context, subscriptionID string, limit int) ([]WebhookDelivery, error) {
+	q := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries WHERE subscription_id = $1 ORDER BY created_at DESC, rowid DESC LIMIT $2`
+	rows, err := r.db.QueryContext(ctx, q, subscriptionID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]WebhookDelivery, 0)
+	for rows.Next() {
+		d, err := scanSQLiteWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *d)
+	}
+	return out, rows.Err()
+}
+
+func (r *SQLiteWebhookRepository) GetWebhookDelivery(ctx context.Context, subscriptionID, id string) (*WebhookDelivery, error) {
+	q := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries WHERE id = $1 AND subscription_id = $2`
+	return scanSQLiteWebhookDelivery(r.db.QueryRowContext(ctx, q, id, subscriptionID))
+}
+
+func (r *SQLiteWebhookRepository) queryWebhookSubscriptions(ctx context.Context, q string, args ...any) ([]WebhookSubscription, error) {
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]WebhookSubscription, 0)
+	for rows.Next() {
+		s, err := scanSQLiteWebhookSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *s)
+	}
+	return out, rows.Err()
+}
+
+func scanSQLiteWebhookSubscription(row interface{ Scan(...any) error }) (*WebhookSubscription, error) {
+	var s WebhookSubscription
+	var types, created string
+	if err := row.Scan(&s.ID, &s.TripID, &s.UserID, &s.URL, &s.Secret, &types, &s.Active, &created); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	_ = json.Unmarshal([]byte(types), &s.EventTypes)
+	s.CreatedAt = parseSQLiteTime(created)
+	return &s, nil
+}
+
+func scanSQLiteWebhookOutbox(row interface{ Scan(...any) error }) (*WebhookOutbox, error) {
+	var o WebhookOutbox
+	var payload, next, created string
+	var delivered sql.NullString
+	if err := row.Scan(&o.ID, &o.SubscriptionID, &o.TripID, &o.EventID, &o.EventType, &payload, &o.Status, &o.Attempts, &next, &o.LastError, &created, &delivered); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	_ = json.Unmarshal([]byte(payload), &o.PayloadJSON)
+	o.NextAttemptAt, o.CreatedAt = parseSQLiteTime(next), parseSQLiteTime(created)
+	o.DeliveredAt = sqliteNullTime(delivered)
+	return &o, nil
+}
+
+func scanSQLiteWebhookDelivery(row interface{ Scan(...any) error }) (*WebhookDelivery, error) {
+	var d WebhookDelivery
+	var status sql.NullInt64
+	var created string
+	if err := row.Scan(&d.ID, &d.OutboxID, &d.SubscriptionID, &d.EventType, &d.Attempt, &status, &d.Error, &d.DurationMS, &created); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if status.Valid {
+		code := int(status.Int64)
+		d.StatusCode = &code
+	}
+	d.CreatedAt = parseSQLiteTime(created)
+	return &d, nil
+}
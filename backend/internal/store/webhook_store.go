@@ -0,0 +1,114 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// Outbox row states.
+const (
+	WebhookPending   = "pending"
+	WebhookDelivered = "delivered"
+	WebhookFailed    = "failed"
+)
+
+type WebhookSubscription struct {
+	ID         string    `json:"id"`
+	TripID     string    `json:"tripId"`
+	UserID     string    `json:"userId"`
+	URL        string    `json:"url"`
+	Secret     string    `json:"-"`
+	EventTypes []string  `json:"eventTypes"`
+	Active     bool      `json:"active"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// Subscribes reports whether the subscription wants events of the given type.
+func (s WebhookSubscription) Subscribes(eventType string) bool {
+	for _, t := range s.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookEnvelope is the JSON body POSTed to subscribers for ev.
+func WebhookEnvelope(ev TripEvent) map[string]any {
+	return map[string]any{
+		"id":        ev.ID,
+		"type":      ev.Type,
+		"tripId":    ev.TripID,
+		"createdAt": ev.CreatedAt.UTC().Format(time.RFC3339Nano),
+		"data":      ev.PayloadJSON,
+	}
+}
+
+type WebhookOutbox struct {
+	ID             string         `json:"id"`
+	SubscriptionID string         `json:"subscriptionId"`
+	TripID         string         `json:"tripId"`
+	EventID        string         `json:"eventId"`
+	EventType      string         `json:"eventType"`
+	PayloadJSON    map[string]any `json:"payload"`
+	Status         string         `json:"status"`
+	Attempts       int            `json:"attempts"`
+	NextAttemptAt  time.Time      `json:"nextAttemptAt"`
+	LastError      string         `json:"lastError,omitempty"`
+	CreatedAt      time.Time      `json:"createdAt"`
+	DeliveredAt    *time.Time     `json:"deliveredAt,omitempty"`
+}
+
+type WebhookDelivery struct {
+	ID             string    `json:"id"`
+	OutboxID       string    `json:"outboxId"`
+	SubscriptionID string    `json:"subscriptionId"`
+	EventType      string    `json:"eventType"`
+	Attempt        int       `json:"attempt"`
+	StatusCode     *int      `json:"statusCode,omitempty"`
+	Error          string    `json:"error,omitempty"`
+	DurationMS     int       `json:"durationMs"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
+// WebhookStore keeps webhook subscriptions, the outbox of events waiting to be delivered and
+// the log of delivery attempts. WebhookRepository (Postgres), SQLiteWebhookRepository and
+// MemoryWebhookRepository implement it.
+type WebhookStore interface {
+	CreateWebhookSubscription(ctx context.Context, tripID, userID, url, secret string, eventTypes []string) (*WebhookSubscription, error)
+	// ListWebhookSubscriptions returns a trip's subscriptions newest first.
+	ListWebhookSubscriptions(ctx context.Context, tripID string) ([]WebhookSubscription, error)
+	// ListWebhookSubscriptionsForEvent returns the active subscriptions on a trip that want
+	// eventType.
+	ListWebhookSubscriptionsForEvent(ctx context.Context, tripID, eventType string) ([]WebhookSubscription, error)
+	// GetWebhookSubscription returns ErrNotFound for unknown IDs.
+	GetWebhookSubscription(ctx context.Context, id string) (*WebhookSubscription, error)
+	// DeleteWebhookSubscription removes a subscription along with its pending outbox rows and
+	// delivery log.
+	DeleteWebhookSubscription(ctx context.Context, tripID, id string) error
+	// EnqueueWebhook adds an outbox row that the delivery worker will pick up immediately.
+	EnqueueWebhook(ctx context.Context, subscriptionID, tripID, eventID, eventType string, payload map[string]any) (*WebhookOutbox, error)
+	GetWebhookOutbox(ctx context.Context, id string) (*WebhookOutbox, error)
+	// ClaimDueWebhooks returns pending outbox rows that are due, oldest first, and pushes their
+	// next attempt out by lease so that a second worker (or a crashed one) does not send them
+	// twice.
+	ClaimDueWebhooks(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]WebhookOutbox, error)
+	// RecordWebhookAttempt logs one delivery attempt and moves the outbox row to status. For
+	// pending rows nextAttemptAt schedules the retry.
+	RecordWebhookAttempt(ctx context.Context, outboxID string, d WebhookDelivery, status string, nextAttemptAt time.Time) (*WebhookDelivery, error)
+	// ListWebhookDeliveries returns the newest attempts for a subscription first.
+	ListWebhookDeliveries(ctx context.Context, subscriptionID string, limit int) ([]WebhookDelivery, error)
+	GetWebhookDelivery(ctx context.Context, subscriptionID, id string) (*WebhookDelivery, error)
+}
+
+var (
+	_ WebhookStore = (*WebhookRepository)(nil)
+	_ WebhookStore = (*SQLiteWebhookRepository)(nil)
+	_ WebhookStore = (*MemoryWebhookRepository)(nil)
+)
+
+const (
+	webhookSubscriptionColumns = `id, trip_id, user_id, url, secret, event_types, active, created_at`
+	webhookOutboxColumns       = `id, subscription_id, trip_id, event_id, event_type, payload_json, status, attempts, next_attempt_at, COALESCE(last_error, ''), created_at, delivered_at`
+	webhookDeliveryColumns     = `id, outbox_id, subscription_id, event_type, attempt, status_code, COALESCE(error, ''), duration_ms, created_at`
+)
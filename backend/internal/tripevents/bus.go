@@ -0,0 +1,45 @@
+package tripevents
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"triploom/backend/internal/events"
+	"triploom/backend/internal/store"
+)
+
+// Topic is the bus topic recorded trip events are published on.
+const Topic = "trip_events"
+
+// BusSink publishes every recorded event on a bus so that sinks on other replicas (the live
+// hub) see it. Sinks that must run exactly once per event, like webhooks, stay on the Recorder.
+type BusSink struct {
+	bus events.Bus
+}
+
+func NewBusSink(bus events.Bus) *BusSink {
+	return &BusSink{bus: bus}
+}
+
+func (s *BusSink) HandleTripEvent(ctx context.Context, ev store.TripEvent) error {
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	return s.bus.Publish(ctx, Topic, payload)
+}
+
+// Forward hands every trip event arriving on the bus to sink until the returned func is called.
+func Forward(bus events.Bus, sink Sink) (stop func()) {
+	return bus.Subscribe(Topic, func(_ string, payload []byte) {
+		var ev store.TripEvent
+		if err := json.Unmarshal(payload, &ev); err != nil {
+			log.Printf("trip event from bus: %v", err)
+			return
+		}
+		if err := sink.HandleTripEvent(context.Background(), ev); err != nil {
+			log.Printf("trip event %s (%s) sink %T: %v", ev.ID, ev.Type, sink, err)
+		}
+	})
+}
@@ -0,0 +1,74 @@
+package tripevents
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"triploom/backend/internal/store"
+)
+
+// Event types that can be recorded on a trip and subscribed to.
+const (
+	TypeFlightStatusChanged = "flight_status_changed"
+	TypeItineraryUpdated    = "itinerary_updated"
+	TypeTripFlightsUpdated  = "trip_flights_updated"
+	TypeExpenseAdded        = "expense_added"
+	TypeAIChatCompleted     = "ai_chat_completed"
+)
+
+// Types lists every event type a subscriber may ask for.
+var Types = []string{TypeFlightStatusChanged, TypeItineraryUpdated, TypeTripFlightsUpdated, TypeExpenseAdded, TypeAIChatCompleted}
+
+func IsKnownType(t string) bool {
+	for _, known := range Types {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Sink receives every trip event after it has been stored.
+type Sink interface {
+	HandleTripEvent(ctx context.Context, ev store.TripEvent) error
+}
+
+// Recorder persists trip events and fans them out to registered sinks. Sink failures are
+// logged, never returned: the event is already durable in trip_events.
+type Recorder struct {
+	repo store.TripEventStore
+
+	mu    sync.RWMutex
+	sinks []Sink
+}
+
+func NewRecorder(repo store.TripEventStore) *Recorder {
+	return &Recorder{repo: repo}
+}
+
+func (r *Recorder) AddSink(s Sink) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sinks = append(r.sinks, s)
+}
+
+func (r *Recorder) Record(ctx context.Context, tripID, eventType string, payload map[string]any) (*store.TripEvent, error) {
+	ev, err := r.repo.InsertTripEvent(ctx, tripID, eventType, payload)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	sinks := append([]Sink(nil), r.sinks...)
+	r.mu.RUnlock()
+	for _, s := range sinks {
+		if err := s.HandleTripEvent(ctx, *ev); err != nil {
+			log.Printf("trip event %s (%s) sink %T: %v", ev.ID, ev.Type, s, err)
+		}
+	}
+	return ev, nil
+}
+
+func (r *Recorder) List(ctx context.Context, tripID string, limit int) ([]store.TripEvent, error) {
+	return r.repo.ListTripEvents(ctx, tripID, limit)
+}
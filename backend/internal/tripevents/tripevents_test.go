@@ -0,0 +1,43 @@
+package tripevents
+
+import (
+	"context"
+	"testing"
+
+	"triploom/backend/internal/events"
+	"triploom/backend/internal/store"
+)
+
+type collect []store.TripEvent
+
+func (c *collect) HandleTripEvent(_ context.Context, ev store.TripEvent) error {
+	*c = append(*c, ev)
+	return nil
+}
+
+func TestRecorderFansOutOverBus(t *testing.T) {
+	ctx := context.Background()
+	bus := events.NewMemory()
+	recorder := NewRecorder(store.NewInMemoryTripEventRepository(nil))
+
+	var direct, replicaA, replicaB collect
+	recorder.AddSink(&direct)
+	recorder.AddSink(NewBusSink(bus))
+	Forward(bus, &replicaA)
+	stop := Forward(bus, &replicaB)
+
+	ev, err := recorder.Record(ctx, "trip-1", TypeExpenseAdded, map[string]any{"amount": 42.5})
+	if err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	stop()
+	_, _ = recorder.Record(ctx, "trip-1", TypeExpenseAdded, nil)
+
+	if len(direct) != 2 || len(replicaA) != 2 || len(replicaB) != 1 {
+		t.Fatalf("unexpected fan-out: direct=%d a=%d b=%d", len(direct), len(replicaA), len(replicaB))
+	}
+	got := replicaB[0]
+	if got.ID != ev.ID || got.TripID != "trip-1" || got.PayloadJSON["amount"] != 42.5 || !got.CreatedAt.Equal(ev.CreatedAt) {
+		t.Fatalf("event changed crossing the bus: %+v vs %+v", got, ev)
+	}
+}
@@ -0,0 +1,82 @@
+// Package trips creates trips and manages their members. In Supabase deployments the Next.js
+// app owns these tables; self-hosted SQLite deployments have no other way to populate them.
+package trips
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"triploom/backend/internal/store"
+)
+
+var (
+	ErrUnauthorizedTrip = errors.New("unauthorized trip access")
+	ErrInvalidInput     = errors.New("invalid input")
+)
+
+// Roles accepted by AddMember; trip creators are always owners.
+var roles = map[string]bool{"owner": true, "editor": true, "viewer": true}
+
+type CreateTripRequest struct {
+	Destination string `json:"destination"`
+	StartDate   string `json:"startDate"`
+	EndDate     string `json:"endDate"`
+	Timezone    string `json:"timezone"`
+}
+
+type AddMemberRequest struct {
+	UserID string `json:"userId"`
+	Role   string `json:"role"`
+}
+
+type Service struct {
+	trips store.TripStore
+}
+
+func NewService(trips store.TripStore) *Service {
+	return &Service{trips: trips}
+}
+
+// CreateTrip stores a trip with the caller as its owner.
+func (s *Service) CreateTrip(ctx context.Context, userID string, req CreateTripRequest) (*store.Trip, error) {
+	destination := strings.TrimSpace(req.Destination)
+	start, errStart := time.Parse("2006-01-02", strings.TrimSpace(req.StartDate))
+	end, errEnd := time.Parse("2006-01-02", strings.TrimSpace(req.EndDate))
+	if userID == "" || destination == "" || errStart != nil || errEnd != nil || end.Before(start) {
+		return nil, ErrInvalidInput
+	}
+	timezone := strings.TrimSpace(req.Timezone)
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return nil, ErrInvalidInput
+	}
+	return s.trips.CreateTrip(ctx, store.Trip{Destination: destination, StartDate: start, EndDate: end, Timezone: timezone}, userID)
+}
+
+// AddMember lets a trip owner invite another user, or change another member's role. Owners
+// cannot change their own role, so a trip never loses its owner this way.
+func (s *Service) AddMember(ctx context.Context, userID, tripID string, req AddMemberRequest) error {
+	member := strings.TrimSpace(req.UserID)
+	role := strings.ToLower(strings.TrimSpace(req.Role))
+	if role == "" {
+		role = "viewer"
+	}
+	if tripID == "" || member == "" || !roles[role] {
+		return ErrInvalidInput
+	}
+	current, err := s.trips.TripRole(ctx, tripID, userID)
+	if err != nil {
+		return err
+	}
+	if current != "owner" {
+		return ErrUnauthorizedTrip
+	}
+	if member == userID {
+		return ErrInvalidInput
+	}
+	return s.trips.AddTripMember(ctx, tripID, member, role)
+}
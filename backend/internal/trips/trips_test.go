@@ -0,0 +1,70 @@
+package trips
+
+import (
+	"context"
+	"testing"
+
+	"triploom/backend/internal/store"
+)
+
+func TestCreateTripAndAddMember(t *testing.T) {
+	ctx := context.Background()
+	repo := store.NewInMemoryAIRepository()
+	svc := NewService(repo)
+
+	if _, err := svc.CreateTrip(ctx, "alice", CreateTripRequest{Destination: "Lisbon", StartDate: "2026-05-02", EndDate: "2026-05-01"}); err != ErrInvalidInput {
+		t.Fatalf("expected end before start to be rejected, got %v", err)
+	}
+	trip, err := svc.CreateTrip(ctx, "alice", CreateTripRequest{Destination: " Lisbon ", StartDate: "2026-05-01", EndDate: "2026-05-04"})
+	if err != nil {
+		t.Fatalf("create trip: %v", err)
+	}
+	if trip.Destination != "Lisbon" || trip.Timezone != "UTC" {
+		t.Fatalf("unexpected trip %+v", trip)
+	}
+
+	if err := svc.AddMember(ctx, "mallory", trip.ID, AddMemberRequest{UserID: "mallory"}); err != ErrUnauthorizedTrip {
+		t.Fatalf("expected non-members to be refused, got %v", err)
+	}
+	if err := svc.AddMember(ctx, "alice", trip.ID, AddMemberRequest{UserID: "bob", Role: "admin"}); err != ErrInvalidInput {
+		t.Fatalf("expected unknown role to be rejected, got %v", err)
+	}
+	if err := svc.AddMember(ctx, "alice", trip.ID, AddMemberRequest{UserID: "bob"}); err != nil {
+		t.Fatalf("add member: %v", err)
+	}
+	if ok, _ := repo.IsTripMember(ctx, trip.ID, "bob"); !ok {
+		t.Fatalf("expected bob to be a member")
+	}
+	if err := svc.AddMember(ctx, "alice", trip.ID, AddMemberRequest{UserID: "alice", Role: "viewer"}); err != ErrInvalidInput {
+		t.Fatalf("expected owners not to change their own role, got %v", err)
+	}
+}
+
+func TestOnlyOwnersManageMembers(t *testing.T) {
+	ctx := context.Background()
+	repo := store.NewInMemoryAIRepository()
+	svc := NewService(repo)
+	trip, err := svc.CreateTrip(ctx, "alice", CreateTripRequest{Destination: "Lisbon", StartDate: "2026-05-01", EndDate: "2026-05-04"})
+	if err != nil {
+		t.Fatalf("create trip: %v", err)
+	}
+	_ = svc.AddMember(ctx, "alice", trip.ID, AddMemberRequest{UserID: "victor", Role: "viewer"})
+	_ = svc.AddMember(ctx, "alice", trip.ID, AddMemberRequest{UserID: "erin", Role: "editor"})
+
+	for _, userID := range []string{"victor", "erin"} {
+		if err := svc.AddMember(ctx, userID, trip.ID, AddMemberRequest{UserID: "mallory", Role: "editor"}); err != ErrUnauthorizedTrip {
+			t.Fatalf("expected %s not to add members, got %v", userID, err)
+		}
+		if err := svc.AddMember(ctx, userID, trip.ID, AddMemberRequest{UserID: userID, Role: "owner"}); err != ErrUnauthorizedTrip {
+			t.Fatalf("expected %s not to promote themselves, got %v", userID, err)
+		}
+	}
+	if ok, _ := repo.IsTripMember(ctx, trip.ID, "mallory"); ok {
+		t.Fatalf("expected mallory not to be added")
+	}
+	for userID, want := range map[string]string{"victor": "viewer", "erin": "editor"} {
+		if role, _ := repo.TripRole(ctx, trip.ID, userID); role != want {
+			t.Fatalf("expected %s to stay %s, got %q", userID, want, role)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"triploom/backend/internal/store"
+	"triploom/backend/internal/tripevents"
+)
+
+var (
+	ErrUnauthorizedTrip = errors.New("unauthorized trip access")
+	ErrInvalidInput     = errors.New("invalid input")
+	ErrNotFound         = store.ErrNotFound
+)
+
+// TripMembership reports whether a user may act on a trip and in which role.
+type TripMembership interface {
+	IsTripMember(ctx context.Context, tripID, userID string) (bool, error)
+	// TripRole returns owner, editor or viewer, or "" for non-members.
+	TripRole(ctx context.Context, tripID, userID string) (string, error)
+}
+
+type CreateSubscriptionRequest struct {
+	URL        string   `json:"url"`
+	EventTypes []string `json:"eventTypes"`
+}
+
+// CreatedSubscription is only returned once, on create: it is the one response that carries
+// the signing secret.
+type CreatedSubscription struct {
+	store.WebhookSubscription
+	Secret string `json:"secret"`
+}
+
+// Service manages subscriptions. Recorded trip events reach the outbox through
+// store.TripEventStore, which enqueues them in the same transaction as the event itself.
+type Service struct {
+	members TripMembership
+	repo    store.WebhookStore
+	// allowPrivate lets tests subscribe httptest servers on loopback.
+	allowPrivate bool
+}
+
+func NewService(members TripMembership, repo store.WebhookStore) *Service {
+	return &Service{members: members, repo: repo}
+}
+
+func (s *Service) CreateSubscription(ctx context.Context, userID, tripID string, req CreateSubscriptionRequest) (*CreatedSubscription, error) {
+	target := strings.TrimSpace(req.URL)
+	if tripID == "" || !validURL(target, s.allowPrivate) || len(req.EventTypes) == 0 {
+		return nil, ErrInvalidInput
+	}
+	types := make([]string, 0, len(req.EventTypes))
+	for _, t := range req.EventTypes {
+		t = strings.TrimSpace(t)
+		if !tripevents.IsKnownType(t) {
+			return nil, ErrInvalidInput
+		}
+		if !contains(types, t) {
+			types = append(types, t)
+		}
+	}
+	if err := s.authorizeChange(ctx, tripID, userID); err != nil {
+		return nil, err
+	}
+
+	secret, err := newSecret()
+	if err != nil {
+		return nil, err
+	}
+	sub, err := s.repo.CreateWebhookSubscription(ctx, tripID, userID, target, secret, types)
+	if err != nil {
+		return nil, err
+	}
+	return &CreatedSubscription{WebhookSubscription: *sub, Secret: secret}, nil
+}
+
+func (s *Service) ListSubscriptions(ctx context.Context, userID, tripID string) ([]store.WebhookSubscription, error) {
+	if err := s.authorize(ctx, tripID, userID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListWebhookSubscriptions(ctx, tripID)
+}
+
+func (s *Service) DeleteSubscription(ctx context.Context, userID, tripID, webhookID string) error {
+	if err := s.authorizeChange(ctx, tripID, userID); err != nil {
+		return err
+	}
+	return s.repo.DeleteWebhookSubscription(ctx, tripID, webhookID)
+}
+
+func (s *Service) ListDeliveries(ctx context.Context, userID, tripID, webhookID string, limit int) ([]store.WebhookDelivery, error) {
+	if _, err := s.subscription(ctx, userID, tripID, webhookID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListWebhookDeliveries(ctx, webhookID, limit)
+}
+
+// Redeliver queues the payload of an earlier delivery again as a fresh outbox row, so it gets
+// its own attempt counter and delivery ID.
+func (s *Service) Redeliver(ctx context.Context, userID, tripID, webhookID, deliveryID string) (*store.WebhookOutbox, error) {
+	if err := s.authorizeChange(ctx, tripID, userID); err != nil {
+		return nil, err
+	}
+	if _, err := s.subscription(ctx, userID, tripID, webhookID); err != nil {
+		return nil, err
+	}
+	d, err := s.repo.GetWebhookDelivery(ctx, webhookID, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	o, err := s.repo.GetWebhookOutbox(ctx, d.OutboxID)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.EnqueueWebhook(ctx, webhookID, tripID, o.EventID, o.EventType, o.PayloadJSON)
+}
+
+func (s *Service) subscription(ctx context.Context, userID, tripID, webhookID string) (*store.WebhookSubscription, error) {
+	if err := s.authorize(ctx, tripID, userID); err != nil {
+		return nil, err
+	}
+	sub, err := s.repo.GetWebhookSubscription(ctx, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	if sub.TripID != tripID {
+		return nil, ErrNotFound
+	}
+	return sub, nil
+}
+
+func (s *Service) authorize(ctx context.Context, tripID, userID string) error {
+	ok, err := s.members.IsTripMember(ctx, tripID, userID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrUnauthorizedTrip
+	}
+	return nil
+}
+
+// authorizeChange lets owners and editors add, remove and redeliver subscriptions: each one
+// sends the trip's events to a URL of the subscriber's choosing. Viewers may only read them.
+func (s *Service) authorizeChange(ctx context.Context, tripID, userID string) error {
+	role, err := s.members.TripRole(ctx, tripID, userID)
+	if err != nil {
+		return err
+	}
+	if role != "owner" && role != "editor" {
+		return ErrUnauthorizedTrip
+	}
+	return nil
+}
+
+func newSecret() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+func contains(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
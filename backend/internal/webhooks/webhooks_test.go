@@ -0,0 +1,233 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"triploom/backend/internal/store"
+	"triploom/backend/internal/tripevents"
+)
+
+type allowAll struct{}
+
+func (allowAll) IsTripMember(context.Context, string, string) (bool, error) { return true, nil }
+func (allowAll) TripRole(context.Context, string, string) (string, error)   { return "editor", nil }
+
+// roles gives each listed user a role on every trip.
+type roles map[string]string
+
+func (r roles) IsTripMember(_ context.Context, _, userID string) (bool, error) {
+	return r[userID] != "", nil
+}
+func (r roles) TripRole(_ context.Context, _, userID string) (string, error) { return r[userID], nil }
+
+type receiver struct {
+	mu       sync.Mutex
+	status   int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = append(r.requests, req)
+	r.bodies = append(r.bodies, body)
+	w.WriteHeader(r.status)
+}
+
+func TestSignMatchesHMACOfTimestampAndBody(t *testing.T) {
+	at := time.Unix(1767225600, 0)
+	body := []byte(`{"id":"evt-1"}`)
+	mac := hmac.New(sha256.New, []byte("whsec_test"))
+	mac.Write([]byte("1767225600." + string(body)))
+	want := "t=1767225600,v1=" + hex.EncodeToString(mac.Sum(nil))
+	if got := Sign("whsec_test", at, body); got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+	if Sign("whsec_other", at, body) == want {
+		t.Fatalf("signature ignores the secret")
+	}
+}
+
+func TestBackoffDoublesAndCaps(t *testing.T) {
+	if Backoff(1) != 30*time.Second || Backoff(2) != time.Minute || Backoff(3) != 2*time.Minute {
+		t.Fatalf("unexpected backoff sequence %s %s %s", Backoff(1), Backoff(2), Backoff(3))
+	}
+	if Backoff(20) != 6*time.Hour {
+		t.Fatalf("expected backoff to cap at 6h, got %s", Backoff(20))
+	}
+}
+
+func TestCreateSubscriptionValidates(t *testing.T) {
+	svc := NewService(allowAll{}, store.NewInMemoryWebhookRepository())
+	ctx := context.Background()
+	bad := []CreateSubscriptionRequest{
+		{URL: "ftp://example.com/hook", EventTypes: []string{tripevents.TypeExpenseAdded}},
+		{URL: "http://example.com/hook", EventTypes: []string{tripevents.TypeExpenseAdded}},
+		{URL: "https://127.0.0.1:8080/hook", EventTypes: []string{tripevents.TypeExpenseAdded}},
+		{URL: "https://169.254.169.254/latest/meta-data", EventTypes: []string{tripevents.TypeExpenseAdded}},
+		{URL: "https://[::ffff:10.0.0.1]/hook", EventTypes: []string{tripevents.TypeExpenseAdded}},
+		{URL: "https://localhost/hook", EventTypes: []string{tripevents.TypeExpenseAdded}},
+		{URL: "https://example.com/hook"},
+		{URL: "https://example.com/hook", EventTypes: []string{"trip_deleted"}},
+	}
+	for _, req := range bad {
+		if _, err := svc.CreateSubscription(ctx, "user-1", "trip-1", req); err != ErrInvalidInput {
+			t.Fatalf("expected invalid input for %+v, got %v", req, err)
+		}
+	}
+	sub, err := svc.CreateSubscription(ctx, "user-1", "trip-1", CreateSubscriptionRequest{
+		URL:        "https://example.com/hook",
+		EventTypes: []string{tripevents.TypeExpenseAdded, tripevents.TypeExpenseAdded},
+	})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if sub.Secret == "" || len(sub.EventTypes) != 1 {
+		t.Fatalf("unexpected subscription %+v", sub)
+	}
+}
+
+func TestOnlyOwnersAndEditorsChangeSubscriptions(t *testing.T) {
+	svc := NewService(roles{"olive": "owner", "eve": "editor", "vic": "viewer"}, store.NewInMemoryWebhookRepository())
+	ctx := context.Background()
+	req := CreateSubscriptionRequest{URL: "https://example.com/hook", EventTypes: []string{tripevents.TypeExpenseAdded}}
+	if _, err := svc.CreateSubscription(ctx, "vic", "trip-1", req); err != ErrUnauthorizedTrip {
+		t.Fatalf("expected a viewer not to subscribe, got %v", err)
+	}
+	sub, err := svc.CreateSubscription(ctx, "olive", "trip-1", req)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if subs, err := svc.ListSubscriptions(ctx, "vic", "trip-1"); err != nil || len(subs) != 1 {
+		t.Fatalf("expected a viewer to list subscriptions, got %v %v", subs, err)
+	}
+	if err := svc.DeleteSubscription(ctx, "vic", "trip-1", sub.ID); err != ErrUnauthorizedTrip {
+		t.Fatalf("expected a viewer not to delete, got %v", err)
+	}
+	if _, err := svc.Redeliver(ctx, "vic", "trip-1", sub.ID, "delivery-1"); err != ErrUnauthorizedTrip {
+		t.Fatalf("expected a viewer not to redeliver, got %v", err)
+	}
+	if err := svc.DeleteSubscription(ctx, "eve", "trip-1", sub.ID); err != nil {
+		t.Fatalf("expected an editor to delete, got %v", err)
+	}
+}
+
+func TestWorkerDeliversRetriesAndRedelivers(t *testing.T) {
+	ctx := context.Background()
+	repo := store.NewInMemoryWebhookRepository()
+	svc := NewService(allowAll{}, repo)
+	svc.allowPrivate = true
+	recorder := tripevents.NewRecorder(store.NewInMemoryTripEventRepository(repo))
+
+	ok := &receiver{status: http.StatusNoContent}
+	okServer := httptest.NewTLSServer(ok)
+	defer okServer.Close()
+	flaky := &receiver{status: http.StatusInternalServerError}
+	flakyServer := httptest.NewTLSServer(flaky)
+	defer flakyServer.Close()
+
+	good, err := svc.CreateSubscription(ctx, "user-1", "trip-1", CreateSubscriptionRequest{URL: okServer.URL, EventTypes: []string{tripevents.TypeFlightStatusChanged}})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	bad, err := svc.CreateSubscription(ctx, "user-1", "trip-1", CreateSubscriptionRequest{URL: flakyServer.URL, EventTypes: []string{tripevents.TypeFlightStatusChanged}})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, err := svc.CreateSubscription(ctx, "user-1", "trip-1", CreateSubscriptionRequest{URL: okServer.URL, EventTypes: []string{tripevents.TypeExpenseAdded}}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	ev, err := recorder.Record(ctx, "trip-1", tripevents.TypeFlightStatusChanged, map[string]any{"flightNumber": "AC856"})
+	if err != nil {
+		t.Fatalf("record: %v", err)
+	}
+
+	now := time.Now().UTC()
+	w := NewWorker(repo, okServer.Client())
+	w.now = func() time.Time { return now }
+	if n, err := w.DeliverDue(ctx); err != nil || n != 2 {
+		t.Fatalf("first run: n=%d err=%v", n, err)
+	}
+
+	if len(ok.requests) != 1 {
+		t.Fatalf("expected one delivery to the healthy receiver, got %d", len(ok.requests))
+	}
+	req, body := ok.requests[0], ok.bodies[0]
+	if req.Header.Get(HeaderEvent) != tripevents.TypeFlightStatusChanged {
+		t.Fatalf("unexpected event header %q", req.Header.Get(HeaderEvent))
+	}
+	if req.Header.Get(HeaderSignature) != Sign(good.Secret, now, body) {
+		t.Fatalf("signature mismatch: %q", req.Header.Get(HeaderSignature))
+	}
+	var envelope map[string]any
+	if err := json.Unmarshal(body, &envelope); err != nil || envelope["id"] != ev.ID || envelope["tripId"] != "trip-1" {
+		t.Fatalf("unexpected body %s (%v)", body, err)
+	}
+
+	if n, _ := w.DeliverDue(ctx); n != 0 {
+		t.Fatalf("expected failed delivery to wait for its backoff, attempted %d", n)
+	}
+	now = now.Add(Backoff(1))
+	flaky.status = http.StatusOK
+	if n, _ := w.DeliverDue(ctx); n != 1 {
+		t.Fatalf("expected retry after backoff, attempted %d", n)
+	}
+	attempts, err := svc.ListDeliveries(ctx, "user-1", "trip-1", bad.ID, 10)
+	if err != nil {
+		t.Fatalf("list deliveries: %v", err)
+	}
+	if len(attempts) != 2 || attempts[0].Attempt != 2 || *attempts[0].StatusCode != 200 || *attempts[1].StatusCode != 500 {
+		t.Fatalf("unexpected delivery log %+v", attempts)
+	}
+
+	goodLog, _ := svc.ListDeliveries(ctx, "user-1", "trip-1", good.ID, 10)
+	if _, err := svc.Redeliver(ctx, "user-1", "trip-1", bad.ID, goodLog[0].ID); err != ErrNotFound {
+		t.Fatalf("expected delivery from another webhook to be not found, got %v", err)
+	}
+	if _, err := svc.Redeliver(ctx, "user-1", "trip-1", good.ID, goodLog[0].ID); err != nil {
+		t.Fatalf("redeliver: %v", err)
+	}
+	if n, _ := w.DeliverDue(ctx); n != 1 || len(ok.requests) != 2 {
+		t.Fatalf("expected redelivery, attempted %d, received %d", n, len(ok.requests))
+	}
+	if ok.requests[0].Header.Get(HeaderDelivery) == ok.requests[1].Header.Get(HeaderDelivery) {
+		t.Fatalf("expected redelivery to carry a new delivery id")
+	}
+	if string(ok.bodies[0]) != string(ok.bodies[1]) {
+		t.Fatalf("expected redelivery to resend the same body")
+	}
+}
+
+func TestDeliveryClientRefusesPrivateAddresses(t *testing.T) {
+	for _, addr := range []string{"127.0.0.1:443", "10.1.2.3:443", "192.168.0.10:443", "169.254.169.254:80", "100.64.0.1:443", "[::1]:443", "[fd00::1]:443", "[::ffff:127.0.0.1]:443", "0.0.0.0:443"} {
+		if err := dialControl("tcp", addr, nil); err == nil {
+			t.Fatalf("expected %s to be refused", addr)
+		}
+	}
+	if err := dialControl("tcp", "93.184.215.14:443", nil); err != nil {
+		t.Fatalf("expected a public address to be allowed, got %v", err)
+	}
+
+	server := httptest.NewTLSServer(&receiver{status: http.StatusOK})
+	defer server.Close()
+	repo := store.NewInMemoryWebhookRepository()
+	sub, _ := repo.CreateWebhookSubscription(context.Background(), "trip-1", "user-1", server.URL, "whsec_test", []string{tripevents.TypeExpenseAdded})
+	w := NewWorker(repo, nil)
+	if _, err := w.send(context.Background(), sub, store.WebhookOutbox{ID: "o-1", EventType: tripevents.TypeExpenseAdded}); err == nil || !strings.Contains(err.Error(), "non-public address") {
+		t.Fatalf("expected the default client to refuse loopback, got %v", err)
+	}
+}
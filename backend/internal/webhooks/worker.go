@@ -0,0 +1,171 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"triploom/backend/internal/store"
+)
+
+// Headers sent with every delivery.
+const (
+	HeaderEvent     = "X-TripLoom-Event"
+	HeaderDelivery  = "X-TripLoom-Delivery"
+	HeaderSignature = "X-TripLoom-Signature"
+)
+
+const (
+	maxAttempts = 10
+	baseBackoff = 30 * time.Second
+	maxBackoff  = 6 * time.Hour
+)
+
+// Worker drains the webhook outbox: it POSTs due rows, logs each attempt and reschedules
+// failures with exponential backoff until maxAttempts.
+type Worker struct {
+	repo       store.WebhookStore
+	httpClient *http.Client
+	tick       time.Duration
+	batch      int
+	lease      time.Duration
+	now        func() time.Time
+}
+
+// NewWorker delivers with httpClient, or when it is nil with a client that only connects to
+// public addresses.
+func NewWorker(repo store.WebhookStore, httpClient *http.Client) *Worker {
+	if httpClient == nil {
+		httpClient = newDeliveryClient()
+	}
+	return &Worker{repo: repo, httpClient: httpClient, tick: 5 * time.Second, batch: 20, lease: time.Minute, now: time.Now}
+}
+
+// Run delivers until ctx is cancelled.
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.tick)
+	defer ticker.Stop()
+	for {
+		if _, err := w.DeliverDue(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("webhook delivery: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeliverDue attempts every outbox row that is due and returns how many were attempted.
+func (w *Worker) DeliverDue(ctx context.Context) (int, error) {
+	now := w.now().UTC()
+	due, err := w.repo.ClaimDueWebhooks(ctx, now, w.lease, w.batch)
+	if err != nil {
+		return 0, err
+	}
+	for _, o := range due {
+		if err := w.deliver(ctx, o); err != nil {
+			log.Printf("webhook outbox %s (%s): %v", o.ID, o.EventType, err)
+		}
+	}
+	return len(due), nil
+}
+
+func (w *Worker) deliver(ctx context.Context, o store.WebhookOutbox) error {
+	sub, err := w.repo.GetWebhookSubscription(ctx, o.SubscriptionID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	attempt := store.WebhookDelivery{SubscriptionID: sub.ID, EventType: o.EventType, Attempt: o.Attempts + 1}
+	if !sub.Active {
+		attempt.Error = "subscription inactive"
+		_, err := w.repo.RecordWebhookAttempt(ctx, o.ID, attempt, store.WebhookFailed, w.now().UTC())
+		return err
+	}
+
+	started := w.now()
+	code, sendErr := w.send(ctx, sub, o)
+	attempt.DurationMS = int(w.now().Sub(started).Milliseconds())
+	if code != 0 {
+		attempt.StatusCode = &code
+	}
+
+	status := store.WebhookDelivered
+	next := w.now().UTC()
+	if sendErr != nil {
+		attempt.Error = sendErr.Error()
+		status = store.WebhookPending
+		next = next.Add(Backoff(attempt.Attempt))
+		if attempt.Attempt >= maxAttempts {
+			status = store.WebhookFailed
+		}
+	}
+	_, err = w.repo.RecordWebhookAttempt(ctx, o.ID, attempt, status, next)
+	return err
+}
+
+func (w *Worker) send(ctx context.Context, sub *store.WebhookSubscription, o store.WebhookOutbox) (int, error) {
+	body, err := json.Marshal(o.PayloadJSON)
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "TripLoom-Webhooks/1")
+	req.Header.Set(HeaderEvent, o.EventType)
+	req.Header.Set(HeaderDelivery, o.ID)
+	req.Header.Set(HeaderSignature, Sign(sub.Secret, w.now(), body))
+
+	res, err := w.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("unexpected status %d", res.StatusCode)
+	}
+	return res.StatusCode, nil
+}
+
+// Sign builds the X-TripLoom-Signature value: the unix timestamp and the hex HMAC-SHA256 of
+// "<timestamp>.<body>" keyed with the subscription secret. Receivers should recompute it and
+// reject stale timestamps.
+func Sign(secret string, at time.Time, body []byte) string {
+	ts := strconv.FormatInt(at.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "t=" + ts + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Backoff is the wait after the given (1-based) failed attempt: 30s doubling up to 6h.
+func Backoff(attempt int) time.Duration {
+	d := baseBackoff
+	for i := 1; i < attempt; i++ {
+		d *= 2
+		if d >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return d
+}
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS ai_audit_logs;
+DROP TABLE IF EXISTS ai_context_snapshots;
+DROP TABLE IF EXISTS ai_tool_snapshots;
+DROP TABLE IF EXISTS ai_messages;
+DROP TABLE IF EXISTS ai_conversations;
+DROP TABLE IF EXISTS trip_members;
+DROP TABLE IF EXISTS trips;
@@ -0,0 +1,9 @@
+DROP POLICY IF EXISTS "Members can delete trips" ON trips;
+DROP POLICY IF EXISTS "Members can update trips" ON trips;
+DROP POLICY IF EXISTS "Users can read own memberships" ON trip_members;
+DROP POLICY IF EXISTS "Members can read trips" ON trips;
+DROP POLICY IF EXISTS "Authenticated can insert self as member" ON trip_members;
+DROP POLICY IF EXISTS "Authenticated can insert trips" ON trips;
+
+ALTER TABLE trip_members DISABLE ROW LEVEL SECURITY;
+ALTER TABLE trips DISABLE ROW LEVEL SECURITY;
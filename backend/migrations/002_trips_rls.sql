@@ -1,5 +1,5 @@
 -- RLS so the frontend (authenticated user) can create trips and add themselves as owner.
--- Run this in Supabase SQL editor or via your migration runner after 001_init.sql.
+-- Applied by `api migrate up` after 001_init.sql.
 
 ALTER TABLE trips ENABLE ROW LEVEL SECURITY;
 ALTER TABLE trip_members ENABLE ROW LEVEL SECURITY;
@@ -0,0 +1 @@
+DROP POLICY IF EXISTS "Members can update trip_flights" ON trip_flights;
@@ -0,0 +1,9 @@
+-- Cached responses from external providers (flight status, transit suggestions), keyed by tool + normalised request.
+CREATE TABLE IF NOT EXISTS provider_cache (
+  cache_key TEXT PRIMARY KEY,
+  value_json JSONB NOT NULL,
+  expires_at TIMESTAMPTZ NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_provider_cache_expires_at ON provider_cache(expires_at);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS trip_events;
+DROP TABLE IF EXISTS flight_watches;
@@ -0,0 +1,28 @@
+-- Flights a user asked the backend to keep polling, plus the change events they produce.
+CREATE TABLE IF NOT EXISTS flight_watches (
+  id TEXT PRIMARY KEY,
+  trip_id TEXT NOT NULL REFERENCES trips(id) ON DELETE CASCADE,
+  user_id TEXT NOT NULL,
+  flight_number TEXT NOT NULL,
+  departure_date TEXT NOT NULL,
+  active BOOLEAN NOT NULL DEFAULT TRUE,
+  last_status_json JSONB,
+  last_polled_at TIMESTAMPTZ,
+  next_poll_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  UNIQUE (trip_id, flight_number, departure_date)
+);
+
+CREATE INDEX IF NOT EXISTS idx_flight_watches_due ON flight_watches(next_poll_at) WHERE active;
+
+-- Append-only feed of things that happened on a trip (flight status changes, ...).
+CREATE TABLE IF NOT EXISTS trip_events (
+  id TEXT PRIMARY KEY,
+  trip_id TEXT NOT NULL REFERENCES trips(id) ON DELETE CASCADE,
+  type TEXT NOT NULL,
+  payload_json JSONB NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_trip_events_trip_created ON trip_events(trip_id, created_at DESC);
@@ -0,0 +1,9 @@
+-- Event bus messages too large for a NOTIFY payload; listeners load them by id.
+CREATE TABLE IF NOT EXISTS event_bus_messages (
+  id TEXT PRIMARY KEY,
+  topic TEXT NOT NULL,
+  payload_json JSONB NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_event_bus_messages_created ON event_bus_messages(created_at);
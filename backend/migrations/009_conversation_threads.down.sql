@@ -0,0 +1 @@
+ALTER TABLE ai_conversations DROP COLUMN IF EXISTS archived_at;
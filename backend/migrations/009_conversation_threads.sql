@@ -0,0 +1,2 @@
+-- Several assistant threads per trip and user; archived threads drop out of the default list.
+ALTER TABLE ai_conversations ADD COLUMN IF NOT EXISTS archived_at TIMESTAMPTZ;
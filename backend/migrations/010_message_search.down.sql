@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_ai_messages_content_tsv;
+ALTER TABLE ai_messages DROP COLUMN IF EXISTS content_tsv;
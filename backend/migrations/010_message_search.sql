@@ -0,0 +1,5 @@
+-- Full-text search over assistant messages (GET /v1/ai/search).
+ALTER TABLE ai_messages ADD COLUMN IF NOT EXISTS content_tsv tsvector
+  GENERATED ALWAYS AS (to_tsvector('english', content)) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_ai_messages_content_tsv ON ai_messages USING GIN (content_tsv);
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_ai_conversations_trip_user_created;
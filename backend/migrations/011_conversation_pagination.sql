@@ -0,0 +1,2 @@
+-- Conversations are paged by (created_at, id); messages already have a matching index.
+CREATE INDEX IF NOT EXISTS idx_ai_conversations_trip_user_created ON ai_conversations(trip_id, user_id, created_at DESC, id);
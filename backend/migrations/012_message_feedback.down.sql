@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS ai_message_feedback;
+DROP INDEX IF EXISTS idx_ai_messages_regenerated_from;
+ALTER TABLE ai_messages DROP COLUMN IF EXISTS regenerated_from;
+ALTER TABLE ai_messages DROP COLUMN IF EXISTS page_key;
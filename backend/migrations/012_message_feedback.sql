@@ -0,0 +1,18 @@
+-- The page an answer was given on, and for regenerated answers the original they replace.
+-- regenerated_from has no foreign key: versions live in one conversation and are deleted with it.
+ALTER TABLE ai_messages ADD COLUMN IF NOT EXISTS page_key TEXT;
+ALTER TABLE ai_messages ADD COLUMN IF NOT EXISTS regenerated_from TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_ai_messages_regenerated_from ON ai_messages(regenerated_from) WHERE regenerated_from IS NOT NULL;
+
+-- One rating per user and message; posting again replaces it.
+CREATE TABLE IF NOT EXISTS ai_message_feedback (
+  message_id TEXT NOT NULL REFERENCES ai_messages(id) ON DELETE CASCADE,
+  user_id TEXT NOT NULL,
+  rating TEXT NOT NULL CHECK (rating IN ('up', 'down')),
+  reasons TEXT[] NOT NULL DEFAULT '{}',
+  comment TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (message_id, user_id)
+);
@@ -0,0 +1,2 @@
+ALTER TABLE ai_messages DROP COLUMN IF EXISTS prompt_version;
+DROP TABLE IF EXISTS ai_prompt_templates;
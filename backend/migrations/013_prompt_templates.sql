@@ -0,0 +1,13 @@
+-- Prompt template versions managed without a deploy. A row replaces the bundled file with the
+-- same name and version; weight is the share of users assigned to it.
+CREATE TABLE IF NOT EXISTS ai_prompt_templates (
+  name TEXT NOT NULL,
+  version TEXT NOT NULL,
+  body TEXT NOT NULL,
+  weight INT NOT NULL DEFAULT 0 CHECK (weight >= 0),
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (name, version)
+);
+
+-- The name@version of the system prompt each answer was generated with.
+ALTER TABLE ai_messages ADD COLUMN IF NOT EXISTS prompt_version TEXT;
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS ai_proposals;
+ALTER TABLE trip_flights DROP COLUMN IF EXISTS flight_number;
+DROP TABLE IF EXISTS trip_expenses;
+DROP TABLE IF EXISTS trip_itinerary_items;
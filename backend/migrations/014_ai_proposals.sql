@@ -0,0 +1,90 @@
+-- Itinerary items and expenses kept by the API, so that approved assistant proposals can be
+-- applied server-side. Columns follow the frontend TripItineraryItem and TripExpense types.
+CREATE TABLE IF NOT EXISTS trip_itinerary_items (
+  id TEXT PRIMARY KEY,
+  trip_id TEXT NOT NULL REFERENCES trips(id) ON DELETE CASCADE,
+  day_index INT NOT NULL CHECK (day_index >= 1),
+  time_block TEXT NOT NULL CHECK (time_block IN ('morning', 'afternoon', 'evening')),
+  status TEXT NOT NULL DEFAULT 'planned',
+  category TEXT NOT NULL DEFAULT 'activities',
+  title TEXT NOT NULL,
+  location_label TEXT NOT NULL DEFAULT '',
+  notes TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_trip_itinerary_items_trip_day ON trip_itinerary_items(trip_id, day_index);
+
+CREATE TABLE IF NOT EXISTS trip_expenses (
+  id TEXT PRIMARY KEY,
+  trip_id TEXT NOT NULL REFERENCES trips(id) ON DELETE CASCADE,
+  expense_date TEXT NOT NULL,
+  category TEXT NOT NULL CHECK (category IN ('flights', 'hotels', 'transit', 'food', 'activities', 'misc')),
+  title TEXT NOT NULL,
+  amount NUMERIC(12, 2) NOT NULL CHECK (amount > 0),
+  currency TEXT NOT NULL,
+  payer_name TEXT NOT NULL DEFAULT '',
+  notes TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_trip_expenses_trip_date ON trip_expenses(trip_id, expense_date);
+
+-- Flights saved from an assistant proposal know their flight number, not an offer.
+ALTER TABLE trip_flights ADD COLUMN IF NOT EXISTS flight_number TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE trip_itinerary_items ENABLE ROW LEVEL SECURITY;
+ALTER TABLE trip_expenses ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY "Members can manage trip_itinerary_items"
+  ON trip_itinerary_items FOR ALL TO authenticated
+  USING (
+    EXISTS (
+      SELECT 1 FROM trip_members
+      WHERE trip_members.trip_id = trip_itinerary_items.trip_id AND trip_members.user_id = auth.uid()::text
+    )
+  )
+  WITH CHECK (
+    EXISTS (
+      SELECT 1 FROM trip_members
+      WHERE trip_members.trip_id = trip_itinerary_items.trip_id AND trip_members.user_id = auth.uid()::text
+    )
+  );
+
+CREATE POLICY "Members can manage trip_expenses"
+  ON trip_expenses FOR ALL TO authenticated
+  USING (
+    EXISTS (
+      SELECT 1 FROM trip_members
+      WHERE trip_members.trip_id = trip_expenses.trip_id AND trip_members.user_id = auth.uid()::text
+    )
+  )
+  WITH CHECK (
+    EXISTS (
+      SELECT 1 FROM trip_members
+      WHERE trip_members.trip_id = trip_expenses.trip_id AND trip_members.user_id = auth.uid()::text
+    )
+  );
+
+-- Changes the assistant proposed. Nothing is written to the trip tables until a member
+-- approves; change_json is what will be applied and preview_json the before/after shown.
+CREATE TABLE IF NOT EXISTS ai_proposals (
+  id TEXT PRIMARY KEY,
+  trip_id TEXT NOT NULL REFERENCES trips(id) ON DELETE CASCADE,
+  conversation_id TEXT REFERENCES ai_conversations(id) ON DELETE SET NULL,
+  message_id TEXT,
+  user_id TEXT NOT NULL,
+  kind TEXT NOT NULL,
+  label TEXT NOT NULL DEFAULT '',
+  change_json JSONB NOT NULL,
+  preview_json JSONB NOT NULL,
+  status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected')),
+  result_id TEXT,
+  decided_by TEXT,
+  decided_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_ai_proposals_trip_status ON ai_proposals(trip_id, status, created_at DESC);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS user_preference_updates;
+DROP TABLE IF EXISTS user_preferences;
@@ -0,0 +1,23 @@
+-- Travel preferences kept per user across trips and planner sessions, e.g. {"seat": "aisle"}.
+CREATE TABLE IF NOT EXISTS user_preferences (
+  user_id TEXT PRIMARY KEY,
+  preferences_json JSONB NOT NULL,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Preference changes the assistant suggested. Nothing is written to user_preferences until the
+-- user confirms; an empty value removes the preference.
+CREATE TABLE IF NOT EXISTS user_preference_updates (
+  id TEXT PRIMARY KEY,
+  user_id TEXT NOT NULL,
+  conversation_id TEXT REFERENCES ai_conversations(id) ON DELETE SET NULL,
+  message_id TEXT,
+  preference TEXT NOT NULL,
+  value TEXT NOT NULL,
+  previous TEXT NOT NULL DEFAULT '',
+  status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected')),
+  decided_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_preference_updates_user_status ON user_preference_updates(user_id, status, created_at DESC);
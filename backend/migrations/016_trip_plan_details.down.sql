@@ -0,0 +1,12 @@
+ALTER TABLE trip_expenses DROP COLUMN IF EXISTS splits_json;
+ALTER TABLE trip_expenses DROP COLUMN IF EXISTS split_mode;
+
+ALTER TABLE trip_itinerary_items DROP COLUMN IF EXISTS end_time_local;
+ALTER TABLE trip_itinerary_items DROP COLUMN IF EXISTS start_time_local;
+ALTER TABLE trip_itinerary_items DROP COLUMN IF EXISTS commute_details;
+ALTER TABLE trip_itinerary_items DROP COLUMN IF EXISTS google_maps_link;
+ALTER TABLE trip_itinerary_items DROP COLUMN IF EXISTS location_link;
+ALTER TABLE trip_itinerary_items DROP COLUMN IF EXISTS lng;
+ALTER TABLE trip_itinerary_items DROP COLUMN IF EXISTS lat;
+ALTER TABLE trip_itinerary_items DROP COLUMN IF EXISTS place_id;
+ALTER TABLE trip_itinerary_items DROP COLUMN IF EXISTS sort_order;
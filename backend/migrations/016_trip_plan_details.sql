@@ -0,0 +1,14 @@
+-- The web app reads and writes trip_itinerary_items and trip_expenses directly, so they also
+-- keep the fields its TripItineraryItem and TripExpense types carry beyond what proposals set.
+ALTER TABLE trip_itinerary_items ADD COLUMN IF NOT EXISTS sort_order INT NOT NULL DEFAULT 0;
+ALTER TABLE trip_itinerary_items ADD COLUMN IF NOT EXISTS place_id TEXT;
+ALTER TABLE trip_itinerary_items ADD COLUMN IF NOT EXISTS lat DOUBLE PRECISION;
+ALTER TABLE trip_itinerary_items ADD COLUMN IF NOT EXISTS lng DOUBLE PRECISION;
+ALTER TABLE trip_itinerary_items ADD COLUMN IF NOT EXISTS location_link TEXT;
+ALTER TABLE trip_itinerary_items ADD COLUMN IF NOT EXISTS google_maps_link TEXT;
+ALTER TABLE trip_itinerary_items ADD COLUMN IF NOT EXISTS commute_details TEXT;
+ALTER TABLE trip_itinerary_items ADD COLUMN IF NOT EXISTS start_time_local TEXT;
+ALTER TABLE trip_itinerary_items ADD COLUMN IF NOT EXISTS end_time_local TEXT;
+
+ALTER TABLE trip_expenses ADD COLUMN IF NOT EXISTS split_mode TEXT NOT NULL DEFAULT 'equal';
+ALTER TABLE trip_expenses ADD COLUMN IF NOT EXISTS splits_json JSONB;
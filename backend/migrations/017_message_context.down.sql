@@ -0,0 +1 @@
+ALTER TABLE ai_messages DROP COLUMN IF EXISTS context_json;
@@ -0,0 +1,3 @@
+-- The context an answer was generated from, so regenerating it uses the same trip data rather
+-- than whatever a trip member chatted about last.
+ALTER TABLE ai_messages ADD COLUMN IF NOT EXISTS context_json JSONB;
@@ -0,0 +1,10 @@
+// Package migrations embeds the SQL schema files so the binary can apply them itself.
+package migrations
+
+import "embed"
+
+// FS holds every NNN_name.sql file in this directory and the NNN_name.down.sql file that
+// reverts it.
+//
+//go:embed *.sql
+var FS embed.FS
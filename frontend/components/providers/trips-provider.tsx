@@ -15,6 +15,13 @@ import {
   getTripsFromSupabase,
   updateTripInSupabase,
 } from "@/lib/supabase-trips"
+import {
+  deleteTripExpenseFromSupabase,
+  deleteTripItineraryItemsFromSupabase,
+  getTripPlansFromSupabase,
+  saveTripExpenseToSupabase,
+  saveTripItineraryItemsToSupabase,
+} from "@/lib/supabase-trip-plan"
 import { createClient } from "@/lib/supabase/client"
 import { toast } from "sonner"
 import {
@@ -36,6 +43,71 @@ function normalizeTransit(trip: Trip): Trip {
   }
 }
 
+// Trip inserts still in flight, so that itinerary and expense rows, which reference the trip,
+// are only written once it exists.
+const pendingTripInserts = new Map<string, Promise<void>>()
+
+function afterTripInsert(tripId: string): Promise<void> {
+  return pendingTripInserts.get(tripId) ?? Promise.resolve()
+}
+
+function syncErrorToast(message: string) {
+  return (e: unknown) => {
+    toast.error(message, { description: e instanceof Error ? e.message : undefined })
+  }
+}
+
+/**
+ * Write the difference between two versions of a trip's itinerary to the DB: items that are
+ * new or changed are upserted, missing ones deleted.
+ */
+function syncItinerary(tripId: string, before: TripItineraryItem[], after: TripItineraryItem[]) {
+  const previous = new Map(before.map((item) => [item.id, JSON.stringify(item)]))
+  const changed = after.filter((item) => previous.get(item.id) !== JSON.stringify(item))
+  const kept = new Set(after.map((item) => item.id))
+  const removed = before.filter((item) => !kept.has(item.id)).map((item) => item.id)
+  afterTripInsert(tripId)
+    .then(() => saveTripItineraryItemsToSupabase(tripId, changed))
+    .catch(syncErrorToast("Itinerary saved locally but could not sync to cloud."))
+  afterTripInsert(tripId)
+    .then(() => deleteTripItineraryItemsFromSupabase(tripId, removed))
+    .catch(syncErrorToast("Could not remove itinerary items from cloud."))
+}
+
+/** Like syncItinerary, for a trip's expenses. */
+function syncExpenses(tripId: string, before: TripExpense[], after: TripExpense[]) {
+  const previous = new Map(before.map((expense) => [expense.id, JSON.stringify(expense)]))
+  for (const expense of after) {
+    if (previous.get(expense.id) === JSON.stringify(expense)) continue
+    afterTripInsert(tripId)
+      .then(() => saveTripExpenseToSupabase(tripId, expense))
+      .catch(syncErrorToast("Expense saved locally but could not sync to cloud."))
+  }
+  const kept = new Set(after.map((expense) => expense.id))
+  for (const expense of before) {
+    if (kept.has(expense.id)) continue
+    afterTripInsert(tripId)
+      .then(() => deleteTripExpenseFromSupabase(tripId, expense.id))
+      .catch(syncErrorToast("Could not remove expense from cloud."))
+  }
+}
+
+/** Merge the itinerary and expenses stored in the DB into trips loaded from it. */
+async function withTripPlans(trips: Trip[]): Promise<Trip[]> {
+  const plans = await getTripPlansFromSupabase(trips.map((trip) => trip.id))
+  return trips.map((trip) => {
+    const plan = plans[trip.id]
+    if (!plan) return trip
+    const itineraryItems = getTripItineraryItems({ ...trip, itineraryItems: plan.itineraryItems })
+    return {
+      ...trip,
+      itineraryItems,
+      itineraryDaysPlanned: computeDaysPlanned(itineraryItems),
+      finance: { ...getTripFinance(trip), expenses: plan.expenses },
+    }
+  })
+}
+
 type TripsContextValue = {
   trips: Trip[]
   getTripById: (id: string) => Trip | undefined
@@ -72,13 +144,39 @@ const TripsContext = React.createContext<TripsContextValue | null>(null)
 export function TripsProvider({ children }: { children: React.ReactNode }) {
   const [trips, setTrips] = React.useState<Trip[]>([])
   const [tripsLoaded, setTripsLoaded] = React.useState(false)
+  // The latest trips, so that a change can be worked out, committed and only then written to
+  // the DB. State updaters may run twice, so they must not sync.
+  const tripsRef = React.useRef<Trip[]>([])
+
+  const commitTrips = React.useCallback((next: Trip[]) => {
+    tripsRef.current = next
+    setTrips(next)
+  }, [])
+
+  /**
+   * Replace one trip with update(trip), then write what changed in its itinerary and expenses
+   * to the DB. Returns the updated trip, or undefined if there is no such trip.
+   */
+  const changeTrip = React.useCallback(
+    (id: string, update: (trip: Trip) => Trip): Trip | undefined => {
+      const before = tripsRef.current.find((trip) => trip.id === id)
+      if (!before) return undefined
+      const after = update(before)
+      commitTrips(tripsRef.current.map((trip) => (trip.id === id ? after : trip)))
+      syncItinerary(id, before.itineraryItems ?? [], after.itineraryItems ?? [])
+      syncExpenses(id, getTripFinance(before).expenses, getTripFinance(after).expenses)
+      return after
+    },
+    [commitTrips]
+  )
 
   const fetchTrips = React.useCallback(() => {
     getTripsFromSupabase()
-      .then((list) => setTrips(list.map(normalizeTransit)))
-      .catch(() => setTrips([]))
+      .then(withTripPlans)
+      .then((list) => commitTrips(list.map(normalizeTransit)))
+      .catch(() => commitTrips([]))
       .finally(() => setTripsLoaded(true))
-  }, [])
+  }, [commitTrips])
 
   React.useEffect(() => {
     fetchTrips()
@@ -144,21 +242,24 @@ export function TripsProvider({ children }: { children: React.ReactNode }) {
       activities: ["Trip created"],
     }
 
-    setTrips((prev) => [next, ...prev.filter((trip) => trip.id !== next.id)])
-    createTripInSupabase({
+    commitTrips([next, ...tripsRef.current.filter((trip) => trip.id !== next.id)])
+    const inserted = createTripInSupabase({
       id: next.id,
       destination: next.destination,
       startDate: next.startDate,
       endDate: next.endDate,
       timezone: next.timezone ?? "UTC",
-    }).catch((e) => {
-      toast.error(
-        "Trip saved locally but could not sync to cloud.",
-        { description: e instanceof Error ? e.message : undefined }
-      )
     })
+      .catch((e) => {
+        toast.error(
+          "Trip saved locally but could not sync to cloud.",
+          { description: e instanceof Error ? e.message : undefined }
+        )
+      })
+      .finally(() => pendingTripInserts.delete(next.id))
+    pendingTripInserts.set(next.id, inserted)
     return next
-  }, [])
+  }, [commitTrips])
 
   const updateTrip = React.useCallback((id: string, partial: Partial<Trip>) => {
     const shouldPersistToDb =
@@ -166,61 +267,78 @@ export function TripsProvider({ children }: { children: React.ReactNode }) {
       "startDate" in partial ||
       "endDate" in partial ||
       "timezone" in partial
-    setTrips((prev) =>
-      prev.map((t) => {
-        if (t.id !== id) return t
-        const merged: Trip = {
-          ...t,
-          ...partial,
-          lastUpdated: new Date().toISOString().slice(0, 10),
-        }
-        if (typeof partial.totalDays === "number" && Array.isArray(t.itineraryItems)) {
-          merged.itineraryItems = coerceItinerary({
-            ...merged,
-            itineraryItems: t.itineraryItems,
-          })
-          merged.itineraryDaysPlanned = computeDaysPlanned(merged.itineraryItems)
-        }
-        const next = normalizeTransit(merged)
-        if (shouldPersistToDb) {
-          updateTripInSupabase(id, {
-            destination: next.destination,
-            startDate: next.startDate,
-            endDate: next.endDate,
-            timezone: next.timezone ?? undefined,
-          }).catch((e) => {
-            toast.error("Could not update trip in cloud.", {
-              description: e instanceof Error ? e.message : undefined,
-            })
-          })
-        }
-        return next
+    const next = changeTrip(id, (t) => {
+      const merged: Trip = {
+        ...t,
+        ...partial,
+        lastUpdated: new Date().toISOString().slice(0, 10),
+      }
+      if (typeof partial.totalDays === "number" && Array.isArray(t.itineraryItems)) {
+        merged.itineraryItems = coerceItinerary({
+          ...merged,
+          itineraryItems: t.itineraryItems,
+        })
+        merged.itineraryDaysPlanned = computeDaysPlanned(merged.itineraryItems)
+      }
+      return normalizeTransit(merged)
+    })
+    if (next && shouldPersistToDb) {
+      updateTripInSupabase(id, {
+        destination: next.destination,
+        startDate: next.startDate,
+        endDate: next.endDate,
+        timezone: next.timezone ?? undefined,
+      }).catch((e) => {
+        toast.error("Could not update trip in cloud.", {
+          description: e instanceof Error ? e.message : undefined,
+        })
       })
-    )
-  }, [])
+    }
+  }, [changeTrip])
 
   const setTripItineraryItems = React.useCallback((id: string, items: TripItineraryItem[]) => {
-    setTrips((prev) =>
-      prev.map((trip) => {
-        if (trip.id !== id) return trip
-        const normalized = coerceItinerary({ ...trip, itineraryItems: items })
-        return {
-          ...trip,
-          itineraryItems: normalized,
-          itineraryDaysPlanned: computeDaysPlanned(normalized),
-          lastUpdated: new Date().toISOString().slice(0, 10),
-        }
-      })
-    )
-  }, [])
+    changeTrip(id, (trip) => {
+      const normalized = coerceItinerary({ ...trip, itineraryItems: items })
+      return {
+        ...trip,
+        itineraryItems: normalized,
+        itineraryDaysPlanned: computeDaysPlanned(normalized),
+        lastUpdated: new Date().toISOString().slice(0, 10),
+      }
+    })
+  }, [changeTrip])
 
   const addTripItineraryItem = React.useCallback((id: string, item: TripItineraryItem) => {
-    setTrips((prev) =>
-      prev.map((trip) => {
-        if (trip.id !== id) return trip
+    changeTrip(id, (trip) => {
+      const next = getTripItineraryItems({
+        ...trip,
+        itineraryItems: [...(trip.itineraryItems ?? []), item],
+      })
+      return {
+        ...trip,
+        itineraryItems: next,
+        itineraryDaysPlanned: computeDaysPlanned(next),
+        lastUpdated: new Date().toISOString().slice(0, 10),
+      }
+    })
+  }, [changeTrip])
+
+  const updateTripItineraryItem = React.useCallback(
+    (id: string, itemId: string, patch: Partial<TripItineraryItem>) => {
+      changeTrip(id, (trip) => {
         const next = getTripItineraryItems({
           ...trip,
-          itineraryItems: [...(trip.itineraryItems ?? []), item],
+          itineraryItems: (trip.itineraryItems ?? []).map((item) =>
+            item.id === itemId
+              ? {
+                  ...item,
+                  ...patch,
+                  id: item.id,
+                  tripId: item.tripId,
+                  updatedAt: new Date().toISOString(),
+                }
+              : item
+          ),
         })
         return {
           ...trip,
@@ -229,66 +347,33 @@ export function TripsProvider({ children }: { children: React.ReactNode }) {
           lastUpdated: new Date().toISOString().slice(0, 10),
         }
       })
-    )
-  }, [])
-
-  const updateTripItineraryItem = React.useCallback(
-    (id: string, itemId: string, patch: Partial<TripItineraryItem>) => {
-      setTrips((prev) =>
-        prev.map((trip) => {
-          if (trip.id !== id) return trip
-          const next = getTripItineraryItems({
-            ...trip,
-            itineraryItems: (trip.itineraryItems ?? []).map((item) =>
-              item.id === itemId
-                ? {
-                    ...item,
-                    ...patch,
-                    id: item.id,
-                    tripId: item.tripId,
-                    updatedAt: new Date().toISOString(),
-                  }
-                : item
-            ),
-          })
-          return {
-            ...trip,
-            itineraryItems: next,
-            itineraryDaysPlanned: computeDaysPlanned(next),
-            lastUpdated: new Date().toISOString().slice(0, 10),
-          }
-        })
-      )
     },
-    []
+    [changeTrip]
   )
 
   const deleteTripItineraryItem = React.useCallback((id: string, itemId: string) => {
-    setTrips((prev) =>
-      prev.map((trip) => {
-        if (trip.id !== id) return trip
-        const next = getTripItineraryItems({
-          ...trip,
-          itineraryItems: (trip.itineraryItems ?? []).filter((item) => item.id !== itemId),
-        })
-        return {
-          ...trip,
-          itineraryItems: next,
-          itineraryDaysPlanned: computeDaysPlanned(next),
-          lastUpdated: new Date().toISOString().slice(0, 10),
-        }
+    changeTrip(id, (trip) => {
+      const next = getTripItineraryItems({
+        ...trip,
+        itineraryItems: (trip.itineraryItems ?? []).filter((item) => item.id !== itemId),
       })
-    )
-  }, [])
+      return {
+        ...trip,
+        itineraryItems: next,
+        itineraryDaysPlanned: computeDaysPlanned(next),
+        lastUpdated: new Date().toISOString().slice(0, 10),
+      }
+    })
+  }, [changeTrip])
 
   const deleteTrip = React.useCallback((id: string) => {
-    setTrips((prev) => prev.filter((trip) => trip.id !== id))
+    commitTrips(tripsRef.current.filter((trip) => trip.id !== id))
     deleteTripInSupabase(id).catch((e) => {
       toast.error("Could not delete trip from cloud.", {
         description: e instanceof Error ? e.message : undefined,
       })
     })
-  }, [])
+  }, [commitTrips])
 
   const withFinanceMirrors = React.useCallback((trip: Trip, finance: TripFinance): Trip => {
     const summary = getFinanceSummary({ ...trip, finance })
@@ -321,23 +406,19 @@ export function TripsProvider({ children }: { children: React.ReactNode }) {
       options?: { runAutomation?: boolean }
     ) => {
       const shouldRunAutomation = options?.runAutomation ?? true
-      setTrips((prev) =>
-        prev.map((trip) => {
-          if (trip.id !== id) return trip
-          const current = getTripFinance(trip)
-          let nextFinance = updater(trip, current)
-          if (shouldRunAutomation) {
-            nextFinance = maybeRunFinanceAutomation(trip, nextFinance)
-          }
-          const mirrored = withFinanceMirrors(trip, nextFinance)
-          return {
-            ...mirrored,
-            lastUpdated: new Date().toISOString().slice(0, 10),
-          }
-        })
-      )
+      changeTrip(id, (trip) => {
+        let nextFinance = updater(trip, getTripFinance(trip))
+        if (shouldRunAutomation) {
+          nextFinance = maybeRunFinanceAutomation(trip, nextFinance)
+        }
+        const mirrored = withFinanceMirrors(trip, nextFinance)
+        return {
+          ...mirrored,
+          lastUpdated: new Date().toISOString().slice(0, 10),
+        }
+      })
     },
-    [maybeRunFinanceAutomation, withFinanceMirrors]
+    [changeTrip, maybeRunFinanceAutomation, withFinanceMirrors]
   )
 
   const setTripBudget = React.useCallback(
@@ -10,15 +10,70 @@ export type AiSource = {
   status: string
   fetchedAt: string
   detail?: string
+  ref?: string
+}
+
+export type AiHighlight = {
+  kind: "tip" | "risk" | "deadline" | "cost"
+  text: string
+}
+
+export type AiSuggestedAction = (
+  | { kind: "add_itinerary_item"; label: string; params: { title: string; date?: string; dayIndex?: number; timeBlock?: "morning" | "afternoon" | "evening"; category?: string } }
+  | { kind: "move_itinerary_item"; label: string; params: { itemId: string; date: string; dayIndex: number; timeBlock: "morning" | "afternoon" | "evening" } }
+  | { kind: "save_flight"; label: string; params: { flightNumber: string; date: string } }
+  | { kind: "add_expense"; label: string; params: { title: string; amount: number; currency: string; category: string; date: string } }
+  | { kind: "ask_missing_field"; label: string; params: { field: string } }
+) & { proposalId?: string }
+
+export type AiProposal = {
+  id: string
+  tripId: string
+  conversationId?: string
+  messageId?: string
+  userId: string
+  kind: "add_itinerary_item" | "move_itinerary_item" | "save_flight" | "add_expense"
+  label: string
+  change: Record<string, unknown>
+  preview: { table: string; before?: Record<string, unknown>; after: Record<string, unknown> }
+  status: "pending" | "approved" | "rejected"
+  resultId?: string
+  decidedBy?: string
+  decidedAt?: string
+  createdAt: string
+}
+
+export type AiPreferenceUpdate = {
+  id: string
+  userId: string
+  conversationId?: string
+  messageId?: string
+  preference: string
+  value: string
+  previous?: string
+  status: "pending" | "approved" | "rejected"
+  decidedAt?: string
+  createdAt: string
+}
+
+export type AiAnswerAttempt = {
+  index: number
+  provider: string
+  model?: string
+  failures?: { provider: string; model: string; reason: string; retryable: boolean }[]
 }
 
 export type AiChatResponse = {
   conversationId: string
   answer: string
-  highlights: string[]
-  suggestedActions: string[]
+  highlights: AiHighlight[]
+  suggestedActions: AiSuggestedAction[]
+  proposals?: AiProposal[]
+  preferenceUpdates?: AiPreferenceUpdate[]
   sources: AiSource[]
   degraded: boolean
+  degradedReason?: string
+  attempt?: AiAnswerAttempt
 }
 
 export type AiPageContext = {
@@ -52,7 +107,10 @@ export type PlannerChatResponse = {
   answer: string
   sources: AiSource[]
   degraded: boolean
+  degradedReason?: string
+  attempt?: AiAnswerAttempt
   plannerDraft?: PlannerDraft
+  preferenceUpdates?: AiPreferenceUpdate[]
 }
 
 type AiChatEnvelope = {
@@ -28,13 +28,15 @@ type TripFlightRow = {
   cost: string
   offer_id: string | null
   book_url: string | null
+  flight_number: string | null
 }
 
 function rowToSaved(row: TripFlightRow): SavedFlightRow {
   return {
     id: row.id,
     source: row.source,
-    route: row.route ?? "",
+    // Flights saved from an approved assistant proposal only know their flight number.
+    route: row.route || row.flight_number || "",
     date: row.flight_date ?? "",
     departure: row.departure ?? "",
     arrival: row.arrival ?? "",
@@ -59,7 +61,7 @@ export async function getTripFlightsFromSupabase(tripId: string): Promise<SavedF
 
   const { data: rows, error } = await supabase
     .from("trip_flights")
-    .select("id, trip_id, source, route, flight_date, departure, arrival, duration, stops, airline, cost, offer_id, book_url")
+    .select("id, trip_id, source, route, flight_date, departure, arrival, duration, stops, airline, cost, offer_id, book_url, flight_number")
     .eq("trip_id", tripId)
     .order("created_at", { ascending: false })
   if (error) return []
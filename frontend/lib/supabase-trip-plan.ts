@@ -0,0 +1,281 @@
+import { createClient } from "@/lib/supabase/client"
+import type {
+  ExpenseCategory,
+  ItineraryCategory,
+  ItineraryStatus,
+  ItineraryTimeBlock,
+  TripExpense,
+  TripExpenseSplit,
+  TripItineraryItem,
+} from "@/lib/trips"
+
+type ItineraryItemRow = {
+  id: string
+  trip_id: string
+  day_index: number
+  time_block: string
+  status: string
+  category: string
+  title: string
+  location_label: string
+  notes: string
+  sort_order: number
+  place_id: string | null
+  lat: number | null
+  lng: number | null
+  location_link: string | null
+  google_maps_link: string | null
+  commute_details: string | null
+  start_time_local: string | null
+  end_time_local: string | null
+  created_at: string
+  updated_at: string
+}
+
+type ExpenseRow = {
+  id: string
+  trip_id: string
+  expense_date: string
+  category: string
+  title: string
+  amount: number | string
+  currency: string
+  payer_name: string
+  notes: string
+  split_mode: string
+  splits_json: TripExpenseSplit[] | null
+  created_at: string
+  updated_at: string
+}
+
+const itineraryColumns =
+  "id, trip_id, day_index, time_block, status, category, title, location_label, notes, sort_order, place_id, lat, lng, location_link, google_maps_link, commute_details, start_time_local, end_time_local, created_at, updated_at"
+
+const expenseColumns =
+  "id, trip_id, expense_date, category, title, amount, currency, payer_name, notes, split_mode, splits_json, created_at, updated_at"
+
+// Rows added by approved assistant proposals have no location or payer; fall back so the
+// trip pages still show them.
+const UNASSIGNED_PAYER = "Unassigned"
+
+function rowToItem(row: ItineraryItemRow): TripItineraryItem {
+  return {
+    id: row.id,
+    tripId: row.trip_id,
+    dayIndex: row.day_index,
+    timeBlock: row.time_block as ItineraryTimeBlock,
+    status: row.status as ItineraryStatus,
+    category: row.category as ItineraryCategory,
+    title: row.title,
+    locationLabel: row.location_label || row.title,
+    placeId: row.place_id ?? undefined,
+    lat: row.lat ?? undefined,
+    lng: row.lng ?? undefined,
+    locationLink: row.location_link ?? undefined,
+    googleMapsLink: row.google_maps_link ?? undefined,
+    commuteDetails: row.commute_details ?? undefined,
+    notes: row.notes || undefined,
+    startTimeLocal: row.start_time_local ?? undefined,
+    endTimeLocal: row.end_time_local ?? undefined,
+    sortOrder: row.sort_order ?? 0,
+    createdAt: row.created_at,
+    updatedAt: row.updated_at,
+  }
+}
+
+function itemToRow(tripId: string, item: TripItineraryItem) {
+  return {
+    id: item.id,
+    trip_id: tripId,
+    day_index: item.dayIndex,
+    time_block: item.timeBlock,
+    status: item.status,
+    category: item.category,
+    title: item.title,
+    location_label: item.locationLabel,
+    notes: item.notes ?? "",
+    sort_order: item.sortOrder,
+    place_id: item.placeId ?? null,
+    lat: item.lat ?? null,
+    lng: item.lng ?? null,
+    location_link: item.locationLink ?? null,
+    google_maps_link: item.googleMapsLink ?? null,
+    commute_details: item.commuteDetails ?? null,
+    start_time_local: item.startTimeLocal ?? null,
+    end_time_local: item.endTimeLocal ?? null,
+    updated_at: item.updatedAt || new Date().toISOString(),
+  }
+}
+
+function rowToExpense(row: ExpenseRow): TripExpense {
+  return {
+    id: row.id,
+    tripId: row.trip_id,
+    date: row.expense_date,
+    category: row.category as ExpenseCategory,
+    title: row.title,
+    amount: Number(row.amount),
+    currency: row.currency,
+    payerName: row.payer_name || UNASSIGNED_PAYER,
+    splitMode: row.split_mode === "custom" ? "custom" : "equal",
+    splits: row.splits_json ?? undefined,
+    notes: row.notes || undefined,
+    createdAt: row.created_at,
+    updatedAt: row.updated_at,
+  }
+}
+
+function expenseToRow(tripId: string, expense: TripExpense) {
+  return {
+    id: expense.id,
+    trip_id: tripId,
+    expense_date: expense.date,
+    category: expense.category,
+    title: expense.title,
+    amount: expense.amount,
+    currency: expense.currency,
+    payer_name: expense.payerName,
+    notes: expense.notes ?? "",
+    split_mode: expense.splitMode,
+    splits_json: expense.splits ?? null,
+    updated_at: expense.updatedAt || new Date().toISOString(),
+  }
+}
+
+export type TripPlan = {
+  itineraryItems: TripItineraryItem[]
+  expenses: TripExpense[]
+}
+
+/**
+ * Load itinerary items and expenses for the given trips from the DB, keyed by trip id. These
+ * are the same tables approved assistant proposals write to. Returns {} if not signed in or
+ * on error.
+ */
+export async function getTripPlansFromSupabase(
+  tripIds: string[]
+): Promise<Record<string, TripPlan>> {
+  if (tripIds.length === 0) return {}
+  const supabase = createClient()
+  const {
+    data: { user },
+    error: userError,
+  } = await supabase.auth.getUser()
+  if (userError || !user) return {}
+
+  const [items, expenses] = await Promise.all([
+    supabase
+      .from("trip_itinerary_items")
+      .select(itineraryColumns)
+      .in("trip_id", tripIds)
+      .order("day_index", { ascending: true })
+      .order("sort_order", { ascending: true }),
+    supabase
+      .from("trip_expenses")
+      .select(expenseColumns)
+      .in("trip_id", tripIds)
+      .order("expense_date", { ascending: true }),
+  ])
+  if (items.error || expenses.error) return {}
+
+  const plans: Record<string, TripPlan> = {}
+  const planFor = (tripId: string) => {
+    if (!plans[tripId]) plans[tripId] = { itineraryItems: [], expenses: [] }
+    return plans[tripId]
+  }
+  for (const row of (items.data ?? []) as ItineraryItemRow[]) {
+    planFor(row.trip_id).itineraryItems.push(rowToItem(row))
+  }
+  for (const row of (expenses.data ?? []) as ExpenseRow[]) {
+    planFor(row.trip_id).expenses.push(rowToExpense(row))
+  }
+  return plans
+}
+
+/**
+ * Save itinerary items to the DB. Upserts by id, so the whole normalized list can be sent
+ * after a change moves several items. Does nothing when not signed in: the trip then only
+ * exists locally.
+ */
+export async function saveTripItineraryItemsToSupabase(
+  tripId: string,
+  items: TripItineraryItem[]
+): Promise<void> {
+  if (items.length === 0) return
+  const supabase = createClient()
+  const {
+    data: { user },
+  } = await supabase.auth.getUser()
+  if (!user) return
+
+  const { error } = await supabase
+    .from("trip_itinerary_items")
+    .upsert(
+      items.map((item) => itemToRow(tripId, item)),
+      { onConflict: "id" }
+    )
+  if (error) throw new Error(error.message)
+}
+
+/**
+ * Remove itinerary items from the DB.
+ */
+export async function deleteTripItineraryItemsFromSupabase(
+  tripId: string,
+  ids: string[]
+): Promise<void> {
+  if (ids.length === 0) return
+  const supabase = createClient()
+  const {
+    data: { user },
+  } = await supabase.auth.getUser()
+  if (!user) return
+
+  const { error } = await supabase
+    .from("trip_itinerary_items")
+    .delete()
+    .eq("trip_id", tripId)
+    .in("id", ids)
+  if (error) throw new Error(error.message)
+}
+
+/**
+ * Save an expense to the DB. Upserts by id (same id = replace). Does nothing when not
+ * signed in.
+ */
+export async function saveTripExpenseToSupabase(
+  tripId: string,
+  expense: TripExpense
+): Promise<void> {
+  const supabase = createClient()
+  const {
+    data: { user },
+  } = await supabase.auth.getUser()
+  if (!user) return
+
+  const { error } = await supabase
+    .from("trip_expenses")
+    .upsert(expenseToRow(tripId, expense), { onConflict: "id" })
+  if (error) throw new Error(error.message)
+}
+
+/**
+ * Remove an expense from the DB.
+ */
+export async function deleteTripExpenseFromSupabase(
+  tripId: string,
+  id: string
+): Promise<void> {
+  const supabase = createClient()
+  const {
+    data: { user },
+  } = await supabase.auth.getUser()
+  if (!user) return
+
+  const { error } = await supabase
+    .from("trip_expenses")
+    .delete()
+    .eq("trip_id", tripId)
+    .eq("id", id)
+  if (error) throw new Error(error.message)
+}